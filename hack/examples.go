@@ -142,7 +142,7 @@ func updateExample(ctx context.Context, example *Example) error {
 		}
 		example.Pod.Volumes = append(example.Pod.Volumes, types.Volume{
 			Name:     n,
-			HostPath: types.HostPath{Path: filepath.Join("volumes", example.Name, filepath.Base(volume))}})
+			HostPath: &types.HostPath{Path: filepath.Join("volumes", example.Name, filepath.Base(volume))}})
 	}
 
 	return nil
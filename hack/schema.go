@@ -5,16 +5,27 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 
 	"github.com/invopop/jsonschema"
 	"github.com/kitproj/kit/internal/types"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func updateSchema() error {
 	log.Println("updating schema")
 	r := new(jsonschema.Reflector)
 	_ = r.AddGoComments("github.com/kitproj/kit", "./")
+	r.Mapper = func(t reflect.Type) *jsonschema.Schema {
+		// quantities are marshalled as strings (e.g. "512Mi"), but may also be numbers (e.g. 2)
+		if t == reflect.TypeOf(resource.Quantity{}) {
+			return &jsonschema.Schema{OneOf: []*jsonschema.Schema{{Type: "string"}, {Type: "number"}}}
+		}
+		return nil
+	}
 	s := r.Reflect(types.Workflow{})
+	// a stable URL, so editors can fetch the schema of the latest version
+	s.ID = "https://raw.githubusercontent.com/kitproj/kit/main/schema/workflow.schema.json"
 	for i, definition := range s.Definitions {
 		definition.Title = i
 		s.Definitions[i] = definition
@@ -26,6 +37,16 @@ func updateSchema() error {
 			}
 		}
 	}
+	// environment variables values may be strings, or objects with a source
+	if value, ok := s.Definitions["EnvVarValue"]; ok {
+		object := *value
+		s.Definitions["EnvVarValue"] = &jsonschema.Schema{OneOf: []*jsonschema.Schema{{Type: "string"}, &object}, Title: value.Title, Description: value.Description}
+	}
+	// dependencies may be a list of tasks, or an object with allOf and anyOf
+	if value, ok := s.Definitions["Dependencies"]; ok {
+		object := *value
+		s.Definitions["Dependencies"] = &jsonschema.Schema{OneOf: []*jsonschema.Schema{{Ref: "#/$defs/Strings"}, &object}, Title: value.Title, Description: value.Description}
+	}
 	data, _ := json.MarshalIndent(s, "", "  ")
 	if err := os.WriteFile("schema/workflow.schema.json", data, 0o777); err != nil {
 		return fmt.Errorf("failed to write schema/workflow.schema.json: %w", err)
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// verifyArtifacts returns an error if any of the task's artifacts were not produced.
+func verifyArtifacts(t types.Task) error {
+	for _, artifact := range t.Artifacts {
+		if _, err := os.Stat(filepath.Join(t.WorkingDir, artifact)); err != nil {
+			return fmt.Errorf("artifact %q was not produced: %w", artifact, err)
+		}
+	}
+	return nil
+}
+
+// stageInputs copies the task's inputs from the working directories of the tasks that produce them.
+func stageInputs(tasks types.Tasks, t types.Task) error {
+	for _, input := range t.Inputs {
+		src := filepath.Join(tasks[input.Task].WorkingDir, input.Artifact)
+		dst := filepath.Join(t.WorkingDir, input.GetPath())
+		if _, err := os.Stat(src); err != nil {
+			return fmt.Errorf("input %q from %q is missing: %w", input.Artifact, input.Task, err)
+		}
+		// nothing to do if the tasks share a working directory
+		if filepath.Clean(src) == filepath.Clean(dst) {
+			continue
+		}
+		if err := copyPath(src, dst); err != nil {
+			return fmt.Errorf("failed to stage input %q from %q: %w", input.Artifact, input.Task, err)
+		}
+	}
+	return nil
+}
+
+// copyPath copies a file, or a directory recursively, replacing anything at dst.
+func copyPath(src, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			_ = out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// artifactsDigest returns a digest of the names and contents of the task's artifacts.
+func artifactsDigest(t types.Task) (string, error) {
+	h := sha256.New()
+	for _, artifact := range t.Artifacts {
+		root := filepath.Join(t.WorkingDir, artifact)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00", path)
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(h, f)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_artifacts(t *testing.T) {
+	producerDir, consumerDir := t.TempDir(), t.TempDir()
+	producer := types.Task{WorkingDir: producerDir, Artifacts: []string{"dist"}}
+	consumer := types.Task{WorkingDir: consumerDir, Inputs: []types.Input{{Task: "producer", Artifact: "dist", Path: "static"}}}
+	tasks := types.Tasks{"producer": producer, "consumer": consumer}
+
+	t.Run("Not produced", func(t *testing.T) {
+		assert.ErrorContains(t, verifyArtifacts(producer), `artifact "dist" was not produced`)
+		assert.ErrorContains(t, stageInputs(tasks, consumer), `input "dist" from "producer" is missing`)
+	})
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(producerDir, "dist", "js"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(producerDir, "dist", "js", "app.js"), []byte("v1"), 0o644))
+
+	t.Run("Produced", func(t *testing.T) {
+		assert.NoError(t, verifyArtifacts(producer))
+		assert.NoError(t, stageInputs(tasks, consumer))
+		data, err := os.ReadFile(filepath.Join(consumerDir, "static", "js", "app.js"))
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", string(data))
+	})
+
+	t.Run("Digest", func(t *testing.T) {
+		v1, err := artifactsDigest(producer)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(producerDir, "dist", "js", "app.js"), []byte("v2"), 0o644))
+		v2, err := artifactsDigest(producer)
+		assert.NoError(t, err)
+		assert.NotEqual(t, v1, v2)
+	})
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// isCI returns true if kit is running in a CI system, nearly all of them set CI=true.
+func isCI() bool {
+	return os.Getenv("CI") == "true"
+}
+
+// githubActions returns true if kit is running in GitHub Actions.
+func githubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// groupBuffer buffers a job's output, so it can be written as a single collapsible group in the GitHub Actions log,
+// rather than being interleaved with the output of other tasks. Services are not buffered, as they may never exit.
+type groupBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (g *groupBuffer) Write(p []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(p)
+}
+
+// flush returns the buffered output as a group.
+func (g *groupBuffer) flush(node *TaskNode) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := fmt.Sprintf("::group::%s (%s)\n%s::endgroup::\n", escapeData(node.Name), node.Snapshot().Phase, g.buf.String())
+	g.buf.Reset()
+	return out
+}
+
+// https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+var (
+	dataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeData(s string) string {
+	return dataEscaper.Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return propertyEscaper.Replace(s)
+}
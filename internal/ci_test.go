@@ -0,0 +1,15 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_groupBuffer(t *testing.T) {
+	g := &groupBuffer{}
+	_, _ = g.Write([]byte("hello\n"))
+	assert.Equal(t, "::group::build (succeeded)\nhello\n::endgroup::\n", g.flush(&TaskNode{Name: "build", PhaseMachine: types.PhaseMachine{Phase: "succeeded"}}))
+	assert.Equal(t, "::group::build (failed)\n::endgroup::\n", g.flush(&TaskNode{Name: "build", PhaseMachine: types.PhaseMachine{Phase: "failed", Message: "exit status 1\noops"}}))
+}
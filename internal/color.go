@@ -1,26 +1,113 @@
 package internal
 
-import "fmt" // an array of colors to use for the logs, we use the same color for the same task
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/kitproj/kit/internal/types"
+)
 
 // https://github.com/gawin/bash-colors-256
-// not too dark or light
+// high contrast colors with distinct hues, that are readable on both dark and light backgrounds
 var colors = []int{
-	34, 35, 36, 37, 38, 39,
-	70, 71, 72, 73, 74, 75,
-	106, 107, 108, 109, 110, 111,
-	142, 143, 144, 145, 146, 147,
-	178, 179, 180, 181, 182, 183,
-	214, 215, 216, 217, 218, 219,
+	33, 208, 40, 163, 37, 166, 99, 172,
+	31, 128, 64, 161, 25, 130, 29, 125,
+}
+
+// the basic colors, that are supported by every terminal
+var namedColors = map[string]int{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+	"gray":    8,
+	"grey":    8,
 }
 
-func color(x string) string {
-	return fmt.Sprintf("\x1b[38;5;%dm", code(x))
+// palette is the colors to use for the logs, we use the same color for the same task
+type palette struct {
+	codes map[string]int
+	// disabled is true if the NO_COLOR environment variable is set, see https://no-color.org
+	disabled bool
 }
 
-func code(x string) int {
-	code := 0
-	for _, x := range x {
-		code += int(x)
+// newPalette assigns each task a color. A task can choose its color, otherwise the color is chosen by hashing the
+// task's name, so it is the same between runs. If that color is taken, then the next free color is used, so tasks
+// have different colors (if there are not too many tasks).
+func newPalette(tasks types.Tasks) (*palette, error) {
+	p := &palette{codes: map[string]int{}, disabled: os.Getenv("NO_COLOR") != ""}
+	used := map[int]bool{}
+	var names []string
+	for name, t := range tasks {
+		if t.Color == "" {
+			names = append(names, name)
+			continue
+		}
+		code, err := parseColor(t.Color)
+		if err != nil {
+			return nil, fmt.Errorf("task %q has invalid color: %w", name, err)
+		}
+		p.codes[name] = code
+		used[code] = true
 	}
-	return colors[code%len(colors)]
+	// sort, so that the assignment is deterministic
+	sort.Strings(names)
+	for _, name := range names {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(name))
+		i := int(h.Sum32() % uint32(len(colors)))
+		for j := 0; j < len(colors) && used[colors[i]]; j++ {
+			i = (i + 1) % len(colors)
+		}
+		p.codes[name] = colors[i]
+		used[colors[i]] = true
+	}
+	return p, nil
+}
+
+// parseColor parses a color name (e.g. "red") or a 256 color code (e.g. "208").
+func parseColor(s string) (int, error) {
+	if code, ok := namedColors[s]; ok {
+		return code, nil
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 0 || code > 255 {
+		return 0, fmt.Errorf("%q must be a color name or a number between 0 and 255", s)
+	}
+	return code, nil
+}
+
+// color returns the escape sequence for the task's color.
+func (p *palette) color(name string) string {
+	if p.disabled {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm", p.codes[name])
+}
+
+// sgr returns the escape sequence to set the graphic rendition, e.g. bold or red.
+func (p *palette) sgr(params ...int) string {
+	if p.disabled {
+		return ""
+	}
+	s := "\x1b["
+	for i, param := range params {
+		if i > 0 {
+			s += ";"
+		}
+		s += strconv.Itoa(param)
+	}
+	return s + "m"
+}
+
+// reset returns the escape sequence to reset the color.
+func (p *palette) reset() string {
+	return p.sgr(0)
 }
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newPalette(t *testing.T) {
+	t.Run("Distinct", func(t *testing.T) {
+		tasks := types.Tasks{}
+		for _, name := range []string{"api", "web", "db", "worker", "queue", "cache", "build", "test"} {
+			tasks[name] = types.Task{}
+		}
+		p, err := newPalette(tasks)
+		assert.NoError(t, err)
+		used := map[int]bool{}
+		for _, code := range p.codes {
+			assert.False(t, used[code])
+			used[code] = true
+		}
+		// stable between runs
+		q, _ := newPalette(tasks)
+		assert.Equal(t, p.codes, q.codes)
+	})
+	t.Run("Configured", func(t *testing.T) {
+		p, err := newPalette(types.Tasks{"api": {Color: "red"}, "web": {Color: "208"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "\x1b[38;5;1m", p.color("api"))
+		assert.Equal(t, "\x1b[38;5;208m", p.color("web"))
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := newPalette(types.Tasks{"api": {Color: "256"}})
+		assert.EqualError(t, err, `task "api" has invalid color: "256" must be a color name or a number between 0 and 255`)
+	})
+	t.Run("NoColor", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "1")
+		p, err := newPalette(types.Tasks{"api": {}})
+		assert.NoError(t, err)
+		assert.Equal(t, "", p.color("api"))
+		assert.Equal(t, "", p.sgr(1, 31))
+	})
+}
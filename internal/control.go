@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// controlSocket returns the path of the socket that a running workflow is controlled with, in its state directory.
+func controlSocket(stateDir string) string {
+	return filepath.Join(stateDir, "control.sock")
+}
+
+// serveControl serves the control socket, so "kit stop" and "kit restart" can stop and restart tasks, and "kit debug"
+// can dump the diagnostics, until the context is cancelled.
+func serveControl(ctx context.Context, logger *log.Logger, socket string, dag DAG[*TaskNode], controls taskControls) error {
+	// the socket is left over if kit was killed, and we hold the instance lock, so no one else is using it
+	_ = os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /debug", func(w http.ResponseWriter, r *http.Request) {
+		path, err := controls.dump()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, path)
+	})
+	// the task is last, as the names of tasks from other workflows contain a slash
+	mux.HandleFunc("POST /{action}/{task...}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("task")
+		dag.RLock()
+		_, ok := dag.Nodes[name]
+		dag.RUnlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("task %q not found", name), http.StatusNotFound)
+			return
+		}
+		switch action := r.PathValue("action"); action {
+		case "stop":
+			controls.stop(name)
+		case "restart":
+			controls.restart(name)
+		case "faults-on", "faults-off":
+			if err := controls.faults(name, action == "faults-on"); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+		default:
+			http.Error(w, fmt.Sprintf("unknown action %q", r.PathValue("action")), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("control socket failed: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// ControlTask stops or restarts ("stop" or "restart"), or turns the faults of ("faults-on" or "faults-off"), a task in
+// the workflow running in this directory, in the namespace.
+func ControlTask(ctx context.Context, namespace, action, task string) error {
+	return controlTask(ctx, controlSocket(options{namespace: namespace}.stateDir()), action, task)
+}
+
+func controlTask(ctx context.Context, socket, action, task string) error {
+	resp, err := postControl(ctx, socket, "/"+action+"/"+task)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(resp.Body)
+		what := action
+		if state, ok := strings.CutPrefix(action, "faults-"); ok {
+			what = "turn " + state + " the faults of"
+		}
+		return fmt.Errorf("failed to %s %q: %s", what, task, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// DebugDump asks the workflow running in this directory, in the namespace, to dump its diagnostics, and returns the
+// path of the file they were written to.
+func DebugDump(ctx context.Context, namespace string) (string, error) {
+	return debugDump(ctx, controlSocket(options{namespace: namespace}.stateDir()))
+}
+
+func debugDump(ctx context.Context, socket string) (string, error) {
+	resp, err := postControl(ctx, socket, "/debug")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to dump diagnostics: %s", strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// postControl posts to the path of the control socket.
+func postControl(ctx context.Context, socket, path string) (*http.Response, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	u := &url.URL{Scheme: "http", Host: "kit", Path: path}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to kit, is it running in this directory? %w", err)
+	}
+	return resp, nil
+}
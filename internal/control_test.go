@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_control(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	socket := filepath.Join(t.TempDir(), "control.sock")
+
+	assert.ErrorContains(t, controlTask(ctx, socket, "stop", "api"), "failed to connect to kit, is it running in this directory?")
+
+	dag := NewDAG[*TaskNode]("test")
+	dag.AddNode("api", &TaskNode{Name: "api"})
+	dag.AddNode("web/serve", &TaskNode{Name: "web/serve"})
+	var stopped, restarted []string
+	faults := false
+	controls := taskControls{
+		stop:    func(name string) { stopped = append(stopped, name) },
+		restart: func(name string) { restarted = append(restarted, name) },
+		faults: func(name string, on bool) error {
+			if name != "api" {
+				return fmt.Errorf("task %q has no faults", name)
+			}
+			faults = on
+			return nil
+		},
+		dump: func() (string, error) { return "/tmp/debug.txt", nil },
+	}
+	assert.NoError(t, serveControl(ctx, log.New(os.Stdout, "", 0), socket, dag, controls))
+
+	assert.NoError(t, controlTask(ctx, socket, "stop", "api"))
+	assert.NoError(t, controlTask(ctx, socket, "restart", "web/serve"))
+	assert.Equal(t, []string{"api"}, stopped)
+	assert.Equal(t, []string{"web/serve"}, restarted)
+
+	assert.NoError(t, controlTask(ctx, socket, "faults-on", "api"))
+	assert.True(t, faults)
+	assert.NoError(t, controlTask(ctx, socket, "faults-off", "api"))
+	assert.False(t, faults)
+	assert.EqualError(t, controlTask(ctx, socket, "faults-on", "web/serve"), `failed to turn on the faults of "web/serve": task "web/serve" has no faults`)
+
+	assert.EqualError(t, controlTask(ctx, socket, "stop", "missing"), `failed to stop "missing": task "missing" not found`)
+	assert.EqualError(t, controlTask(ctx, socket, "pause", "api"), `failed to pause "api": unknown action "pause"`)
+
+	path, err := debugDump(ctx, socket)
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/debug.txt", path)
+}
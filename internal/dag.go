@@ -1,8 +1,15 @@
 package internal
 
+import (
+	"sort"
+	"sync"
+)
+
 // describe a directed acyclic graph
 
 type DAG[Node any] struct {
+	// guards the graph, as tasks can be added and removed while it is read
+	*sync.RWMutex `json:"-"`
 	// Name of the graph
 	Name string `json:"name"`
 	// Nodes in the graph
@@ -15,6 +22,7 @@ type DAG[Node any] struct {
 
 func NewDAG[Node any](name string) DAG[Node] {
 	return DAG[Node]{
+		RWMutex:  &sync.RWMutex{},
 		Name:     name,
 		Nodes:    make(map[string]Node),
 		Children: make(map[string][]string),
@@ -50,3 +58,46 @@ func (d *DAG[Node]) Subgraph(nodeNames []string) map[string]bool {
 	}
 	return visited
 }
+
+// Cycle returns a cycle in the graph as the chain of nodes, starting and ending with the same node, or nil if there is
+// none.
+func (d *DAG[Node]) Cycle() []string {
+	var names []string
+	for name := range d.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// nodes on the current path are visiting, nodes whose descendants have all been visited are done
+	const visiting, done = 1, 2
+	state := map[string]int{}
+	var path []string
+	var visit func(string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, child := range d.Children[name] {
+			if cycle := visit(child); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range names {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -21,3 +22,19 @@ func TestDAG_Subgraph(t *testing.T) {
 		t.Fatalf("expected c in subgraph")
 	}
 }
+
+func TestDAG_Cycle(t *testing.T) {
+	d := NewDAG[int]("")
+	d.AddNode("a", 1)
+	d.AddNode("b", 2)
+	d.AddNode("c", 3)
+	d.AddEdge("a", "b")
+	d.AddEdge("b", "c")
+	if cycle := d.Cycle(); cycle != nil {
+		t.Fatalf("expected no cycle, got %v", cycle)
+	}
+	d.AddEdge("c", "b")
+	if cycle := strings.Join(d.Cycle(), " → "); cycle != "b → c → b" {
+		t.Fatalf("expected b → c → b, got %v", cycle)
+	}
+}
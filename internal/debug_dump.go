@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// diagnostics is what kit is doing that is not in the status of the tasks, i.e. the files it is watching and the
+// restarts it is waiting to do, so a hang can be diagnosed.
+type diagnostics struct {
+	mu sync.Mutex
+	// the paths each task's watcher is registered for
+	watches map[string][]string
+	// when each task that is backing off will be restarted
+	backoffs map[string]time.Time
+}
+
+func newDiagnostics() *diagnostics {
+	return &diagnostics{watches: map[string][]string{}, backoffs: map[string]time.Time{}}
+}
+
+// watching records the paths the task's watcher is registered for, nil if it has no watcher.
+func (d *diagnostics) watching(task string, paths []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if paths == nil {
+		delete(d.watches, task)
+	} else {
+		d.watches[task] = paths
+	}
+}
+
+// backoff records when the task will be restarted, zero once it has been (or will not be).
+func (d *diagnostics) backoff(task string, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if until.IsZero() {
+		delete(d.backoffs, task)
+	} else {
+		d.backoffs[task] = until
+	}
+}
+
+// the number of lines of each task's recent output in a dump
+const dumpLines = 20
+
+// write writes the status of every task, and its recent output, the watchers, the pending backoffs, and the stacks of
+// every goroutine.
+func (d *diagnostics) write(w io.Writer, dag DAG[*TaskNode], now time.Time) error {
+	_, _ = fmt.Fprintf(w, "kit debug dump of %q (pid %d) at %s\n", dag.Name, os.Getpid(), now.Format(time.RFC3339))
+
+	_, _ = fmt.Fprintf(w, "\n== tasks ==\n")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tPHASE\tRESTARTS\tMESSAGE")
+	dag.RLock()
+	var names []string
+	for name := range dag.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	recent := map[string][]string{}
+	for _, name := range names {
+		node := dag.Nodes[name]
+		s := node.Snapshot()
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", name, s.Phase, node.restarts.Load(), s.Message)
+		recent[name] = node.recent.Tail(dumpLines)
+	}
+	dag.RUnlock()
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(w, "\n== recent output ==\n")
+	for _, name := range names {
+		for _, line := range recent[name] {
+			_, _ = fmt.Fprintf(w, "[%s] %s\n", name, line)
+		}
+	}
+
+	d.mu.Lock()
+	_, _ = fmt.Fprintf(w, "\n== watches ==\n")
+	for _, name := range sortedKeys(d.watches) {
+		_, _ = fmt.Fprintf(w, "%s: %s\n", name, strings.Join(d.watches[name], ", "))
+	}
+	_, _ = fmt.Fprintf(w, "\n== backoffs ==\n")
+	for _, name := range sortedKeys(d.backoffs) {
+		until := d.backoffs[name]
+		_, _ = fmt.Fprintf(w, "%s: restarting at %s (in %v)\n", name, until.Format(time.RFC3339), until.Sub(now).Round(time.Millisecond))
+	}
+	d.mu.Unlock()
+
+	_, _ = fmt.Fprintf(w, "\n== goroutines ==\n")
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dump writes the diagnostics to a new file in the state directory, and returns its path.
+func (d *diagnostics) dump(stateDir string, dag DAG[*TaskNode]) (string, error) {
+	now := time.Now()
+	path := filepath.Join(stateDir, fmt.Sprintf("debug-%s.txt", now.Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create debug dump: %w", err)
+	}
+	if err := d.write(f, dag, now); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write debug dump: %w", err)
+	}
+	return path, f.Close()
+}
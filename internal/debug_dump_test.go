@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/ring"
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diagnostics(t *testing.T) {
+	dag := NewDAG[*TaskNode]("test")
+	api := &TaskNode{Name: "api", PhaseMachine: types.PhaseMachine{Phase: "running"}}
+	api.restarts.Store(2)
+	dag.AddNode("api", api)
+	recent := ring.New(100)
+	_, _ = recent.Write([]byte("connecting\nconnection refused\n"))
+	dag.AddNode("db", &TaskNode{Name: "db", PhaseMachine: types.PhaseMachine{Phase: "failed", Message: "exit code 1"}, recent: recent})
+	d := newDiagnostics()
+	d.watching("api", []string{"src", "go.mod"})
+	d.watching("db", []string{"schema.sql"})
+	d.watching("db", nil)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	d.backoff("db", now.Add(2*time.Second))
+
+	path, err := d.dump(t.TempDir(), dag)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	dump := string(data)
+
+	assert.Contains(t, dump, `kit debug dump of "test"`)
+	assert.Contains(t, dump, "NAME  PHASE    RESTARTS  MESSAGE\napi   running  2         \ndb    failed   0         exit code 1\n")
+	assert.Contains(t, dump, "== recent output ==\n[db] connecting\n[db] connection refused\n\n")
+	assert.Contains(t, dump, "== watches ==\napi: src, go.mod\n\n")
+	assert.Contains(t, dump, "== backoffs ==\ndb: restarting at 2026-01-02T03:04:07Z")
+	// the stacks include the test's own goroutine
+	assert.True(t, strings.Contains(dump, "== goroutines ==\ngoroutine ") && strings.Contains(dump, "Test_diagnostics"))
+}
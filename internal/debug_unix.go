@@ -0,0 +1,11 @@
+//go:build !windows
+
+package internal
+
+import (
+	"os"
+	"syscall"
+)
+
+// debugSignals are the signals that dump the diagnostics, e.g. "kill -USR1 <pid>".
+var debugSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,6 @@
+package internal
+
+import "os"
+
+// debugSignals are the signals that dump the diagnostics, Windows has none, so use "kit debug".
+var debugSignals []os.Signal
@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsResolver answers queries for the tasks' names in the domain with 127.0.0.1 (or ::1), so services can be configured
+// with hostnames like those in a cluster.
+type dnsResolver struct {
+	domain string
+	// exists returns true if the hostname is a task's
+	exists func(hostname string) bool
+}
+
+// serve answers the queries received on the connection until the context is cancelled.
+func (r dnsResolver) serve(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		resp, err := r.answer(buf[:n])
+		if err != nil {
+			// not a query we understand, so there's no one to tell
+			continue
+		}
+		_, _ = conn.WriteTo(resp, addr)
+	}
+}
+
+// answer returns the response to the query.
+func (r dnsResolver) answer(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+	header := dnsmessage.Header{ID: h.ID, Response: true, OpCode: h.OpCode, Authoritative: true, RecursionDesired: h.RecursionDesired}
+	name := strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))
+	var answer func(b *dnsmessage.Builder) error
+	resource := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 5}
+	switch {
+	case name != r.domain && !strings.HasSuffix(name, "."+r.domain):
+		// we only resolve the domain, not a recursive resolver
+		header.RCode = dnsmessage.RCodeRefused
+	case !r.exists(name):
+		header.RCode = dnsmessage.RCodeNameError
+	case q.Type == dnsmessage.TypeA:
+		answer = func(b *dnsmessage.Builder) error {
+			return b.AResource(resource, dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+		}
+	case q.Type == dnsmessage.TypeAAAA:
+		answer = func(b *dnsmessage.Builder) error {
+			return b.AAAAResource(resource, dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}})
+		}
+	}
+	b := dnsmessage.NewBuilder(nil, header)
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if answer != nil {
+		if err := b.StartAnswers(); err != nil {
+			return nil, err
+		}
+		if err := answer(&b); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// updateHosts replaces the block of the hosts file for the domain and namespace with an entry for each hostname, or
+// removes it if there are none. The rest of the file, including the blocks of other instances of kit, is left as it is.
+// The file is replaced, rather than written in place, so a resolver never reads half of it.
+func updateHosts(path, domain, namespace string, hostnames []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	marker := "kit " + domain
+	if namespace != "" {
+		marker += " " + namespace
+	}
+	begin, end := "# begin "+marker, "# end "+marker
+	var lines []string
+	inBlock := false
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		switch strings.TrimSuffix(line, "\n") {
+		case begin:
+			inBlock = true
+		case end:
+			inBlock = false
+		default:
+			if !inBlock && line != "" {
+				lines = append(lines, strings.TrimSuffix(line, "\n"))
+			}
+		}
+	}
+	if len(hostnames) > 0 {
+		lines = append(lines, begin)
+		for _, hostname := range hostnames {
+			lines = append(lines, "127.0.0.1 "+hostname, "::1 "+hostname)
+		}
+		lines = append(lines, end)
+	}
+	out := strings.Join(lines, "\n")
+	if out != "" {
+		out += "\n"
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to update hosts file, its directory must be writable: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(out); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to update hosts file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to update hosts file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to update hosts file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to update hosts file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dnsResolver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	r := dnsResolver{domain: "kit.local", exists: func(hostname string) bool { return hostname == "api.kit.local" }}
+	go func() { _ = r.serve(ctx, conn) }()
+
+	resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "udp", conn.LocalAddr().String())
+	}}
+	ips, err := resolver.LookupIP(ctx, "ip4", "API.kit.local")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ips[0].String())
+	ips, err = resolver.LookupIP(ctx, "ip6", "api.kit.local")
+	assert.NoError(t, err)
+	assert.Equal(t, "::1", ips[0].String())
+
+	_, err = resolver.LookupIP(ctx, "ip4", "db.kit.local")
+	var dnsErr *net.DNSError
+	if assert.ErrorAs(t, err, &dnsErr) {
+		assert.True(t, dnsErr.IsNotFound)
+	}
+	_, err = resolver.LookupIP(ctx, "ip4", "example.com")
+	assert.Error(t, err)
+}
+
+func Test_updateHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	assert.NoError(t, os.WriteFile(path, []byte("127.0.0.1 localhost\n\n::1 localhost\n"), 0o644))
+
+	assert.NoError(t, updateHosts(path, "kit.local", "", []string{"api.kit.local"}))
+	assert.NoError(t, updateHosts(path, "kit.local", "", []string{"api.kit.local", "db.kit.local"}))
+	// another instance of kit, in a namespace, has its own block
+	assert.NoError(t, updateHosts(path, "kit.local", "test", []string{"api.kit.local"}))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `127.0.0.1 localhost
+
+::1 localhost
+# begin kit kit.local
+127.0.0.1 api.kit.local
+::1 api.kit.local
+127.0.0.1 db.kit.local
+::1 db.kit.local
+# end kit kit.local
+# begin kit kit.local test
+127.0.0.1 api.kit.local
+::1 api.kit.local
+# end kit kit.local test
+`, string(data))
+
+	assert.NoError(t, updateHosts(path, "kit.local", "", nil))
+	assert.NoError(t, updateHosts(path, "kit.local", "test", nil))
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n\n::1 localhost\n", string(data))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left")
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kitproj/kit/internal/types"
+	"k8s.io/utils/strings/slices"
+)
+
+// DryRun writes the plan to run the tasks, without running anything. The tasks are listed in the order they'd start:
+// each stage starts once the stage before it is ready. For each task, it lists whether it'd be skipped, the ports and
+// locks it'd use, and the tasks that would re-run when the files it watches change.
+func DryRun(w io.Writer, wf *types.Workflow, taskNames, tasksToSkip []string) error {
+	p, err := newPlan("", wf, taskNames, tasksToSkip)
+	if err != nil {
+		return err
+	}
+
+	// a task's stage is one more than the latest stage of its dependencies
+	stages := map[string]int{}
+	var stage func(string) int
+	stage = func(name string) int {
+		if s, ok := stages[name]; ok {
+			return s
+		}
+		s := 1
+		for _, parent := range p.dag.Parents[name] {
+			s = max(s, stage(parent)+1)
+		}
+		stages[name] = s
+		return s
+	}
+	var names []string
+	for name := range p.visited {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if si, sj := stage(names[i]), stage(names[j]); si != sj {
+			return si < sj
+		}
+		if pi, pj := wf.Tasks[names[i]].Priority, wf.Tasks[names[j]].Priority; pi != pj {
+			return pi > pj
+		}
+		return names[i] < names[j]
+	})
+
+	// when a task re-runs, so do the tasks that depend on it
+	reruns := func(name string) []string {
+		visited := map[string]bool{}
+		var visit func(string)
+		visit = func(name string) {
+			if visited[name] || !p.visited[name] {
+				return
+			}
+			visited[name] = true
+			for _, child := range p.dag.Children[name] {
+				visit(child)
+			}
+		}
+		visit(name)
+		var out []string
+		for _, name := range names {
+			if visited[name] {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STAGE\tTASK\tTYPE\tSKIP\tPORTS\tLOCKS\tWATCH\tRE-RUNS ON CHANGE")
+	for _, name := range names {
+		t := wf.Tasks[name]
+		skip := ""
+		switch {
+		case slices.Contains(p.tasksToSkip, name):
+			skip = "requested"
+		case t.Skip():
+			skip = "up to date"
+		}
+		var ports []string
+		for _, port := range t.GetHostPorts() {
+			ports = append(ports, strconv.Itoa(int(port)))
+		}
+		var locks []string
+		if t.Mutex != "" {
+			locks = append(locks, "mutex:"+t.Mutex)
+		}
+		if t.Semaphore != "" {
+			locks = append(locks, "semaphore:"+t.Semaphore)
+		}
+		var rerun []string
+		if len(t.Watch) > 0 {
+			rerun = reruns(name)
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", stage(name), name, t.GetType(), skip,
+			strings.Join(ports, ","), strings.Join(locks, ","), strings.Join(t.Watch, ","), strings.Join(rerun, ","))
+	}
+	return tw.Flush()
+}
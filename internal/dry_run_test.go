@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Run("Plan", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: types.Tasks{
+				"build": {Sh: "go build .", Watch: types.Strings{"testdata"}},
+				"db":    {Image: "postgres", Ports: []types.Port{{ContainerPort: 5432}}, Semaphore: "db"},
+				"api":   {Sh: "./api", Ports: []types.Port{{ContainerPort: 8080}}, Dependencies: &types.Dependencies{AllOf: types.Strings{"build", "db"}}},
+				"lint":  {Sh: "golangci-lint run"},
+				"docs":  {Sh: "mkdocs build"},
+			},
+		}
+		buf := &bytes.Buffer{}
+		assert.NoError(t, DryRun(buf, wf, []string{"api", "lint"}, []string{"lint"}))
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			lines = append(lines, strings.TrimRight(line, " "))
+		}
+		assert.Equal(t, []string{
+			"STAGE  TASK   TYPE     SKIP       PORTS  LOCKS         WATCH     RE-RUNS ON CHANGE",
+			"1      build  Job                                      testdata  build,api",
+			"1      db     Service             5432   semaphore:db",
+			"1      lint   Job      requested",
+			"2      api    Service             8080",
+		}, lines)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		assert.EqualError(t, DryRun(&bytes.Buffer{}, &types.Workflow{}, []string{"api"}, nil), `task "api" not found in workflow`)
+	})
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// envVar is an environment variable that a task is run with, and where it is set.
+type envVar struct {
+	name, value, source string
+}
+
+// taskEnv returns the environment variables the task is run with, in the order they are set, so later ones take
+// precedence. Secrets are not read, but masked.
+func taskEnv(wf *types.Workflow, name string) ([]envVar, error) {
+	t, ok := wf.Tasks[name]
+	if !ok {
+		return nil, fmt.Errorf("task %q not found", name)
+	}
+	var vars []envVar
+	add := func(environ []string, source string) {
+		for _, e := range environ {
+			k, v, _ := strings.Cut(e, "=")
+			vars = append(vars, envVar{name: k, value: v, source: source})
+		}
+	}
+	addEnv := func(env types.EnvVars, source string) {
+		var names []string
+		for k := range env {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			if v := env[k]; v.ValueFrom != nil {
+				vars = append(vars, envVar{name: k, value: "********", source: source + ", from " + v.ValueFrom.String()})
+			} else {
+				vars = append(vars, envVar{name: k, value: v.Value, source: source})
+			}
+		}
+	}
+	spec := types.Spec(*wf)
+	add(spec.NetworkEnviron(t), "network")
+	for _, f := range wf.Envfile {
+		environ, err := types.Envfile{f}.Environ("")
+		if err != nil {
+			return nil, err
+		}
+		add(environ, "envfile "+f)
+	}
+	addEnv(wf.Env, "workflow env")
+	for _, dependency := range t.GetDependencies() {
+		for _, o := range wf.Tasks[dependency].Outputs {
+			vars = append(vars, envVar{name: o.Name, source: "output of " + dependency + ", set when it runs"})
+		}
+	}
+	for _, f := range t.Envfile {
+		environ, err := types.Envfile{f}.Environ(t.WorkingDir)
+		if err != nil {
+			return nil, err
+		}
+		add(environ, "envfile "+f)
+	}
+	for _, source := range t.EnvFrom {
+		environ, err := source.Environ(t.WorkingDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read envFrom: %w", err)
+		}
+		add(environ, "envFrom")
+	}
+	// the outputs of other tasks are not known until they run
+	env, err := t.Env.ExpandTaskReferences(func(r types.TaskReference) (string, error) {
+		if r.Kind == "outputs" {
+			return r.String(), nil
+		}
+		return spec.ResolvePort(r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	addEnv(env, "task env")
+	add(types.HostEnviron(t), "host")
+	return vars, nil
+}
+
+// resolveTaskReference returns the value of a reference in a task's env to a fact about another task, i.e. its port,
+// or an output it has set.
+func resolveTaskReference(wf *types.Workflow, dag DAG[*TaskNode], r types.TaskReference) (string, error) {
+	if r.Kind != "outputs" {
+		return types.Spec(*wf).ResolvePort(r)
+	}
+	dag.RLock()
+	node, ok := dag.Nodes[r.Task]
+	dag.RUnlock()
+	if ok {
+		if v, ok := node.outputs.env()[r.Field]; ok {
+			return v.Value, nil
+		}
+	}
+	return "", fmt.Errorf("task %q has not set output %q", r.Task, r.Field)
+}
+
+// PrintEnv prints the environment variables the task is run with, sorted by name, with where each is set, and what it
+// overrides, e.g. to find out why a task works in your shell, but not under kit.
+func PrintEnv(w io.Writer, wf *types.Workflow, name string) error {
+	vars, err := taskEnv(wf, name)
+	if err != nil {
+		return err
+	}
+	effective := map[string]envVar{}
+	overrides := map[string][]string{}
+	for _, v := range vars {
+		if previous, ok := effective[v.name]; ok && previous.value != v.value {
+			overrides[v.name] = append(overrides[v.name], previous.source)
+		}
+		effective[v.name] = v
+	}
+	var names []string
+	for k := range effective {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		v := effective[k]
+		comment := v.source
+		if len(overrides[k]) > 0 {
+			comment += ", overrides " + strings.Join(overrides[k], ", ")
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s  # %s\n", k, v.value, comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
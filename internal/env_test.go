@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintEnv(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("LOG=info\nREGION=eu\n"), 0o644))
+	t.Setenv("REGION", "us")
+	wf := &types.Workflow{
+		Env: types.EnvVars{"LOG": {Value: "warn"}},
+		Tasks: types.Tasks{
+			"build": {Outputs: []types.Output{{Name: "VERSION"}}},
+			"api": {
+				WorkingDir:   dir,
+				Envfile:      types.Envfile{".env"},
+				Env:          types.EnvVars{"TOKEN": {ValueFrom: &types.EnvVarSource{Vault: "secret/data/dev#token"}}},
+				Dependencies: &types.Dependencies{AllOf: types.Strings{"build"}},
+			},
+			"db": {Image: "postgres", Env: types.EnvVars{"POSTGRES_PASSWORD": {Value: "password"}}},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, PrintEnv(buf, wf, "api"))
+	out := buf.String()
+	assert.Contains(t, out, "LOG=info  # envfile .env, overrides workflow env\n")
+	assert.Contains(t, out, "REGION=us  # host, overrides envfile .env\n")
+	assert.Contains(t, out, "TOKEN=********  # task env, from vault secret/data/dev#token\n")
+	assert.Contains(t, out, "VERSION=  # output of build, set when it runs\n")
+
+	t.Run("Container", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, PrintEnv(buf, wf, "db"))
+		assert.Equal(t, "LOG=warn  # workflow env\nPOSTGRES_PASSWORD=password  # task env\n", buf.String())
+	})
+	t.Run("Clean env", func(t *testing.T) {
+		wf.Tasks["job"] = types.Task{CleanEnv: true, Env: types.EnvVars{"REGION": {Value: "eu"}}}
+		buf := &bytes.Buffer{}
+		assert.NoError(t, PrintEnv(buf, wf, "job"))
+		assert.Equal(t, "LOG=warn  # workflow env\nREGION=eu  # task env\n", buf.String())
+	})
+	t.Run("Not found", func(t *testing.T) {
+		assert.EqualError(t, PrintEnv(&bytes.Buffer{}, wf, "web"), `task "web" not found`)
+	})
+}
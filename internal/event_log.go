@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// lifecycleEvent is a single entry in the event log.
+type lifecycleEvent struct {
+	Time time.Time `json:"time"`
+	// the task, empty for workflow events
+	Task string `json:"task,omitempty"`
+	// the type of event, e.g. "start" (of the workflow), "transition", "watch" or "restart"
+	Event string `json:"event"`
+	// for transitions, the previous and new phase
+	From  types.Phase `json:"from,omitempty"`
+	Phase types.Phase `json:"phase,omitempty"`
+	// a human-readable message, e.g. the reason for the transition
+	Message string `json:"message,omitempty"`
+}
+
+// eventLog is an append-only log of task lifecycle events (as JSON lines), so you can find out why a task restarted.
+// A nil event log does nothing.
+type eventLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openEventLog opens the event log for appending, creating it if it does not exist.
+func openEventLog(name string) (*eventLog, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for event log: %w", err)
+	}
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return &eventLog{file: file}, nil
+}
+
+// record appends the event, errors are ignored as the log is only for diagnostics.
+func (l *eventLog) record(e lifecycleEvent) {
+	if l == nil {
+		return
+	}
+	e.Time = time.Now()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.file.Write(append(data, '\n'))
+}
+
+func (l *eventLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_eventLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".kit", "events.jsonl")
+	for i := 0; i < 2; i++ {
+		l, err := openEventLog(name)
+		assert.NoError(t, err)
+		l.record(lifecycleEvent{Task: "api", Event: "transition", From: "starting", Phase: "running", Message: "readiness probe succeeded"})
+		assert.NoError(t, l.Close())
+	}
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, `^\{"time":".*","task":"api","event":"transition","from":"starting","phase":"running","message":"readiness probe succeeded"\}$`, lines[1])
+
+	var nilLog *eventLog
+	nilLog.record(lifecycleEvent{})
+	assert.NoError(t, nilLog.Close())
+}
@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// extensionEvent is written to an extension's stdin, as a line of JSON.
+type extensionEvent struct {
+	// "statusChange", "logLine" or "preStart"
+	Event   string      `json:"event"`
+	Task    string      `json:"task"`
+	Phase   types.Phase `json:"phase,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Line    string      `json:"line,omitempty"`
+}
+
+// extensionResponse is read from an extension's stdout, as a line of JSON, for each event.
+type extensionResponse struct {
+	// the line to log instead, for logLine events
+	Line *string `json:"line,omitempty"`
+	// do not log the line, for logLine events
+	Drop bool `json:"drop,omitempty"`
+	// stops the task starting, for preStart events
+	Error string `json:"error,omitempty"`
+	// a message for kit to log
+	Log string `json:"log,omitempty"`
+}
+
+// extension is a WebAssembly module, run in a sandbox for the whole session: it cannot access the network, nor any
+// files except its dir, nor run anything. It is sent each event, and must respond to it, before it is sent the next.
+type extension struct {
+	types.Extension
+	name    string
+	logger  *log.Logger
+	mu      sync.Mutex
+	runtime wazero.Runtime
+	// cancel stops the module, even if it is stuck, e.g. in a loop
+	cancel context.CancelFunc
+	// done is closed when the module exits
+	done   chan struct{}
+	stdin  *io.PipeWriter
+	output *io.PipeReader
+	stdout *bufio.Scanner
+	// once an extension fails, e.g. it crashed, it is not sent any more events
+	failed bool
+}
+
+// extensions are the workflow's extensions. A nil extensions does nothing.
+type extensions []*extension
+
+// startExtensions starts the workflow's extensions, which run until they are stopped.
+func startExtensions(logger *log.Logger, specs []types.Extension) (extensions, error) {
+	var started extensions
+	for _, spec := range specs {
+		e, err := startExtension(logger, spec)
+		if err != nil {
+			return started, fmt.Errorf("failed to start extension %q: %w", spec.Module, err)
+		}
+		started = append(started, e)
+	}
+	return started, nil
+}
+
+func startExtension(logger *log.Logger, spec types.Extension) (*extension, error) {
+	code, err := os.ReadFile(spec.Module)
+	if err != nil {
+		return nil, err
+	}
+	// not the context of the workflow, as the extension is sent the events of tasks being stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	compiled, err := func() (wazero.CompiledModule, error) {
+		if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+			return nil, err
+		}
+		return r.CompileModule(ctx, code)
+	}()
+	if err != nil {
+		cancel()
+		_ = r.Close(context.Background())
+		return nil, err
+	}
+	stdin, input := io.Pipe()
+	output, stdout := io.Pipe()
+	e := &extension{
+		Extension: spec,
+		name:      filepath.Base(spec.Module),
+		logger:    logger,
+		runtime:   r,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		stdin:     input,
+		output:    output,
+		stdout:    bufio.NewScanner(output),
+	}
+	e.stdout.Buffer(make([]byte, 64*1024), 1024*1024)
+	fs := wazero.NewFSConfig()
+	if spec.Dir != "" {
+		fs = fs.WithDirMount(spec.Dir, "/")
+	}
+	config := wazero.NewModuleConfig().
+		WithName(e.name).
+		WithArgs(e.name).
+		WithStdin(stdin).
+		WithStdout(stdout).
+		WithStderr(funcWriter(func(p []byte) (int, error) {
+			logger.Printf("[%s] %s", e.name, p)
+			return len(p), nil
+		})).
+		WithFSConfig(fs).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	go func() {
+		defer close(e.done)
+		// the module's _start function runs until it exits, e.g. at the end of its stdin
+		_, err := r.InstantiateModule(ctx, compiled, config)
+		exit := &sys.ExitError{}
+		if err != nil && ctx.Err() == nil && !(errors.As(err, &exit) && exit.ExitCode() == 0) {
+			logger.Printf("[%s] extension exited: %v\n", e.name, err)
+		}
+		// so a call waiting for a response fails, rather than waiting for its deadline
+		_ = stdout.Close()
+		_ = stdin.CloseWithError(io.ErrClosedPipe)
+	}()
+	return e, nil
+}
+
+// stop closes the extensions' stdin, so they exit, and stops any that have not exited after a few seconds.
+func (es extensions) stop() {
+	for _, e := range es {
+		_ = e.stdin.Close()
+		select {
+		case <-e.done:
+		case <-time.After(3 * time.Second):
+		}
+		e.cancel()
+		// a module writing a response that will not be read would never exit
+		_ = e.output.Close()
+		<-e.done
+		_ = e.runtime.Close(context.Background())
+	}
+}
+
+// call sends the event to the extension, and returns its response. An extension that does not respond before its
+// timeout is stopped, and not sent any more events, so it cannot hold up the tasks.
+func (e *extension) call(event extensionEvent) (extensionResponse, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failed {
+		return extensionResponse{}, false
+	}
+	type result struct {
+		response extensionResponse
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		response := extensionResponse{}
+		err := func() error {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if _, err := e.stdin.Write(append(data, '\n')); err != nil {
+				return err
+			}
+			if !e.stdout.Scan() {
+				if err := e.stdout.Err(); err != nil {
+					return err
+				}
+				return io.ErrUnexpectedEOF
+			}
+			return json.Unmarshal(e.stdout.Bytes(), &response)
+		}()
+		results <- result{response, err}
+	}()
+	timer := time.NewTimer(e.GetTimeout())
+	defer timer.Stop()
+	r := result{}
+	select {
+	case r = <-results:
+	case <-timer.C:
+		e.cancel()
+		r.err = fmt.Errorf("did not respond to %s within %v", event.Event, e.GetTimeout())
+	}
+	if r.err != nil {
+		e.failed = true
+		e.logger.Printf("[%s] extension failed, not sending it any more events: %v\n", e.name, r.err)
+		return extensionResponse{}, false
+	}
+	if r.response.Log != "" {
+		e.logger.Printf("[%s] %s\n", e.name, r.response.Log)
+	}
+	return r.response, true
+}
+
+// preStart returns an error if an extension stops the task starting.
+func (es extensions) preStart(task string) error {
+	for _, e := range es {
+		if !e.Wants("preStart", task) {
+			continue
+		}
+		if response, ok := e.call(extensionEvent{Event: "preStart", Task: task}); ok && response.Error != "" {
+			return fmt.Errorf("extension %s: %s", e.name, response.Error)
+		}
+	}
+	return nil
+}
+
+// statusChange sends the task's new status to the extensions.
+func (es extensions) statusChange(node *TaskNode) {
+	for _, e := range es {
+		if e.Wants("statusChange", node.Name) {
+			s := node.Snapshot()
+			e.call(extensionEvent{Event: "statusChange", Task: node.Name, Phase: s.Phase, Message: s.Message})
+		}
+	}
+}
+
+// logLine returns the line as changed by the extensions, or false if an extension drops it.
+func (es extensions) logLine(task, line string) (string, bool) {
+	for _, e := range es {
+		if !e.Wants("logLine", task) {
+			continue
+		}
+		response, ok := e.call(extensionEvent{Event: "logLine", Task: task, Line: line})
+		if !ok {
+			continue
+		}
+		if response.Drop {
+			return "", false
+		}
+		if response.Line != nil {
+			line = *response.Line
+		}
+	}
+	return line, true
+}
+
+// writer returns a writer that sends each line of the task's output to the extensions, before writing it to w. A
+// line is only written once it is finished.
+func (es extensions) writer(task string, w io.Writer) io.Writer {
+	wanted := false
+	for _, e := range es {
+		wanted = wanted || e.Wants("logLine", task)
+	}
+	if !wanted {
+		return w
+	}
+	mu := &sync.Mutex{}
+	var partial []byte
+	return funcWriter(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		partial = append(partial, p...)
+		out := &strings.Builder{}
+		for {
+			i := bytes.IndexByte(partial, '\n')
+			if i < 0 {
+				break
+			}
+			if line, ok := es.logLine(task, string(partial[:i])); ok {
+				out.WriteString(line + "\n")
+			}
+			partial = partial[i+1:]
+		}
+		if out.Len() > 0 {
+			if _, err := io.WriteString(w, out.String()); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	})
+}
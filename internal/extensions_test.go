@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// buildExtension builds the test extension for WASI.
+func buildExtension(t *testing.T) string {
+	module := filepath.Join(t.TempDir(), "redact.wasm")
+	cmd := exec.Command("go", "build", "-o", module, "./testdata/extension")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to build extension: %v: %s", err, out)
+	}
+	return module
+}
+
+func Test_extensions(t *testing.T) {
+	module := buildExtension(t)
+	buffer := &bytes.Buffer{}
+	logger := log.New(buffer, "", 0)
+	dir := t.TempDir()
+	exts, err := startExtensions(logger, []types.Extension{
+		{Module: module, Dir: dir},
+	})
+	assert.NoError(t, err)
+	defer exts.stop()
+
+	assert.NoError(t, exts.preStart("api"))
+	assert.EqualError(t, exts.preStart("blocked"), "extension redact.wasm: not allowed")
+
+	exts.statusChange(&TaskNode{Name: "api", PhaseMachine: types.PhaseMachine{Phase: "running"}})
+	assert.Contains(t, buffer.String(), "[redact.wasm] status changed")
+	status, err := os.ReadFile(filepath.Join(dir, "api"))
+	assert.NoError(t, err)
+	assert.Equal(t, "running", string(status))
+
+	out := &bytes.Buffer{}
+	w := exts.writer("api", out)
+	_, err = fmt.Fprint(w, "password=secret\ndebug: noisy\npart")
+	assert.NoError(t, err)
+	_, err = fmt.Fprint(w, "ial\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "password=******\npartial\n", out.String())
+
+	t.Run("Hung", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		exts, err := startExtensions(log.New(buffer, "", 0), []types.Extension{
+			{Module: module, Timeout: &metav1.Duration{Duration: 100 * time.Millisecond}},
+		})
+		assert.NoError(t, err)
+		defer exts.stop()
+		assert.NoError(t, exts.preStart("hung"))
+		assert.Contains(t, buffer.String(), "[redact.wasm] extension failed, not sending it any more events: did not respond to preStart within 100ms")
+		// it is not sent any more events, so it does not hold up the tasks
+		assert.NoError(t, exts.preStart("blocked"))
+	})
+	t.Run("Not found", func(t *testing.T) {
+		_, err := startExtensions(logger, []types.Extension{{Module: "missing.wasm"}})
+		assert.ErrorContains(t, err, `failed to start extension "missing.wasm"`)
+	})
+	t.Run("Not wanted", func(t *testing.T) {
+		exts := extensions{{Extension: types.Extension{Events: types.Strings{"preStart"}}}}
+		out := &bytes.Buffer{}
+		assert.Same(t, out, exts.writer("api", out))
+	})
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FailedTasksError is returned by RunSubgraph when tasks failed. Its exit code is the one the failed tasks exited
+// with, if they all exited with the same one, otherwise 1.
+type FailedTasksError struct {
+	Tasks    []string
+	ExitCode int
+	// the codes the failed tasks that exited with one exited with
+	ExitCodes map[string]int
+}
+
+func (e *FailedTasksError) Error() string {
+	var tasks []string
+	for _, name := range e.Tasks {
+		if code, ok := e.ExitCodes[name]; ok {
+			name = fmt.Sprintf("%s (exit code %d)", name, code)
+		}
+		tasks = append(tasks, name)
+	}
+	return "failed tasks: " + strings.Join(tasks, ", ")
+}
+
+// failedTasks returns an error listing the failed tasks, or nil if none failed.
+func failedTasks(dag DAG[*TaskNode]) error {
+	var failures []string
+	codes := map[int]bool{}
+	exitCodes := map[string]int{}
+	for name, node := range dag.Nodes {
+		if node.Snapshot().Phase == "failed" {
+			failures = append(failures, name)
+			codes[node.exitCode] = true
+			// a task that failed for another reason, e.g. its probe, may have exited with zero, or been killed
+			if node.exitCode > 0 {
+				exitCodes[name] = node.exitCode
+			}
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Strings(failures)
+	exitCode := 1
+	// a task that did not exit, e.g. its probe failed, has no exit code
+	if len(codes) == 1 {
+		for code := range codes {
+			exitCode = max(code, 1)
+		}
+	}
+	return &FailedTasksError{Tasks: failures, ExitCode: exitCode, ExitCodes: exitCodes}
+}
+
+// doomed returns true if the task will not complete, because it, or a task it depends on, failed and will not be
+// restarted.
+func doomed(dag DAG[*TaskNode], name string) bool {
+	node, ok := dag.Nodes[name]
+	if !ok {
+		return false
+	}
+	switch node.Snapshot().Phase {
+	case "failed":
+		return node.Task.GetRestartPolicy() == "Never"
+	case "pending":
+		// only one of the any-of dependencies needs to complete
+		anyOf, anyOfDoomed := 0, 0
+		for _, parent := range dag.Parents[name] {
+			isAnyOf := node.Task.Dependencies.IsAnyOf(parent)
+			if isAnyOf {
+				anyOf++
+			}
+			if !doomed(dag, parent) {
+				continue
+			}
+			if !isAnyOf {
+				return true
+			}
+			anyOfDoomed++
+		}
+		return anyOf > 0 && anyOfDoomed == anyOf
+	default:
+		return false
+	}
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_failedTasks(t *testing.T) {
+	dag := NewDAG[*TaskNode]("")
+	dag.AddNode("a", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "succeeded"}})
+	assert.NoError(t, failedTasks(dag))
+
+	dag.AddNode("b", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "failed"}, exitCode: 3})
+	assert.Equal(t, &FailedTasksError{Tasks: []string{"b"}, ExitCode: 3, ExitCodes: map[string]int{"b": 3}}, failedTasks(dag))
+
+	dag.AddNode("c", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "failed"}, exitCode: 3})
+	assert.Equal(t, &FailedTasksError{Tasks: []string{"b", "c"}, ExitCode: 3, ExitCodes: map[string]int{"b": 3, "c": 3}}, failedTasks(dag))
+
+	dag.AddNode("d", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "failed"}, exitCode: -1})
+	err := failedTasks(dag)
+	assert.Equal(t, &FailedTasksError{Tasks: []string{"b", "c", "d"}, ExitCode: 1, ExitCodes: map[string]int{"b": 3, "c": 3}}, err)
+	assert.EqualError(t, err, "failed tasks: b (exit code 3), c (exit code 3), d")
+}
+
+func Test_doomed(t *testing.T) {
+	dag := NewDAG[*TaskNode]("")
+	dag.AddNode("failed", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "failed"}})
+	dag.AddNode("retried", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "failed"}, Task: types.Task{RestartPolicy: "OnFailure"}})
+	dag.AddNode("running", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "running"}})
+	dag.AddNode("allOf", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "pending"}})
+	dag.AddEdge("running", "allOf")
+	dag.AddEdge("failed", "allOf")
+	dag.AddNode("anyOf", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "pending"}, Task: types.Task{Dependencies: &types.Dependencies{AnyOf: types.Strings{"failed", "retried"}}}})
+	dag.AddEdge("failed", "anyOf")
+	dag.AddEdge("retried", "anyOf")
+	dag.AddNode("child", &TaskNode{PhaseMachine: types.PhaseMachine{Phase: "pending"}})
+	dag.AddEdge("allOf", "child")
+
+	assert.True(t, doomed(dag, "failed"))
+	assert.False(t, doomed(dag, "retried"))
+	assert.False(t, doomed(dag, "running"))
+	assert.True(t, doomed(dag, "allOf"))
+	assert.False(t, doomed(dag, "anyOf"))
+	assert.True(t, doomed(dag, "child"))
+}
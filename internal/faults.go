@@ -0,0 +1,18 @@
+package internal
+
+import (
+	"github.com/kitproj/kit/internal/proxy"
+	"github.com/kitproj/kit/internal/types"
+)
+
+// proxyFaults returns the faults the router in front of the task injects.
+func proxyFaults(f types.Faults) *proxy.Faults {
+	p := &proxy.Faults{Bandwidth: f.Bandwidth, ErrorRate: f.ErrorRate}
+	if f.Latency != nil {
+		p.Latency = f.Latency.Duration
+	}
+	if f.Jitter != nil {
+		p.Jitter = f.Jitter.Duration
+	}
+	return p
+}
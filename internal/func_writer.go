@@ -1,7 +1,33 @@
 package internal
 
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
 type funcWriter func([]byte) (int, error)
 
 func (f funcWriter) Write(p []byte) (n int, err error) {
 	return f(p)
 }
+
+// lineWriter returns a writer that calls f with each line written, without its line ending, and writes it to w.
+func lineWriter(w io.Writer, f func(line string)) io.Writer {
+	mu := sync.Mutex{}
+	var partial []byte
+	return funcWriter(func(p []byte) (int, error) {
+		mu.Lock()
+		partial = append(partial, p...)
+		for {
+			i := bytes.IndexByte(partial, '\n')
+			if i < 0 {
+				break
+			}
+			f(string(bytes.TrimSuffix(partial[:i], []byte("\r"))))
+			partial = partial[i+1:]
+		}
+		mu.Unlock()
+		return w.Write(p)
+	})
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// service is kit running a workflow as a user-level service, started at login, e.g. for always-on databases.
+type service struct {
+	// the name of the service, unique to the workspace
+	name string
+	// the workspace
+	dir string
+	// the command to run kit
+	command []string
+	// the PATH to run kit with, as services are not started with the user's shell's PATH
+	path string
+}
+
+var nonName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// newService returns the service that runs kit in the directory, with the arguments.
+func newService(dir string, args []string) (service, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return service{}, err
+	}
+	kit, err := os.Executable()
+	if err != nil {
+		return service{}, fmt.Errorf("failed to find kit: %w", err)
+	}
+	return service{name: serviceName(dir), dir: dir, command: append([]string{kit}, args...), path: os.Getenv("PATH")}, nil
+}
+
+// serviceName returns the name of the service for the directory, e.g. "kit-api-1a2b3c4d". The hash means two
+// workspaces with the same name have different services.
+func serviceName(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return "kit-" + nonName.ReplaceAllString(filepath.Base(dir), "-") + "-" + hex.EncodeToString(sum[:4])
+}
+
+// systemdUnit returns the systemd unit for the service.
+func (s service) systemdUnit() string {
+	var args []string
+	for _, arg := range s.command {
+		args = append(args, systemdQuote(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=kit workflow in %s
+
+[Service]
+WorkingDirectory=%s
+Environment=%s
+ExecStart=%s
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`, systemdEscape(s.dir), systemdQuote(s.dir), systemdQuote("PATH="+s.path), strings.Join(args, " "))
+}
+
+// systemdEscape escapes the specifiers, e.g. "%h", that systemd would otherwise expand.
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote quotes the value, if needed, so systemd does not split it.
+func systemdQuote(s string) string {
+	s = systemdEscape(s)
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// launchdPlist returns the launchd property list for the service. Its output is written to a file in the state
+// directory, as launchd does not have a journal.
+func (s service) launchdPlist() string {
+	buf := &bytes.Buffer{}
+	escape := func(v string) string {
+		b := &bytes.Buffer{}
+		_ = xml.EscapeText(b, []byte(v))
+		return b.String()
+	}
+	_, _ = fmt.Fprintf(buf, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>%s</string>
+  <key>ProgramArguments</key>
+  <array>
+`, escape(s.name))
+	for _, arg := range s.command {
+		_, _ = fmt.Fprintf(buf, "    <string>%s</string>\n", escape(arg))
+	}
+	logFile := filepath.Join(s.dir, ".kit", "service.log")
+	_, _ = fmt.Fprintf(buf, `  </array>
+  <key>WorkingDirectory</key>
+  <string>%s</string>
+  <key>EnvironmentVariables</key>
+  <dict>
+    <key>PATH</key>
+    <string>%s</string>
+  </dict>
+  <key>RunAtLoad</key>
+  <true/>
+  <key>KeepAlive</key>
+  <dict>
+    <key>SuccessfulExit</key>
+    <false/>
+  </dict>
+  <key>StandardOutPath</key>
+  <string>%s</string>
+  <key>StandardErrorPath</key>
+  <string>%s</string>
+</dict>
+</plist>
+`, escape(s.dir), escape(s.path), escape(logFile), escape(logFile))
+	return buf.String()
+}
+
+// serviceFile returns the file the service is defined in, and the commands to start and stop it.
+func (s service) serviceFile() (string, [][]string, [][]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	switch runtime.GOOS {
+	case "linux":
+		file := filepath.Join(home, ".config", "systemd", "user", s.name+".service")
+		return file,
+			[][]string{{"systemctl", "--user", "daemon-reload"}, {"systemctl", "--user", "enable", "--now", s.name}},
+			[][]string{{"systemctl", "--user", "disable", "--now", s.name}},
+			nil
+	case "darwin":
+		file := filepath.Join(home, "Library", "LaunchAgents", s.name+".plist")
+		return file,
+			[][]string{{"launchctl", "load", "-w", file}},
+			[][]string{{"launchctl", "unload", "-w", file}},
+			nil
+	default:
+		return "", nil, nil, fmt.Errorf("installing a service is not supported on %s", runtime.GOOS)
+	}
+}
+
+// Install installs a user-level service (a systemd unit on Linux, or a launchd agent on macOS), that runs kit with the
+// arguments in the directory at login, and starts it.
+func Install(logger *log.Logger, dir string, args []string) error {
+	s, err := newService(dir, args)
+	if err != nil {
+		return err
+	}
+	file, start, _, err := s.serviceFile()
+	if err != nil {
+		return err
+	}
+	content := s.systemdUnit()
+	if runtime.GOOS == "darwin" {
+		content = s.launchdPlist()
+		if err := os.MkdirAll(filepath.Join(s.dir, ".kit"), 0o755); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write service: %w", err)
+	}
+	logger.Printf("wrote %s\n", file)
+	if err := runCommands(start); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	logger.Printf("started %s\n", s.name)
+	return nil
+}
+
+// Uninstall stops and removes the service installed for the directory.
+func Uninstall(logger *log.Logger, dir string) error {
+	s, err := newService(dir, nil)
+	if err != nil {
+		return err
+	}
+	file, _, stop, err := s.serviceFile()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); err != nil {
+		return fmt.Errorf("service %s is not installed: %w", s.name, err)
+	}
+	if err := runCommands(stop); err != nil {
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+	if err := os.Remove(file); err != nil {
+		return fmt.Errorf("failed to remove service: %w", err)
+	}
+	logger.Printf("removed %s\n", file)
+	return nil
+}
+
+func runCommands(commands [][]string) error {
+	for _, command := range commands {
+		out, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %w: %s", strings.Join(command, " "), err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
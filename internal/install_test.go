@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_serviceName(t *testing.T) {
+	assert.Regexp(t, `^kit-my-app-[0-9a-f]{8}$`, serviceName("/home/alex/my app"))
+	assert.NotEqual(t, serviceName("/home/alex/api"), serviceName("/home/sam/api"))
+}
+
+func Test_service(t *testing.T) {
+	s := service{
+		name:    "kit-api-1a2b3c4d",
+		dir:     "/home/alex/my api",
+		command: []string{"/usr/local/bin/kit", "-f", "/home/alex/my api/tasks.yaml", "db"},
+		path:    "/usr/local/bin:/usr/bin",
+	}
+	t.Run("systemd", func(t *testing.T) {
+		assert.Equal(t, `[Unit]
+Description=kit workflow in /home/alex/my api
+
+[Service]
+WorkingDirectory="/home/alex/my api"
+Environment=PATH=/usr/local/bin:/usr/bin
+ExecStart=/usr/local/bin/kit -f "/home/alex/my api/tasks.yaml" db
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`, s.systemdUnit())
+	})
+	t.Run("launchd", func(t *testing.T) {
+		plist := s.launchdPlist()
+		assert.Contains(t, plist, "<key>Label</key>\n  <string>kit-api-1a2b3c4d</string>")
+		assert.Contains(t, plist, "    <string>/home/alex/my api/tasks.yaml</string>\n    <string>db</string>\n  </array>")
+		assert.Contains(t, plist, "<string>/home/alex/my api/.kit/service.log</string>")
+	})
+	t.Run("Quote", func(t *testing.T) {
+		assert.Equal(t, `"say \"hi\" 100%%"`, systemdQuote(`say "hi" 100%`))
+	})
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kitproj/kit/internal/util"
+)
+
+// lockInstance locks the workflow's state directory, so that a second instance of it cannot run in the same workspace,
+// where they'd fight over ports. The PID of the instance is written to the directory, so we can say which it is.
+func lockInstance(stateDir, name string) (func(), error) {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	pidFile := filepath.Join(stateDir, "pid")
+	unlock, err := util.TryLockFile(filepath.Join(stateDir, "lock"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock workflow: %w", err)
+	}
+	if unlock == nil {
+		pid := "unknown"
+		if data, err := os.ReadFile(pidFile); err == nil {
+			pid = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("workflow %q is already running in this directory (PID %s), stop it, or use --namespace to run another copy", name, pid)
+	}
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to write PID: %w", err)
+	}
+	return func() {
+		_ = os.Remove(pidFile)
+		unlock()
+	}, nil
+}
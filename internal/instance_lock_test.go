@@ -0,0 +1,23 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lockInstance(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := lockInstance(dir, "my-workflow")
+	assert.NoError(t, err)
+
+	_, err = lockInstance(dir, "my-workflow")
+	assert.EqualError(t, err, fmt.Sprintf(`workflow "my-workflow" is already running in this directory (PID %d), stop it, or use --namespace to run another copy`, os.Getpid()))
+
+	unlock()
+	unlock, err = lockInstance(dir, "my-workflow")
+	assert.NoError(t, err)
+	unlock()
+}
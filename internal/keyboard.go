@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/browser"
+)
+
+var (
+	keys     chan rune
+	keysOnce sync.Once
+)
+
+// stdinKeys returns the characters read from stdin. They are read by one goroutine, so that the picker and readKeys do
+// not steal each other's key presses. The channel is closed at the end of the input.
+func stdinKeys() <-chan rune {
+	keysOnce.Do(func() {
+		keys = make(chan rune)
+		go func() {
+			defer close(keys)
+			reader := bufio.NewReader(os.Stdin)
+			for {
+				r, _, err := reader.ReadRune()
+				if err != nil {
+					return
+				}
+				keys <- r
+			}
+		}()
+	})
+	return keys
+}
+
+// confirm asks the question on the terminal, and returns true unless the answer is no. If there is no terminal to ask
+// on, e.g. in CI, the answer is yes.
+func confirm(logger *log.Logger, question string) bool {
+	if !interactive() {
+		logger.Print(question + "yes")
+		return true
+	}
+	_, _ = fmt.Fprint(logger.Writer(), question)
+	line := ""
+	for r := range stdinKeys() {
+		if r == '\n' {
+			break
+		}
+		line += string(r)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "n", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+// taskControls are what the keyboard can do to the running tasks.
+type taskControls struct {
+	// restart re-runs the task
+	restart func(name string)
+	// stop stops the task, it is not restarted until it is re-run
+	stop func(name string)
+	// pauseWatching pauses or resumes re-running tasks when their watched files change, returning true if paused
+	pauseWatching func() bool
+	// faults turns the faults injected into the task's connections on or off
+	faults func(name string, on bool) error
+	// quit stops every task, and exits
+	quit func()
+	// dump writes the diagnostics to a file, returning its path
+	dump func() (string, error)
+}
+
+// the number of lines of a task's recent output the "o" key shows
+const recentLines = 200
+
+// readKeys reads key presses from the terminal, forever:
+//
+//   - "/" searches the logs of the tasks, and only shows new output that matches.
+//   - "t" only shows the output of some tasks.
+//   - "c" clears the search and task filter.
+//   - "r" restarts a task.
+//   - "s" stops a task.
+//   - "l" opens a task's log.
+//   - "o" shows the recent output of a task, e.g. one that failed, that has scrolled away.
+//   - "p" pauses or resumes watching files.
+//   - "q" quits.
+//
+// The task to restart, stop, or show the log or output of, defaults to the one last chosen.
+func readKeys(logger *log.Logger, filter *logFilter, palette *palette, dag DAG[*TaskNode], controls taskControls) {
+	keys := stdinKeys()
+	// prompt reads a line, with echo and line editing enabled
+	prompt := func(label string) string {
+		lineMode(true)
+		defer lineMode(false)
+		_, _ = fmt.Fprint(logger.Writer(), label)
+		line := ""
+		for r := range keys {
+			if r == '\n' {
+				break
+			}
+			line += string(r)
+		}
+		return strings.TrimSpace(line)
+	}
+	selected := ""
+	// selectTask prompts for a task, returning false if there is no such task
+	selectTask := func(action string) (*TaskNode, bool) {
+		label := action + " task: "
+		if selected != "" {
+			label = fmt.Sprintf("%s task (%s): ", action, selected)
+		}
+		name := prompt(label)
+		if name == "" {
+			name = selected
+		}
+		dag.RLock()
+		node, ok := dag.Nodes[name]
+		dag.RUnlock()
+		if !ok {
+			logger.Printf("no task named %q\n", name)
+			return nil, false
+		}
+		selected = name
+		return node, true
+	}
+	for r := range keys {
+		switch r {
+		case '/':
+			if err := filter.setSearch(prompt("/")); err != nil {
+				logger.Println(err)
+				continue
+			}
+			if filter.active() {
+				printMatches(logger, filter, palette, dag)
+			}
+		case 't':
+			filter.setTasks(strings.Split(prompt("tasks (comma separated): "), ","))
+		case 'c':
+			_ = filter.setSearch("")
+			filter.setTasks(nil)
+			logger.Println("cleared search and task filter")
+		case 'r':
+			if node, ok := selectTask("restart"); ok {
+				controls.restart(node.Name)
+			}
+		case 's':
+			if node, ok := selectTask("stop"); ok {
+				controls.stop(node.Name)
+			}
+		case 'l':
+			if node, ok := selectTask("open log of"); ok {
+				if err := browser.OpenFile(node.logFile); err != nil {
+					logger.Printf("failed to open log: %v\n", err)
+				}
+			}
+		case 'o':
+			if node, ok := selectTask("show output of"); ok {
+				for _, line := range node.recent.Tail(recentLines) {
+					logger.Printf("%s[%s]  %s%s\n", palette.color(node.Name), node.Name, line, palette.reset())
+				}
+			}
+		case 'p':
+			if controls.pauseWatching() {
+				logger.Println("paused watching files, press p to resume")
+			} else {
+				logger.Println("resumed watching files")
+			}
+		case 'q':
+			logger.Println("quitting")
+			controls.quit()
+			return
+		}
+	}
+}
+
+// printMatches prints the lines of the task logs that match the filter, so output is not lost to scrollback.
+func printMatches(logger *log.Logger, filter *logFilter, palette *palette, dag DAG[*TaskNode]) {
+	for _, node := range sortedNodes(dag) {
+		file, err := os.Open(node.logFile)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line, ok := filter.apply(node.Name, scanner.Text()); ok {
+				logger.Printf("%s[%s]  %s%s\n", palette.color(node.Name), node.Name, line, palette.reset())
+			}
+		}
+		_ = file.Close()
+	}
+	logger.Println("only showing matching output, press c to clear")
+}
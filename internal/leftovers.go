@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/types"
+)
+
+// leftovers records what each running task may leave running if kit is killed, e.g. the process group of "npm start",
+// so the next run of kit can stop it.
+type leftovers struct {
+	mu    sync.Mutex
+	file  string
+	tasks map[string]proc.Leftover
+}
+
+// loadLeftovers loads what the last run of kit recorded. If it exited cleanly, then there is nothing.
+func loadLeftovers(file string) *leftovers {
+	l := &leftovers{file: file, tasks: map[string]proc.Leftover{}}
+	if data, err := os.ReadFile(file); err == nil {
+		_ = json.Unmarshal(data, &l.tasks)
+	}
+	return l
+}
+
+// add records what the task may leave running.
+func (l *leftovers) add(name string, leftover proc.Leftover) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tasks[name] = leftover
+	return l.save()
+}
+
+// remove forgets the task's leftover, because it has stopped.
+func (l *leftovers) remove(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.tasks[name]; !ok {
+		return nil
+	}
+	delete(l.tasks, name)
+	return l.save()
+}
+
+func (l *leftovers) save() error {
+	if err := writeJSON(l.file, l.tasks); err != nil {
+		return fmt.Errorf("failed to save leftovers: %w", err)
+	}
+	return nil
+}
+
+// stop stops what the last run of kit left running, if confirm returns true, and then forgets it.
+func (l *leftovers) stop(ctx context.Context, logger *log.Logger, spec types.Spec, confirm func(question string) bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var names, descriptions []string
+	for name, leftover := range l.tasks {
+		running, err := leftover.Running(ctx, spec)
+		if err != nil {
+			logger.Printf("[%s] failed to check if %s is running: %v\n", name, leftover, err)
+		} else if running {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", l.tasks[name], name))
+	}
+	if len(names) > 0 && confirm(fmt.Sprintf("the last run of kit left %s running, stop them? [Y/n] ", strings.Join(descriptions, ", "))) {
+		for _, name := range names {
+			leftover := l.tasks[name]
+			logger.Printf("[%s] stopping %s, left running by the last run of kit\n", name, leftover)
+			if err := leftover.Stop(ctx, spec); err != nil {
+				logger.Printf("[%s] failed to stop %s: %v\n", name, leftover, err)
+			}
+		}
+	}
+	clear(l.tasks)
+	return l.save()
+}
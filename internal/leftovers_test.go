@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_leftovers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "leftovers.json")
+	l := loadLeftovers(file)
+	assert.NoError(t, l.add("api", proc.Leftover{PGID: 1 << 30, StartTime: "1"}))
+	assert.NoError(t, l.add("worker", proc.Leftover{PGID: 1<<30 + 1, StartTime: "1"}))
+	assert.NoError(t, l.remove("worker"))
+
+	l = loadLeftovers(file)
+	assert.Equal(t, map[string]proc.Leftover{"api": {PGID: 1 << 30, StartTime: "1"}}, l.tasks)
+
+	// the process is not running, so there is nothing to ask about
+	buffer := &bytes.Buffer{}
+	err := l.stop(context.Background(), log.New(buffer, "", 0), types.Spec{}, func(string) bool {
+		t.Fatal("should not ask")
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, buffer.String())
+	assert.Empty(t, loadLeftovers(file).tasks)
+}
@@ -0,0 +1,460 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kitproj/kit/internal"
+	"github.com/kitproj/kit/internal/types"
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+)
+
+// Problem is a problem found in a config file.
+type Problem struct {
+	// The position of the problem, zero if it's unknown.
+	Line, Column int
+	Message      string
+	// Warning is true if the config might not work, e.g. on some machines, rather than being wrong.
+	Warning bool
+}
+
+func (p Problem) String() string {
+	message := p.Message
+	if p.Warning {
+		message = "warning: " + message
+	}
+	if p.Line == 0 {
+		return message
+	}
+	return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, message)
+}
+
+type linter struct {
+	root     *yamlv3.Node
+	problems []Problem
+	// the task each task extends
+	extends map[string]string
+}
+
+// Lint validates the config file without running anything, returning any problems found. Dependencies on tasks in other
+// workflows (e.g. "other-repo/api") are not checked.
+func Lint(configFile string) ([]Problem, error) {
+	in, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configFile, err)
+	}
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(in, doc); err != nil {
+		return []Problem{{Message: err.Error()}}, nil
+	}
+	if len(doc.Content) == 0 {
+		return []Problem{{Message: "file is empty"}}, nil
+	}
+	l := &linter{root: doc.Content[0]}
+	l.checkFields(l.root, reflect.TypeOf(types.Workflow{}))
+	wf := &types.Workflow{}
+	if err := yaml.Unmarshal(in, wf); err != nil {
+		l.problems = append(l.problems, Problem{Message: err.Error()})
+	} else {
+		missing := false
+		l.extends = map[string]string{}
+		for name, t := range wf.Tasks {
+			l.extends[name] = t.Extends
+			if _, ok := wf.Tasks[t.Extends]; t.Extends != "" && !ok {
+				l.report(l.find("tasks", name, "extends"), "task %q extends %q, which is not defined", name, t.Extends)
+				missing = true
+			}
+			if err := t.ValidateKind(); err != nil {
+				l.report(l.find("tasks", name, "kind"), "task %q has invalid kind: %v", name, err)
+				missing = true
+			}
+		}
+		if err := (*types.Spec)(wf).ApplyExtends(); err != nil && !missing {
+			l.report(l.find("tasks"), "%v", err)
+		}
+		(*types.Spec)(wf).ApplyTaskDefaults()
+		l.checkWorkflow(filepath.Dir(configFile), wf)
+	}
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		return l.problems[i].Line < l.problems[j].Line
+	})
+	return l.problems, nil
+}
+
+// base returns the task that the task extends, directly or indirectly, that does not extend another.
+func (l *linter) base(name string) string {
+	for i := 0; i < len(l.extends) && l.extends[name] != ""; i++ {
+		name = l.extends[name]
+	}
+	return name
+}
+
+// report records a problem at the node.
+func (l *linter) report(node *yamlv3.Node, format string, args ...any) {
+	l.problems = append(l.problems, Problem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// warn records a warning at the node.
+func (l *linter) warn(node *yamlv3.Node, format string, args ...any) {
+	l.problems = append(l.problems, Problem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// find returns the node at the path of keys, or the deepest node found on the way.
+func (l *linter) find(path ...string) *yamlv3.Node {
+	node := l.root
+	for _, key := range path {
+		if node.Kind != yamlv3.MappingNode {
+			return node
+		}
+		found := false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				node, found = node.Content[i+1], true
+				break
+			}
+		}
+		if !found {
+			return node
+		}
+	}
+	return node
+}
+
+// checkFields reports the keys of mappings that are not fields of the type, as these are otherwise ignored or only
+// reported without a position.
+func (l *linter) checkFields(node *yamlv3.Node, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch node.Kind {
+	case yamlv3.AliasNode:
+		l.checkFields(node.Alias, t)
+	case yamlv3.MappingNode:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				field, ok := fields[key.Value]
+				if !ok {
+					l.report(key, "unknown field %q", key.Value)
+					continue
+				}
+				l.checkFields(value, field)
+			}
+		case reflect.Map:
+			for i := 1; i < len(node.Content); i += 2 {
+				l.checkFields(node.Content[i], t.Elem())
+			}
+		}
+	case yamlv3.SequenceNode:
+		if t.Kind() == reflect.Slice {
+			for _, item := range node.Content {
+				l.checkFields(item, t.Elem())
+			}
+		}
+	}
+}
+
+// jsonFields returns the types of the struct's fields by their JSON name, including those of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range jsonFields(embedded) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func (l *linter) checkWorkflow(dir string, wf *types.Workflow) {
+	var names []string
+	for name := range wf.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// a task can never start if it depends on a task that is not defined, or is in a cycle
+	dag := internal.NewDAG[bool]("")
+	broken := map[string]bool{}
+	for _, name := range names {
+		t := wf.Tasks[name]
+		dag.AddNode(name, true)
+		for _, dependency := range t.GetDependencies() {
+			if strings.Contains(dependency, "/") {
+				continue
+			}
+			if _, ok := wf.Tasks[dependency]; !ok {
+				l.report(l.find("tasks", name, "dependencies"), "task %q depends on %q, which is not defined", name, dependency)
+				broken[name] = true
+			}
+			dag.AddEdge(dependency, name)
+		}
+		if t.OnFailure != "" && !strings.Contains(t.OnFailure, "/") {
+			if _, ok := wf.Tasks[t.OnFailure]; !ok {
+				l.report(l.find("tasks", name, "onFailure"), "task %q has onFailure %q, which is not defined", name, t.OnFailure)
+			}
+		}
+	}
+	if cycle := dag.Cycle(); cycle != nil {
+		l.report(l.find("tasks", cycle[0], "dependencies"), "dependency cycle: %s", strings.Join(cycle, " → "))
+		for _, name := range cycle {
+			broken[name] = true
+		}
+	}
+	// the tasks that depend on a broken task can never start either
+	unreachable := map[string]bool{}
+	var visit func(string)
+	visit = func(name string) {
+		for _, child := range dag.Children[name] {
+			if !broken[child] && !unreachable[child] {
+				unreachable[child] = true
+				visit(child)
+			}
+		}
+	}
+	for name := range broken {
+		visit(name)
+	}
+	for _, name := range names {
+		if unreachable[name] {
+			l.report(l.find("tasks", name), "task %q is unreachable, as it depends on a task that can never start", name)
+		}
+	}
+
+	ports := map[uint16]string{}
+	mutexes := map[string][]string{}
+	for _, name := range names {
+		t := wf.Tasks[name]
+		l.checkProbe(name, "readinessProbe", t.ReadinessProbe)
+		if t.Ready != nil {
+			if t.ReadinessProbe != nil {
+				l.report(l.find("tasks", name, "ready"), "task %q has both ready and a readinessProbe, only one may be used", name)
+			}
+			if err := t.Ready.Validate(); err != nil {
+				l.report(l.find("tasks", name, "ready"), "task %q has invalid ready: %v", name, err)
+			}
+		}
+		l.checkProbe(name, "livenessProbe", t.LivenessProbe)
+		for _, port := range t.GetHostPorts() {
+			// a task that extends another is a variant of it, e.g. to debug it, so is not run with it
+			if other, ok := ports[port]; ok && l.base(other) != l.base(name) {
+				l.report(l.find("tasks", name, "ports"), "task %q uses port %d, which is also used by %q", name, port, other)
+				continue
+			}
+			ports[port] = name
+			// containers are published by the container runtime, which is usually root
+			if types.PrivilegedPort(port) && t.Image == "" {
+				l.warn(l.find("tasks", name, "ports"), "task %q uses port %d, which is privileged, so listening on it needs root on Linux", name, port)
+			}
+		}
+		if t.TLS != nil {
+			if err := t.TLS.Validate(); err != nil {
+				l.report(l.find("tasks", name, "tls"), "task %q has invalid tls: %v", name, err)
+			} else if types.PrivilegedPort(t.TLS.Port) {
+				l.warn(l.find("tasks", name, "tls", "port"), "task %q uses port %d for TLS, which is privileged, so listening on it needs root on Linux", name, t.TLS.Port)
+			}
+		}
+		switch t.RestartPolicy {
+		case "", "Always", "Never", "OnFailure", "OnWatch":
+		default:
+			l.report(l.find("tasks", name, "restartPolicy"), "task %q has invalid restartPolicy %q, must be Always, Never, OnFailure or OnWatch", name, t.RestartPolicy)
+		}
+		if t.Trigger != nil {
+			if err := t.Trigger.Validate(); err != nil {
+				l.report(l.find("tasks", name, "trigger"), "task %q has invalid trigger: %v", name, err)
+			}
+		}
+		if err := t.ValidateRollingRestart(); err != nil {
+			l.report(l.find("tasks", name, "rollingRestart"), "task %q has invalid rollingRestart: %v", name, err)
+		}
+		if err := t.ValidateFaults(); err != nil {
+			l.report(l.find("tasks", name, "faults"), "task %q has invalid faults: %v", name, err)
+		}
+		if t.PodLogs != nil {
+			if err := t.PodLogs.Validate(); err != nil {
+				l.report(l.find("tasks", name, "podLogs"), "task %q has invalid podLogs: %v", name, err)
+			}
+		}
+		if t.Plugin != nil {
+			if err := t.Plugin.Validate(); err != nil {
+				l.report(l.find("tasks", name, "plugin"), "task %q has invalid plugin: %v", name, err)
+			}
+		}
+		if t.WatchPackages != nil {
+			if err := t.WatchPackages.Validate(); err != nil {
+				l.report(l.find("tasks", name, "watchPackages"), "task %q has invalid watchPackages: %v", name, err)
+			}
+		}
+		if t.Image != "" && t.Build != nil {
+			l.report(l.find("tasks", name, "build"), "task %q has both image and build, but can only run an image or build one", name)
+		}
+		if t.Intercept != nil {
+			if err := t.Intercept.Validate(); err != nil {
+				l.report(l.find("tasks", name, "intercept"), "task %q has invalid intercept: %v", name, err)
+			} else if len(t.Ports) == 0 {
+				l.report(l.find("tasks", name, "intercept"), "task %q has intercept, but no ports to route the traffic to", name)
+			}
+		}
+		for _, code := range t.SuccessCodes {
+			if code < 0 {
+				l.report(l.find("tasks", name, "successCodes"), "task %q has invalid successCodes: %d is not an exit code", name, code)
+			}
+		}
+		if t.CleanEnv && t.Image != "" {
+			l.report(l.find("tasks", name, "cleanEnv"), "task %q has cleanEnv, but containers never inherit kit's environment", name)
+		}
+		if len(t.Once) > 0 && t.GetType() != types.TaskTypeJob {
+			l.report(l.find("tasks", name, "once"), "task %q has once, but only jobs can be applied once", name)
+		}
+		for _, r := range t.Env.TaskReferences() {
+			if strings.Contains(r.Task, "/") {
+				continue
+			}
+			if err := types.Spec(*wf).CheckTaskReference(name, t, r); err != nil {
+				l.report(l.find("tasks", name, "env"), "%v", err)
+			}
+		}
+		if t.Open != "" {
+			if err := checkOpen(wf, t.Open); err != nil {
+				l.report(l.find("tasks", name, "open"), "task %q has invalid open: %v", name, err)
+			}
+		}
+		if t.Semaphore != "" {
+			if _, ok := wf.Semaphores[t.Semaphore]; !ok {
+				l.report(l.find("tasks", name, "semaphore"), "task %q uses semaphore %q, which is not defined in semaphores", name, t.Semaphore)
+			}
+		}
+		if t.Mutex != "" {
+			mutexes[t.Mutex] = append(mutexes[t.Mutex], name)
+		}
+		workingDir, err := types.Spec(*wf).WorkingDir(dir, t)
+		if err != nil {
+			l.report(l.find("tasks", name, "root"), "task %q %v", name, err)
+			workingDir = filepath.Join(dir, t.WorkingDir)
+		} else if t.Image == "" {
+			// the directory may be made by a dependency, e.g. a clone
+			if err := types.CheckWorkingDir(workingDir); err != nil {
+				l.warn(l.find("tasks", name, "workingDir"), "task %q has invalid workingDir: %v", name, err)
+			}
+		}
+		for _, path := range t.Watch {
+			if _, err := os.Stat(filepath.Join(workingDir, path)); err != nil {
+				l.report(l.find("tasks", name, "watch"), "task %q watches %q, which does not exist", name, path)
+			}
+		}
+		for _, path := range t.Once {
+			if _, err := os.Stat(filepath.Join(workingDir, path)); err != nil {
+				l.report(l.find("tasks", name, "once"), "task %q applies %q once, which does not exist", name, path)
+			}
+		}
+	}
+	for _, u := range wf.Open {
+		if err := checkOpen(wf, u); err != nil {
+			l.report(l.find("open"), "invalid open: %v", err)
+		}
+	}
+	for _, e := range wf.Extensions {
+		if err := e.Validate(); err != nil {
+			l.report(l.find("extensions"), "extension %q is invalid: %v", e.Module, err)
+		}
+	}
+	if wf.DNS != nil {
+		if err := wf.DNS.Validate(); err != nil {
+			l.report(l.find("dns"), "dns is invalid: %v", err)
+		}
+	}
+	if err := types.CheckKitVersion(wf.MinKitVersion, ""); err != nil {
+		l.report(l.find("minKitVersion"), "%v", err)
+	}
+	if wf.Cache != nil {
+		if err := wf.Cache.Validate(); err != nil {
+			l.report(l.find("cache", "url"), "invalid cache: %v", err)
+		}
+	}
+	// a mutex is only useful if it's shared, so one that is not is probably a typo
+	for mutex, tasks := range mutexes {
+		if len(tasks) == 1 {
+			l.report(l.find("tasks", tasks[0], "mutex"), "mutex %q is only used by task %q", mutex, tasks[0])
+		}
+	}
+}
+
+func (l *linter) checkProbe(name, field string, probe *types.Probe) {
+	if probe == nil {
+		return
+	}
+	node := l.find("tasks", name, field)
+	actions := 0
+	for _, ok := range []bool{probe.TCPSocket != nil, probe.HTTPGet != nil, probe.LogMatch != ""} {
+		if ok {
+			actions++
+		}
+	}
+	switch {
+	case actions == 0:
+		l.report(node, "task %q has a %s without tcpSocket, httpGet or logMatch", name, field)
+	case actions > 1:
+		l.report(node, "task %q has a %s with more than one of tcpSocket, httpGet and logMatch", name, field)
+	}
+	if probe.HTTPGet != nil {
+		if err := probe.HTTPGet.Validate(); err != nil {
+			l.report(node, "task %q has a %s with %v", name, field, err)
+		}
+	}
+	if probe.LogMatch != "" {
+		if field != "readinessProbe" {
+			l.report(node, "task %q has a %s with logMatch, which is only for readiness probes", name, field)
+		} else if _, err := regexp.Compile(probe.LogMatch); err != nil {
+			l.report(node, "task %q has invalid logMatch: %v", name, err)
+		}
+	}
+	if probe.InitialDelaySeconds < 0 || probe.PeriodSeconds < 0 || probe.SuccessThreshold < 0 || probe.FailureThreshold < 0 || probe.TimeoutSeconds < 0 {
+		l.report(node, "task %q has a %s with negative settings", name, field)
+	}
+}
+
+// checkOpen returns an error if the URL to open is invalid, or references the ports of a task without any, other than
+// a task in another workflow.
+func checkOpen(wf *types.Workflow, u string) error {
+	if err := types.ValidateOpen(u); err != nil {
+		return err
+	}
+	for _, r := range types.FindTaskReferences(u) {
+		if strings.Contains(r.Task, "/") {
+			continue
+		}
+		if _, err := types.Spec(*wf).ResolvePort(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
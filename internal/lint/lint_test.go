@@ -0,0 +1,198 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lint(t *testing.T, config string) []string {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tasks.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(config), 0o644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0o755))
+	problems, err := Lint(file)
+	assert.NoError(t, err)
+	var out []string
+	for _, p := range problems {
+		out = append(out, p.String())
+	}
+	return out
+}
+
+func TestLint(t *testing.T) {
+	t.Run("No problems", func(t *testing.T) {
+		assert.Empty(t, lint(t, `
+semaphores:
+  db: 1
+tasks:
+  build:
+    command: go build .
+    watch: [src]
+  api:
+    command: go run .
+    ports: "8080"
+    dependencies: [build]
+    semaphore: db
+    readinessProbe: http://:8080/healthz
+`))
+	})
+	t.Run("Problems", func(t *testing.T) {
+		assert.Equal(t, []string{
+			`5:19: task "api" depends on "missing", which is not defined`,
+			`6:16: task "api" uses semaphore "dbs", which is not defined in semaphores`,
+			`7:12: mutex "lonely" is only used by task "api"`,
+			`8:12: task "api" watches "nope", which does not exist`,
+			`10:7: task "api" has a readinessProbe without tcpSocket, httpGet or logMatch`,
+			`11:7: unknown field "bogus"`,
+			`13:5: task "web" is unreachable, as it depends on a task that can never start`,
+			`14:12: task "web" uses port 8080, which is also used by "api"`,
+			`16:5: unknown field "envv"`,
+			`20:19: dependency cycle: a → b → a`,
+		}, lint(t, `tasks:
+  api:
+    command: go run .
+    ports: "8080"
+    dependencies: [missing]
+    semaphore: dbs
+    mutex: lonely
+    watch: [src, nope]
+    readinessProbe:
+      periodSeconds: 1
+      bogus: true
+  web:
+    command: npm start
+    ports: "8080"
+    dependencies: [a]
+    envv:
+      FOO: bar
+  a:
+    command: "true"
+    dependencies: [b]
+  b:
+    command: "true"
+    dependencies: [a]
+`))
+	})
+	t.Run("Extends", func(t *testing.T) {
+		assert.Equal(t, []string{`5:14: task "debug" extends "apii", which is not defined`}, lint(t, `tasks:
+  api:
+    command: go run .
+  debug:
+    extends: apii
+`))
+		assert.Empty(t, lint(t, `tasks:
+  api:
+    command: go run .
+    ports: 8080
+  debug:
+    extends: api
+    command: dlv debug
+  trace:
+    extends: api
+    env:
+      TRACE: "1"
+`), "variants of a task use its ports")
+		assert.Equal(t, []string{`2:3: task "a" extends itself: a → b → a`}, lint(t, `tasks:
+  a:
+    extends: b
+  b:
+    extends: a
+`))
+	})
+	t.Run("Kind", func(t *testing.T) {
+		assert.Equal(t, []string{`3:11: task "s3" has invalid kind: kind "minio" cannot be seeded`}, lint(t, `tasks:
+  s3:
+    kind: minio
+    seed: seed
+`))
+		assert.Empty(t, lint(t, `tasks:
+  aws:
+    kind: localstack
+`))
+	})
+	t.Run("Task references", func(t *testing.T) {
+		assert.Equal(t, []string{`7:7: task "api" references {{ ports.dbb.host }}, but "dbb" is not found`}, lint(t, `tasks:
+  db:
+    image: postgres
+    ports: 5432:15432
+  api:
+    env:
+      DATABASE_URL: postgres://localhost:{{ ports.dbb.host }}/app
+      WEB: http://localhost:{{ ports.web/serve.host }}
+`))
+	})
+	t.Run("Clean env", func(t *testing.T) {
+		assert.Equal(t, []string{`4:15: task "db" has cleanEnv, but containers never inherit kit's environment`}, lint(t, `tasks:
+  db:
+    image: postgres
+    cleanEnv: true
+`))
+	})
+	t.Run("Image and build", func(t *testing.T) {
+		assert.Equal(t, []string{`5:7: task "api" has both image and build, but can only run an image or build one`}, lint(t, `tasks:
+  api:
+    image: api
+    build:
+      context: .
+`))
+	})
+	t.Run("Privileged ports", func(t *testing.T) {
+		assert.Equal(t, []string{
+			`3:12: warning: task "api" uses port 80, which is privileged, so listening on it needs root on Linux`,
+			`6:13: warning: task "api" uses port 443 for TLS, which is privileged, so listening on it needs root on Linux`,
+		}, lint(t, `tasks:
+  api:
+    ports: "80"
+    command: go run .
+    tls:
+      port: 443
+  web:
+    image: nginx
+    ports: "80:8080"
+`))
+	})
+	t.Run("TLS", func(t *testing.T) {
+		assert.Equal(t, []string{`6:7: task "api" has invalid tls: port is required`}, lint(t, `tasks:
+  api:
+    ports: "8080"
+    command: go run .
+    tls:
+      hosts: [localhost]
+`))
+	})
+	t.Run("Working directories", func(t *testing.T) {
+		assert.Equal(t, []string{
+			`5:17: warning: task "web" has invalid workingDir: working directory "/nonexistent/frontend/app" does not exist`,
+			`8:11: task "api" has root "backend", which is not one of the workflow's roots`,
+		}, lint(t, `roots:
+  frontend: /nonexistent/frontend
+tasks:
+  web:
+    workingDir: app
+    root: frontend
+  api:
+    root: backend
+  build:
+    workingDir: src
+`))
+	})
+	t.Run("Open", func(t *testing.T) {
+		assert.Equal(t, []string{
+			`1:7: invalid open: "localhost:3000" must be an http or https URL`,
+			`5:11: task "web" has invalid open: task "api" has no ports`,
+		}, lint(t, `open: [localhost:3000]
+tasks:
+  web:
+    ports: "3000"
+    open: http://localhost:{{ ports.api.host }}
+  api:
+    command: go run .
+`))
+	})
+	t.Run("Invalid YAML", func(t *testing.T) {
+		assert.Equal(t, []string{"yaml: line 1: did not find expected node content"}, lint(t, "tasks: [\n"))
+	})
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// ListTasks writes a table of the tasks in the workflow, grouped by their group, with the ports they listen on, the
+// tasks they depend on, and their description, so someone new to the workflow can find the task they need.
+func ListTasks(w io.Writer, wf *types.Workflow) error {
+	var names []string
+	for name := range wf.Tasks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if gi, gj := wf.Tasks[names[i]].Group, wf.Tasks[names[j]].Group; gi != gj {
+			return gi < gj
+		}
+		return names[i] < names[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "GROUP\tTASK\tTYPE\tPORTS\tDEPENDENCIES\tDESCRIPTION")
+	for _, name := range names {
+		t := wf.Tasks[name]
+		var ports []string
+		for _, port := range t.GetHostPorts() {
+			ports = append(ports, strconv.Itoa(int(port)))
+		}
+		dependencies := t.GetDependencies()
+		sort.Strings(dependencies)
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Group, name, t.GetType(), strings.Join(ports, ","),
+			strings.Join(dependencies, ","), t.Description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListTasks(t *testing.T) {
+	wf := &types.Workflow{
+		Tasks: types.Tasks{
+			"db":    {Image: "postgres", Ports: []types.Port{{ContainerPort: 5432}}, Group: "backend", Description: "The database."},
+			"api":   {Sh: "./api", Ports: []types.Port{{ContainerPort: 8080}}, Group: "backend", Dependencies: &types.Dependencies{AllOf: types.Strings{"db", "build"}}},
+			"build": {Sh: "go build .", Description: "Build the API."},
+		},
+	}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, ListTasks(buf, wf))
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	assert.Equal(t, []string{
+		"GROUP    TASK   TYPE     PORTS  DEPENDENCIES  DESCRIPTION",
+		"         build  Job                           Build the API.",
+		"backend  api    Service  8080   build,db",
+		"backend  db     Service  5432                 The database.",
+	}, lines)
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// logFilter filters the task output shown in the console, by search pattern and by task. Matches are highlighted.
+type logFilter struct {
+	mu     sync.RWMutex
+	search *regexp.Regexp
+	// the tasks to show, if empty all tasks are shown
+	tasks map[string]bool
+}
+
+// setSearch sets the search pattern, an empty pattern clears the search. Like vim's smartcase, the search is case
+// insensitive unless the pattern contains upper case letters.
+func (f *logFilter) setSearch(pattern string) error {
+	var search *regexp.Regexp
+	if pattern != "" {
+		if strings.ToLower(pattern) == pattern {
+			pattern = "(?i)" + pattern
+		}
+		var err error
+		search, err = regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid search: %w", err)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.search = search
+	return nil
+}
+
+// setTasks only shows the output of the tasks, no tasks shows the output of all tasks.
+func (f *logFilter) setTasks(names []string) {
+	tasks := map[string]bool{}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			tasks[name] = true
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tasks = tasks
+}
+
+// apply returns the line with any matches highlighted, and whether it should be shown. A nil filter shows everything.
+func (f *logFilter) apply(task, line string) (string, bool) {
+	if f == nil {
+		return line, true
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.tasks) > 0 && !f.tasks[task] {
+		return line, false
+	}
+	if f.search == nil {
+		return line, true
+	}
+	if !f.search.MatchString(line) {
+		return line, false
+	}
+	// highlight using reverse video
+	return f.search.ReplaceAllString(line, "\033[7m${0}\033[27m"), true
+}
+
+// active returns true if the filter hides any output.
+func (f *logFilter) active() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.search != nil || len(f.tasks) > 0
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_logFilter(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		var f *logFilter
+		line, ok := f.apply("api", "hello")
+		assert.True(t, ok)
+		assert.Equal(t, "hello", line)
+	})
+	t.Run("Search", func(t *testing.T) {
+		f := &logFilter{}
+		assert.NoError(t, f.setSearch("error"))
+		assert.True(t, f.active())
+		_, ok := f.apply("api", "hello")
+		assert.False(t, ok)
+		line, ok := f.apply("api", "an Error occurred")
+		assert.True(t, ok)
+		assert.Equal(t, "an \033[7mError\033[27m occurred", line)
+	})
+	t.Run("SmartCase", func(t *testing.T) {
+		f := &logFilter{}
+		assert.NoError(t, f.setSearch("Error"))
+		_, ok := f.apply("api", "an error occurred")
+		assert.False(t, ok)
+	})
+	t.Run("InvalidSearch", func(t *testing.T) {
+		f := &logFilter{}
+		assert.EqualError(t, f.setSearch("("), "invalid search: error parsing regexp: missing closing ): `(?i)(`")
+	})
+	t.Run("Tasks", func(t *testing.T) {
+		f := &logFilter{}
+		f.setTasks([]string{"api", " web "})
+		_, ok := f.apply("api", "hello")
+		assert.True(t, ok)
+		_, ok = f.apply("web", "hello")
+		assert.True(t, ok)
+		_, ok = f.apply("db", "hello")
+		assert.False(t, ok)
+		f.setTasks(nil)
+		assert.False(t, f.active())
+	})
+}
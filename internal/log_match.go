@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// logMatcher is a readiness probe that waits for a line of the task's output to match, for dev servers that have no
+// health endpoint, but print a well-known line when they start.
+type logMatcher struct {
+	mu       sync.Mutex
+	pattern  *regexp.Regexp
+	matched  bool
+	callback func(ok bool, err error)
+}
+
+// newLogMatcher returns a matcher that calls back when a line matches, or fails if none matches before the probe's
+// failure threshold is reached, as a polling probe would.
+func newLogMatcher(ctx context.Context, probe types.Probe, callback func(ok bool, err error)) *logMatcher {
+	m := &logMatcher{pattern: regexp.MustCompile(probe.LogMatch), callback: callback}
+	timeout := probe.GetInitialDelay() + time.Duration(probe.GetFailureThreshold())*probe.GetPeriod()
+	timer := time.AfterFunc(timeout, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if !m.matched && ctx.Err() == nil {
+			m.matched = true
+			callback(false, fmt.Errorf("no line matched %q after %v", probe.LogMatch, timeout))
+		}
+	})
+	context.AfterFunc(ctx, func() { timer.Stop() })
+	return m
+}
+
+// writer returns a writer that matches each line written, and writes it to w.
+func (m *logMatcher) writer(w io.Writer) io.Writer {
+	var partial []byte
+	return funcWriter(func(p []byte) (int, error) {
+		m.mu.Lock()
+		if !m.matched {
+			partial = append(partial, p...)
+			for {
+				i := bytes.IndexByte(partial, '\n')
+				if i < 0 {
+					break
+				}
+				if m.pattern.Match(partial[:i]) {
+					m.matched = true
+					m.callback(true, nil)
+					break
+				}
+				partial = partial[i+1:]
+			}
+		}
+		m.mu.Unlock()
+		return w.Write(p)
+	})
+}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_logMatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	t.Run("Match", func(t *testing.T) {
+		var calls []bool
+		m := newLogMatcher(ctx, types.Probe{LogMatch: `Listening on port \d+`}, func(ok bool, err error) {
+			calls = append(calls, ok)
+		})
+		buf := &bytes.Buffer{}
+		w := m.writer(buf)
+		_, _ = w.Write([]byte("starting\nListening on "))
+		assert.Empty(t, calls)
+		_, _ = w.Write([]byte("port 8080\nListening on port 8080\n"))
+		assert.Equal(t, []bool{true}, calls)
+		assert.Equal(t, "starting\nListening on port 8080\nListening on port 8080\n", buf.String())
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		errs := make(chan error, 1)
+		newLogMatcher(ctx, types.Probe{LogMatch: "ready", InitialDelaySeconds: 1, FailureThreshold: 1, PeriodSeconds: 1}, func(ok bool, err error) {
+			errs <- err
+		})
+		assert.EqualError(t, <-errs, `no line matched "ready" after 2s`)
+	})
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// rotatingFile is a task's log file, that is rotated when it reaches its maximum size, e.g. "api.log" is renamed
+// "api.log.1", and the previous "api.log.1" is renamed "api.log.2".
+type rotatingFile struct {
+	path     string
+	rotation *types.LogRotation
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	// only regular files are rotated, not, e.g., /dev/null
+	regular bool
+}
+
+// createLog creates (or truncates) the log file.
+func createLog(path string, rotation *types.LogRotation) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, rotation: rotation}
+	if err := f.create(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) create() error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file, f.size, f.regular = file, 0, info.Mode().IsRegular()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if maxSize := f.rotation.GetMaxSize(); f.regular && maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate log: %w", err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Sync()
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// rotate renames the log file, and each rotated file, to the next number, deleting those beyond the retention, and
+// starts a new log file.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	maxFiles := f.rotation.GetMaxFiles()
+	if maxFiles > 0 {
+		for i := maxFiles - 1; i >= 1; i-- {
+			if err := os.Rename(rotatedLog(f.path, i), rotatedLog(f.path, i+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(f.path, rotatedLog(f.path, 1)); err != nil {
+			return err
+		}
+	}
+	// e.g. those older than the max age, or left over from when more files were kept
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(match, f.path+"."))
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(match)
+		if err != nil {
+			return err
+		}
+		if expired(f.rotation, n, info.ModTime()) {
+			if err := os.Remove(match); err != nil {
+				return err
+			}
+		}
+	}
+	return f.create()
+}
+
+func rotatedLog(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// expired returns true if the n-th rotated file, last modified at the time, is beyond the retention.
+func expired(rotation *types.LogRotation, n int, modTime time.Time) bool {
+	maxAge := rotation.GetMaxAge()
+	return n > rotation.GetMaxFiles() || maxAge > 0 && time.Since(modTime) > maxAge
+}
+
+// logFileName matches the log files of a task, e.g. "api.log", "api.log.1", or "api.stderr.log"
+var logFileName = regexp.MustCompile(`^(.+?)(?:\.stderr)?\.log(?:\.(\d+))?$`)
+
+// pruneLogs deletes the log files in the directory of tasks that do not exist, and the rotated files beyond the
+// retention, returning the number deleted.
+func pruneLogs(dir string, rotation *types.LogRotation, exists func(task string) bool) (int, error) {
+	deleted := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		// the logs of tasks namespaced by their workflow are in a sub-directory
+		m := logFileName.FindStringSubmatch(filepath.ToSlash(rel))
+		if m == nil {
+			return nil
+		}
+		remove := !exists(m[1])
+		if n, _ := strconv.Atoi(m[2]); m[2] != "" && !remove {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			remove = expired(rotation, n, info.ModTime())
+		}
+		if !remove {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// PruneLogs deletes the log files of tasks that are no longer in the workflow, and the rotated log files beyond the
+// workflow's retention, e.g. those older than its maxAge, in the namespace.
+func PruneLogs(logger *log.Logger, wf *types.Workflow, namespace string) error {
+	dir := options{namespace: namespace}.logsDir()
+	deleted, err := pruneLogs(dir, wf.LogRotation, func(task string) bool {
+		_, ok := wf.Tasks[task]
+		return ok
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to prune logs: %w", err)
+	}
+	logger.Printf("deleted %d log file(s) from %s\n", deleted, dir)
+	return nil
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_rotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+	maxSize := resource.MustParse("10")
+	maxFiles := 2
+	f, err := createLog(path, &types.LogRotation{MaxSize: &maxSize, MaxFiles: &maxFiles})
+	assert.NoError(t, err)
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+		_, err := f.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, f.Close())
+
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+	assert.Equal(t, "six\n", read("api.log"))
+	assert.Equal(t, "four\nfive\n", read("api.log.1"))
+	assert.Equal(t, "three\n", read("api.log.2"))
+	assert.NoFileExists(t, filepath.Join(dir, "api.log.3"), "one and two are beyond the retention")
+
+	t.Run("Not a regular file", func(t *testing.T) {
+		f, err := createLog(os.DevNull, &types.LogRotation{MaxSize: &maxSize})
+		assert.NoError(t, err)
+		_, err = f.Write([]byte("a line that is longer than the maximum size\n"))
+		assert.NoError(t, err)
+		_, err = f.Write([]byte("another\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+	})
+}
+
+func Test_pruneLogs(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for name, modTime := range map[string]time.Time{
+		"api.log":            time.Now(),
+		"api.log.1":          time.Now(),
+		"api.log.2":          old,
+		"api.log.3":          time.Now(),
+		"api.stderr.log":     time.Now(),
+		"web/serve.log":      time.Now(),
+		"removed.log":        time.Now(),
+		"removed.log.1":      time.Now(),
+		"removed.stderr.log": time.Now(),
+		"notes.txt":          old,
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, nil, 0o644))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	maxFiles := 2
+	rotation := &types.LogRotation{MaxFiles: &maxFiles, MaxAge: &metav1.Duration{Duration: 24 * time.Hour}}
+
+	deleted, err := pruneLogs(dir, rotation, func(task string) bool { return task == "api" || task == "web/serve" })
+	assert.NoError(t, err)
+	assert.Equal(t, 5, deleted)
+	for _, name := range []string{"api.log", "api.log.1", "api.stderr.log", "web/serve.log", "notes.txt"} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// logRecord is a line of a task's log, as streamed to subscribers.
+type logRecord struct {
+	Task string `json:"task"`
+	// the stream the line was written to, "stderr", or empty if it is from the task's whole log
+	Stream string `json:"stream,omitempty"`
+	Line   string `json:"line"`
+}
+
+// tailLog sends the last lines of the log file, and then each new line, until the context is cancelled. The file is
+// truncated when the task is re-run, and then it is read from the start again.
+func tailLog(ctx context.Context, path string, backfill int, send func(line string) error) error {
+	var offset int64
+	var partial []byte
+	first := true
+	for {
+		data, size, err := readFrom(path, offset)
+		if err != nil {
+			return err
+		}
+		if size < offset {
+			// the file was truncated, so read it from the start
+			offset, partial = 0, nil
+			continue
+		}
+		offset += int64(len(data))
+		lines := bytes.Split(append(partial, data...), []byte("\n"))
+		// the last element is the start of a line that is not finished yet
+		partial = lines[len(lines)-1]
+		lines = lines[:len(lines)-1]
+		if first {
+			lines = lines[max(len(lines)-backfill, 0):]
+			first = false
+		}
+		for _, line := range lines {
+			if err := send(string(line)); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// readFrom reads the file from the offset, returning what was read, and the file's size. A file that does not exist
+// yet is empty.
+func readFrom(path string, offset int64) ([]byte, int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() < offset {
+		return nil, info.Size(), nil
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	data, err := io.ReadAll(file)
+	return data, info.Size(), err
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_tailLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "api.log")
+	assert.NoError(t, os.WriteFile(path, []byte("1\n2\n3\n4"), 0o644))
+
+	lines := make(chan string, 10)
+	go func() {
+		_ = tailLog(ctx, path, 2, func(line string) error {
+			lines <- line
+			return nil
+		})
+	}()
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			return "timeout"
+		}
+	}
+
+	t.Run("Backfill", func(t *testing.T) {
+		assert.Equal(t, "2", next())
+		assert.Equal(t, "3", next())
+	})
+	t.Run("New lines", func(t *testing.T) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		assert.NoError(t, err)
+		_, err = file.WriteString("\n5\n")
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+		assert.Equal(t, "4", next())
+		assert.Equal(t, "5", next())
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("a\n"), 0o644))
+		assert.Equal(t, "a", next())
+	})
+}
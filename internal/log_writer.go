@@ -2,7 +2,11 @@ package internal
 
 import (
 	"bytes"
+	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type logWriter struct {
@@ -10,19 +14,66 @@ type logWriter struct {
 	prefixSuffixProvider func() (string, string)
 	buffer               bytes.Buffer
 	logger               *log.Logger
+	// the name of the task, and a filter for which lines to show
+	name   string
+	filter *logFilter
+	// plain removes escape sequences from the lines
+	plain bool
+	// cr is true if the last byte written was a carriage return
+	cr bool
 }
 
 func (lw *logWriter) Write(p []byte) (int, error) {
 	prefix, suffix := lw.prefixSuffixProvider()
 
 	for _, b := range p {
-		if b == '\n' {
-			lw.logger.Printf("%s%s%s\n", prefix, lw.buffer.String(), suffix)
+		// a carriage return that is not followed by a newline overwrites the line, e.g. to update a progress bar, so only
+		// the last update is logged, once the line is finished, rather than a line for each update
+		if lw.cr && b != '\n' {
 			lw.buffer.Reset()
-		} else {
+		}
+		lw.cr = false
+		switch b {
+		case '\n':
+			line := lw.buffer.String()
+			if lw.plain {
+				line = plainText(line)
+			}
+			if line, ok := lw.filter.apply(lw.name, line); ok {
+				lw.logger.Printf("%s%s%s\n", prefix, line, suffix)
+			}
+			lw.buffer.Reset()
+		case '\r':
+			lw.cr = true
+		default:
 			lw.buffer.WriteByte(b)
 		}
 	}
 
 	return len(p), nil
 }
+
+// timestamp returns the timestamp to prefix log lines with, or empty string if timestamps are disabled.
+func timestamp(mode string, startedAt time.Time) string {
+	switch mode {
+	case "rfc3339":
+		return time.Now().Format(time.RFC3339) + " "
+	case "elapsed":
+		return fmt.Sprintf("+%.3fs ", time.Since(startedAt).Seconds())
+	default:
+		return ""
+	}
+}
+
+// escapeSequence matches ANSI escape sequences, e.g. colors, cursor movement, and window titles.
+var escapeSequence = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[@-Z\\-_])`)
+
+// plainText returns what a terminal would show for the line, without escape sequences, and only what follows the last
+// carriage return, e.g. the last update of a progress bar.
+func plainText(line string) string {
+	line = strings.TrimSuffix(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	return escapeSequence.ReplaceAllString(line, "")
+}
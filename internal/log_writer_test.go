@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_timestamp(t *testing.T) {
+	assert.Equal(t, "", timestamp("", time.Now()))
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}.* $`, timestamp("rfc3339", time.Now()))
+	assert.Regexp(t, `^\+1\.\d{3}s $`, timestamp("elapsed", time.Now().Add(-time.Second)))
+}
+
+func Test_plainText(t *testing.T) {
+	assert.Equal(t, "hello", plainText("hello"))
+	assert.Equal(t, "error: failed", plainText("\x1b[1;31merror:\x1b[0m failed"))
+	assert.Equal(t, "100%", plainText("10%\r50%\r100%"))
+	assert.Equal(t, "windows", plainText("windows\r"))
+	assert.Equal(t, "title", plainText("\x1b]0;kit\x07title"))
+}
+
+func Test_logWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lw := &logWriter{
+		prefixSuffixProvider: func() (string, string) { return "[npm] ", "" },
+		logger:               log.New(buf, "", 0),
+		name:                 "npm",
+	}
+	for _, p := range []string{"installing\n", "10%", "\r50%", "\r", "100%\n", "done\r\n", "partial"} {
+		_, err := lw.Write([]byte(p))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "[npm] installing\n[npm] 100%\n[npm] done\n", buf.String(), "only the last update of a line is logged, once it is finished")
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// desktopNotify shows a desktop notification. It does nothing on unsupported platforms.
+func desktopNotify(title, message string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", message, title)
+		return exec.Command("osascript", "-e", script).Run()
+	case "linux":
+		return exec.Command("notify-send", "--app-name=kit", title, message).Run()
+	default:
+		return nil
+	}
+}
+
+// lastLine returns the last non-empty line of the file, or empty string if it cannot be read.
+func lastLine(name string) string {
+	file, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	// we only need the end of the file
+	if stat, err := file.Stat(); err == nil && stat.Size() > 4096 {
+		_, _ = file.Seek(-4096, io.SeekEnd)
+	}
+	data, _ := io.ReadAll(file)
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	return string(bytes.TrimSpace(lines[len(lines)-1]))
+}
+
+// taskEvents returns the events for the phase the task has just transitioned to, i.e. the phase itself, and "recovered"
+// if the task is running or succeeded after failing. A task that fails repeatedly (e.g. a restarting service) is only
+// "failed" once. It may be called by any of the task's goroutines, e.g. its probes.
+func taskEvents(node *TaskNode, phase types.Phase) []string {
+	switch phase {
+	case "failed":
+		if !node.failing.CompareAndSwap(false, true) {
+			return nil
+		}
+	case "running", "succeeded":
+		if node.failing.CompareAndSwap(true, false) {
+			return []string{string(phase), "recovered"}
+		}
+	}
+	return []string{string(phase)}
+}
+
+// notifyDesktop shows a desktop notification if the task has just failed, or has recovered, with the last line of its
+// log, or otherwise the message of the transition.
+func notifyDesktop(node *TaskNode, event, message string) {
+	if event != "failed" && event != "recovered" {
+		return
+	}
+	title := fmt.Sprintf("%s %s", node.Name, event)
+	if line := lastLine(node.logFile); line != "" {
+		message = line
+	}
+	// notifications are best-effort, and must not block the task
+	go func() { _ = desktopNotify(title, message) }()
+}
@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lastLine(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.log")
+	assert.Equal(t, "", lastLine(name))
+	assert.NoError(t, os.WriteFile(name, []byte("foo\nbar\n\n"), 0644))
+	assert.Equal(t, "bar", lastLine(name))
+}
+
+func Test_taskEvents(t *testing.T) {
+	node := &TaskNode{}
+	assert.Equal(t, []string{"running"}, taskEvents(node, "running"))
+	assert.Equal(t, []string{"failed"}, taskEvents(node, "failed"))
+	assert.Empty(t, taskEvents(node, "failed"))
+	assert.Equal(t, []string{"running", "recovered"}, taskEvents(node, "running"))
+}
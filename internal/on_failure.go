@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/types"
+)
+
+// runOnFailure runs the task's onFailure handler, e.g. to dump logs or reset state, before the failed task is restarted.
+// The handler is told which task failed, and its exit code.
+func runOnFailure(ctx context.Context, logger *log.Logger, wf *types.Workflow, name string, t types.Task, taskErr error, stdout, stderr io.Writer) error {
+	handler := wf.Tasks[t.OnFailure]
+	if len(handler.Shell) == 0 {
+		handler.Shell = wf.Shell
+	}
+	env := types.EnvVars{}
+	for k, v := range handler.Env {
+		env[k] = v
+	}
+	env["KIT_FAILED_TASK"] = types.EnvVarValue{Value: name}
+	env["KIT_EXIT_CODE"] = types.EnvVarValue{Value: strconv.Itoa(proc.ExitCode(taskErr))}
+	handler.Env = env
+	logger.Printf("running onFailure task %q", t.OnFailure)
+	if err := proc.New(t.OnFailure, handler, logger, types.Spec(*wf)).Run(ctx, stdout, stderr); err != nil {
+		return fmt.Errorf("onFailure task %q failed: %w", t.OnFailure, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"log"
+	"sync"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// validateOpen returns an error if the URL to open is invalid, or references the ports of a task without any.
+func validateOpen(wf *types.Workflow, u string) error {
+	if err := types.ValidateOpen(u); err != nil {
+		return err
+	}
+	for _, r := range types.FindTaskReferences(u) {
+		if _, err := types.Spec(*wf).ResolvePort(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// opener opens each task's URL in the browser the first time the task is ready, and the workflow's URLs the first time
+// every task is, like "npm start" does. A task that is restarted is not opened again.
+type opener struct {
+	logger *log.Logger
+	wf     *types.Workflow
+	dag    DAG[*TaskNode]
+	// open opens the URL in the browser, nil if URLs are not opened, e.g. in CI
+	open   func(url string) error
+	mu     sync.Mutex
+	opened map[string]bool
+}
+
+// ready is called when the task is ready.
+func (o *opener) ready(node *TaskNode) {
+	if o.open == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.opened == nil {
+		o.opened = map[string]bool{}
+	}
+	if u := node.Task.Open; u != "" && !o.opened[node.Name] {
+		o.opened[node.Name] = true
+		o.openURL("["+node.Name+"] ", u)
+	}
+	// the workflow's URLs are opened once all of its tasks are ready
+	if len(o.wf.Open) == 0 || o.opened[""] {
+		return
+	}
+	o.dag.RLock()
+	defer o.dag.RUnlock()
+	for _, n := range o.dag.Nodes {
+		if n.blocked() {
+			return
+		}
+	}
+	o.opened[""] = true
+	for _, u := range o.wf.Open {
+		o.openURL("", u)
+	}
+}
+
+// openURL opens the URL, prefixing what is logged, e.g. with the task's name.
+func (o *opener) openURL(prefix, u string) {
+	u, err := types.ExpandTaskReferences(u, types.Spec(*o.wf).ResolvePort)
+	if err != nil {
+		o.logger.Printf("%sfailed to open %s: %v\n", prefix, u, err)
+		return
+	}
+	o.logger.Printf("%sopening %s\n", prefix, u)
+	// opening the browser may be slow, so it does not hold up the task
+	go func() {
+		if err := o.open(u); err != nil {
+			o.logger.Printf("%sfailed to open %s: %v\n", prefix, u, err)
+		}
+	}()
+}
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+type options struct {
+	// desktopNotifications shows a desktop notification when a task fails or recovers
+	desktopNotifications bool
+	// junitReport is the file to write a JUnit XML report to
+	junitReport string
+	// quiet only shows errors in the console by default
+	quiet bool
+	// output is the format to write the status of the tasks in, rather than their logs
+	output string
+	// reporter is the name of the reporter to report the tasks' transitions and output to, as well as the UI
+	reporter string
+	// timestamps overrides the workflow's timestamps mode
+	timestamps string
+	// summaryReport is the file to append a markdown summary to
+	summaryReport string
+	// timings prints how long each task took, and the critical path, when the workflow exits
+	timings bool
+	// plain writes plain lines, without colors or escape sequences, e.g. when the output is a file
+	plain bool
+	// terminalTitle sets the terminal's title to the status of the tasks
+	terminalTitle bool
+	// width returns the width of the terminal the console is, or zero if it is not one
+	width func() int
+	// concurrency overrides the workflow's maxParallel
+	concurrency int
+	// namespace suffixes the logs and state directories, so two instances can run side by side
+	namespace string
+	// dir is the directory the logs and state directories are in, the current directory if empty
+	dir string
+	// configFiles are watched, and the workflow is reloaded using load when they change
+	configFiles []string
+	load        func() (*types.Workflow, error)
+	// failFast exits as soon as a job fails, even if it would be restarted
+	failFast bool
+	// keepGoing runs every task that does not depend on a failed task, rather than exiting when a task fails
+	keepGoing bool
+	// record is the file to write a recording of the session to, for a bug report
+	record string
+	// openURL opens a task's URL in the browser when it is ready, nil to not open them, e.g. in CI
+	openURL func(url string) error
+	// exited is called with the exit code of each task that exits, rather than being cancelled
+	exited func(task string, code int)
+}
+
+// logsDir is the directory to write the logs of tasks to.
+func (o options) logsDir() string {
+	return filepath.Join(o.dir, suffix("logs", o.namespace))
+}
+
+// stateDir is the directory to write the state of the workflow to.
+func (o options) stateDir() string {
+	return filepath.Join(o.dir, suffix(".kit", o.namespace))
+}
+
+func suffix(name, namespace string) string {
+	if namespace == "" {
+		return name
+	}
+	return name + "-" + namespace
+}
+
+// Option configures how a workflow is run.
+type Option func(*options)
+
+// WithDesktopNotifications shows a desktop notification when a task fails, or recovers after failing.
+func WithDesktopNotifications(enabled bool) Option {
+	return func(o *options) {
+		o.desktopNotifications = enabled
+	}
+}
+
+// WithJUnitReport writes a JUnit XML report of the tasks to the file when the workflow exits.
+func WithJUnitReport(file string) Option {
+	return func(o *options) {
+		o.junitReport = file
+	}
+}
+
+// WithSummaryReport appends a markdown summary of the tasks to the file when the workflow exits. In CI, this defaults
+// to GitHub's job summary.
+func WithSummaryReport(file string) Option {
+	return func(o *options) {
+		o.summaryReport = file
+	}
+}
+
+// WithTimings prints how long each task spent waiting, queued and running, and the critical path through the tasks,
+// when the workflow exits. In CI, this defaults to true.
+func WithTimings(enabled bool) Option {
+	return func(o *options) {
+		o.timings = enabled
+	}
+}
+
+// WithTimestamps prefixes each line of task output with a timestamp, "rfc3339" or "elapsed". This overrides the
+// workflow's setting.
+func WithTimestamps(mode string) Option {
+	return func(o *options) {
+		if mode != "" {
+			o.timestamps = mode
+		}
+	}
+}
+
+// WithPlain writes plain lines to the console, without colors, and without the escape sequences in the output of
+// tasks, e.g. when the output is written to a file, or shown in an editor.
+func WithPlain(plain bool) Option {
+	return func(o *options) {
+		o.plain = plain
+	}
+}
+
+// WithTerminalTitle sets the terminal's title to the status of the tasks, e.g. "kit: 7 running, 1 failed". Where
+// supported, the terminal also shows their progress, and a notification when a task fails.
+func WithTerminalTitle(enabled bool) Option {
+	return func(o *options) {
+		o.terminalTitle = enabled
+	}
+}
+
+// WithTerminalWidth fits the status of the tasks, shown when the workflow exits, to the width of the terminal the file
+// is, so a long message does not wrap in a small pane. Nothing is shortened if the file is not a terminal.
+func WithTerminalWidth(f *os.File) Option {
+	return func(o *options) {
+		o.width = func() int { return terminalWidth(f) }
+	}
+}
+
+// WithQuiet only shows the errors (i.e. stderr) of tasks in the console, unless the task has a log level.
+func WithQuiet(quiet bool) Option {
+	return func(o *options) {
+		o.quiet = quiet
+	}
+}
+
+// WithOutput periodically writes the status of the tasks as "json" or "yaml", rather than their logs, for consumption
+// by other programs.
+func WithOutput(output string) Option {
+	return func(o *options) {
+		o.output = output
+	}
+}
+
+// WithReporter reports each task's transitions and output with the named reporter, one of Reporters(). In GitHub
+// Actions, the default is "github", otherwise it is "console".
+func WithReporter(name string) Option {
+	return func(o *options) {
+		o.reporter = name
+	}
+}
+
+// WithDir writes the logs and state directories in the directory, rather than the current directory.
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithNamespace writes logs and state to directories suffixed by the namespace, so two instances of the workflow can
+// run side by side. The workflow itself must be namespaced with types.Spec.SetNamespace.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithFailFast exits as soon as any job fails, stopping the other tasks, even if the job would be restarted.
+func WithFailFast(enabled bool) Option {
+	return func(o *options) {
+		o.failFast = enabled
+	}
+}
+
+// WithKeepGoing keeps running the tasks that do not depend on a failed task, and exits once every requested task has
+// completed, or cannot, rather than as soon as a task fails.
+func WithKeepGoing(enabled bool) Option {
+	return func(o *options) {
+		o.keepGoing = enabled
+	}
+}
+
+// WithRecording writes a recording of the session to the file when kit exits: the workflow, the tasks' logs, events
+// and output, the versions of kit and the OS, and the environment. Anything that looks like a secret is redacted from the
+// workflow and the environment, and the values of those secrets from the logs and output.
+func WithRecording(file string) Option {
+	return func(o *options) {
+		o.record = file
+	}
+}
+
+// WithConcurrency limits the number of jobs that run at the same time. This overrides the workflow's maxParallel.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithReload watches the config files, and when they change, reloads the workflow using load. The tasks that were
+// added are started, the tasks that were removed are stopped, and the tasks that changed are restarted.
+func WithReload(configFiles []string, load func() (*types.Workflow, error)) Option {
+	return func(o *options) {
+		o.configFiles = configFiles
+		o.load = load
+	}
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// outputCapture captures the values of a task's outputs from what it writes, so they can be passed to the tasks that depend on it.
+type outputCapture struct {
+	mu      sync.Mutex
+	outputs []types.Output
+	regexps []*regexp.Regexp
+	// the values matched by regexps
+	values map[string]string
+	// all of stdout, only kept if an output needs it
+	stdout *bytes.Buffer
+}
+
+func newOutputCapture(outputs []types.Output) *outputCapture {
+	c := &outputCapture{outputs: outputs, values: map[string]string{}}
+	for _, o := range outputs {
+		var r *regexp.Regexp
+		if o.Regex != "" {
+			r = regexp.MustCompile(o.Regex)
+		} else {
+			c.stdout = &bytes.Buffer{}
+		}
+		c.regexps = append(c.regexps, r)
+	}
+	return c
+}
+
+// reset clears the values, ready for the next run.
+func (c *outputCapture) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values = map[string]string{}
+	if c.stdout != nil {
+		c.stdout.Reset()
+	}
+}
+
+// writer returns a writer that captures the output, and writes it to w.
+func (c *outputCapture) writer(w io.Writer, stdout bool) io.Writer {
+	if len(c.outputs) == 0 {
+		return w
+	}
+	var partial []byte
+	return funcWriter(func(p []byte) (int, error) {
+		c.mu.Lock()
+		if stdout && c.stdout != nil {
+			c.stdout.Write(p)
+		}
+		partial = append(partial, p...)
+		for {
+			i := bytes.IndexByte(partial, '\n')
+			if i < 0 {
+				break
+			}
+			c.match(string(partial[:i]))
+			partial = partial[i+1:]
+		}
+		c.mu.Unlock()
+		return w.Write(p)
+	})
+}
+
+// match sets the value of any output whose regexp matches the line. Must be called holding the lock.
+func (c *outputCapture) match(line string) {
+	for i, r := range c.regexps {
+		if r == nil {
+			continue
+		}
+		m := r.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value := m[0]
+		if len(m) > 1 {
+			value = m[1]
+		}
+		c.values[c.outputs[i].Name] = value
+	}
+}
+
+// env returns the captured outputs as environment variables.
+func (c *outputCapture) env() types.EnvVars {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	env := types.EnvVars{}
+	for i, o := range c.outputs {
+		if c.regexps[i] == nil {
+			env[o.Name] = types.EnvVarValue{Value: strings.TrimSpace(c.stdout.String())}
+		} else if value, ok := c.values[o.Name]; ok {
+			env[o.Name] = types.EnvVarValue{Value: value}
+		}
+	}
+	return env
+}
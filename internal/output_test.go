@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"io"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_outputCapture(t *testing.T) {
+	c := newOutputCapture([]types.Output{
+		{Name: "PORT", Regex: `listening on port (\d+)`},
+		{Name: "READY", Regex: `ready`},
+		{Name: "STDOUT"},
+	})
+	stdout, stderr := c.writer(io.Discard, true), c.writer(io.Discard, false)
+	_, _ = stdout.Write([]byte("hello\nlistening on "))
+	_, _ = stdout.Write([]byte("port 5432\n"))
+	_, _ = stderr.Write([]byte("ready\n"))
+	assert.Equal(t, types.EnvVars{"PORT": {Value: "5432"}, "READY": {Value: "ready"}, "STDOUT": {Value: "hello\nlistening on port 5432"}}, c.env())
+
+	c.reset()
+	assert.Equal(t, types.EnvVars{"STDOUT": {}}, c.env())
+}
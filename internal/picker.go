@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// the most items the picker shows at once
+const pickerHeight = 10
+
+// pickerItem is a task or group that can be picked.
+type pickerItem struct {
+	name, description string
+}
+
+// picker picks a task or group by fuzzy searching their names and descriptions, like fzf.
+type picker struct {
+	items    []pickerItem
+	query    []rune
+	selected int
+	// the escape sequence read so far, e.g. "\x1b[" for an arrow key
+	escape string
+	// the width of each line last rendered, so they can be cleared
+	lines []int
+	// the width of the terminal, zero if it is not known
+	width int
+}
+
+func newPicker(wf *types.Workflow) *picker {
+	var names []string
+	groups := map[string][]string{}
+	for name, t := range wf.Tasks {
+		names = append(names, name)
+		if t.Group != "" {
+			groups[t.Group] = append(groups[t.Group], name)
+		}
+	}
+	sort.Strings(names)
+	p := &picker{}
+	for _, name := range names {
+		p.items = append(p.items, pickerItem{name: name, description: wf.Tasks[name].Description})
+	}
+	var groupNames []string
+	for name := range groups {
+		// a task's name takes precedence over a group's
+		if _, ok := wf.Tasks[name]; !ok {
+			groupNames = append(groupNames, name)
+		}
+	}
+	sort.Strings(groupNames)
+	for _, name := range groupNames {
+		members := groups[name]
+		sort.Strings(members)
+		p.items = append(p.items, pickerItem{name: name, description: "group: " + strings.Join(members, ", ")})
+	}
+	return p
+}
+
+// fuzzyScore returns how well the query matches the text, or false if the query's characters are not all in the text,
+// in order. Matches at the start of words, and consecutive matches, score higher.
+func fuzzyScore(query []rune, text string) (int, bool) {
+	score, i, last := 0, 0, -2
+	runes := []rune(strings.ToLower(text))
+	for j, r := range runes {
+		if i == len(query) {
+			break
+		}
+		if r != unicode.ToLower(query[i]) {
+			continue
+		}
+		score++
+		if j == last+1 {
+			score += 2
+		}
+		if j == 0 || !unicode.IsLetter(runes[j-1]) && !unicode.IsDigit(runes[j-1]) {
+			score += 3
+		}
+		last = j
+		i++
+	}
+	return score, i == len(query)
+}
+
+// matches returns the items that match the query, best first.
+func (p *picker) matches() []pickerItem {
+	type match struct {
+		item  pickerItem
+		score int
+	}
+	var matches []match
+	for _, item := range p.items {
+		// a match on the name is better than a match on the description
+		if score, ok := fuzzyScore(p.query, item.name); ok {
+			matches = append(matches, match{item, 2*score + 1})
+		} else if score, ok := fuzzyScore(p.query, item.description); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	var items []pickerItem
+	for _, m := range matches {
+		items = append(items, m.item)
+	}
+	return items
+}
+
+// key handles a key press, returning the name of the item picked, if it was enter.
+func (p *picker) key(r rune) (string, bool) {
+	// arrow keys are sent as escape sequences, e.g. up is "\x1b[A"
+	if p.escape != "" || r == '\x1b' {
+		p.escape += string(r)
+		switch p.escape {
+		case "\x1b", "\x1b[":
+			return "", false
+		case "\x1b[A":
+			r = '\x10'
+		case "\x1b[B":
+			r = '\x0e'
+		}
+		p.escape = ""
+	}
+	matches := p.matches()
+	switch r {
+	case '\r', '\n':
+		if p.selected < len(matches) {
+			return matches[p.selected].name, true
+		}
+	case '\x10': // up, or ctrl+p
+		p.selected = max(p.selected-1, 0)
+	case '\x0e': // down, or ctrl+n
+		p.selected = max(min(p.selected+1, min(len(matches), pickerHeight)-1), 0)
+	case '\x7f', '\b':
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.selected = 0
+		}
+	default:
+		if unicode.IsPrint(r) {
+			p.query = append(p.query, r)
+			p.selected = 0
+		}
+	}
+	return "", false
+}
+
+// render draws the picker, replacing what was drawn before. The matches are listed above the prompt. Each line is
+// shortened to fit the terminal, as a line that wraps would not be cleared.
+func (p *picker) render(w io.Writer, palette *palette) {
+	buf := &strings.Builder{}
+	p.clear(buf)
+	matches := p.matches()
+	if len(matches) > pickerHeight {
+		matches = matches[:pickerHeight]
+	}
+	width := 0
+	for _, item := range matches {
+		width = max(width, len(item.name))
+	}
+	var lines []string
+	for i, item := range matches {
+		line := fit(fmt.Sprintf("  %-*s  %s", width, item.name, item.description), p.width)
+		if i == p.selected {
+			line = palette.sgr(7) + ">" + line[1:] + palette.reset()
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, fit(fmt.Sprintf("%d/%d > %s", len(p.matches()), len(p.items), string(p.query)), p.width))
+	buf.WriteString(strings.Join(lines, "\n"))
+	p.lines = nil
+	for _, line := range lines {
+		p.lines = append(p.lines, utf8.RuneCountInString(escapeSequence.ReplaceAllString(line, "")))
+	}
+	_, _ = io.WriteString(w, buf.String())
+}
+
+// clear erases what was last rendered. If the terminal has been made narrower since, it may have wrapped the lines, so
+// they take more rows.
+func (p *picker) clear(w io.StringWriter) {
+	rows := 0
+	for _, n := range p.lines {
+		rows++
+		if p.width > 0 && n > p.width {
+			rows += (n - 1) / p.width
+		}
+	}
+	if rows > 1 {
+		_, _ = w.WriteString(fmt.Sprintf("\x1b[%dA", rows-1))
+	}
+	_, _ = w.WriteString("\r\x1b[J")
+	p.lines = nil
+}
+
+// PickTasks lets the user pick a task or group to run in the terminal. It returns nothing if stdin is not a terminal,
+// or the user cancels.
+func PickTasks(ctx context.Context, wf *types.Workflow) ([]string, error) {
+	if len(wf.Tasks) == 0 {
+		return nil, nil
+	}
+	restore, ok := cbreak()
+	if !ok {
+		return nil, nil
+	}
+	defer restore()
+	palette, err := newPalette(nil)
+	if err != nil {
+		return nil, err
+	}
+	p := newPicker(wf)
+	// the picker is drawn on stderr, so that it is not captured if stdout is redirected
+	w := os.Stderr
+	defer func() {
+		buf := &strings.Builder{}
+		p.clear(buf)
+		_, _ = w.WriteString(buf.String())
+	}()
+	keys := stdinKeys()
+	resizes, stop := resized(w)
+	defer stop()
+	for {
+		p.width = terminalWidth(w)
+		p.render(w, palette)
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-resizes:
+		case r, ok := <-keys:
+			if !ok {
+				return nil, nil
+			}
+			if name, ok := p.key(r); ok {
+				return []string{name}, nil
+			}
+		}
+	}
+}
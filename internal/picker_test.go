@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPicker(t *testing.T) {
+	wf := &types.Workflow{
+		Tasks: types.Tasks{
+			"api":      {Group: "backend", Description: "the REST API"},
+			"db":       {Group: "backend", Description: "postgres"},
+			"web":      {Description: "the web app"},
+			"api-docs": {Description: "generate the API docs"},
+		},
+	}
+	names := func(items []pickerItem) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.name)
+		}
+		return names
+	}
+	typing := func(p *picker, s string) (string, bool) {
+		var name string
+		var ok bool
+		for _, r := range s {
+			name, ok = p.key(r)
+		}
+		return name, ok
+	}
+	t.Run("Items", func(t *testing.T) {
+		p := newPicker(wf)
+		assert.Equal(t, []string{"api", "api-docs", "db", "web", "backend"}, names(p.matches()))
+		assert.Equal(t, "group: api, db", p.items[4].description)
+	})
+	t.Run("Fuzzy", func(t *testing.T) {
+		p := newPicker(wf)
+		typing(p, "ad")
+		// a match on the name is better than on the description
+		assert.Equal(t, []string{"api-docs", "backend"}, names(p.matches()))
+	})
+	t.Run("Backspace", func(t *testing.T) {
+		p := newPicker(wf)
+		typing(p, "webx\x7f")
+		assert.Equal(t, []string{"web"}, names(p.matches()))
+	})
+	t.Run("Pick", func(t *testing.T) {
+		p := newPicker(wf)
+		name, ok := typing(p, "api\r")
+		assert.True(t, ok)
+		assert.Equal(t, "api", name)
+	})
+	t.Run("Arrow keys", func(t *testing.T) {
+		p := newPicker(wf)
+		name, ok := typing(p, "\x1b[B\x1b[B\x1b[A\r")
+		assert.True(t, ok)
+		assert.Equal(t, "api-docs", name)
+	})
+	t.Run("No matches", func(t *testing.T) {
+		p := newPicker(wf)
+		_, ok := typing(p, "zzz\x1b[B\r")
+		assert.False(t, ok)
+	})
+	t.Run("Narrow terminal", func(t *testing.T) {
+		p := newPicker(wf)
+		p.width = 16
+		buf := &strings.Builder{}
+		typing(p, "api")
+		p.render(buf, &palette{disabled: true})
+		assert.Equal(t, "\r\x1b[J> api       the…\n  api-docs  gen…\n  backend   gro…\n3/5 > api", buf.String(), "lines do not wrap")
+
+		// the terminal wraps the lines if it is made narrower
+		p.width = 8
+		buf.Reset()
+		p.clear(buf)
+		assert.Equal(t, "\x1b[7A\r\x1b[J", buf.String())
+	})
+}
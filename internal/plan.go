@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kitproj/kit/internal/types"
+	"k8s.io/utils/strings/slices"
+)
+
+// plan is the tasks of a workflow to run.
+type plan struct {
+	// the graph of all the workflow's tasks
+	dag DAG[bool]
+	// the requested tasks, with groups expanded
+	taskNames []string
+	// the tasks to skip, with groups expanded
+	tasksToSkip []string
+	// the requested tasks, and the tasks they depend on
+	visited map[string]bool
+	// the host ports of the tasks to run
+	ports portRegistry
+}
+
+// newPlan validates the workflow, and plans how to run the requested tasks.
+func newPlan(name string, wf *types.Workflow, taskNames, tasksToSkip []string) (*plan, error) {
+	// expand groups into their tasks, this also checks that the task names are valid
+	taskNames, err := wf.Tasks.Expand(taskNames)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range wf.Extensions {
+		if err := e.Validate(); err != nil {
+			return nil, fmt.Errorf("extension %q is invalid: %w", e.Module, err)
+		}
+	}
+
+	if wf.DNS != nil {
+		if err := wf.DNS.Validate(); err != nil {
+			return nil, fmt.Errorf("dns is invalid: %w", err)
+		}
+	}
+
+	for _, u := range wf.Open {
+		if err := validateOpen(wf, u); err != nil {
+			return nil, fmt.Errorf("invalid open: %w", err)
+		}
+	}
+
+	// check the log levels, outputs and dependencies are valid
+	for name, t := range wf.Tasks {
+		switch t.LogLevel {
+		case "", "info", "error", "none":
+		default:
+			return nil, fmt.Errorf("task %q has invalid logLevel %q, must be info, error or none", name, t.LogLevel)
+		}
+		switch t.RestartPolicy {
+		case "", "Always", "Never", "OnFailure", "OnWatch":
+		default:
+			return nil, fmt.Errorf("task %q has invalid restartPolicy %q, must be Always, Never, OnFailure or OnWatch", name, t.RestartPolicy)
+		}
+		for _, o := range t.Outputs {
+			if err := o.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid output: %w", name, err)
+			}
+		}
+		if t.Trigger != nil {
+			if err := t.Trigger.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid trigger: %w", name, err)
+			}
+		}
+		if p := t.LivenessProbe; p != nil && p.LogMatch != "" {
+			return nil, fmt.Errorf("task %q has a livenessProbe with logMatch, which is only for readiness probes", name)
+		}
+		for _, p := range []*types.Probe{t.LivenessProbe, t.ReadinessProbe} {
+			if p != nil && p.HTTPGet != nil {
+				if err := p.HTTPGet.Validate(); err != nil {
+					return nil, fmt.Errorf("task %q has invalid probe: %w", name, err)
+				}
+			}
+		}
+		if p := t.ReadinessProbe; p != nil && p.LogMatch != "" {
+			if _, err := regexp.Compile(p.LogMatch); err != nil {
+				return nil, fmt.Errorf("task %q has invalid logMatch: %w", name, err)
+			}
+		}
+		if t.Ready != nil {
+			if t.ReadinessProbe != nil {
+				return nil, fmt.Errorf("task %q has both ready and a readinessProbe, only one may be used", name)
+			}
+			if err := t.Ready.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid ready: %w", name, err)
+			}
+		}
+		if err := t.ValidateRollingRestart(); err != nil {
+			return nil, fmt.Errorf("task %q has invalid rollingRestart: %w", name, err)
+		}
+		if err := t.ValidateFaults(); err != nil {
+			return nil, fmt.Errorf("task %q has invalid faults: %w", name, err)
+		}
+		if t.PodLogs != nil {
+			if err := t.PodLogs.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid podLogs: %w", name, err)
+			}
+		}
+		if t.Plugin != nil {
+			if err := t.Plugin.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid plugin: %w", name, err)
+			}
+		}
+		if t.WatchPackages != nil {
+			if err := t.WatchPackages.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid watchPackages: %w", name, err)
+			}
+		}
+		if t.Image != "" && t.Build != nil {
+			return nil, fmt.Errorf("task %q has both image and build, but can only run an image or build one", name)
+		}
+		if t.Intercept != nil {
+			if err := t.Intercept.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid intercept: %w", name, err)
+			}
+			if len(t.Ports) == 0 {
+				return nil, fmt.Errorf("task %q has intercept, but no ports to route the traffic to", name)
+			}
+		}
+		if t.TLS != nil {
+			if err := t.TLS.Validate(); err != nil {
+				return nil, fmt.Errorf("task %q has invalid tls: %w", name, err)
+			}
+		}
+		for _, code := range t.SuccessCodes {
+			if code < 0 {
+				return nil, fmt.Errorf("task %q has invalid successCodes: %d is not an exit code", name, code)
+			}
+		}
+		if len(t.Once) > 0 && t.GetType() != types.TaskTypeJob {
+			return nil, fmt.Errorf("task %q has once, but only jobs can be applied once", name)
+		}
+		if t.OnFailure != "" {
+			if _, ok := wf.Tasks[t.OnFailure]; !ok || t.OnFailure == name {
+				return nil, fmt.Errorf("task %q has onFailure %q, which is not another task in workflow", name, t.OnFailure)
+			}
+		}
+		for _, r := range t.Env.TaskReferences() {
+			if err := types.Spec(*wf).CheckTaskReference(name, t, r); err != nil {
+				return nil, err
+			}
+		}
+		for _, target := range t.WaitFor {
+			if err := validateWaitFor(target); err != nil {
+				return nil, fmt.Errorf("task %q has invalid waitFor: %w", name, err)
+			}
+		}
+		if t.Open != "" {
+			if err := validateOpen(wf, t.Open); err != nil {
+				return nil, fmt.Errorf("task %q has invalid open: %w", name, err)
+			}
+		}
+		for _, input := range t.Inputs {
+			producer, ok := wf.Tasks[input.Task]
+			if !ok {
+				return nil, fmt.Errorf("task %q has input from %q, which is not found in workflow", name, input.Task)
+			}
+			if !slices.Contains(producer.Artifacts, input.Artifact) {
+				return nil, fmt.Errorf("task %q has input %q, which is not an artifact of %q", name, input.Artifact, input.Task)
+			}
+		}
+		for _, dependency := range t.GetDependencies() {
+			if _, ok := wf.Tasks[dependency]; !ok {
+				return nil, fmt.Errorf("task %q depends on %q, which is not found in workflow", name, dependency)
+			}
+		}
+	}
+
+	// skipped groups are expanded too
+	tasksToSkip, err = wf.Tasks.Expand(tasksToSkip)
+	if err != nil {
+		return nil, fmt.Errorf("skipped %w", err)
+	}
+
+	dag := NewDAG[bool](name)
+	for name, t := range wf.Tasks {
+		dag.AddNode(name, true)
+		for _, dependency := range t.GetDependencies() {
+			dag.AddEdge(dependency, name)
+		}
+	}
+	// a task in a cycle would wait forever
+	if cycle := dag.Cycle(); cycle != nil {
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " → "))
+	}
+	visited := dag.Subgraph(taskNames)
+
+	// read secrets at startup, so we fail fast, rather than part way through
+	if _, err := wf.Env.Environ(); err != nil {
+		return nil, err
+	}
+	for name := range visited {
+		if _, err := wf.Tasks[name].Env.Environ(); err != nil {
+			return nil, fmt.Errorf("task %q: %w", name, err)
+		}
+	}
+
+	// check the environment variables the tasks we'll run need are set
+	var toRun []string
+	for name := range visited {
+		if !slices.Contains(tasksToSkip, name) {
+			toRun = append(toRun, name)
+		}
+	}
+	if err := checkEnv(wf, toRun); err != nil {
+		return nil, err
+	}
+
+	// two tasks cannot listen on the same host port
+	ports := newPortRegistry(wf).only(toRun)
+	if err := ports.checkCollisions(); err != nil {
+		return nil, err
+	}
+
+	return &plan{dag: dag, taskNames: taskNames, tasksToSkip: tasksToSkip, visited: visited, ports: ports}, nil
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// portOwner finds the process listening on the port, by finding the inode of the listening socket in /proc/net/tcp,
+// and then the process with a file descriptor for it. Processes of other users cannot be seen, so zero is returned.
+func portOwner(port uint16) (int, string, error) {
+	inodes := map[string]bool{}
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := listeningInodes(file, port, inodes); err != nil {
+			return 0, "", err
+		}
+	}
+	if len(inodes) == 0 {
+		return 0, "", nil
+	}
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, "", err
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join("/proc", entry.Name(), "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join("/proc", entry.Name(), "fd", fd.Name()))
+			if err != nil {
+				continue
+			}
+			if inode, ok := strings.CutPrefix(link, "socket:["); ok && inodes[strings.TrimSuffix(inode, "]")] {
+				cmdline, _ := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+				return pid, strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " ")), nil
+			}
+		}
+	}
+	return 0, "", nil
+}
+
+// listeningInodes adds the inodes of the sockets listening on the port, read from a /proc/net/tcp file.
+func listeningInodes(file string, port uint16, inodes map[string]bool) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	suffix := fmt.Sprintf(":%04X", port)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		// 0A is TCP_LISTEN
+		if len(fields) > 9 && strings.HasSuffix(fields[1], suffix) && fields[3] == "0A" {
+			inodes[fields[9]] = true
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,30 @@
+//go:build !linux
+
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// portOwner finds the process listening on the port using lsof. Zero is returned if there is no such process, or
+// lsof is not installed.
+func portOwner(port uint16) (int, string, error) {
+	out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return 0, "", nil
+	}
+	pid, command := 0, ""
+	// each field is on its own line, prefixed by its name, e.g. "p123" and "cnode"
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p") && pid == 0:
+			pid, _ = strconv.Atoi(line[1:])
+		case strings.HasPrefix(line, "c") && command == "":
+			command = line[1:]
+		}
+	}
+	return pid, command, nil
+}
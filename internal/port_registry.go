@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// portUse is a host port that a task listens on, or that kit listens on for it.
+type portUse struct {
+	port uint16
+	task string
+	// what the port is for: "port", one of the task's own, or "tls", the HTTPS proxy kit runs in front of it
+	use string
+}
+
+// portRegistry is every host port the tasks of a workflow use, sorted by port, then task. Random ports (i.e. zero) are
+// not known until the task runs, so are not included.
+type portRegistry []portUse
+
+func newPortRegistry(wf *types.Workflow) portRegistry {
+	var r portRegistry
+	for name, t := range wf.Tasks {
+		for _, port := range t.GetHostPorts() {
+			r = append(r, portUse{port: port, task: name, use: "port"})
+		}
+		if t.TLS != nil {
+			r = append(r, portUse{port: t.TLS.Port, task: name, use: "tls"})
+		}
+	}
+	r = slices.DeleteFunc(r, func(u portUse) bool { return u.port == 0 })
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].port != r[j].port {
+			return r[i].port < r[j].port
+		}
+		if r[i].task != r[j].task {
+			return r[i].task < r[j].task
+		}
+		return r[i].use < r[j].use
+	})
+	return r
+}
+
+// only returns the ports used by the tasks.
+func (r portRegistry) only(tasks []string) portRegistry {
+	return slices.DeleteFunc(slices.Clone(r), func(u portUse) bool { return !slices.Contains(tasks, u.task) })
+}
+
+// checkCollisions returns an error if two uses are of the same port, e.g. a container published on the port a host
+// process listens on, as only one of them can listen on it.
+func (r portRegistry) checkCollisions() error {
+	for i := 1; i < len(r); i++ {
+		if r[i].port == r[i-1].port {
+			return fmt.Errorf("tasks %q and %q both use host port %d", r[i-1].task, r[i].task, r[i].port)
+		}
+	}
+	return nil
+}
+
+// privileged returns the uses of privileged ports (i.e. below 1024), that a host process needs to be root to listen on,
+// on Linux. Containers are published by the container runtime, which is usually root.
+func (r portRegistry) privileged(wf *types.Workflow) portRegistry {
+	return slices.DeleteFunc(slices.Clone(r), func(u portUse) bool {
+		return !types.PrivilegedPort(u.port) || (u.use == "port" && wf.Tasks[u.task].Image != "")
+	})
+}
+
+// PrintPorts prints every host port the workflow's tasks use, with the task, what it is for, and whether something is
+// listening on it, and if so, what.
+func PrintPorts(w io.Writer, wf *types.Workflow) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PORT\tTASK\tUSE\tSTATUS")
+	for _, u := range newPortRegistry(wf) {
+		status := "free"
+		if portFree(u.port) != nil {
+			status = describePortOwner(u.port)
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", u.port, u.task, u.use, status)
+	}
+	return tw.Flush()
+}
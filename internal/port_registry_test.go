@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_portRegistry(t *testing.T) {
+	wf := &types.Workflow{Tasks: types.Tasks{
+		"api":  {Command: types.Strings{"go", "run", "."}, Ports: types.Ports{{ContainerPort: 80}}, TLS: &types.TLS{Port: 443}},
+		"web":  {Image: "nginx", Ports: types.Ports{{ContainerPort: 80, HostPort: 8080}, {ContainerPort: 8443, HostPort: 443}}},
+		"db":   {Image: "postgres", Ports: types.Ports{{ContainerPort: 5432, HostPort: 15432}}},
+		"tmp":  {Command: types.Strings{"./tmp"}, Ports: types.Ports{{}}},
+		"docs": {Command: types.Strings{"hugo", "serve"}},
+	}}
+	r := newPortRegistry(wf)
+	assert.Equal(t, portRegistry{
+		{port: 80, task: "api", use: "port"},
+		{port: 443, task: "api", use: "tls"},
+		{port: 443, task: "web", use: "port"},
+		{port: 8080, task: "web", use: "port"},
+		{port: 15432, task: "db", use: "port"},
+	}, r, "sorted by port, without random ports")
+
+	assert.EqualError(t, r.checkCollisions(), `tasks "api" and "web" both use host port 443`)
+	assert.NoError(t, r.only([]string{"api", "db"}).checkCollisions())
+
+	assert.Equal(t, portRegistry{
+		{port: 80, task: "api", use: "port"},
+		{port: 443, task: "api", use: "tls"},
+	}, r.privileged(wf), "containers are published by the container runtime")
+}
+
+func TestPrintPorts(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	defer l.Close()
+	used := uint16(l.Addr().(*net.TCPAddr).Port)
+	wf := &types.Workflow{Tasks: types.Tasks{"api": {Ports: types.Ports{{ContainerPort: used}}}}}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, PrintPorts(buf, wf))
+	assert.Regexp(t, `^PORT\s+TASK\s+USE\s+STATUS\n\d+\s+api\s+port\s+used by `, buf.String())
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// waitForPorts blocks until the host ports are free, e.g. while the last run of the task exits, and returns an error
+// if one is still in use after the timeout. Each port that is in use is reported, with the process using it, if known.
+func waitForPorts(ctx context.Context, ports []uint16, timeout time.Duration, report func(message string)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for _, port := range ports {
+		// zero means a random port
+		if port == 0 {
+			continue
+		}
+		reported := false
+		for {
+			err := portFree(port)
+			if err == nil {
+				break
+			}
+			if !reported {
+				report(fmt.Sprintf("waiting for port %d to be free, %s", port, describePortOwner(port)))
+				reported = true
+			}
+			select {
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return fmt.Errorf("port %d is still in use after %v, %s", port, timeout, describePortOwner(port))
+				}
+				return ctx.Err()
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	}
+	return nil
+}
+
+// portFree returns an error if something is listening on the port.
+func portFree(port uint16) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
+
+// describePortOwner describes the process listening on the port, e.g. "used by pid 123 (node server.js)".
+func describePortOwner(port uint16) string {
+	pid, command, err := portOwner(port)
+	if err != nil || pid == 0 {
+		return "used by an unknown process"
+	}
+	return fmt.Sprintf("used by pid %d (%s)", pid, command)
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_waitForPorts(t *testing.T) {
+	ctx := context.Background()
+	l, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+
+	t.Run("In use", func(t *testing.T) {
+		var messages []string
+		err := waitForPorts(ctx, []uint16{0, port}, 300*time.Millisecond, func(message string) {
+			messages = append(messages, message)
+		})
+		assert.ErrorContains(t, err, fmt.Sprintf("port %d is still in use after 300ms, used by", port))
+		assert.Len(t, messages, 1)
+		if runtime.GOOS == "linux" {
+			assert.Contains(t, messages[0], fmt.Sprintf("used by pid %d", os.Getpid()))
+		}
+	})
+	t.Run("Freed", func(t *testing.T) {
+		time.AfterFunc(300*time.Millisecond, func() { _ = l.Close() })
+		assert.NoError(t, waitForPorts(ctx, []uint16{port}, 5*time.Second, func(string) {}))
+	})
+}
@@ -2,59 +2,175 @@ package internal
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/kitproj/kit/internal/types"
 )
 
-func probeLoop(ctx context.Context, probe types.Probe, callback func(ok bool, err error)) {
-
-	initialDelay := probe.GetInitialDelay()
+// probeLoop runs the probe until the context is cancelled, calling back when the success or failure threshold is
+// reached. Each attempt times out, so a slow server cannot block the loop. Only changes between passing and failing are
+// logged, unless the probe logs every attempt.
+func probeLoop(ctx context.Context, name string, probe types.Probe, logf func(format string, args ...any), callback func(ok bool, err error)) {
 	period := probe.GetPeriod()
-	time.Sleep(initialDelay)
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(probe.GetInitialDelay()):
+	}
 	successes, failures := 0, 0
+	// whether the probe has ever passed, so that failing at start-up is not logged
+	passed := false
 	for {
+		attemptCtx, cancel := context.WithTimeout(ctx, probe.GetTimeout())
+		var err error
+		if tcp := probe.TCPSocket; tcp != nil {
+			err = tcpSocketProbe(attemptCtx, *tcp)
+		} else if httpGet := probe.HTTPGet; httpGet != nil {
+			err = httpGetProbe(attemptCtx, *httpGet)
+		} else {
+			panic(fmt.Errorf("probe not supported"))
+		}
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case probe.LogAttempts && err == nil:
+			logf("%s passed\n", name)
+		case probe.LogAttempts:
+			logf("%s failed: %v\n", name, err)
+		case err == nil && failures > 0 && passed:
+			logf("%s passing again\n", name)
+		case err != nil && successes > 0:
+			logf("%s failing: %v\n", name, err)
+		}
+
+		if err == nil {
+			passed = true
+			failures = 0
+			successes++
+		} else {
+			successes = 0
+			failures++
+		}
+
+		if successes == probe.GetSuccessThreshold() {
+			callback(true, nil)
+		} else if failures == probe.GetFailureThreshold() {
+			callback(false, err)
+		}
+
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			var err error
-			if tcp := probe.TCPSocket; tcp != nil {
-				_, err = net.Dial("tcp", fmt.Sprintf("localhost:%v", tcp.Port))
-			} else if httpGet := probe.HTTPGet; httpGet != nil {
-				err = func() error {
-					resp, err := http.Get(httpGet.GetURL())
-					if err != nil {
-						return fmt.Errorf("failed to get %q: %w", httpGet.GetURL(), err)
-					}
-					if resp.StatusCode >= 300 {
-						data, _ := io.ReadAll(resp.Body)
-						return fmt.Errorf("%s: %q", resp.Status, data)
-					}
-					return nil
-				}()
-			} else {
-				panic(fmt.Errorf("probe not supported"))
-			}
+		case <-time.After(period):
+		}
+	}
+}
 
-			if err == nil {
-				failures = 0
-				successes++
-			} else {
-				successes = 0
-				failures++
-			}
+func httpGetProbe(ctx context.Context, a types.HTTPGetAction) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.GetURL(), nil)
+	if err != nil {
+		return err
+	}
+	for _, h := range a.HTTPHeaders {
+		req.Header.Add(h.Name, h.Value)
+	}
+	if a.Host != "" {
+		req.Host = a.Host
+	}
+	client := http.DefaultClient
+	if a.InsecureSkipVerify {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		client = &http.Client{Transport: transport}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get %q: %w", a.GetURL(), err)
+	}
+	defer resp.Body.Close()
+	ok, err := a.AcceptsStatus(resp.StatusCode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %q", resp.Status, data)
+	}
+	return nil
+}
 
-			if successes == probe.GetSuccessThreshold() {
-				callback(true, nil)
-			} else if failures == probe.GetFailureThreshold() {
-				callback(false, err)
+func tcpSocketProbe(ctx context.Context, a types.TCPSocketAction) error {
+	addr := fmt.Sprintf("localhost:%v", a.Port)
+	var conn net.Conn
+	var err error
+	if a.TLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: a.GetServerName(), InsecureSkipVerify: a.InsecureSkipVerify}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	if a.Send != "" {
+		if _, err := io.WriteString(conn, a.Send); err != nil {
+			return fmt.Errorf("failed to send: %w", err)
+		}
+	}
+	if a.Expect != "" {
+		reply := make([]byte, len(a.Expect))
+		n, err := io.ReadFull(conn, reply)
+		if string(reply[:n]) != a.Expect {
+			if err != nil {
+				return fmt.Errorf("expected %q, got %q: %w", a.Expect, reply[:n], err)
 			}
-			time.Sleep(period)
+			return fmt.Errorf("expected %q, got %q", a.Expect, reply[:n])
+		}
+	}
+	return nil
+}
+
+// checkReady returns an error if the ready condition does not hold. Paths are relative to the working directory.
+func checkReady(ctx context.Context, workingDir string, r types.Ready) error {
+	if r.PathExists != "" {
+		path := r.PathExists
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+	}
+	if r.URLOk != "" {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URLOk, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("%s: %s", r.URLOk, resp.Status)
 		}
 	}
+	return nil
 }
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_httpGetProbe(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.local" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	action := func(server *httptest.Server) types.HTTPGetAction {
+		u, _ := url.Parse(server.URL)
+		port, _ := strconv.Atoi(u.Port())
+		return types.HTTPGetAction{Scheme: u.Scheme, Port: uint16(port)}
+	}
+	ctx := context.Background()
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		assert.EqualError(t, httpGetProbe(ctx, action(server)), `401 Unauthorized: ""`)
+	})
+	t.Run("Status codes", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		a := action(server)
+		a.StatusCodes = "200-299,401"
+		assert.NoError(t, httpGetProbe(ctx, a))
+	})
+	t.Run("Host and headers", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		a := action(server)
+		a.Host = "api.local"
+		a.HTTPHeaders = []types.HTTPHeader{{Name: "Authorization", Value: "Bearer token"}}
+		assert.NoError(t, httpGetProbe(ctx, a))
+	})
+	t.Run("Self-signed certificate", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		a := action(server)
+		assert.Error(t, httpGetProbe(ctx, a))
+		a.InsecureSkipVerify = true
+		assert.NoError(t, httpGetProbe(ctx, a))
+	})
+}
+
+func port(addr net.Addr) uint16 {
+	return uint16(addr.(*net.TCPAddr).Port)
+}
+
+func Test_tcpSocketProbe(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Closed", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "localhost:0")
+		assert.NoError(t, err)
+		assert.NoError(t, listener.Close())
+		assert.Error(t, tcpSocketProbe(ctx, types.TCPSocketAction{Port: port(listener.Addr())}))
+	})
+	t.Run("Banner", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "localhost:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				// like Redis, reply to a ping
+				buf := make([]byte, 6)
+				if _, err := io.ReadFull(conn, buf); err == nil && string(buf) == "PING\r\n" {
+					_, _ = conn.Write([]byte("+PONG\r\n"))
+				}
+				_ = conn.Close()
+			}
+		}()
+		a := types.TCPSocketAction{Port: port(listener.Addr()), Send: "PING\r\n", Expect: "+PONG"}
+		assert.NoError(t, tcpSocketProbe(ctx, a))
+		a.Expect = "-ERR"
+		assert.EqualError(t, tcpSocketProbe(ctx, a), `expected "-ERR", got "+PON"`)
+	})
+	t.Run("TLS", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		a := types.TCPSocketAction{Port: port(server.Listener.Addr()), TLS: true}
+		// the certificate is self-signed
+		assert.Error(t, tcpSocketProbe(ctx, a))
+		a.InsecureSkipVerify = true
+		assert.NoError(t, tcpSocketProbe(ctx, a))
+	})
+	t.Run("Not TLS", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		a := types.TCPSocketAction{Port: port(server.Listener.Addr()), TLS: true, InsecureSkipVerify: true}
+		assert.Error(t, tcpSocketProbe(ctx, a))
+	})
+}
+
+func Test_probeLoop(t *testing.T) {
+	t.Run("Timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(3 * time.Second):
+			}
+		}))
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		probe := types.Probe{HTTPGet: &types.HTTPGetAction{Port: port(server.Listener.Addr())}, InitialDelaySeconds: 1, TimeoutSeconds: 1, FailureThreshold: 1}
+		errs := make(chan error, 1)
+		go probeLoop(ctx, "readiness probe", probe, t.Logf, func(ok bool, err error) {
+			errs <- err
+		})
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(2500 * time.Millisecond):
+			assert.Fail(t, "the probe did not time out")
+		}
+	})
+	t.Run("Only transitions are logged", func(t *testing.T) {
+		healthy := make(chan bool, 10)
+		healthy <- true
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-healthy:
+			default:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		probe := types.Probe{HTTPGet: &types.HTTPGetAction{Port: port(server.Listener.Addr())}, InitialDelaySeconds: 1, PeriodSeconds: 1}
+		logs := make(chan string, 10)
+		go probeLoop(ctx, "readiness probe", probe, func(format string, args ...any) {
+			logs <- fmt.Sprintf(format, args...)
+		}, func(ok bool, err error) {})
+		select {
+		case log := <-logs:
+			assert.Equal(t, "readiness probe failing: 503 Service Unavailable: \"\"\n", log)
+		case <-time.After(2500 * time.Millisecond):
+			assert.Fail(t, "nothing was logged")
+		}
+		cancel()
+		assert.Empty(t, logs)
+	})
+}
+
+func Test_checkReady(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Run("Path exists", func(t *testing.T) {
+		ready := types.Ready{PathExists: "tmp/server.sock"}
+		assert.Error(t, checkReady(ctx, dir, ready))
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "tmp"), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "tmp", "server.sock"), nil, 0o644))
+		assert.NoError(t, checkReady(ctx, dir, ready))
+	})
+	t.Run("URL ok", func(t *testing.T) {
+		healthy := atomic.Bool{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+		ready := types.Ready{URLOk: server.URL + "/_health"}
+		assert.EqualError(t, checkReady(ctx, dir, ready), server.URL+"/_health: 503 Service Unavailable")
+		healthy.Store(true)
+		assert.NoError(t, checkReady(ctx, dir, ready))
+	})
+}
@@ -0,0 +1,75 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// build builds a container image using BuildKit, by shelling out to the container runtime's CLI, as the Docker API
+// requires a BuildKit session to support features such as cache mounts.
+type build struct {
+	name string
+	log  *log.Logger
+	spec types.Spec
+	types.Task
+}
+
+func (b *build) Run(ctx context.Context, stdout, stderr io.Writer) error {
+	log := b.log
+	command := runtimeName(b.spec)
+	log.Printf("building image from %q", b.Build.GetDockerfile(b.WorkingDir))
+	args, err := b.args(command)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdout = stdout
+	// BuildKit writes progress to stderr
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+	return nil
+}
+
+func (b *build) args(command string) ([]string, error) {
+	x := b.Build
+	args := []string{"build"}
+	// Podman uses Buildah rather than BuildKit, and does not support the progress flag
+	if command != runtimePodman {
+		args = append(args, "--progress=plain")
+	}
+	args = append(args, "--file", x.GetDockerfile(b.WorkingDir))
+	for _, tag := range x.GetTags(b.name) {
+		args = append(args, "--tag", tag)
+	}
+	buildArgs, err := x.BuildArgs.Environ()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build args: %w", err)
+	}
+	// sort build args so that the command is stable
+	sort.Strings(buildArgs)
+	for _, arg := range buildArgs {
+		args = append(args, "--build-arg", arg)
+	}
+	if x.Target != "" {
+		args = append(args, "--target", x.Target)
+	}
+	for _, from := range x.CacheFrom {
+		args = append(args, "--cache-from", from)
+	}
+	for _, to := range x.CacheTo {
+		args = append(args, "--cache-to", to)
+	}
+	return append(args, x.GetContext(b.WorkingDir)), nil
+}
+
+var _ Interface = &build{}
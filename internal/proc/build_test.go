@@ -0,0 +1,43 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_build_args(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		b := &build{name: "foo", Task: types.Task{Build: &types.Build{}}}
+		args, err := b.args("docker")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"build", "--progress=plain", "--file", "Dockerfile", "--tag", "foo", "."}, args)
+	})
+	t.Run("Podman", func(t *testing.T) {
+		b := &build{name: "foo", Task: types.Task{Build: &types.Build{}}}
+		args, err := b.args("podman")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"build", "--file", "Dockerfile", "--tag", "foo", "."}, args)
+	})
+	t.Run("All", func(t *testing.T) {
+		b := &build{name: "foo", Task: types.Task{WorkingDir: "src", Build: &types.Build{
+			Context:    "app",
+			Dockerfile: "Dockerfile.dev",
+			Tags:       types.Strings{"app:dev"},
+			BuildArgs:  types.EnvVars{"B": {Value: "2"}, "A": {Value: "1"}},
+			Target:     "dev",
+			CacheFrom:  types.Strings{"type=local,src=.cache"},
+			CacheTo:    types.Strings{"type=local,dest=.cache"},
+		}}}
+		args, err := b.args("docker")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"build", "--progress=plain", "--file", "src/app/Dockerfile.dev",
+			"--tag", "app:dev",
+			"--build-arg", "A=1", "--build-arg", "B=2",
+			"--target", "dev",
+			"--cache-from", "type=local,src=.cache",
+			"--cache-to", "type=local,dest=.cache",
+			"src/app"}, args)
+	})
+}
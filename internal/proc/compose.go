@@ -0,0 +1,134 @@
+package proc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// compose runs services from a Compose file, by shelling out to the container runtime's compose command.
+type compose struct {
+	log  *log.Logger
+	spec types.Spec
+	types.Task
+}
+
+func (c *compose) command(ctx context.Context, args ...string) *exec.Cmd {
+	args = append([]string{"compose", "--file", c.Compose.GetFile(c.WorkingDir)}, args...)
+	cmd := exec.CommandContext(ctx, runtimeName(c.spec), args...)
+	cmd.Dir = c.WorkingDir
+	return cmd
+}
+
+func (c *compose) Run(ctx context.Context, stdout, stderr io.Writer) error {
+	log := c.log
+	services := c.Compose.Services
+
+	log.Printf("starting compose services %s\n", services)
+	up := c.command(ctx, append([]string{"up", "--detach"}, services...)...)
+	up.Stdout = stdout
+	up.Stderr = stderr
+	if err := up.Run(); err != nil {
+		return fmt.Errorf("failed to start compose services: %w", err)
+	}
+
+	// take the services down, even if the task was cancelled
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		log.Printf("stopping compose services %s\n", services)
+		down := c.command(ctx, append([]string{"down"}, services...)...)
+		down.Stdout = stdout
+		down.Stderr = stderr
+		if err := down.Run(); err != nil {
+			log.Printf("failed to stop compose services: %v\n", err)
+		}
+	}()
+
+	logs := c.command(ctx, append([]string{"logs", "--follow", "--since", time.Now().Format(time.RFC3339)}, services...)...)
+	logs.Stdout = stdout
+	logs.Stderr = stderr
+	if err := logs.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to follow compose logs: %w", err)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	// the logs stop when every service has exited
+	return errors.New("compose services exited")
+}
+
+// Ready returns true when the services are running, and healthy if they have a health check.
+func (c *compose) Ready(ctx context.Context) (bool, error) {
+	ps := c.command(ctx, append([]string{"ps", "--all", "--format", "json"}, c.Compose.Services...)...)
+	out, err := ps.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to get compose services: %w", err)
+	}
+	states, err := parseComposeStates(out)
+	if err != nil {
+		return false, err
+	}
+	return composeReady(states, c.Compose.Services), nil
+}
+
+// composeState is the state of a service's container, as output by "compose ps".
+type composeState struct {
+	Service string `json:"Service"`
+	State   string `json:"State"`
+	// "healthy", "unhealthy", "starting", or empty if there is no health check
+	Health string `json:"Health"`
+}
+
+// parseComposeStates parses the output of "compose ps --format json", which is a JSON array in older versions of
+// Compose, and a JSON object per line in newer ones.
+func parseComposeStates(out []byte) ([]composeState, error) {
+	out = bytes.TrimSpace(out)
+	var states []composeState
+	if bytes.HasPrefix(out, []byte("[")) {
+		if err := json.Unmarshal(out, &states); err != nil {
+			return nil, fmt.Errorf("failed to parse compose services: %w", err)
+		}
+		return states, nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		state := composeState{}
+		if err := json.Unmarshal([]byte(line), &state); err != nil {
+			return nil, fmt.Errorf("failed to parse compose services: %w", err)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// composeReady returns true if every container is running and healthy, and every requested service has a container.
+func composeReady(states []composeState, services []string) bool {
+	if len(states) == 0 {
+		return false
+	}
+	found := map[string]bool{}
+	for _, s := range states {
+		if s.State != "running" || s.Health != "" && s.Health != "healthy" {
+			return false
+		}
+		found[s.Service] = true
+	}
+	for _, service := range services {
+		if !found[service] {
+			return false
+		}
+	}
+	return true
+}
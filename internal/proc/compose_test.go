@@ -0,0 +1,38 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseComposeStates(t *testing.T) {
+	expected := []composeState{
+		{Service: "db", State: "running", Health: "healthy"},
+		{Service: "redis", State: "running"},
+	}
+	t.Run("Array", func(t *testing.T) {
+		states, err := parseComposeStates([]byte(`[{"Service":"db","State":"running","Health":"healthy"},{"Service":"redis","State":"running","Health":""}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, states)
+	})
+	t.Run("Lines", func(t *testing.T) {
+		states, err := parseComposeStates([]byte("{\"Service\":\"db\",\"State\":\"running\",\"Health\":\"healthy\"}\n{\"Service\":\"redis\",\"State\":\"running\",\"Health\":\"\"}\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, states)
+	})
+	t.Run("Empty", func(t *testing.T) {
+		states, err := parseComposeStates([]byte("\n"))
+		assert.NoError(t, err)
+		assert.Empty(t, states)
+	})
+}
+
+func Test_composeReady(t *testing.T) {
+	assert.False(t, composeReady(nil, nil), "no containers")
+	assert.True(t, composeReady([]composeState{{Service: "db", State: "running"}}, nil), "running, no health check")
+	assert.True(t, composeReady([]composeState{{Service: "db", State: "running", Health: "healthy"}}, []string{"db"}), "healthy")
+	assert.False(t, composeReady([]composeState{{Service: "db", State: "running", Health: "starting"}}, nil), "health check starting")
+	assert.False(t, composeReady([]composeState{{Service: "db", State: "exited"}}, nil), "exited")
+	assert.False(t, composeReady([]composeState{{Service: "db", State: "running"}}, []string{"db", "redis"}), "missing service")
+}
@@ -1,9 +1,7 @@
 package proc
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"hash/adler32"
@@ -11,23 +9,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"time"
 
-	"github.com/docker/cli/cli/config"
-	"github.com/docker/distribution/reference"
-	dockertypes "github.com/docker/docker/api/types"
-	dockercontainer "github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/api/types/strslice"
-	"github.com/docker/docker/client"
 	"github.com/docker/docker/errdefs"
-	"github.com/docker/docker/pkg/archive"
-	"github.com/docker/docker/pkg/stdcopy"
-	"github.com/docker/docker/registry"
-	"github.com/docker/go-connections/nat"
 	"github.com/kitproj/kit/internal/types"
-	v1 "github.com/opencontainers/image-spec/specs-go/v1"
-	"k8s.io/utils/strings/slices"
 )
 
 type container struct {
@@ -35,27 +19,37 @@ type container struct {
 	log  *log.Logger
 	spec types.Spec
 	types.Task
+	leaver
+}
+
+// hash returns a hash of what the container was created with, so it is re-created when that changes: the task, and the
+// network it is attached to.
+func (c *container) hash() string {
+	data, _ := json.Marshal(struct {
+		types.Task
+		Network string `json:"network,omitempty"`
+	}{c.Task, c.spec.Network})
+	return fmt.Sprintf("%x", adler32.Checksum(data))
 }
 
 func (c *container) Run(ctx context.Context, stdout, stderr io.Writer) error {
 
 	log := c.log
-	data, _ := json.Marshal(c.Task)
-	expectedHash := fmt.Sprintf("%x", adler32.Checksum(data))
+	expectedHash := c.hash()
 
-	cli, err := client.NewClientWithOpts(client.FromEnv)
+	cli, err := newRuntime(c.spec)
 	if err != nil {
-		return fmt.Errorf("failed to create docker client: %w", err)
+		return fmt.Errorf("failed to create container runtime: %w", err)
 	}
 	defer cli.Close()
 
 	dockerfile := filepath.Join(c.Image, "Dockerfile")
-	id, existingHash, err := c.getContainer(ctx, cli)
+	id, existingHash, err := cli.Find(ctx, c.name)
 
 	// If the container exists and the hash is different, remove it.
 	if id != "" && existingHash != expectedHash {
 		log.Println("removing container")
-		if err := cli.ContainerRemove(ctx, id, dockertypes.ContainerRemoveOptions{Force: true}); err != nil {
+		if err := cli.Remove(ctx, id); err != nil {
 			return fmt.Errorf("failed to remove container: %w", err)
 		}
 		id = ""
@@ -71,78 +65,21 @@ func (c *container) Run(ctx context.Context, stdout, stderr io.Writer) error {
 	} else if id != "" {
 		log.Printf("container already exists, skipping build/pull\n")
 	} else if _, err := os.Stat(dockerfile); err == nil {
-		log.Printf("creating tar image from %q", dockerfile)
-		r, err := archive.TarWithOptions(filepath.Dir(dockerfile), &archive.TarOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to create tar: %w", err)
-		}
-		defer r.Close()
 		log.Printf("building image from %q", dockerfile)
-		resp, err := cli.ImageBuild(ctx, r, dockertypes.ImageBuildOptions{Dockerfile: filepath.Base(dockerfile), Tags: []string{c.name}})
-		if err != nil {
+		if err := cli.Build(ctx, dockerfile, c.name, stdout); err != nil {
 			return fmt.Errorf("failed to build image: %w", err)
 		}
-		defer resp.Body.Close()
-		log.Printf("building image from %q (logs)", dockerfile)
-		if _, err = io.Copy(stdout, resp.Body); err != nil {
-			return fmt.Errorf("failed to build image (logs): %w", err)
-		}
-	} else if c.ImagePullPolicy != "Never" {
+	} else if c.spec.BuildsImage(c.Image) {
+		log.Printf("image %q is built by the workflow, skipping pull\n", c.Image)
+	} else if pull, err := c.shouldPull(ctx, cli); err != nil {
+		return fmt.Errorf("failed to determine if image should be pulled: %w", err)
+	} else if pull {
 		log.Printf("pulling image %q", c.Image)
-
-		ref, err := reference.ParseNormalizedNamed(c.Image)
-		if err != nil {
-			return fmt.Errorf("unable to parse image: %w", err)
-		}
-		repoInfo, err := registry.ParseRepositoryInfo(ref)
-		if err != nil {
-			return fmt.Errorf("unable to parse repository info: %w", err)
-		}
-
-		var server string
-		if repoInfo.Index.Official {
-			info, err := cli.Info(ctx)
-			if err != nil || info.IndexServerAddress == "" {
-				server = registry.IndexServer
-			} else {
-				server = info.IndexServerAddress
-			}
-		} else {
-			server = repoInfo.Index.Name
-		}
-		errBuf := &bytes.Buffer{}
-		cf := config.LoadDefaultConfigFile(errBuf)
-		if errBuf.Len() > 0 {
-			return fmt.Errorf("unable to load docker config: %s", errBuf.String())
-		}
-		authConfig, err := cf.GetAuthConfig(server)
-		if err != nil {
-			return fmt.Errorf("failed to get auth config: %w", err)
-		}
-		buf, err := json.Marshal(authConfig)
-		if err != nil {
-			return fmt.Errorf("failed to marshal auth config: %w", err)
-		}
-		encodedAuth := base64.URLEncoding.EncodeToString(buf)
-
-		r, err := cli.ImagePull(ctx, c.Image, dockertypes.ImagePullOptions{
-			RegistryAuth: encodedAuth,
-		})
-		if err != nil {
+		if err := cli.Pull(ctx, c.Image, stdout); err != nil {
 			return fmt.Errorf("failed to pull image: %w", err)
 		}
-		if _, err = io.Copy(stdout, r); err != nil {
-			return fmt.Errorf("failed to pull image (logs): %w", err)
-		}
-		if err = r.Close(); err != nil {
-			return fmt.Errorf("failed to pull image (close): %w", err)
-		}
 	}
 
-	portSet, portBindings, err := c.createPorts()
-	if err != nil {
-		return fmt.Errorf("failed to create ports: %w", err)
-	}
 	binds, err := c.createBinds()
 	if err != nil {
 		return fmt.Errorf("failed to create binds: %w", err)
@@ -152,93 +89,98 @@ func (c *container) Run(ctx context.Context, stdout, stderr io.Writer) error {
 		image = c.name
 	}
 
-	log.Printf("creating container")
-	_, err = cli.ContainerCreate(ctx, &dockercontainer.Config{
-		Hostname:     c.name,
-		ExposedPorts: portSet,
-		Tty:          c.TTY,
-		Env:          environ,
-		Cmd:          strslice.StrSlice(c.Args),
-		Image:        image,
-		User:         c.User,
-		WorkingDir:   c.WorkingDir,
-		Entrypoint:   strslice.StrSlice(c.GetCommand()),
-		Labels:       map[string]string{hashLabel: expectedHash},
-	}, &dockercontainer.HostConfig{
-		PortBindings: portBindings,
-		Binds:        binds,
-	}, &network.NetworkingConfig{}, &v1.Platform{}, c.name)
-	if ignoreConflict(err) != nil {
-		return fmt.Errorf("failed to create container: %w", err)
+	if network := c.spec.Network; network != "" {
+		if err := cli.CreateNetwork(ctx, network); err != nil {
+			return fmt.Errorf("failed to create network %q: %w", network, err)
+		}
 	}
-	id, _, err = c.getContainer(ctx, cli)
-	if err != nil {
-		return fmt.Errorf("failed to get container ID: %w", err)
+
+	if id == "" {
+		log.Printf("creating container")
+		err = cli.Create(ctx, containerConfig{
+			Name:       c.name,
+			Hostname:   c.name,
+			Image:      image,
+			Entrypoint: c.GetCommand(),
+			Cmd:        c.Args,
+			Env:        environ,
+			User:       c.User,
+			WorkingDir: c.WorkingDir,
+			Tty:        c.TTY,
+			Ports:      c.Ports,
+			Binds:      binds,
+			Labels:     map[string]string{hashLabel: expectedHash},
+			Network:    c.spec.Network,
+			CPU:        c.Resources.GetCPULimit(),
+			Memory:     c.Resources.GetMemoryLimit(),
+		})
+		if ignoreConflict(err) != nil {
+			return fmt.Errorf("failed to create container: %w", err)
+		}
+		id, _, err = cli.Find(ctx, c.name)
+		if err != nil {
+			return fmt.Errorf("failed to get container ID: %w", err)
+		}
 	}
-	if err = cli.ContainerStart(ctx, id, dockertypes.ContainerStartOptions{}); err != nil {
+	if err = cli.Start(ctx, id); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
+	c.started(Leftover{Container: id})
 	go func() {
 		<-ctx.Done()
 		if err := c.stop(context.Background()); err != nil {
 			log.Printf("failed to stop: %v", err)
 		}
 	}()
-	logs, err := cli.ContainerLogs(ctx, c.name, dockertypes.ContainerLogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Follow:     true,
-		Since:      time.Now().Format(time.RFC3339),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to log container: %w", err)
-	}
-	defer logs.Close()
-	if _, err = stdcopy.StdCopy(stdout, stderr, logs); err != nil {
+	if err = cli.Logs(ctx, id, stdout, stderr); err != nil {
 		// ignore errors, might be content cancelled, we still need to wait for the container to exit
 		log.Printf("failed to log container: %v", err)
 	}
-	waitC, errC := cli.ContainerWait(context.Background(), id, dockercontainer.WaitConditionNotRunning)
-	select {
-	case wait := <-waitC:
-		if wait.StatusCode != 0 {
-			return fmt.Errorf("exit code %d", wait.StatusCode)
-		}
-		return nil
-	case err := <-errC:
+	code, err := cli.Wait(context.Background(), id)
+	if err != nil {
 		return fmt.Errorf("failed to wait for container: %w", err)
 	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
 }
 
-func (c *container) createPorts() (nat.PortSet, map[nat.Port][]nat.PortBinding, error) {
-	portSet := nat.PortSet{}
-	portBindings := map[nat.Port][]nat.PortBinding{}
-	for _, p := range c.Ports {
-		port, err := nat.NewPort("tcp", fmt.Sprint(p.ContainerPort))
-		if err != nil {
-			return nil, nil, err
-		}
-		portSet[port] = struct{}{}
-		hostPort := p.GetHostPort()
-		portBindings[port] = []nat.PortBinding{{
-			HostPort: fmt.Sprint(hostPort),
-		}}
+func (c *container) shouldPull(ctx context.Context, cli runtime) (bool, error) {
+	switch policy := c.GetImagePullPolicy(); policy {
+	case "Always":
+		return true, nil
+	case "Never":
+		return false, nil
+	case "IfNotPresent":
+		exists, err := cli.ImageExists(ctx, c.Image)
+		return !exists, err
+	default:
+		return false, fmt.Errorf("invalid image pull policy %q", policy)
 	}
-	return portSet, portBindings, nil
 }
 
 func (c *container) createBinds() ([]string, error) {
 	var binds []string
 	for _, mount := range c.VolumeMounts {
-		for _, volume := range c.spec.Volumes {
-			if volume.Name == mount.Name {
-				abs, err := filepath.Abs(volume.HostPath.Path)
-				if err != nil {
-					return nil, err
-				}
-				binds = append(binds, fmt.Sprintf("%s:%s", abs, mount.MountPath))
+		volume, ok := c.spec.GetVolume(mount.Name)
+		if !ok {
+			return nil, fmt.Errorf("volume %q not found", mount.Name)
+		}
+		// a volume without a host path is a named volume, managed by the container runtime
+		source := volume.Name
+		if volume.HostPath != nil {
+			abs, err := filepath.Abs(volume.HostPath.Path)
+			if err != nil {
+				return nil, err
 			}
+			source = abs
 		}
+		bind := fmt.Sprintf("%s:%s", source, mount.MountPath)
+		if mount.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
 	}
 	return binds, nil
 }
@@ -248,12 +190,12 @@ func (c *container) stop(ctx context.Context) error {
 		return nil
 	}
 	log := c.log
-	cli, err := client.NewClientWithOpts(client.FromEnv)
+	cli, err := newRuntime(c.spec)
 	if err != nil {
-		return fmt.Errorf("failed to create docker client: %w", err)
+		return fmt.Errorf("failed to create container runtime: %w", err)
 	}
 	defer cli.Close()
-	id, _, err := c.getContainer(ctx, cli)
+	id, _, err := cli.Find(ctx, c.name)
 	if err != nil {
 		return fmt.Errorf("failed to get container ID: %w", err)
 	}
@@ -263,31 +205,38 @@ func (c *container) stop(ctx context.Context) error {
 	log.Printf("stopping container\n")
 	grace := c.spec.GetTerminationGracePeriod()
 	timeout := int(grace.Seconds())
-	err = cli.ContainerStop(ctx, id, dockercontainer.StopOptions{
-		Timeout: &timeout,
-	})
+	err = cli.Stop(ctx, id, timeout)
 	if ignoreNotExist(err) != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
 	return nil
 }
 
-const hashLabel = "kit.hash"
-
-func (c *container) getContainer(ctx context.Context, cli *client.Client) (string, string, error) {
-	list, err := cli.ContainerList(ctx, dockertypes.ContainerListOptions{All: true})
+func (c *container) Sync(ctx context.Context, files []string) error {
+	archive, err := syncArchive(c.Task, files)
 	if err != nil {
-		return "", "", err
+		return fmt.Errorf("failed to archive files: %w", err)
 	}
-	for _, existing := range list {
-		if slices.Contains(existing.Names, "/"+c.name) {
-			id := existing.ID
-			return id, existing.Labels[hashLabel], nil
-		}
+	cli, err := newRuntime(c.spec)
+	if err != nil {
+		return fmt.Errorf("failed to create container runtime: %w", err)
 	}
-	return "", "", nil
+	defer cli.Close()
+	id, _, err := cli.Find(ctx, c.name)
+	if err != nil {
+		return fmt.Errorf("failed to get container ID: %w", err)
+	}
+	if id == "" {
+		return fmt.Errorf("container %q not found", c.name)
+	}
+	if err := cli.CopyTo(ctx, id, archive); err != nil {
+		return fmt.Errorf("failed to copy files: %w", err)
+	}
+	return nil
 }
 
+const hashLabel = "kit.hash"
+
 func ignoreConflict(err error) error {
 	if errdefs.IsConflict(err) {
 		return nil
@@ -304,3 +253,4 @@ func ignoreNotExist(err error) error {
 }
 
 var _ Interface = &container{}
+var _ Leaver = &container{}
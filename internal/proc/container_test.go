@@ -0,0 +1,43 @@
+package proc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_container_createBinds(t *testing.T) {
+	abs, err := filepath.Abs("src")
+	assert.NoError(t, err)
+	spec := types.Spec{Volumes: []types.Volume{
+		{Name: "src", HostPath: &types.HostPath{Path: "src"}},
+		{Name: "data"},
+	}}
+	t.Run("HostPath", func(t *testing.T) {
+		c := &container{spec: spec, Task: types.Task{VolumeMounts: []types.VolumeMount{{Name: "src", MountPath: "/src", ReadOnly: true}}}}
+		binds, err := c.createBinds()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{abs + ":/src:ro"}, binds)
+	})
+	t.Run("NamedVolume", func(t *testing.T) {
+		c := &container{spec: spec, Task: types.Task{VolumeMounts: []types.VolumeMount{{Name: "data", MountPath: "/data"}}}}
+		binds, err := c.createBinds()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"data:/data"}, binds)
+	})
+	t.Run("Missing", func(t *testing.T) {
+		c := &container{spec: spec, Task: types.Task{VolumeMounts: []types.VolumeMount{{Name: "missing", MountPath: "/missing"}}}}
+		_, err := c.createBinds()
+		assert.EqualError(t, err, `volume "missing" not found`)
+	})
+}
+
+func Test_container_hash(t *testing.T) {
+	c := &container{Task: types.Task{Image: "postgres"}}
+	hash := c.hash()
+	assert.Equal(t, hash, (&container{Task: types.Task{Image: "postgres"}}).hash())
+	c.spec.Network = "kit"
+	assert.NotEqual(t, hash, c.hash(), "a container on another network is re-created")
+}
@@ -0,0 +1,69 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// ExitError is returned when a container exits with a non-zero exit code.
+type ExitError struct {
+	Code int64
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit code %d", e.Code)
+}
+
+// ExitCode returns the exit code of the process that returned the error: zero if there was no error, or -1 if it is unknown,
+// e.g. the process was killed by a signal, or failed to start.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var hostErr *exec.ExitError
+	if errors.As(err, &hostErr) {
+		return hostErr.ExitCode()
+	}
+	var containerErr *ExitError
+	if errors.As(err, &containerErr) {
+		return int(containerErr.Code)
+	}
+	return -1
+}
+
+// RunHooks runs the commands on the host one after the other, in the task's working directory and with its environment,
+// stopping at the first that fails.
+func RunHooks(ctx context.Context, log *log.Logger, spec types.Spec, t types.Task, commands []types.Strings, env types.EnvVars, stdout, stderr io.Writer) error {
+	for _, command := range commands {
+		if len(command) == 0 {
+			continue
+		}
+		hook := &host{
+			log:  log,
+			spec: spec,
+			Task: types.Task{
+				Command:    command,
+				WorkingDir: t.WorkingDir,
+				Env:        types.EnvVars{},
+				Envfile:    t.Envfile,
+				EnvFrom:    t.EnvFrom,
+			},
+		}
+		for k, v := range t.Env {
+			hook.Env[k] = v
+		}
+		for k, v := range env {
+			hook.Env[k] = v
+		}
+		if err := hook.Run(ctx, stdout, stderr); err != nil {
+			return fmt.Errorf("%q failed: %w", command.String(), err)
+		}
+	}
+	return nil
+}
@@ -5,10 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"os/exec"
-	"strings"
-	"syscall"
 	"time"
 
 	"github.com/kitproj/kit/internal/types"
@@ -18,6 +15,8 @@ type host struct {
 	log  *log.Logger
 	spec types.Spec
 	types.Task
+	processSampler
+	leaver
 }
 
 func (h *host) Run(ctx context.Context, stdout, stderr io.Writer) error {
@@ -29,61 +28,100 @@ func (h *host) Run(ctx context.Context, stdout, stderr io.Writer) error {
 		return fmt.Errorf("error getting spec environ: %w", err)
 	}
 
-	command := h.GetCommand()
-	path := command[0]
-	cmd := exec.CommandContext(ctx, path, append(command[1:], h.Args...)...)
+	command := append(append([]string{}, h.GetCommand()...), h.Args...)
+	if len(h.Command) == 0 && h.Sh != "" && len(h.Shell) == 0 {
+		command = shellCommand(command)
+	}
+	// limit resources using a cgroup if we can, otherwise we enforce them ourselves
+	limited := h.Resources == nil
+	if !limited {
+		command, limited = cgroupCommand(h.Resources, command)
+	}
+	// not exec.CommandContext, as that would kill the process, rather than giving it and its children a chance to exit
+	cmd := exec.Command(command[0], command[1:]...)
 	cmd.Dir = h.WorkingDir
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-	}
-	cmd.Env = append(environ, os.Environ()...)
+	cmd.SysProcAttr = sysProcAttr()
+	cmd.Env = append(environ, types.HostEnviron(h.Task)...)
 	log := h.log
 	log.Println("starting process")
 	err = cmd.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start process: %w", err)
 	}
-	// capture pgid straight away because it's not available after the process exits,
-	// the process may exit and leave children behind.
+	// capture the process group straight away, so we can stop any children the process starts
 	pid := cmd.Process.Pid
-	pgid, err := syscall.Getpgid(pid)
+	group, err := newProcessGroup(cmd.Process)
 	if err != nil {
-		return fmt.Errorf("failed get pgid: %w", err)
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("failed to get process group: %w", err)
+	}
+	defer group.close()
+	if leftover, ok := group.leftover(); ok {
+		h.started(leftover)
 	}
+	h.track(pid)
+	defer h.untrack()
+	killed := func() bool { return false }
+	if !limited {
+		killed = enforceLimits(ctx, log, h.Resources, pid, group)
+	}
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		<-ctx.Done()
-		if err := h.stop(pgid); err != nil {
+		if err := h.stop(group); err != nil {
 			log.Printf("failed to stop process: %v", err)
 		}
 	}()
-	return cmd.Wait()
+	err = cmd.Wait()
+	// the process may have left children behind (e.g. "sh -c 'npm start'"), they must be stopped before we return, or
+	// they may outlive kit, and hold on to the task's ports
+	cancel()
+	<-stopped
+	if killed() {
+		return memoryLimitExceeded(h.Resources)
+	}
+	return err
 }
 
-func (h *host) stop(pid int) error {
-	target, err := os.FindProcess(-pid)
-	if err != nil {
-		return fmt.Errorf("failed to find process: %w", err)
+// stop asks the processes to exit, and kills them if they have not exited by the end of the grace period.
+func (h *host) stop(group *processGroup) error {
+	if !group.alive() {
+		return nil
 	}
-	log := h.log
-	if err := target.Signal(syscall.SIGTERM); ignoreProcessFinishedErr(err) != nil {
-		log.Printf("failed to terminate: %v", err)
+	return stopGroup(group, h.spec.GetTerminationGracePeriod())
+}
+
+// stopGroup asks the processes to exit, and kills them if they have not exited by the end of the grace period. It
+// returns an error if any are still running after that.
+func stopGroup(group *processGroup, gracePeriod time.Duration) error {
+	// if they cannot be asked to exit, they're killed straight away
+	if err := group.terminate(); err == nil && exited(group, gracePeriod) {
+		return nil
 	}
-	gracePeriod := h.spec.GetTerminationGracePeriod()
-	time.Sleep(gracePeriod)
-	err = target.Signal(os.Kill)
-	if ignoreProcessFinishedErr(err) != nil {
+	if err := group.kill(); err != nil {
 		return fmt.Errorf("failed to kill: %w", err)
 	}
+	if !exited(group, time.Second) {
+		return fmt.Errorf("processes are still running after being killed")
+	}
 	return nil
 }
 
-func ignoreProcessFinishedErr(err error) error {
-	if err != nil && !strings.Contains(err.Error(), "process already finished") {
-		return err
+// exited waits for the processes to exit, returning false if any are still running after the timeout.
+func exited(group *processGroup, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for group.alive() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
 	}
-	return nil
+	return true
 }
 
 var _ Interface = &host{}
+var _ Sampler = &host{}
+var _ Leaver = &host{}
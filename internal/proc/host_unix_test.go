@@ -0,0 +1,60 @@
+//go:build !windows
+
+package proc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_host_stopsChildren(t *testing.T) {
+	gracePeriod := int32(1)
+	spec := types.Spec{TerminationGracePeriodSeconds: &gracePeriod}
+	run := func(ctx context.Context, sh string) (int, error) {
+		h := &host{log: log.New(os.Stdout, "", 0), spec: spec, Task: types.Task{Command: []string{"sh", "-c", sh}}}
+		stdout := &bytes.Buffer{}
+		err := h.Run(ctx, stdout, os.Stderr)
+		pid, _ := strconv.Atoi(strings.TrimSpace(stdout.String()))
+		return pid, err
+	}
+	gone := func(t *testing.T, pid int) {
+		assert.NotZero(t, pid)
+		err := syscall.Kill(pid, 0)
+		assert.True(t, errors.Is(err, syscall.ESRCH) || zombie(pid), "process %d is still running: %v", pid, err)
+	}
+
+	t.Run("Exited", func(t *testing.T) {
+		// the child does not hold stdout, so the shell exits straight away, leaving it behind
+		pid, err := run(context.Background(), "sleep 30 > /dev/null & echo $!")
+		assert.NoError(t, err)
+		gone(t, pid)
+	})
+	t.Run("Stopped", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		// the child ignores SIGTERM, so it must be killed
+		start := time.Now()
+		pid, _ := run(ctx, "(trap '' TERM; sleep 30) > /dev/null & echo $!; wait")
+		assert.Less(t, time.Since(start), 5*time.Second)
+		gone(t, pid)
+	})
+}
+
+func Test_host_cleanEnv(t *testing.T) {
+	t.Setenv("HOST_VAR", "1")
+	h := &host{log: log.New(os.Stdout, "", 0), Task: types.Task{Command: []string{"/usr/bin/env"}, CleanEnv: true, Env: types.EnvVars{"TASK_VAR": {Value: "2"}}}}
+	stdout := &bytes.Buffer{}
+	assert.NoError(t, h.Run(context.Background(), stdout, os.Stderr))
+	assert.Equal(t, "TASK_VAR=2\n", stdout.String())
+}
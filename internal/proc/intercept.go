@@ -0,0 +1,292 @@
+package proc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const interceptLabel = x + "/intercept"
+
+// the service's original selector and ports, so it can be restored, even if kit was killed
+const originalAnnotation = x + "/original"
+
+// the port the proxy pod receives the service's traffic on
+const interceptPort = 7777
+
+// the port kit connects to, through a port-forward, to receive the service's traffic
+const tunnelPort = 7778
+
+// the number of idle connections kit keeps open to the proxy pod, i.e. the number of connections to the service that
+// can be accepted at once
+const tunnelConnections = 4
+
+// the proxy pod pairs each connection from kit with a connection to the service, using socat
+const interceptImage = "alpine/socat"
+
+type originalService struct {
+	Selector map[string]string    `json:"selector,omitempty"`
+	Ports    []corev1.ServicePort `json:"ports"`
+}
+
+// Intercept routes the traffic for the task's intercepted service, which must have one port, to the task's first port,
+// until the context is cancelled. It then restores the service.
+//
+// It runs a proxy pod, and points the service at it. The proxy accepts a connection to the service for each connection
+// kit makes to it, through a port-forward. So only protocols where the client speaks first, such as HTTP, work.
+func Intercept(ctx context.Context, log *log.Logger, t types.Task) error {
+	config, namespace, err := connect(t.Namespace)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+	services := clientset.CoreV1().Services(namespace)
+	pods := clientset.CoreV1().Pods(namespace)
+	name := t.Intercept.Service
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "kit-intercept-" + name,
+			Labels: map[string]string{interceptLabel: name},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "proxy",
+				Image: interceptImage,
+				// each connection from kit forks a listener for a single connection to the service
+				Args:  []string{fmt.Sprintf("TCP-LISTEN:%d,reuseaddr,fork", tunnelPort), fmt.Sprintf("TCP-LISTEN:%d,reuseport", interceptPort)},
+				Ports: []corev1.ContainerPort{{ContainerPort: interceptPort}},
+			}},
+		},
+	}
+
+	// the pod is left over if kit was killed
+	if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete proxy pod: %w", err)
+	}
+	for {
+		if _, err := pods.Get(ctx, pod.Name, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to get proxy pod: %w", err)
+		}
+		if err := sleep(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+	log.Printf("creating proxy pod %s/%s\n", namespace, pod.Name)
+	if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create proxy pod: %w", err)
+	}
+
+	// clean up with a new context, as the context is cancelled when kit is stopped
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	defer func() {
+		if err := pods.Delete(cleanupCtx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Printf("failed to delete proxy pod: %v\n", err)
+		}
+	}()
+
+	for {
+		p, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get proxy pod: %w", err)
+		}
+		if p.Status.Phase == corev1.PodRunning {
+			break
+		}
+		if p.Status.Phase == corev1.PodFailed || p.Status.Phase == corev1.PodSucceeded {
+			return fmt.Errorf("proxy pod %s", p.Status.Phase)
+		}
+		if err := sleep(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+
+	svc, err := services.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get service: %w", err)
+	}
+	if err := interceptService(svc, t.Intercept.Port); err != nil {
+		return err
+	}
+	log.Printf("intercepting service %s/%s\n", namespace, name)
+	if _, err := services.Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	defer func() {
+		svc, err := services.Get(cleanupCtx, name, metav1.GetOptions{})
+		if err == nil && restoreService(svc) {
+			log.Printf("restoring service %s/%s\n", namespace, name)
+			_, err = services.Update(cleanupCtx, svc, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			log.Printf("failed to restore service: %v\n", err)
+		}
+	}()
+
+	// port-forward to the proxy pod's tunnel port, on a random local port
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod.Name).
+		SubResource("portforward")
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return fmt.Errorf("failed to create round tripper: %w", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+	readyChan := make(chan struct{})
+	fw, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", tunnelPort)}, ctx.Done(), readyChan, io.Discard, io.Discard)
+	if err != nil {
+		return fmt.Errorf("failed to create port-forward: %w", err)
+	}
+	forwardErr := make(chan error, 1)
+	go func() {
+		forwardErr <- fw.ForwardPorts()
+	}()
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-forwardErr:
+		return fmt.Errorf("failed to port-forward: %w", err)
+	case <-readyChan:
+	}
+	ports, err := fw.GetPorts()
+	if err != nil {
+		return fmt.Errorf("failed to get forwarded ports: %w", err)
+	}
+	tunnelAddr := fmt.Sprintf("localhost:%d", ports[0].Local)
+	target := fmt.Sprintf("localhost:%d", t.Ports[0].GetHostPort())
+
+	for i := 0; i < tunnelConnections; i++ {
+		go func() {
+			for ctx.Err() == nil {
+				if err := tunnel(ctx, tunnelAddr, target); err != nil && ctx.Err() == nil {
+					log.Printf("intercept tunnel failed: %v\n", err)
+					_ = sleep(ctx, time.Second)
+				}
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-forwardErr:
+		return fmt.Errorf("port-forward stopped: %w", err)
+	}
+}
+
+// interceptService points the service's port at the proxy pod, remembering how it was. The service's selector is
+// replaced, so a service with more than one port is not intercepted, as its other ports would have nothing to route to.
+func interceptService(svc *corev1.Service, port uint16) error {
+	if len(svc.Spec.Ports) > 1 {
+		return fmt.Errorf("service %q has %d ports, but only a service with one port can be intercepted", svc.Name, len(svc.Spec.Ports))
+	}
+	if len(svc.Spec.Ports) == 0 || port != 0 && svc.Spec.Ports[0].Port != int32(port) {
+		return fmt.Errorf("service %q does not have port %d", svc.Name, port)
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	// if kit was killed, the service is still intercepted, so what was saved then is the original
+	if _, ok := svc.Annotations[originalAnnotation]; !ok {
+		data, err := json.Marshal(originalService{Selector: svc.Spec.Selector, Ports: svc.Spec.Ports})
+		if err != nil {
+			return err
+		}
+		svc.Annotations[originalAnnotation] = string(data)
+	}
+	svc.Spec.Selector = map[string]string{interceptLabel: svc.Name}
+	svc.Spec.Ports[0].TargetPort = intstr.FromInt(interceptPort)
+	return nil
+}
+
+// restoreService restores the service's original selector and ports, returning false if it is not intercepted.
+func restoreService(svc *corev1.Service) bool {
+	data, ok := svc.Annotations[originalAnnotation]
+	if !ok {
+		return false
+	}
+	original := originalService{}
+	if err := json.Unmarshal([]byte(data), &original); err != nil {
+		return false
+	}
+	svc.Spec.Selector = original.Selector
+	svc.Spec.Ports = original.Ports
+	delete(svc.Annotations, originalAnnotation)
+	return true
+}
+
+// tunnel opens a connection to the proxy pod, and waits for the proxy to pair it with a connection to the service.
+// The connection is then copied to and from the target, in the background.
+func tunnel(ctx context.Context, tunnelAddr, target string) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", tunnelAddr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	// the first byte is only sent once the proxy has accepted a connection to the service
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(conn, first); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	go func() {
+		defer stop()
+		defer conn.Close()
+		local, err := net.Dial("tcp", target)
+		if err != nil {
+			return
+		}
+		defer local.Close()
+		if _, err := local.Write(first); err != nil {
+			return
+		}
+		splice(conn, local)
+	}()
+	return nil
+}
+
+// splice copies between the connections, until either is closed.
+func splice(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// sleep waits for the duration, returning an error if the context is cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package proc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func Test_interceptService(t *testing.T) {
+	newService := func() *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "api"},
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromString("http")},
+				},
+			},
+		}
+	}
+	t.Run("Default port", func(t *testing.T) {
+		svc := newService()
+		assert.NoError(t, interceptService(svc, 0))
+		assert.Equal(t, map[string]string{interceptLabel: "api"}, svc.Spec.Selector)
+		assert.Equal(t, intstr.FromInt(interceptPort), svc.Spec.Ports[0].TargetPort)
+	})
+	t.Run("Port", func(t *testing.T) {
+		svc := newService()
+		assert.NoError(t, interceptService(svc, 80))
+		assert.Equal(t, intstr.FromInt(interceptPort), svc.Spec.Ports[0].TargetPort)
+	})
+	t.Run("Missing port", func(t *testing.T) {
+		assert.EqualError(t, interceptService(newService(), 8080), `service "api" does not have port 8080`)
+	})
+	t.Run("Several ports", func(t *testing.T) {
+		svc := newService()
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Name: "grpc", Port: 9090, TargetPort: intstr.FromInt(9090)})
+		assert.EqualError(t, interceptService(svc, 80), `service "api" has 2 ports, but only a service with one port can be intercepted`)
+		assert.Equal(t, map[string]string{"app": "api"}, svc.Spec.Selector, "the service is not changed")
+	})
+	t.Run("Restore", func(t *testing.T) {
+		svc := newService()
+		assert.False(t, restoreService(svc))
+		assert.NoError(t, interceptService(svc, 0))
+		// intercepting again, e.g. after kit was killed, keeps the original
+		assert.NoError(t, interceptService(svc, 0))
+		assert.True(t, restoreService(svc))
+		assert.Equal(t, newService().Spec, svc.Spec)
+		assert.Empty(t, svc.Annotations)
+	})
+}
+
+func Test_tunnel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the task, which echoes what it receives
+	target, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer target.Close()
+	go func() {
+		conn, err := target.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	// the proxy pod, which sends a request from the cluster once kit connects
+	proxy, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer proxy.Close()
+	reply := make(chan string)
+	go func() {
+		conn, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("hello"))
+		buf := make([]byte, 5)
+		_, _ = io.ReadFull(conn, buf)
+		reply <- string(buf)
+	}()
+
+	err = tunnel(ctx, proxy.Addr().String(), target.Addr().String())
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", <-reply)
+}
@@ -27,9 +27,12 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/client-go/transport/spdy"
 	"k8s.io/utils/strings/slices"
 	"sigs.k8s.io/yaml"
@@ -47,6 +50,81 @@ const x = "kit.kitproj.github.com"
 const nameLabel = x + "/name"
 const versionLabel = x + "/version"
 
+// connect returns the config to connect to the cluster, and the namespace to use if a resource does not have one, which
+// is the given namespace, or the namespace of the current context.
+func connect(namespace string) (*rest.Config, string, error) {
+	kubeConfig := os.Getenv("KUBECONFIG")
+	if kubeConfig == "" {
+		kubeConfig = clientcmd.RecommendedHomeFile
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build config: %w", err)
+	}
+
+	if namespace != "" {
+		return config, namespace, nil
+	}
+
+	// Get the namespace associated with the current context
+	namespace, _, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig},
+		&clientcmd.ConfigOverrides{},
+	).Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	return config, namespace, nil
+}
+
+// Sync copies the files into the first container of each running pod of the task, using the container's tar.
+func (k *k8s) Sync(ctx context.Context, files []string) error {
+	archive, err := syncArchive(k.Task, files)
+	if err != nil {
+		return fmt.Errorf("failed to archive files: %w", err)
+	}
+	config, _, err := connect(k.Namespace)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", nameLabel, k.name)})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	synced := 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		req := clientset.CoreV1().RESTClient().Post().Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: pod.Spec.Containers[0].Name,
+				Command:   []string{"tar", "-xf", "-", "-C", "/"},
+				Stdin:     true,
+				Stderr:    true,
+			}, scheme.ParameterCodec)
+		exec, err := remotecommand.NewSPDYExecutor(config, http.MethodPost, req.URL())
+		if err != nil {
+			return fmt.Errorf("failed to exec in pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		stderr := &bytes.Buffer{}
+		if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: bytes.NewReader(archive.Bytes()), Stderr: stderr}); err != nil {
+			return fmt.Errorf("failed to copy files to pod %s/%s: %w: %s", pod.Namespace, pod.Name, err, stderr.String())
+		}
+		synced++
+	}
+	if synced == 0 {
+		return fmt.Errorf("no running pods")
+	}
+	return nil
+}
+
 func (k *k8s) Run(ctx context.Context, stdout io.Writer, stderr io.Writer) error {
 
 	log := k.log
@@ -76,27 +154,9 @@ func (k *k8s) Run(ctx context.Context, stdout io.Writer, stderr io.Writer) error
 	}
 
 	// connect to the k8s cluster
-	kubeConfig := os.Getenv("KUBECONFIG")
-	if kubeConfig == "" {
-		kubeConfig = clientcmd.RecommendedHomeFile
-	}
-
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	config, defaultNamespace, err := connect(k.Namespace)
 	if err != nil {
-		return fmt.Errorf("failed to build config: %w", err)
-	}
-
-	// Get the namespace associated with the current context
-	defaultNamespace, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig},
-		&clientcmd.ConfigOverrides{},
-	).Namespace()
-	if err != nil {
-		return fmt.Errorf("failed to get namespace: %w", err)
-	}
-
-	if k.Namespace != "" {
-		defaultNamespace = k.Namespace
+		return err
 	}
 
 	// Create a Kubernetes clientset
@@ -0,0 +1,81 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// Leftover is something a task started that is left running if kit is killed, such as a process group or a container.
+// They're recorded, so the next run of kit can stop them, before they get in its way, e.g. by using the task's ports.
+type Leftover struct {
+	// The process group of a host task.
+	PGID int `json:"pgid,omitempty"`
+	// When the process group's leader started, so a process that has since been given its PID is not mistaken for it.
+	StartTime string `json:"startTime,omitempty"`
+	// The ID of a container task's container.
+	Container string `json:"container,omitempty"`
+}
+
+// Leaver is implemented by processes that may leave something running.
+type Leaver interface {
+	// OnStart sets a function that is called with what the process may leave running, once it has started.
+	OnStart(func(Leftover))
+}
+
+// leaver implements Leaver.
+type leaver struct {
+	onStart func(Leftover)
+}
+
+func (l *leaver) OnStart(f func(Leftover)) {
+	l.onStart = f
+}
+
+func (l *leaver) started(leftover Leftover) {
+	if l.onStart != nil {
+		l.onStart(leftover)
+	}
+}
+
+func (l Leftover) String() string {
+	if l.Container != "" {
+		return fmt.Sprintf("container %.12s", l.Container)
+	}
+	return fmt.Sprintf("process group %d", l.PGID)
+}
+
+// Running returns true if the leftover is still running.
+func (l Leftover) Running(ctx context.Context, spec types.Spec) (bool, error) {
+	if l.Container != "" {
+		cli, err := newRuntime(spec)
+		if err != nil {
+			return false, fmt.Errorf("failed to create container runtime: %w", err)
+		}
+		defer cli.Close()
+		return cli.Running(ctx, l.Container)
+	}
+	_, ok := adoptProcessGroup(l)
+	return ok, nil
+}
+
+// Stop stops the leftover, killing it if it does not stop within the grace period.
+func (l Leftover) Stop(ctx context.Context, spec types.Spec) error {
+	if l.Container != "" {
+		cli, err := newRuntime(spec)
+		if err != nil {
+			return fmt.Errorf("failed to create container runtime: %w", err)
+		}
+		defer cli.Close()
+		if err := ignoreNotExist(cli.Stop(ctx, l.Container, int(spec.GetTerminationGracePeriod().Seconds()))); err != nil {
+			return fmt.Errorf("failed to stop container: %w", err)
+		}
+		return nil
+	}
+	group, ok := adoptProcessGroup(l)
+	if !ok {
+		return nil
+	}
+	return stopGroup(group, spec.GetTerminationGracePeriod())
+}
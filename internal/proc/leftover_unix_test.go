@@ -0,0 +1,44 @@
+//go:build !windows
+
+package proc
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeftover(t *testing.T) {
+	ctx := context.Background()
+	gracePeriod := int32(1)
+	spec := types.Spec{TerminationGracePeriodSeconds: &gracePeriod}
+
+	cmd := exec.Command("sh", "-c", "sleep 30 & wait")
+	cmd.SysProcAttr = sysProcAttr()
+	assert.NoError(t, cmd.Start())
+	group, err := newProcessGroup(cmd.Process)
+	assert.NoError(t, err)
+	defer group.close()
+	leftover, ok := group.leftover()
+	assert.True(t, ok)
+	assert.Equal(t, cmd.Process.Pid, leftover.PGID)
+	go func() { _ = cmd.Wait() }()
+
+	running, err := leftover.Running(ctx, spec)
+	assert.NoError(t, err)
+	assert.True(t, running)
+
+	t.Run("PID reused", func(t *testing.T) {
+		running, err := Leftover{PGID: leftover.PGID, StartTime: "0"}.Running(ctx, spec)
+		assert.NoError(t, err)
+		assert.False(t, running)
+	})
+
+	assert.NoError(t, leftover.Stop(ctx, spec))
+	running, err = leftover.Running(ctx, spec)
+	assert.NoError(t, err)
+	assert.False(t, running)
+}
@@ -0,0 +1,53 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// enforceLimits enforces the resource limits of a process group on a best-effort basis, for when a cgroup is not
+// available. CPU is limited by lowering the priority of the processes, and the processes are killed if their memory
+// exceeds the limit. The returned function reports whether they were killed.
+func enforceLimits(ctx context.Context, log *log.Logger, resources *types.Resources, pid int, group *processGroup) func() bool {
+	if resources.GetCPULimit() > 0 {
+		if err := group.lowerPriority(); err != nil {
+			log.Printf("failed to lower priority: %v", err)
+		}
+	}
+	killed := &atomic.Bool{}
+	limit := resources.GetMemoryLimit()
+	if limit > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					processes, err := listProcesses()
+					if err != nil {
+						continue
+					}
+					if rss, _ := treeUsage(processes, pid); rss > uint64(limit) {
+						log.Printf("killing process, memory %dMiB exceeds limit %dMiB", rss>>20, limit>>20)
+						killed.Store(true)
+						_ = group.kill()
+						return
+					}
+				}
+			}
+		}()
+	}
+	return killed.Load
+}
+
+// memoryLimitExceeded is the error returned when a process is killed for exceeding its memory limit.
+func memoryLimitExceeded(resources *types.Resources) error {
+	return fmt.Errorf("memory limit %s exceeded", resources.Limits.Memory)
+}
@@ -0,0 +1,31 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// cgroupCommand wraps the command, so that it runs in a transient systemd scope (i.e. a cgroup) that enforces the
+// resource limits. The kernel kills the process if it exceeds its memory limit. Returns false if the user's systemd
+// instance is not available.
+func cgroupCommand(resources *types.Resources, command []string) ([]string, bool) {
+	if _, err := exec.LookPath("systemd-run"); err != nil {
+		return command, false
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "systemd", "private")); err != nil {
+		return command, false
+	}
+	args := []string{"systemd-run", "--user", "--scope", "--quiet", "--collect"}
+	if cpu := resources.GetCPULimit(); cpu > 0 {
+		// CPUQuota is a percentage of a single core
+		args = append(args, "--property", fmt.Sprintf("CPUQuota=%d%%", (cpu+9)/10))
+	}
+	if memory := resources.GetMemoryLimit(); memory > 0 {
+		args = append(args, "--property", fmt.Sprintf("MemoryMax=%d", memory), "--property", "MemorySwapMax=0")
+	}
+	return append(append(args, "--"), command...), true
+}
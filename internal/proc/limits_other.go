@@ -0,0 +1,10 @@
+//go:build !linux
+
+package proc
+
+import "github.com/kitproj/kit/internal/types"
+
+// cgroupCommand returns false, as cgroups are only available on Linux.
+func cgroupCommand(_ *types.Resources, command []string) ([]string, bool) {
+	return command, false
+}
@@ -0,0 +1,36 @@
+package proc
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_enforceLimits(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = sysProcAttr()
+	assert.NoError(t, cmd.Start())
+	pid := cmd.Process.Pid
+	group, err := newProcessGroup(cmd.Process)
+	assert.NoError(t, err)
+	defer group.close()
+	memory := resource.MustParse("1")
+	killed := enforceLimits(context.Background(), log.New(os.Stdout, "", 0), &types.Resources{Limits: &types.ResourceList{Memory: &memory}}, pid, group)
+	done := make(chan error)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+		assert.True(t, killed())
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("process was not killed")
+	}
+}
@@ -0,0 +1,125 @@
+package proc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// the version of the plugin protocol, so plugins can tell if they understand the request
+const pluginProtocolVersion = 1
+
+// pluginRequest is written to the plugin's stdin for each action.
+type pluginRequest struct {
+	Version int `json:"version"`
+	// the name of the task, unique to the workflow, e.g. to name the resources the plugin creates
+	Name string     `json:"name"`
+	Task types.Task `json:"task"`
+}
+
+// pluginProbeResponse is written by the plugin to its stdout for the probe action.
+type pluginProbeResponse struct {
+	Ready bool `json:"ready"`
+}
+
+// plugin runs the task using an exec plugin. Each action runs the plugin's executable with the action as its argument,
+// and the request as JSON on its stdin:
+//
+//   - "run" does the work, writing logs to stdout and stderr, until it is done (jobs), or until it is stopped (services).
+//   - "stop" is run when the task is stopped, and should make "run" exit. If it fails, "run" is killed.
+//   - "probe" writes {"ready": true} to stdout once a service is ready.
+type plugin struct {
+	name string
+	log  *log.Logger
+	spec types.Spec
+	types.Task
+}
+
+func (p *plugin) command(ctx context.Context, action string) (*exec.Cmd, error) {
+	environ, err := types.Environ(p.spec, p.Task)
+	if err != nil {
+		return nil, fmt.Errorf("error getting spec environ: %w", err)
+	}
+	request, err := json.Marshal(pluginRequest{Version: pluginProtocolVersion, Name: p.name, Task: p.Task})
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, p.Plugin.GetExecutable(), action)
+	cmd.Dir = p.WorkingDir
+	cmd.Env = append(environ, types.HostEnviron(p.Task)...)
+	cmd.Stdin = bytes.NewReader(request)
+	return cmd, nil
+}
+
+func (p *plugin) Run(ctx context.Context, stdout, stderr io.Writer) error {
+	// not cancelled with ctx, as the plugin is asked to stop first
+	cmd, err := p.command(context.WithoutCancel(ctx), "run")
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	p.log.Printf("starting plugin %s\n", p.Plugin.GetExecutable())
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+	p.log.Println("stopping plugin")
+	stop, err := p.command(stopCtx, "stop")
+	if err == nil {
+		stop.Stdout = stdout
+		stop.Stderr = stderr
+		err = stop.Run()
+	}
+	if err != nil {
+		p.log.Printf("failed to stop plugin, killing it: %v\n", err)
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(p.spec.GetTerminationGracePeriod()):
+		_ = cmd.Process.Kill()
+		<-done
+	}
+	return nil
+}
+
+// Ready asks the plugin if a service is ready. Jobs are never ready, and services with ports or a readiness probe use
+// those instead.
+func (p *plugin) Ready(ctx context.Context) (bool, error) {
+	if p.GetType() != types.TaskTypeService || len(p.Ports) > 0 || p.ReadinessProbe != nil {
+		return false, nil
+	}
+	cmd, err := p.command(ctx, "probe")
+	if err != nil {
+		return false, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to probe plugin: %w", err)
+	}
+	response := pluginProbeResponse{}
+	if err := json.Unmarshal(out, &response); err != nil {
+		return false, fmt.Errorf("failed to parse plugin probe response: %w", err)
+	}
+	return response.Ready, nil
+}
@@ -0,0 +1,83 @@
+//go:build !windows
+
+package proc
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// a plugin that prints its request, and runs until a file is created by stop
+const testPlugin = `#!/bin/sh
+request=$(cat)
+case "$1" in
+run)
+  echo "$request"
+  while [ ! -f stopped ]; do sleep 0.1; done
+  echo "stopped" ;;
+stop)
+  touch stopped ;;
+probe)
+  echo '{"ready": true}' ;;
+esac
+`
+
+// lockedBuffer is a buffer that the plugin's run and stop commands can both write to at once.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_plugin(t *testing.T) {
+	bin := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "kit-task-test"), []byte(testPlugin), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	task := types.Task{
+		Type:       types.TaskTypeService,
+		WorkingDir: t.TempDir(),
+		Plugin:     &types.Plugin{Kind: "test", Config: map[string]string{"foo": "bar"}},
+	}
+	p := New("emulator", task, log.New(os.Stdout, "", 0), types.Spec{})
+	assert.IsType(t, &plugin{}, p)
+
+	ready, err := p.(Readier).Ready(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ready)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Second, cancel)
+	stdout := &lockedBuffer{}
+	assert.NoError(t, p.Run(ctx, stdout, os.Stderr))
+	assert.Contains(t, stdout.String(), `"version":1,"name":"emulator"`)
+	assert.Contains(t, stdout.String(), `"config":{"foo":"bar"}`)
+	assert.Contains(t, stdout.String(), "stopped")
+
+	t.Run("Job", func(t *testing.T) {
+		task := types.Task{Plugin: &types.Plugin{Kind: "test"}}
+		ready, err := New("job", task, log.New(os.Stdout, "", 0), types.Spec{}).(Readier).Ready(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, ready)
+	})
+}
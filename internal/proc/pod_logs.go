@@ -0,0 +1,107 @@
+package proc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podLogs streams the logs of the pods that match a selector, until it is stopped.
+type podLogs struct {
+	log *log.Logger
+	types.Task
+}
+
+func (p *podLogs) Run(ctx context.Context, stdout io.Writer, stderr io.Writer) error {
+	config, namespace, err := connect(p.Namespace)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	// only logs written since the task started are streamed, so a container that is streamed again is not repeated
+	since := metav1.Now()
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 10*time.Second,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = p.PodLogs.Selector
+		}))
+	podInformer := factory.Core().V1().Pods().Informer()
+
+	logging := sync.Map{} // namespace/name/container -> true
+
+	processPod := func(obj any) {
+		pod := obj.(*corev1.Pod)
+		for _, s := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if s.State.Running == nil || p.PodLogs.Container != "" && s.Name != p.PodLogs.Container {
+				continue
+			}
+			key := pod.Namespace + "/" + pod.Name + "/" + s.Name
+			if _, loaded := logging.LoadOrStore(key, true); loaded {
+				continue
+			}
+			go func(container string) {
+				defer logging.Delete(key)
+				podLogs, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+					Follow:    true,
+					Container: container,
+					SinceTime: &since,
+				}).Stream(ctx)
+				if err != nil {
+					p.log.Printf("failed to stream logs of %s: %v\n", key, err)
+					return
+				}
+				defer podLogs.Close()
+				// the lines are prefixed, so the logs of each pod can be told apart
+				if err := copyLines(stdout, podLogs, pod.Name+"/"+container+": "); err != nil && !errors.Is(err, context.Canceled) {
+					p.log.Printf("failed to stream logs of %s: %v\n", key, err)
+				}
+			}(s.Name)
+		}
+	}
+	_, err = podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: processPod,
+		UpdateFunc: func(_, newObj any) {
+			processPod(newObj)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add event handler: %w", err)
+	}
+
+	p.log.Printf("streaming logs of pods in %q matching %q\n", namespace, p.PodLogs.Selector)
+	factory.Start(ctx.Done())
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// copyLines copies each line read to the writer, with the prefix. A line is written in one go, so lines from
+// concurrent copies are not mixed up.
+func copyLines(w io.Writer, r io.Reader, prefix string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if _, err := fmt.Fprintf(w, "%s%s\n", prefix, scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
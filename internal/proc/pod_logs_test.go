@@ -0,0 +1,16 @@
+package proc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_copyLines(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := copyLines(buf, strings.NewReader("foo\nbar"), "api-0/main: ")
+	assert.NoError(t, err)
+	assert.Equal(t, "api-0/main: foo\napi-0/main: bar\n", buf.String())
+}
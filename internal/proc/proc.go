@@ -13,7 +13,14 @@ type Interface interface {
 	Run(ctx context.Context, stdout, stderr io.Writer) error
 }
 
+// Readier is implemented by processes that know when they are ready, rather than needing a readiness probe.
+type Readier interface {
+	// Ready returns true once the process is ready.
+	Ready(ctx context.Context) (bool, error)
+}
+
 func New(name string, t types.Task, log *log.Logger, spec types.Spec) Interface {
+	name = spec.ResourceName(name)
 	if t.Image != "" {
 		return &container{
 			name: name,
@@ -22,6 +29,29 @@ func New(name string, t types.Task, log *log.Logger, spec types.Spec) Interface
 			Task: t,
 		}
 	}
+	if t.Build != nil {
+		return &build{
+			name: name,
+			log:  log,
+			spec: spec,
+			Task: t,
+		}
+	}
+	if t.Compose != nil {
+		return &compose{
+			log:  log,
+			spec: spec,
+			Task: t,
+		}
+	}
+	if t.Plugin != nil {
+		return &plugin{
+			name: name,
+			log:  log,
+			spec: spec,
+			Task: t,
+		}
+	}
 	if len(t.GetCommand()) > 0 {
 		return &host{
 			log:  log,
@@ -37,5 +67,11 @@ func New(name string, t types.Task, log *log.Logger, spec types.Spec) Interface
 			spec: spec,
 		}
 	}
+	if t.PodLogs != nil {
+		return &podLogs{
+			log:  log,
+			Task: t,
+		}
+	}
 	return &noop{}
 }
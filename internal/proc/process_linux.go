@@ -0,0 +1,75 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// onlyZombies returns true if every process in the group has exited, but not been reaped. This happens when a
+// process's parent exits, and it is adopted by an init process that does not reap its children, as in some containers.
+func onlyZombies(pgid int) bool {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		state, group, ok := processStat(pid)
+		if ok && group == pgid && state != "Z" {
+			return false
+		}
+	}
+	return true
+}
+
+// zombie returns true if the process has exited, but not been reaped.
+func zombie(pid int) bool {
+	state, _, ok := processStat(pid)
+	return ok && state == "Z"
+}
+
+// processStat returns the state and process group of the process.
+func processStat(pid int) (string, int, bool) {
+	fields, ok := statFields(pid)
+	if !ok {
+		return "", 0, false
+	}
+	pgrp, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return fields[0], pgrp, true
+}
+
+// processStartTime returns when the process started, in clock ticks since boot.
+func processStartTime(pid int) (string, error) {
+	fields, ok := statFields(pid)
+	if !ok {
+		return "", fmt.Errorf("process %d not found", pid)
+	}
+	return fields[19], nil
+}
+
+// statFields returns the fields of /proc/<pid>/stat that follow the command, starting with the state.
+func statFields(pid int) ([]string, bool) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, false
+	}
+	// the command is in brackets, and may contain spaces
+	i := strings.LastIndexByte(string(data), ')')
+	if i < 0 {
+		return nil, false
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 20 {
+		return nil, false
+	}
+	return fields, true
+}
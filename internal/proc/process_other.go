@@ -0,0 +1,28 @@
+//go:build !linux && !windows
+
+package proc
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// onlyZombies returns false, as the init process on these systems reaps the processes it adopts.
+func onlyZombies(int) bool {
+	return false
+}
+
+// zombie returns false, for the same reason.
+func zombie(int) bool {
+	return false
+}
+
+// processStartTime returns when the process started.
+func processStartTime(pid int) (string, error) {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,84 @@
+//go:build !windows
+
+package proc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processGroup is the group of processes started by a command, so that children it starts are stopped with it.
+type processGroup struct {
+	pgid int
+}
+
+func sysProcAttr() *syscall.SysProcAttr {
+	// a new session, and so a new process group, so the processes do not receive signals meant for kit, such as Ctrl+C
+	// in the terminal, and can be signalled together
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// newProcessGroup must be called straight after the process starts, because the pgid is not available after it exits,
+// and the process may exit and leave children behind.
+func newProcessGroup(p *os.Process) (*processGroup, error) {
+	pgid, err := syscall.Getpgid(p.Pid)
+	if err != nil {
+		return nil, err
+	}
+	return &processGroup{pgid: pgid}, nil
+}
+
+// terminate asks the processes to exit gracefully.
+func (g *processGroup) terminate() error {
+	return g.signal(syscall.SIGTERM)
+}
+
+// kill kills the processes.
+func (g *processGroup) kill() error {
+	return g.signal(syscall.SIGKILL)
+}
+
+func (g *processGroup) signal(sig syscall.Signal) error {
+	if err := syscall.Kill(-g.pgid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+// alive returns true if any of the processes are still running.
+func (g *processGroup) alive() bool {
+	return syscall.Kill(-g.pgid, 0) == nil && !onlyZombies(g.pgid)
+}
+
+// leftover returns the process group as something that is left running if kit is killed.
+func (g *processGroup) leftover() (Leftover, bool) {
+	start, err := processStartTime(g.pgid)
+	if err != nil {
+		return Leftover{}, false
+	}
+	return Leftover{PGID: g.pgid, StartTime: start}, true
+}
+
+// adoptProcessGroup returns the process group left running by the last run of kit, or false if it is not running. The
+// group's leader may have exited, but if a process with its PID is running, it must have started when the leader did.
+func adoptProcessGroup(l Leftover) (*processGroup, bool) {
+	if start, err := processStartTime(l.PGID); err == nil && start != l.StartTime {
+		return nil, false
+	}
+	g := &processGroup{pgid: l.PGID}
+	return g, g.alive()
+}
+
+// lowerPriority lowers the scheduling priority of the processes.
+func (g *processGroup) lowerPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PGRP, g.pgid, 10)
+}
+
+// close releases any resources.
+func (g *processGroup) close() {}
+
+// shellCommand returns the command to run a shell script.
+func shellCommand(command []string) []string {
+	return command
+}
@@ -0,0 +1,125 @@
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processGroup is the group of processes started by a command, so that children it starts are stopped with it. On
+// Windows this is a job object, processes started by a process in a job are also in the job.
+type processGroup struct {
+	pid int
+	job windows.Handle
+}
+
+func sysProcAttr() *syscall.SysProcAttr {
+	// a new process group, so that we can send it CTRL_BREAK without interrupting ourselves
+	return &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP}
+}
+
+// newProcessGroup creates a job object for the process. It must be called straight after the process starts, any
+// children the process starts before then are not in the job.
+func newProcessGroup(p *os.Process) (*processGroup, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	g := &processGroup{pid: p.Pid, job: job}
+	// if kit exits, then the job is closed, and the processes are killed
+	if err := g.setLimits(windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE, 0); err != nil {
+		g.close()
+		return nil, err
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(p.Pid))
+	if err != nil {
+		g.close()
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+	if err := windows.AssignProcessToJobObject(job, h); err != nil {
+		g.close()
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *processGroup) setLimits(flags, priorityClass uint32) error {
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{LimitFlags: flags, PriorityClass: priorityClass},
+	}
+	_, err := windows.SetInformationJobObject(g.job, windows.JobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)))
+	return err
+}
+
+// terminate asks the processes to exit gracefully. Windows does not have SIGTERM, so we send CTRL_BREAK, which console
+// programs handle like Ctrl+C.
+func (g *processGroup) terminate() error {
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(g.pid))
+}
+
+// kill kills the processes.
+func (g *processGroup) kill() error {
+	return windows.TerminateJobObject(g.job, 1)
+}
+
+// jobAccounting is JOBOBJECT_BASIC_ACCOUNTING_INFORMATION, which is not in golang.org/x/sys/windows.
+type jobAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// alive returns true if any of the processes in the job are still running.
+func (g *processGroup) alive() bool {
+	info := jobAccounting{}
+	if err := windows.QueryInformationJobObject(g.job, windows.JobObjectBasicAccountingInformation, uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)), nil); err != nil {
+		return false
+	}
+	return info.ActiveProcesses > 0
+}
+
+// leftover returns false, as the processes in the job are killed when kit exits, even if it is killed.
+func (g *processGroup) leftover() (Leftover, bool) {
+	return Leftover{}, false
+}
+
+// adoptProcessGroup returns false, as processes are never left running.
+func adoptProcessGroup(Leftover) (*processGroup, bool) {
+	return nil, false
+}
+
+// lowerPriority lowers the scheduling priority of the processes.
+func (g *processGroup) lowerPriority() error {
+	return g.setLimits(windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE|windows.JOB_OBJECT_LIMIT_PRIORITY_CLASS, windows.BELOW_NORMAL_PRIORITY_CLASS)
+}
+
+// close closes the job, killing any processes left behind.
+func (g *processGroup) close() {
+	_ = windows.CloseHandle(g.job)
+}
+
+// shellCommand returns the command to run a shell script. If sh is not installed (e.g. by Git for Windows), then
+// scripts are run using PowerShell.
+func shellCommand(command []string) []string {
+	if len(command) != 3 || command[0] != "sh" || command[1] != "-c" {
+		return command
+	}
+	if _, err := exec.LookPath("sh"); err == nil {
+		return command
+	}
+	for _, shell := range []string{"pwsh", "powershell"} {
+		if _, err := exec.LookPath(shell); err == nil {
+			return []string{shell, "-NoProfile", "-NonInteractive", "-Command", command[2]}
+		}
+	}
+	return []string{"cmd", "/C", command[2]}
+}
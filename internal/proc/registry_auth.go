@@ -0,0 +1,86 @@
+package proc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/docker/cli/cli/config"
+	configtypes "github.com/docker/cli/cli/config/types"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/registry"
+)
+
+var (
+	ecrRegistry = regexp.MustCompile(`^\d+\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+	gcrRegistry = regexp.MustCompile(`^([a-z]+\.)?gcr\.io$|^[a-z0-9-]+-docker\.pkg\.dev$`)
+)
+
+// registryAuth returns the credentials for the registry server. Credentials are read from the Docker config file,
+// including any credential helpers it configures. If there are none, then ECR and GCR credentials are fetched using
+// the AWS or gcloud CLI, if they're installed, so private images work without running `docker login` first.
+func registryAuth(ctx context.Context, server string) (configtypes.AuthConfig, error) {
+	errBuf := &bytes.Buffer{}
+	cf := config.LoadDefaultConfigFile(errBuf)
+	if errBuf.Len() > 0 {
+		return configtypes.AuthConfig{}, fmt.Errorf("unable to load docker config: %s", errBuf.String())
+	}
+	authConfig, err := cf.GetAuthConfig(server)
+	if err != nil {
+		return configtypes.AuthConfig{}, fmt.Errorf("failed to get auth config: %w", err)
+	}
+	if authConfig.Username != "" || authConfig.IdentityToken != "" || authConfig.RegistryToken != "" {
+		return authConfig, nil
+	}
+	username, command := tokenCommand(server)
+	if len(command) == 0 {
+		return authConfig, nil
+	}
+	// if the CLI is not installed, then we try anonymously
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return authConfig, nil
+	}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stderr = stderr
+	password, err := cmd.Output()
+	if err != nil {
+		return configtypes.AuthConfig{}, fmt.Errorf("failed to get token using %q: %w: %s", strings.Join(command, " "), err, stderr.String())
+	}
+	return configtypes.AuthConfig{
+		Username:      username,
+		Password:      strings.TrimSpace(string(password)),
+		ServerAddress: server,
+	}, nil
+}
+
+// registryServer returns the server of the image's registry, as its credentials are named in the Docker config file,
+// e.g. "https://index.docker.io/v1/" for Docker Hub.
+func registryServer(image string) (string, error) {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse image: %w", err)
+	}
+	repoInfo, err := registry.ParseRepositoryInfo(ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse repository info: %w", err)
+	}
+	if repoInfo.Index.Official {
+		return registry.IndexServer, nil
+	}
+	return repoInfo.Index.Name, nil
+}
+
+// tokenCommand returns the username, and the command to get a password for cloud registries.
+func tokenCommand(server string) (string, []string) {
+	if m := ecrRegistry.FindStringSubmatch(server); m != nil {
+		return "AWS", []string{"aws", "ecr", "get-login-password", "--region", m[1]}
+	}
+	if gcrRegistry.MatchString(server) {
+		return "oauth2accesstoken", []string{"gcloud", "auth", "print-access-token"}
+	}
+	return "", nil
+}
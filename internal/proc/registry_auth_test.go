@@ -0,0 +1,28 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_tokenCommand(t *testing.T) {
+	t.Run("ECR", func(t *testing.T) {
+		username, command := tokenCommand("123456789012.dkr.ecr.eu-west-2.amazonaws.com")
+		assert.Equal(t, "AWS", username)
+		assert.Equal(t, []string{"aws", "ecr", "get-login-password", "--region", "eu-west-2"}, command)
+	})
+	t.Run("GCR", func(t *testing.T) {
+		username, command := tokenCommand("eu.gcr.io")
+		assert.Equal(t, "oauth2accesstoken", username)
+		assert.Equal(t, []string{"gcloud", "auth", "print-access-token"}, command)
+	})
+	t.Run("ArtifactRegistry", func(t *testing.T) {
+		_, command := tokenCommand("europe-west2-docker.pkg.dev")
+		assert.NotEmpty(t, command)
+	})
+	t.Run("DockerHub", func(t *testing.T) {
+		_, command := tokenCommand("https://index.docker.io/v1/")
+		assert.Empty(t, command)
+	})
+}
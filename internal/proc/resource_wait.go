@@ -0,0 +1,112 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// WaitForResource blocks until the resource in the cluster of the current context, e.g. "deployment/backend", is
+// available. It is in the namespace, or the namespace of the current context if that is empty. The resource is watched,
+// rather than polled, so the wait ends as soon as it is available.
+func WaitForResource(ctx context.Context, resource, namespace string) error {
+	kind, name, ok := strings.Cut(resource, "/")
+	if !ok || kind == "" || name == "" {
+		return fmt.Errorf("invalid resource %q, must be kind/name, e.g. deployment/backend", resource)
+	}
+	config, namespace, err := connect(namespace)
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	// the kind may be singular, plural, or short, e.g. "deployment", "deployments" or "deploy", like kubectl
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)), discoveryClient)
+	gvr, err := mapper.ResourceFor(schema.ParseGroupResource(kind).WithVersion(""))
+	if err != nil {
+		return fmt.Errorf("failed to find resource %q: %w", kind, err)
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return fmt.Errorf("failed to find kind of %q: %w", kind, err)
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return fmt.Errorf("failed to find mapping of %q: %w", kind, err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	var client dynamic.ResourceInterface = dynamicClient.Resource(gvr)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		client = dynamicClient.Resource(gvr).Namespace(namespace)
+	}
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (k8sruntime.Object, error) {
+			options.FieldSelector = selector
+			return client.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return client.Watch(ctx, options)
+		},
+	}
+	_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, nil, func(e watch.Event) (bool, error) {
+		u, ok := e.Object.(*unstructured.Unstructured)
+		return ok && e.Type != watch.Deleted && resourceAvailable(u), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s not available: %w", resource, err)
+	}
+	return nil
+}
+
+// resourceAvailable returns true if the resource is available: its Available, Ready or Complete condition is true (e.g. a
+// deployment, pod or job), all the replicas of a stateful set or daemon set are ready, or it has no status at all (e.g. a
+// config map).
+func resourceAvailable(u *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		c, _ := c.(map[string]any)
+		switch c["type"] {
+		case "Available", "Ready", "Complete":
+			if c["status"] == "True" {
+				return true
+			}
+		}
+	}
+	observed, _, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+	switch u.GetKind() {
+	case "StatefulSet":
+		replicas, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		ready, _, _ := unstructured.NestedInt64(u.Object, "status", "readyReplicas")
+		return observed >= u.GetGeneration() && ready >= replicas
+	case "DaemonSet":
+		desired, _, _ := unstructured.NestedInt64(u.Object, "status", "desiredNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(u.Object, "status", "numberReady")
+		return observed >= u.GetGeneration() && ready >= desired
+	}
+	_, hasStatus := u.Object["status"]
+	return !hasStatus
+}
@@ -0,0 +1,43 @@
+package proc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_resourceAvailable(t *testing.T) {
+	resource := func(kind string, object map[string]any) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: object}
+		u.SetKind(kind)
+		u.SetGeneration(2)
+		return u
+	}
+	condition := func(kind, condition, status string) *unstructured.Unstructured {
+		return resource(kind, map[string]any{"status": map[string]any{"conditions": []any{map[string]any{"type": condition, "status": status}}}})
+	}
+	assert.True(t, resourceAvailable(condition("Deployment", "Available", "True")))
+	assert.False(t, resourceAvailable(condition("Deployment", "Available", "False")))
+	assert.True(t, resourceAvailable(condition("Pod", "Ready", "True")))
+	assert.True(t, resourceAvailable(condition("Job", "Complete", "True")))
+	assert.False(t, resourceAvailable(condition("Job", "Failed", "True")))
+
+	statefulSet := func(observed, ready int64) *unstructured.Unstructured {
+		return resource("StatefulSet", map[string]any{
+			"spec":   map[string]any{"replicas": int64(3)},
+			"status": map[string]any{"observedGeneration": observed, "readyReplicas": ready},
+		})
+	}
+	assert.True(t, resourceAvailable(statefulSet(2, 3)))
+	assert.False(t, resourceAvailable(statefulSet(2, 2)))
+	assert.False(t, resourceAvailable(statefulSet(1, 3)), "the change has not been rolled out")
+
+	assert.True(t, resourceAvailable(resource("DaemonSet", map[string]any{"status": map[string]any{"observedGeneration": int64(2), "desiredNumberScheduled": int64(2), "numberReady": int64(2)}})))
+	assert.True(t, resourceAvailable(resource("ConfigMap", map[string]any{"data": map[string]any{}})), "exists")
+}
+
+func TestWaitForResource(t *testing.T) {
+	assert.EqualError(t, WaitForResource(context.Background(), "backend", "dev"), `invalid resource "backend", must be kind/name, e.g. deployment/backend`)
+}
@@ -0,0 +1,114 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+	"github.com/kitproj/kit/internal/types"
+)
+
+// runtime is a container runtime, e.g. Docker, Podman or containerd.
+type runtime interface {
+	// Find returns the ID and hash label of the named container, or empty strings if it does not exist.
+	Find(ctx context.Context, name string) (string, string, error)
+	// Remove force removes the container.
+	Remove(ctx context.Context, id string) error
+	// Build builds the image from the Dockerfile, and tags it.
+	Build(ctx context.Context, dockerfile, tag string, out io.Writer) error
+	// ImageExists returns true if the image has already been pulled or built.
+	ImageExists(ctx context.Context, image string) (bool, error)
+	// Pull pulls the image.
+	Pull(ctx context.Context, image string, out io.Writer) error
+	// CreateNetwork creates the network, if it does not already exist.
+	CreateNetwork(ctx context.Context, name string) error
+	// Create creates the container.
+	Create(ctx context.Context, c containerConfig) error
+	// Start starts the container.
+	Start(ctx context.Context, id string) error
+	// Logs follows the logs of the container until it exits.
+	Logs(ctx context.Context, id string, stdout, stderr io.Writer) error
+	// Wait waits for the container to stop, and returns its exit code.
+	Wait(ctx context.Context, id string) (int64, error)
+	// CopyTo extracts the tar archive at the root of the running container.
+	CopyTo(ctx context.Context, id string, archive io.Reader) error
+	// Running returns true if the container exists, and is running.
+	Running(ctx context.Context, id string) (bool, error)
+	// Stop stops the container, killing it after the timeout.
+	Stop(ctx context.Context, id string, timeout int) error
+	// Close releases any resources.
+	Close() error
+}
+
+// containerConfig is the runtime independent configuration of a container.
+type containerConfig struct {
+	Name       string
+	Hostname   string
+	Image      string
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	User       string
+	WorkingDir string
+	Tty        bool
+	Ports      types.Ports
+	Binds      []string
+	Labels     map[string]string
+	// Network is the network to connect to, the container can be resolved by its name on the network.
+	Network string
+	// CPU is the CPU limit in millicores, zero means no limit.
+	CPU int64
+	// Memory is the memory limit in bytes, zero means no limit.
+	Memory int64
+}
+
+const (
+	runtimeDocker  = "docker"
+	runtimePodman  = "podman"
+	runtimeNerdctl = "nerdctl"
+)
+
+// runtimeName returns the configured container runtime, or detects it from the commands on the PATH.
+func runtimeName(spec types.Spec) string {
+	if spec.ContainerRuntime != "" {
+		return spec.ContainerRuntime
+	}
+	for _, name := range []string{runtimeDocker, runtimePodman, runtimeNerdctl} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name
+		}
+	}
+	return runtimeDocker
+}
+
+func newRuntime(spec types.Spec) (runtime, error) {
+	switch name := runtimeName(spec); name {
+	case runtimeDocker:
+		return newDockerRuntime(client.FromEnv)
+	case runtimePodman:
+		// Podman provides a Docker compatible API
+		return newDockerRuntime(client.FromEnv, client.WithHost(podmanHost()))
+	case runtimeNerdctl:
+		return &cliRuntime{command: name}, nil
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q", name)
+	}
+}
+
+// podmanHost returns the address of the Podman API socket.
+func podmanHost() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		socket := filepath.Join(dir, "podman", "podman.sock")
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
@@ -0,0 +1,214 @@
+package proc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cliRuntime uses a Docker compatible CLI, such as nerdctl for containerd.
+type cliRuntime struct {
+	command string
+}
+
+func (r *cliRuntime) run(ctx context.Context, stdout io.Writer, args ...string) error {
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, r.command, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", r.command, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// list returns the IDs of the containers or images the command lists, e.g. "container ls --all --quiet". Listing,
+// rather than inspecting, means a missing container is an empty list, rather than an error that can only be told apart
+// from a failure of the runtime by its message, which differs between CLIs.
+func (r *cliRuntime) list(ctx context.Context, args ...string) ([]string, error) {
+	out := &bytes.Buffer{}
+	if err := r.run(ctx, out, args...); err != nil {
+		return nil, err
+	}
+	return strings.Fields(out.String()), nil
+}
+
+func (r *cliRuntime) Find(ctx context.Context, name string) (string, string, error) {
+	ids, err := r.list(ctx, "container", "ls", "--all", "--quiet", "--no-trunc", "--filter", "name=^"+regexp.QuoteMeta(name)+"$")
+	if err != nil || len(ids) == 0 {
+		return "", "", err
+	}
+	out := &bytes.Buffer{}
+	if err := r.run(ctx, out, "container", "inspect", "--format", "{{json .Config.Labels}}", ids[0]); err != nil {
+		return "", "", err
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(out.Bytes(), &labels); err != nil {
+		return "", "", fmt.Errorf("failed to unmarshal container labels: %w", err)
+	}
+	return ids[0], labels[hashLabel], nil
+}
+
+func (r *cliRuntime) Remove(ctx context.Context, id string) error {
+	return r.run(ctx, io.Discard, "rm", "--force", id)
+}
+
+func (r *cliRuntime) Build(ctx context.Context, dockerfile, tag string, out io.Writer) error {
+	return r.run(ctx, out, "build", "--file", dockerfile, "--tag", tag, filepath.Dir(dockerfile))
+}
+
+func (r *cliRuntime) ImageExists(ctx context.Context, image string) (bool, error) {
+	// an image without a tag is listed with all of its tags, rather than only "latest", as it is when it is run
+	if repository := image[strings.LastIndex(image, "/")+1:]; !strings.ContainsAny(repository, ":@") {
+		image += ":latest"
+	}
+	ids, err := r.list(ctx, "image", "ls", "--quiet", image)
+	return len(ids) > 0, err
+}
+
+// Pull logs in to the image's registry first, as the CLI does not fetch ECR or GCR credentials itself.
+func (r *cliRuntime) Pull(ctx context.Context, image string, out io.Writer) error {
+	server, err := registryServer(image)
+	if err != nil {
+		return err
+	}
+	authConfig, err := registryAuth(ctx, server)
+	if err != nil {
+		return err
+	}
+	if authConfig.Username != "" && authConfig.Password != "" {
+		if err := r.login(ctx, server, authConfig.Username, authConfig.Password); err != nil {
+			return err
+		}
+	}
+	return r.run(ctx, out, "pull", image)
+}
+
+// login logs in to the registry, passing the password on stdin, so it is not in the process list.
+func (r *cliRuntime) login(ctx context.Context, server, username, password string) error {
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, r.command, "login", "--username", username, "--password-stdin", server)
+	cmd.Stdin = strings.NewReader(password)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to log in to %s: %w: %s", server, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (r *cliRuntime) CreateNetwork(ctx context.Context, name string) error {
+	if err := r.run(ctx, io.Discard, "network", "inspect", name); err == nil {
+		return nil
+	}
+	return r.run(ctx, io.Discard, "network", "create", name)
+}
+
+func (r *cliRuntime) Create(ctx context.Context, c containerConfig) error {
+	return r.run(ctx, io.Discard, createArgs(c)...)
+}
+
+func createArgs(c containerConfig) []string {
+	args := []string{"create", "--name", c.Name}
+	if c.Hostname != "" {
+		args = append(args, "--hostname", c.Hostname)
+	}
+	if c.User != "" {
+		args = append(args, "--user", c.User)
+	}
+	if c.WorkingDir != "" {
+		args = append(args, "--workdir", c.WorkingDir)
+	}
+	if c.Tty {
+		args = append(args, "--tty")
+	}
+	for _, env := range c.Env {
+		args = append(args, "--env", env)
+	}
+	for _, p := range c.Ports {
+		args = append(args, "--publish", fmt.Sprintf("%d:%d", p.GetHostPort(), p.ContainerPort))
+	}
+	for _, bind := range c.Binds {
+		args = append(args, "--volume", bind)
+	}
+	if c.CPU > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(c.CPU)/1000, 'f', -1, 64))
+	}
+	if c.Memory > 0 {
+		args = append(args, "--memory", strconv.FormatInt(c.Memory, 10), "--memory-swap", strconv.FormatInt(c.Memory, 10))
+	}
+	if c.Network != "" {
+		args = append(args, "--network", c.Network, "--add-host", "host.docker.internal:host-gateway")
+	}
+	// sort labels so that the command is stable
+	var keys []string
+	for k := range c.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--label", k+"="+c.Labels[k])
+	}
+	// the CLI only allows a single entrypoint, any others are prepended to the command
+	cmd := c.Cmd
+	if len(c.Entrypoint) > 0 {
+		args = append(args, "--entrypoint", c.Entrypoint[0])
+		cmd = append(append([]string{}, c.Entrypoint[1:]...), cmd...)
+	}
+	args = append(args, c.Image)
+	return append(args, cmd...)
+}
+
+func (r *cliRuntime) Start(ctx context.Context, id string) error {
+	return r.run(ctx, io.Discard, "start", id)
+}
+
+func (r *cliRuntime) Logs(ctx context.Context, id string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, r.command, "logs", "--follow", "--since", time.Now().Format(time.RFC3339), id)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+func (r *cliRuntime) Wait(ctx context.Context, id string) (int64, error) {
+	out := &bytes.Buffer{}
+	if err := r.run(ctx, out, "wait", id); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(out.String()), 10, 64)
+}
+
+func (r *cliRuntime) CopyTo(ctx context.Context, id string, archive io.Reader) error {
+	// not every CLI can copy an archive, so it's extracted by the container's tar
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, r.command, "exec", "-i", id, "tar", "-xf", "-", "-C", "/")
+	cmd.Stdin = archive
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s exec: %w: %s", r.command, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (r *cliRuntime) Running(ctx context.Context, id string) (bool, error) {
+	ids, err := r.list(ctx, "container", "ls", "--quiet", "--no-trunc", "--filter", "id="+id, "--filter", "status=running")
+	return len(ids) > 0, err
+}
+
+func (r *cliRuntime) Stop(ctx context.Context, id string, timeout int) error {
+	return r.run(ctx, io.Discard, "stop", "--time", strconv.Itoa(timeout), id)
+}
+
+func (r *cliRuntime) Close() error {
+	return nil
+}
+
+var _ runtime = &cliRuntime{}
@@ -0,0 +1,51 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_createArgs(t *testing.T) {
+	t.Run("Minimal", func(t *testing.T) {
+		assert.Equal(t, []string{"create", "--name", "foo", "nginx"}, createArgs(containerConfig{Name: "foo", Image: "nginx"}))
+	})
+	t.Run("All", func(t *testing.T) {
+		args := createArgs(containerConfig{
+			Name:       "foo",
+			Hostname:   "foo",
+			Image:      "nginx",
+			Entrypoint: []string{"sh", "-c"},
+			Cmd:        []string{"echo hello"},
+			Env:        []string{"FOO=1"},
+			User:       "root",
+			WorkingDir: "/work",
+			Tty:        true,
+			Ports:      types.Ports{{ContainerPort: 80, HostPort: 8080}},
+			Binds:      []string{"/tmp:/tmp"},
+			Labels:     map[string]string{hashLabel: "abc"},
+			Network:    "kit",
+			CPU:        500,
+			Memory:     1 << 20,
+		})
+		assert.Equal(t, []string{"create", "--name", "foo",
+			"--hostname", "foo",
+			"--user", "root",
+			"--workdir", "/work",
+			"--tty",
+			"--env", "FOO=1",
+			"--publish", "8080:80",
+			"--volume", "/tmp:/tmp",
+			"--cpus", "0.5",
+			"--memory", "1048576", "--memory-swap", "1048576",
+			"--network", "kit", "--add-host", "host.docker.internal:host-gateway",
+			"--label", "kit.hash=abc",
+			"--entrypoint", "sh",
+			"nginx", "-c", "echo hello"}, args)
+	})
+}
+
+func Test_runtimeName(t *testing.T) {
+	assert.Equal(t, "podman", runtimeName(types.Spec{ContainerRuntime: "podman"}))
+}
@@ -0,0 +1,86 @@
+//go:build !windows
+
+package proc
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// a CLI with a container "api", running, an image "nginx:latest", and a registry "registry.example.com" that the user
+// "me" can log in to
+const fakeCLI = `#!/bin/sh
+case "$*" in
+"login --username me --password-stdin registry.example.com") [ "$(cat)" = "secret" ] || exit 1 ; touch "$(dirname "$0")/logged-in" ;;
+"pull registry.example.com/app:1") [ -e "$(dirname "$0")/logged-in" ] || exit 1 ;;
+"pull nginx") ;;
+"container ls --all --quiet --no-trunc --filter name=^api$") echo abc123 ;;
+"container ls --quiet --no-trunc --filter id=abc123 --filter status=running") echo abc123 ;;
+"container ls "*) ;;
+"container inspect --format {{json .Config.Labels}} abc123") echo '{"kit.hash":"h1"}' ;;
+"image ls --quiet nginx:latest") echo sha256:1 ;;
+"image ls "*) ;;
+*) echo "unexpected: $*" >&2; exit 1 ;;
+esac
+`
+
+func Test_cliRuntime(t *testing.T) {
+	ctx := context.Background()
+	command := filepath.Join(t.TempDir(), "docker")
+	assert.NoError(t, os.WriteFile(command, []byte(fakeCLI), 0o755))
+	r := &cliRuntime{command: command}
+
+	t.Run("Find", func(t *testing.T) {
+		id, hash, err := r.Find(ctx, "api")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", id)
+		assert.Equal(t, "h1", hash)
+		id, _, err = r.Find(ctx, "db")
+		assert.NoError(t, err)
+		assert.Empty(t, id)
+	})
+	t.Run("Image exists", func(t *testing.T) {
+		ok, err := r.ImageExists(ctx, "nginx")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = r.ImageExists(ctx, "redis")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("Running", func(t *testing.T) {
+		ok, err := r.Running(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = r.Running(ctx, "def456")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("Pull", func(t *testing.T) {
+		dir := config.Dir()
+		t.Cleanup(func() { config.SetDir(dir) })
+		config.SetDir(t.TempDir())
+		assert.NoError(t, r.Pull(ctx, "nginx", io.Discard))
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(command), "logged-in"), "anonymous pulls do not log in")
+
+		auth := base64.StdEncoding.EncodeToString([]byte("me:secret"))
+		assert.NoError(t, os.WriteFile(filepath.Join(config.Dir(), "config.json"), []byte(`{"auths":{"registry.example.com":{"auth":"`+auth+`"}}}`), 0o600))
+		assert.NoError(t, r.Pull(ctx, "registry.example.com/app:1", io.Discard))
+	})
+	t.Run("Runtime failed", func(t *testing.T) {
+		// e.g. the daemon is not running, which must not be mistaken for the container or image not existing
+		r := &cliRuntime{command: "false"}
+		_, _, err := r.Find(ctx, "api")
+		assert.Error(t, err)
+		_, err = r.ImageExists(ctx, "nginx")
+		assert.Error(t, err)
+		_, err = r.Running(ctx, "abc123")
+		assert.Error(t, err)
+	})
+}
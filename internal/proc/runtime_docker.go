@@ -0,0 +1,247 @@
+package proc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	dockertypes "github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/docker/registry"
+	"github.com/docker/go-connections/nat"
+	"github.com/kitproj/kit/internal/types"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+// dockerRuntime uses the Docker API, which is also provided by Podman.
+type dockerRuntime struct {
+	cli *client.Client
+}
+
+func newDockerRuntime(opts ...client.Opt) (*dockerRuntime, error) {
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return &dockerRuntime{cli: cli}, nil
+}
+
+func (d *dockerRuntime) Find(ctx context.Context, name string) (string, string, error) {
+	list, err := d.cli.ContainerList(ctx, dockertypes.ContainerListOptions{All: true})
+	if err != nil {
+		return "", "", err
+	}
+	for _, existing := range list {
+		if slices.Contains(existing.Names, "/"+name) {
+			return existing.ID, existing.Labels[hashLabel], nil
+		}
+	}
+	return "", "", nil
+}
+
+func (d *dockerRuntime) Remove(ctx context.Context, id string) error {
+	return d.cli.ContainerRemove(ctx, id, dockertypes.ContainerRemoveOptions{Force: true})
+}
+
+func (d *dockerRuntime) Build(ctx context.Context, dockerfile, tag string, out io.Writer) error {
+	r, err := archive.TarWithOptions(filepath.Dir(dockerfile), &archive.TarOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create tar: %w", err)
+	}
+	defer r.Close()
+	resp, err := d.cli.ImageBuild(ctx, r, dockertypes.ImageBuildOptions{Dockerfile: filepath.Base(dockerfile), Tags: []string{tag}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err = io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to build image (logs): %w", err)
+	}
+	return nil
+}
+
+func (d *dockerRuntime) ImageExists(ctx context.Context, image string) (bool, error) {
+	_, _, err := d.cli.ImageInspectWithRaw(ctx, image)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *dockerRuntime) Pull(ctx context.Context, image string, out io.Writer) error {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("unable to parse image: %w", err)
+	}
+	repoInfo, err := registry.ParseRepositoryInfo(ref)
+	if err != nil {
+		return fmt.Errorf("unable to parse repository info: %w", err)
+	}
+
+	var server string
+	if repoInfo.Index.Official {
+		info, err := d.cli.Info(ctx)
+		if err != nil || info.IndexServerAddress == "" {
+			server = registry.IndexServer
+		} else {
+			server = info.IndexServerAddress
+		}
+	} else {
+		server = repoInfo.Index.Name
+	}
+	authConfig, err := registryAuth(ctx, server)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(authConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal auth config: %w", err)
+	}
+	encodedAuth := base64.URLEncoding.EncodeToString(buf)
+
+	r, err := d.cli.ImagePull(ctx, image, dockertypes.ImagePullOptions{
+		RegistryAuth: encodedAuth,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, r); err != nil {
+		return fmt.Errorf("failed to pull image (logs): %w", err)
+	}
+	if err = r.Close(); err != nil {
+		return fmt.Errorf("failed to pull image (close): %w", err)
+	}
+	return nil
+}
+
+func (d *dockerRuntime) CreateNetwork(ctx context.Context, name string) error {
+	_, err := d.cli.NetworkInspect(ctx, name, dockertypes.NetworkInspectOptions{})
+	if !errdefs.IsNotFound(err) {
+		return err
+	}
+	_, err = d.cli.NetworkCreate(ctx, name, dockertypes.NetworkCreate{CheckDuplicate: true})
+	return ignoreConflict(err)
+}
+
+func (d *dockerRuntime) Create(ctx context.Context, c containerConfig) error {
+	portSet, portBindings, err := createPorts(c.Ports)
+	if err != nil {
+		return fmt.Errorf("failed to create ports: %w", err)
+	}
+	hostConfig := &dockercontainer.HostConfig{
+		PortBindings: portBindings,
+		Binds:        c.Binds,
+		Resources: dockercontainer.Resources{
+			NanoCPUs: c.CPU * 1e6,
+			Memory:   c.Memory,
+			// prevent the container from using swap, rather than being killed
+			MemorySwap: c.Memory,
+		},
+	}
+	networkingConfig := &network.NetworkingConfig{}
+	if c.Network != "" {
+		hostConfig.NetworkMode = dockercontainer.NetworkMode(c.Network)
+		// allow containers to reach processes on the host, this is built-in on Docker Desktop, but not Linux
+		hostConfig.ExtraHosts = []string{"host.docker.internal:host-gateway"}
+		networkingConfig.EndpointsConfig = map[string]*network.EndpointSettings{
+			c.Network: {Aliases: []string{c.Name}},
+		}
+	}
+	_, err = d.cli.ContainerCreate(ctx, &dockercontainer.Config{
+		Hostname:     c.Hostname,
+		ExposedPorts: portSet,
+		Tty:          c.Tty,
+		Env:          c.Env,
+		Cmd:          strslice.StrSlice(c.Cmd),
+		Image:        c.Image,
+		User:         c.User,
+		WorkingDir:   c.WorkingDir,
+		Entrypoint:   strslice.StrSlice(c.Entrypoint),
+		Labels:       c.Labels,
+	}, hostConfig, networkingConfig, &v1.Platform{}, c.Name)
+	return err
+}
+
+func createPorts(ports types.Ports) (nat.PortSet, map[nat.Port][]nat.PortBinding, error) {
+	portSet := nat.PortSet{}
+	portBindings := map[nat.Port][]nat.PortBinding{}
+	for _, p := range ports {
+		port, err := nat.NewPort("tcp", fmt.Sprint(p.ContainerPort))
+		if err != nil {
+			return nil, nil, err
+		}
+		portSet[port] = struct{}{}
+		hostPort := p.GetHostPort()
+		portBindings[port] = []nat.PortBinding{{
+			HostPort: fmt.Sprint(hostPort),
+		}}
+	}
+	return portSet, portBindings, nil
+}
+
+func (d *dockerRuntime) Start(ctx context.Context, id string) error {
+	return d.cli.ContainerStart(ctx, id, dockertypes.ContainerStartOptions{})
+}
+
+func (d *dockerRuntime) Logs(ctx context.Context, id string, stdout, stderr io.Writer) error {
+	logs, err := d.cli.ContainerLogs(ctx, id, dockertypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Since:      time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	_, err = stdcopy.StdCopy(stdout, stderr, logs)
+	return err
+}
+
+func (d *dockerRuntime) Wait(ctx context.Context, id string) (int64, error) {
+	waitC, errC := d.cli.ContainerWait(ctx, id, dockercontainer.WaitConditionNotRunning)
+	select {
+	case wait := <-waitC:
+		return wait.StatusCode, nil
+	case err := <-errC:
+		return 0, err
+	}
+}
+
+func (d *dockerRuntime) CopyTo(ctx context.Context, id string, archive io.Reader) error {
+	return d.cli.CopyToContainer(ctx, id, "/", archive, dockertypes.CopyToContainerOptions{})
+}
+
+func (d *dockerRuntime) Running(ctx context.Context, id string) (bool, error) {
+	info, err := d.cli.ContainerInspect(ctx, id)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return info.State != nil && info.State.Running, nil
+}
+
+func (d *dockerRuntime) Stop(ctx context.Context, id string, timeout int) error {
+	return d.cli.ContainerStop(ctx, id, dockercontainer.StopOptions{
+		Timeout: &timeout,
+	})
+}
+
+func (d *dockerRuntime) Close() error {
+	return d.cli.Close()
+}
+
+var _ runtime = &dockerRuntime{}
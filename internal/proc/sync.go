@@ -0,0 +1,65 @@
+package proc
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// Syncer is implemented by processes that files can be copied into while they are running.
+type Syncer interface {
+	// Sync copies the files, which are in the task's sync sources, into the running process.
+	Sync(ctx context.Context, files []string) error
+}
+
+// syncArchive returns a tar archive of the files, to extract at the root of the container or pod. Files that no longer
+// exist, or are not in a sync source, are left out.
+func syncArchive(t types.Task, files []string) (*bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	for _, file := range files {
+		target, ok := t.GetSyncTarget(file)
+		if !ok {
+			continue
+		}
+		info, err := os.Stat(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return nil, err
+		}
+		header.Name = strings.TrimPrefix(target, "/")
+		if err := w.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if err := copyFile(w, file); err != nil {
+			return nil, fmt.Errorf("failed to archive %q: %w", file, err)
+		}
+	}
+	return buf, w.Close()
+}
+
+func copyFile(w io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,44 @@
+package proc
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_syncArchive(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "lib"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", "lib", "main.py"), []byte("print()"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.py"), []byte(""), 0o644))
+	task := types.Task{WorkingDir: dir, Sync: []types.Sync{{Src: "src", Dest: "/app"}}}
+
+	buf, err := syncArchive(task, []string{
+		filepath.Join(dir, "src", "lib", "main.py"),
+		filepath.Join(dir, "src", "lib"),
+		filepath.Join(dir, "src", "deleted.py"),
+		filepath.Join(dir, "other.py"),
+	})
+	assert.NoError(t, err)
+
+	r := tar.NewReader(buf)
+	var names []string
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "print()", string(data))
+	}
+	assert.Equal(t, []string{"app/lib/main.py"}, names)
+}
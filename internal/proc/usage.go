@@ -0,0 +1,100 @@
+package proc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Usage is the resource usage of a process and its children.
+type Usage struct {
+	// CPU is the percentage of a single core used since the last sample.
+	CPU float64 `json:"cpu"`
+	// Memory is the resident set size in bytes.
+	Memory uint64 `json:"memory"`
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%.0f%% %dMiB", u.CPU, u.Memory>>20)
+}
+
+// Sampler is implemented by processes that can report their resource usage.
+type Sampler interface {
+	// Usage returns the current usage, or nil if the process is not running.
+	Usage() (*Usage, error)
+}
+
+// process is a snapshot of a single OS process
+type process struct {
+	ppid int
+	// resident set size in bytes
+	rss uint64
+	// total CPU time (user and system)
+	cpu time.Duration
+}
+
+// treeUsage returns the total memory and CPU time of the process and all of its descendants.
+func treeUsage(processes map[int]process, pid int) (uint64, time.Duration) {
+	children := map[int][]int{}
+	for p, x := range processes {
+		children[x.ppid] = append(children[x.ppid], p)
+	}
+	var rss uint64
+	var cpu time.Duration
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if x, ok := processes[p]; ok {
+			rss += x.rss
+			cpu += x.cpu
+		}
+		queue = append(queue, children[p]...)
+	}
+	return rss, cpu
+}
+
+// processSampler samples the usage of a process tree, CPU usage is calculated from the CPU time used between samples.
+type processSampler struct {
+	mu      sync.Mutex
+	pid     int
+	lastCPU time.Duration
+	lastAt  time.Time
+}
+
+// track starts sampling the process tree rooted at the pid.
+func (s *processSampler) track(pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pid = pid
+	s.lastCPU = 0
+	s.lastAt = time.Now()
+}
+
+// untrack stops sampling, e.g. because the process exited.
+func (s *processSampler) untrack() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pid = 0
+}
+
+func (s *processSampler) Usage() (*Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pid == 0 {
+		return nil, nil
+	}
+	processes, err := listProcesses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	rss, cpu := treeUsage(processes, s.pid)
+	now := time.Now()
+	usage := &Usage{Memory: rss}
+	if elapsed := now.Sub(s.lastAt); elapsed > 0 && cpu >= s.lastCPU {
+		usage.CPU = 100 * float64(cpu-s.lastCPU) / float64(elapsed)
+	}
+	s.lastCPU = cpu
+	s.lastAt = now
+	return usage, nil
+}
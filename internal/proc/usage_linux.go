@@ -0,0 +1,50 @@
+package proc
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the kernel reports CPU time in clock ticks, this is 100 on all mainstream architectures
+const clockTicks = 100
+
+// listProcesses reads every process from /proc.
+func listProcesses() (map[int]process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	pageSize := uint64(os.Getpagesize())
+	processes := map[int]process{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// the process may have exited since we listed the directory
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// the command name is in parentheses and may contain spaces, so we split after it
+		stat := string(data)
+		fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+		// fields[0] is field 3 (state) in proc(5)
+		if len(fields) < 22 {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		utime, _ := strconv.ParseUint(fields[11], 10, 64)
+		stime, _ := strconv.ParseUint(fields[12], 10, 64)
+		rss, _ := strconv.ParseUint(fields[21], 10, 64)
+		processes[pid] = process{
+			ppid: ppid,
+			rss:  rss * pageSize,
+			cpu:  time.Duration(utime+stime) * time.Second / clockTicks,
+		}
+	}
+	return processes, nil
+}
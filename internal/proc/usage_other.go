@@ -0,0 +1,46 @@
+//go:build !linux && !windows
+
+package proc
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listProcesses lists every process using ps.
+func listProcesses() (map[int]process, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,rss=,time=").Output()
+	if err != nil {
+		return nil, err
+	}
+	processes := map[int]process{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		pid, _ := strconv.Atoi(fields[0])
+		ppid, _ := strconv.Atoi(fields[1])
+		rss, _ := strconv.ParseUint(fields[2], 10, 64)
+		processes[pid] = process{ppid: ppid, rss: rss << 10, cpu: parseCPUTime(fields[3])}
+	}
+	return processes, nil
+}
+
+// parseCPUTime parses the ps time format, [[dd-]hh:]mm:ss[.cc]
+func parseCPUTime(s string) time.Duration {
+	var d time.Duration
+	if days, rest, ok := strings.Cut(s, "-"); ok {
+		n, _ := strconv.Atoi(days)
+		d += time.Duration(n) * 24 * time.Hour
+		s = rest
+	}
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		n, _ := strconv.ParseFloat(part, 64)
+		seconds = seconds*60 + n
+	}
+	return d + time.Duration(seconds*float64(time.Second))
+}
@@ -0,0 +1,34 @@
+package proc
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_treeUsage(t *testing.T) {
+	processes := map[int]process{
+		1: {ppid: 0, rss: 1, cpu: time.Second},
+		2: {ppid: 1, rss: 2, cpu: time.Second},
+		3: {ppid: 2, rss: 4, cpu: time.Second},
+		4: {ppid: 1, rss: 8, cpu: time.Second},
+	}
+	rss, cpu := treeUsage(processes, 2)
+	assert.Equal(t, uint64(6), rss)
+	assert.Equal(t, 2*time.Second, cpu)
+	rss, _ = treeUsage(processes, 5)
+	assert.Zero(t, rss)
+}
+
+func Test_processSampler(t *testing.T) {
+	s := &processSampler{}
+	usage, err := s.Usage()
+	assert.NoError(t, err)
+	assert.Nil(t, usage)
+	s.track(os.Getpid())
+	usage, err = s.Usage()
+	assert.NoError(t, err)
+	assert.NotZero(t, usage.Memory)
+}
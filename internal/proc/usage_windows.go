@@ -0,0 +1,57 @@
+package proc
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetProcessMemoryInfo = windows.NewLazySystemDLL("kernel32.dll").NewProc("K32GetProcessMemoryInfo")
+
+// processMemoryCounters is the PROCESS_MEMORY_COUNTERS structure.
+type processMemoryCounters struct {
+	Cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// listProcesses lists every process using a Toolhelp snapshot. Processes we cannot open (e.g. system processes) are
+// listed without their usage.
+func listProcesses() (map[int]process, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snapshot)
+	processes := map[int]process{}
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		p := process{ppid: int(entry.ParentProcessID)}
+		if h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, entry.ProcessID); err == nil {
+			var creation, exit, kernel, user windows.Filetime
+			if err := windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err == nil {
+				p.cpu = filetimeDuration(kernel) + filetimeDuration(user)
+			}
+			counters := processMemoryCounters{Cb: uint32(unsafe.Sizeof(processMemoryCounters{}))}
+			if r, _, _ := procGetProcessMemoryInfo.Call(uintptr(h), uintptr(unsafe.Pointer(&counters)), uintptr(counters.Cb)); r != 0 {
+				p.rss = uint64(counters.WorkingSetSize)
+			}
+			_ = windows.CloseHandle(h)
+		}
+		processes[int(entry.ProcessID)] = p
+	}
+	return processes, nil
+}
+
+// filetimeDuration converts a FILETIME duration, in 100ns intervals, to a duration.
+func filetimeDuration(t windows.Filetime) time.Duration {
+	return time.Duration(uint64(t.HighDateTime)<<32|uint64(t.LowDateTime)) * 100
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// ServeHTTPS terminates TLS on the port, and forwards requests to the target port on localhost. It blocks until the
+// context is cancelled.
+func ServeHTTPS(ctx context.Context, port, target uint16, cert *tls.Certificate) error {
+	backend := &url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", target)}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(backend)
+			r.SetXForwarded()
+			// keep the original host, so that redirects and cookies work
+			r.Out.Host = r.In.Host
+		},
+	}
+	server := &http.Server{
+		Addr:      fmt.Sprintf("localhost:%d", port),
+		Handler:   proxy,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{*cert}},
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ca, err := LoadCA(t.TempDir())
+	assert.NoError(t, err)
+	cert, err := ca.Issue([]string{"localhost", "127.0.0.1"})
+	assert.NoError(t, err)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s %s", r.Header.Get("X-Forwarded-Proto"), r.Host)
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+	target, _ := strconv.Atoi(u.Port())
+
+	port := freePort(t)
+	go func() {
+		assert.NoError(t, ServeHTTPS(ctx, port, uint16(target), cert))
+	}()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.CertPool()}}}
+	var resp *http.Response
+	assert.Eventually(t, func() bool {
+		resp, err = client.Get(fmt.Sprintf("https://localhost:%d", port))
+		return err == nil
+	}, 5*time.Second, 100*time.Millisecond)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("https localhost:%d", port), string(body))
+}
+
+func TestLoadCA(t *testing.T) {
+	dir := t.TempDir()
+	a, err := LoadCA(dir)
+	assert.NoError(t, err)
+	b, err := LoadCA(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, a.cert.Raw, b.cert.Raw)
+}
+
+func TestRouter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serve := func(name string) uint16 {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name)
+		}))
+		t.Cleanup(server.Close)
+		u, _ := url.Parse(server.URL)
+		port, _ := strconv.Atoi(u.Port())
+		return uint16(port)
+	}
+	old, replacement := serve("old"), serve("new")
+
+	port := freePort(t)
+	router, err := ListenRouter(port)
+	assert.NoError(t, err)
+	go func() {
+		assert.NoError(t, router.Serve(ctx))
+	}()
+	get := func() string {
+		// a new connection each time, as a connection stays with its port
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%d", port))
+		if err != nil {
+			return err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	router.Route(old)
+	assert.Equal(t, "old", get())
+	router.Route(replacement)
+	assert.Equal(t, "new", get())
+}
+
+func TestRouter_SetFaults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	target, _ := strconv.Atoi(u.Port())
+
+	port := freePort(t)
+	router, err := ListenRouter(port)
+	assert.NoError(t, err)
+	go func() {
+		assert.NoError(t, router.Serve(ctx))
+	}()
+	router.Route(uint16(target))
+	get := func() (string, time.Duration) {
+		start := time.Now()
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%d", port))
+		if err != nil {
+			return "failed", time.Since(start)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), time.Since(start)
+	}
+
+	t.Run("Latency", func(t *testing.T) {
+		router.SetFaults(&Faults{Latency: 200 * time.Millisecond})
+		body, took := get()
+		assert.Equal(t, "ok", body)
+		assert.GreaterOrEqual(t, took, 200*time.Millisecond)
+	})
+	t.Run("Errors", func(t *testing.T) {
+		router.SetFaults(&Faults{ErrorRate: 100})
+		body, _ := get()
+		assert.Equal(t, "failed", body)
+	})
+	t.Run("Off", func(t *testing.T) {
+		router.SetFaults(nil)
+		body, took := get()
+		assert.Equal(t, "ok", body)
+		assert.Less(t, took, 200*time.Millisecond)
+	})
+}
+
+func TestFaults_delay(t *testing.T) {
+	f := &Faults{Latency: time.Second, Jitter: time.Second, Bandwidth: 1000}
+	assert.Equal(t, 500*time.Millisecond, f.delay(500, false), "only the bandwidth limit applies to requests")
+	d := f.delay(500, true)
+	assert.GreaterOrEqual(t, d, 1500*time.Millisecond)
+	assert.Less(t, d, 2500*time.Millisecond)
+}
+
+func freePort(t *testing.T) uint16 {
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	return uint16(l.Addr().(*net.TCPAddr).Port)
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Router forwards connections to a port on localhost to another port, which can be changed while it is serving, e.g.
+// to restart a server without downtime. A connection stays with the port it was opened to.
+type Router struct {
+	listener net.Listener
+	target   atomic.Uint32
+	faults   atomic.Pointer[Faults]
+}
+
+// Faults degrade the connections a router forwards, e.g. to test how clients behave when a service is slow or flaky.
+type Faults struct {
+	// added to each response, i.e. the first data the service sends back after the client sent some
+	Latency time.Duration
+	// up to this is randomly added to the latency
+	Jitter time.Duration
+	// the maximum bytes per second sent in each direction, or zero for no limit
+	Bandwidth int
+	// the percentage of new connections that are reset
+	ErrorRate int
+}
+
+// ListenRouter listens on the port. Connections are closed until it is routed.
+func ListenRouter(port uint16) (*Router, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return nil, err
+	}
+	return &Router{listener: listener}, nil
+}
+
+// Route forwards new connections to the target port.
+func (r *Router) Route(target uint16) {
+	r.target.Store(uint32(target))
+}
+
+// SetFaults injects the faults into the data forwarded from now on, including on open connections, or none if nil.
+func (r *Router) SetFaults(f *Faults) {
+	r.faults.Store(f)
+}
+
+// Serve forwards connections until the context is cancelled.
+func (r *Router) Serve(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		_ = r.listener.Close()
+	}()
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go r.forward(conn)
+	}
+}
+
+func (r *Router) forward(conn net.Conn) {
+	defer conn.Close()
+	if f := r.faults.Load(); f != nil && rand.Intn(100) < f.ErrorRate {
+		// reset, rather than close, the connection, like a service that crashed
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			_ = tcp.SetLinger(0)
+		}
+		return
+	}
+	target := r.target.Load()
+	if target == 0 {
+		return
+	}
+	backend, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", target))
+	// the connection may have been accepted just before the route changed, and the old target stopped
+	if next := r.target.Load(); err != nil && next != target {
+		backend, err = net.Dial("tcp", fmt.Sprintf("localhost:%d", next))
+	}
+	if err != nil {
+		return
+	}
+	defer backend.Close()
+	// either side closing ends the connection
+	done := make(chan struct{}, 2)
+	// whether the client sent something the service has not responded to yet
+	requested := &atomic.Bool{}
+	go func() {
+		r.copy(backend, conn, func() { requested.Store(true) }, nil)
+		done <- struct{}{}
+	}()
+	go func() {
+		r.copy(conn, backend, nil, requested)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// copy copies the data, delaying it according to the faults at the time. If requested is not nil, the data is a
+// response, and the first chunk of each is delayed by the latency. Sending is called before each chunk is written, so it
+// happens before any response to it.
+func (r *Router) copy(dst io.Writer, src io.Reader, sending func(), requested *atomic.Bool) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if f := r.faults.Load(); f != nil {
+				time.Sleep(f.delay(n, requested != nil && requested.Swap(false)))
+			}
+			if sending != nil {
+				sending()
+			}
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// delay returns how long to wait before sending a chunk of n bytes.
+func (f *Faults) delay(n int, response bool) time.Duration {
+	var d time.Duration
+	if response {
+		d += f.Latency
+		if f.Jitter > 0 {
+			d += time.Duration(rand.Int63n(int64(f.Jitter)))
+		}
+	}
+	if f.Bandwidth > 0 {
+		d += time.Duration(n) * time.Second / time.Duration(f.Bandwidth)
+	}
+	return d
+}
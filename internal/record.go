@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"sigs.k8s.io/yaml"
+)
+
+// the names of environment variables whose values are redacted from a recording, as they're probably secrets
+var secretName = regexp.MustCompile(`(?i)token|secret|passw(or)?d|key|credential|auth|cookie|private`)
+
+// a secret assigned in a command, e.g. "PGPASSWORD=postgres psql" or "--api-key=abc"
+var secretAssignment = regexp.MustCompile(`(?i)([\w-]*(token|secret|passw(or)?d|key|credential|auth|cookie|private)[\w-]*=)[^\s'"]+`)
+
+const redacted = "[redacted]"
+
+// recording is what "--record" writes when kit exits, so a user can attach a complete reproduction to a bug report.
+type recording struct {
+	// the directory of the workflow's state
+	stateDir string
+	// the session's output, written by the session log
+	sessionFile string
+	startedAt   time.Time
+}
+
+// write writes a gzipped tar archive of the session to the file: the workflow (as kit resolved it), the status, logs
+// and lifecycle events of its tasks, the output they wrote, the versions of kit and the OS, and kit's environment. The
+// values of the secrets in the workflow and the environment are redacted from every file, e.g. a token a task printed.
+func (r recording) write(file string, wf *types.Workflow, dag DAG[*TaskNode]) error {
+	redactedWf, secrets := redactWorkflow(wf)
+	secrets = append(secrets, environSecrets(os.Environ())...)
+
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create recording: %w", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		for _, secret := range secrets {
+			data = bytes.ReplaceAll(data, []byte(secret), []byte(redacted))
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	config, err := yaml.Marshal(redactedWf)
+	if err != nil {
+		return fmt.Errorf("failed to record workflow: %w", err)
+	}
+	if err := add("workflow.yaml", config); err != nil {
+		return fmt.Errorf("failed to record workflow: %w", err)
+	}
+
+	status := &bytes.Buffer{}
+	dag.RLock()
+	err = WriteStatus(status, dag.Nodes, "")
+	var logFiles []string
+	for _, node := range dag.Nodes {
+		logFiles = append(logFiles, node.logFile, node.stderrLogFile)
+	}
+	dag.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to record status: %w", err)
+	}
+	if err := add("status.txt", status.Bytes()); err != nil {
+		return fmt.Errorf("failed to record status: %w", err)
+	}
+
+	sort.Strings(logFiles)
+	for _, logFile := range logFiles {
+		data, err := os.ReadFile(logFile)
+		if errors.Is(err, os.ErrNotExist) {
+			// the task never ran
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to record log: %w", err)
+		}
+		// logs are named by the logs directory, e.g. "logs/api.log", wherever it is
+		if err := add(path.Join(filepath.Base(filepath.Dir(logFile)), filepath.Base(logFile)), data); err != nil {
+			return fmt.Errorf("failed to record log: %w", err)
+		}
+	}
+
+	// the event log has every session's events, only this one's are needed
+	events := &bytes.Buffer{}
+	if err := readJSONLines(filepath.Join(r.stateDir, "events.jsonl"), func(e lifecycleEvent) {
+		if !e.Time.Before(r.startedAt) {
+			data, _ := json.Marshal(e)
+			events.Write(append(data, '\n'))
+		}
+	}); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to record events: %w", err)
+	}
+	if err := add("events.jsonl", events.Bytes()); err != nil {
+		return fmt.Errorf("failed to record events: %w", err)
+	}
+
+	if r.sessionFile != "" {
+		data, err := os.ReadFile(r.sessionFile)
+		if err != nil {
+			return fmt.Errorf("failed to record output: %w", err)
+		}
+		if err := add("session.jsonl", data); err != nil {
+			return fmt.Errorf("failed to record output: %w", err)
+		}
+	}
+
+	if err := add("versions.txt", []byte(versions(wf))); err != nil {
+		return fmt.Errorf("failed to record versions: %w", err)
+	}
+	if err := add("environment.txt", []byte(strings.Join(redactEnviron(os.Environ()), "\n")+"\n")); err != nil {
+		return fmt.Errorf("failed to record environment: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return f.Close()
+}
+
+// redactWorkflow returns a copy of the workflow, without anything that looks like a secret: the values of environment
+// variables and build args that look like secrets, and those values wherever else they're used, e.g. in a command, the
+// values of the probes' headers, and the paths of the notifications' and the cache's URLs. It also returns those
+// values, so they can be redacted from the logs.
+func redactWorkflow(wf *types.Workflow) (*types.Workflow, []string) {
+	var secrets []string
+	redactEnv := func(env types.EnvVars) types.EnvVars {
+		if env == nil {
+			return nil
+		}
+		out := types.EnvVars{}
+		for name, v := range env {
+			if v.Value != "" && secretName.MatchString(name) {
+				secrets = append(secrets, v.Value)
+				v.Value = redacted
+			}
+			out[name] = v
+		}
+		return out
+	}
+	w := *wf
+	w.Env = redactEnv(wf.Env)
+	w.Tasks = types.Tasks{}
+	for name, t := range wf.Tasks {
+		t.Env = redactEnv(t.Env)
+		if t.Build != nil {
+			b := *t.Build
+			b.BuildArgs = redactEnv(b.BuildArgs)
+			t.Build = &b
+		}
+		t.LivenessProbe, t.ReadinessProbe = redactProbe(t.LivenessProbe), redactProbe(t.ReadinessProbe)
+		w.Tasks[name] = t
+	}
+	// the commands are redacted once all the secrets are known, as a task may use another's, e.g. the workflow's
+	redactCommand := func(s string) string {
+		s = secretAssignment.ReplaceAllString(s, "${1}"+redacted)
+		for _, secret := range secrets {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+		return s
+	}
+	redactCommands := func(commands []types.Strings) []types.Strings {
+		var out []types.Strings
+		for _, c := range commands {
+			out = append(out, mapStrings(c, redactCommand))
+		}
+		return out
+	}
+	for name, t := range w.Tasks {
+		t.Command, t.Args, t.Sh = mapStrings(t.Command, redactCommand), mapStrings(t.Args, redactCommand), redactCommand(t.Sh)
+		t.PreRun, t.PostRun, t.OnChange = redactCommands(t.PreRun), redactCommands(t.PostRun), redactCommands(t.OnChange)
+		w.Tasks[name] = t
+	}
+	w.Notifications = nil
+	for _, n := range wf.Notifications {
+		n.URL = redactURL(n.URL)
+		w.Notifications = append(w.Notifications, n)
+	}
+	if wf.Cache != nil {
+		c := *wf.Cache
+		c.URL = redactURL(c.URL)
+		w.Cache = &c
+	}
+	return &w, secrets
+}
+
+// redactProbe returns a copy of the probe, without the values of its headers, e.g. an auth header.
+func redactProbe(p *types.Probe) *types.Probe {
+	if p == nil || p.HTTPGet == nil {
+		return p
+	}
+	out := *p
+	get := *p.HTTPGet
+	get.HTTPHeaders = nil
+	for _, h := range p.HTTPGet.HTTPHeaders {
+		get.HTTPHeaders = append(get.HTTPHeaders, types.HTTPHeader{Name: h.Name, Value: redacted})
+	}
+	out.HTTPGet = &get
+	return &out
+}
+
+// redactURL returns the URL without its credentials, path and query, as a webhook's secret is often in its path, e.g.
+// a Slack incoming webhook.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// mapStrings returns the strings with f applied to each, or nil if there are none.
+func mapStrings(s types.Strings, f func(string) string) types.Strings {
+	if s == nil {
+		return nil
+	}
+	out := make(types.Strings, len(s))
+	for i, x := range s {
+		out[i] = f(x)
+	}
+	return out
+}
+
+// redactEnviron returns the environment, sorted, without the values of variables that look like secrets.
+func redactEnviron(environ []string) []string {
+	var out []string
+	for _, kv := range environ {
+		if name, _, ok := strings.Cut(kv, "="); ok && secretName.MatchString(name) {
+			kv = name + "=" + redacted
+		}
+		out = append(out, kv)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// environSecrets returns the values of the variables in the environment that look like secrets.
+func environSecrets(environ []string) []string {
+	var out []string
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && value != "" && secretName.MatchString(name) {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// versions returns the versions of kit, Go and the OS, and of Docker if the workflow runs containers.
+func versions(wf *types.Workflow) string {
+	version := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+	}
+	out := fmt.Sprintf("kit %s\n%s %s/%s\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	for _, t := range wf.Tasks {
+		if t.Image != "" {
+			// the server, as that's what runs the containers
+			docker, err := exec.Command("docker", "version", "--format", "{{.Server.Version}}").Output()
+			if err == nil {
+				out += fmt.Sprintf("docker %s\n", strings.TrimSpace(string(docker)))
+			}
+			break
+		}
+	}
+	return out
+}
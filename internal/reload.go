@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kitproj/kit/internal/types"
+)
+
+// watchConfig watches the config files, and when they change, loads the workflow and queues it for the main loop. If the
+// workflow cannot be loaded, e.g. because it's being edited, it is logged and the tasks keep running.
+func watchConfig(ctx context.Context, logger *log.Logger, configFiles []string, load func() (*types.Workflow, error), sched *scheduler) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	// editors often replace a file, rather than write it, so we watch the directories the files are in
+	files := map[string]bool{}
+	for _, file := range configFiles {
+		files[filepath.Clean(file)] = true
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("failed to watch %q: %w", file, err)
+		}
+	}
+	go func() {
+		defer watcher.Close()
+		debounceTimer := time.AfterFunc(0, func() {})
+		defer debounceTimer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				if !files[filepath.Clean(event.Name)] || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				debounceTimer.Stop()
+				debounceTimer = time.AfterFunc(100*time.Millisecond, func() {
+					logger.Printf("%s changed, reloading\n", event.Name)
+					wf, err := load()
+					if err != nil {
+						logger.Printf("not reloading: %v\n", err)
+						return
+					}
+					sched.send(wf)
+				})
+			}
+		}
+	}()
+	return nil
+}
+
+// sessionSettings returns the task's settings that are applied when kit starts, rather than when the task does: its
+// https proxy, the router in front of it, and its intercept, and the ports they listen on or route to. A reload cannot
+// change them.
+func sessionSettings(t types.Task) []any {
+	settings := []any{t.TLS, t.RollingRestart, t.Faults, t.Intercept}
+	if t.TLS != nil || t.RollingRestart || t.Faults != nil || t.Intercept != nil {
+		settings = append(settings, t.Ports)
+	}
+	return settings
+}
@@ -0,0 +1,350 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// remoteCache stores archives of jobs' targets, keyed by a digest of their inputs.
+type remoteCache interface {
+	// get downloads the archive to the file, returning false if there is none
+	get(ctx context.Context, key, file string) (bool, error)
+	// put uploads the file as the archive
+	put(ctx context.Context, key, file string) error
+}
+
+// newRemoteCache returns the remote cache for the workflow's cache, or nil if it does not have one.
+func newRemoteCache(c *types.Cache) (remoteCache, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(c.URL, "/")
+	u, _ := url.Parse(c.URL)
+	switch u.Scheme {
+	case "s3":
+		return &cliCache{url: base, cp: []string{"aws", "s3", "cp", "--only-show-errors"}}, nil
+	case "gs":
+		return &cliCache{url: base, cp: []string{"gcloud", "storage", "cp"}}, nil
+	default:
+		return &httpCache{url: base, token: os.Getenv("KIT_CACHE_TOKEN")}, nil
+	}
+}
+
+// httpCache is a cache that GETs and PUTs archives, e.g. nginx with WebDAV, or bazel-remote.
+type httpCache struct {
+	url   string
+	token string
+}
+
+func (c *httpCache) get(ctx context.Context, key, file string) (bool, error) {
+	req, err := c.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to get %s: %s", req.URL, resp.Status)
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		_ = out.Close()
+		return false, err
+	}
+	return true, out.Close()
+}
+
+func (c *httpCache) put(ctx context.Context, key, file string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := c.request(ctx, http.MethodPut, key, in)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to put %s: %s", req.URL, resp.Status)
+	}
+	return nil
+}
+
+func (c *httpCache) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+"/"+key+".tar.gz", body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// cliCache is a cache in a bucket, copied to and from using the cloud provider's CLI, so it uses the same credentials
+// as the user.
+type cliCache struct {
+	url string
+	// the command to copy a file, e.g. "aws s3 cp"
+	cp []string
+}
+
+func (c *cliCache) get(ctx context.Context, key, file string) (bool, error) {
+	stderr, err := c.run(ctx, c.url+"/"+key+".tar.gz", file)
+	// the CLIs do not have an exit code for a missing object, so we look for their messages
+	if err != nil && (strings.Contains(stderr, "404") || strings.Contains(stderr, "matched no objects")) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c *cliCache) put(ctx context.Context, key, file string) error {
+	_, err := c.run(ctx, file, c.url+"/"+key+".tar.gz")
+	return err
+}
+
+func (c *cliCache) run(ctx context.Context, src, dst string) (string, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, c.cp[0], append(c.cp[1:], src, dst)...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return stderr.String(), fmt.Errorf("failed to run %s: %w: %s", strings.Join(c.cp, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stderr.String(), nil
+}
+
+// cacheable returns true if the job's targets can be cached, which needs its inputs to be known from its watches.
+func cacheable(t types.Task) bool {
+	return t.GetType() == types.TaskTypeJob && len(t.Watch) > 0 && len(t.Targets) > 0
+}
+
+// cacheKey returns a digest of the task, of the names and contents of its watched files, and of the platform. Unlike
+// taskHash, it does not use modification times, so it is the same on any machine with the same files.
+func cacheKey(t types.Task) (string, error) {
+	h := sha256.New()
+	spec, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	h.Write(spec)
+	_, _ = fmt.Fprintf(h, "%s/%s\x00", runtime.GOOS, runtime.GOARCH)
+	for _, path := range t.Watch {
+		root := filepath.Join(t.WorkingDir, path)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(t.WorkingDir, path)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(h, f)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// archiveTargets writes the task's targets to a gzipped tar, with paths relative to its working directory.
+func archiveTargets(t types.Task, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, target := range t.Targets {
+		root := filepath.Join(t.WorkingDir, target)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(t.WorkingDir, path)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive target %q: %w", target, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// extractTargets replaces the task's targets with those in the archive. The files are not given their original
+// modification times, so they are newer than the task's sources.
+func extractTargets(t types.Task, r io.Reader) error {
+	for _, target := range t.Targets {
+		if err := os.RemoveAll(filepath.Join(t.WorkingDir, target)); err != nil {
+			return err
+		}
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// an archive could have been pushed by anyone, so it must not write outside the working directory
+		if !filepath.IsLocal(filepath.FromSlash(header.Name)) {
+			return fmt.Errorf("invalid path in archive %q", header.Name)
+		}
+		path := filepath.Join(t.WorkingDir, filepath.FromSlash(header.Name))
+		mode := header.FileInfo().Mode().Perm()
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(path, mode|0o700); err != nil {
+				return err
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			_ = out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+// restoreTargets gets the task's targets from the cache, returning false if they are not there.
+func restoreTargets(ctx context.Context, cache remoteCache, key string, t types.Task) (bool, error) {
+	file, err := tempFile()
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(file)
+	ok, err := cache.get(ctx, key, file)
+	if err != nil || !ok {
+		return false, err
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	if err := extractTargets(t, in); err != nil {
+		return false, fmt.Errorf("failed to extract targets: %w", err)
+	}
+	return true, nil
+}
+
+// pushTargets puts the task's targets in the cache.
+func pushTargets(ctx context.Context, cache remoteCache, key string, t types.Task) error {
+	file, err := tempFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := archiveTargets(t, out); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return cache.put(ctx, key, file)
+}
+
+// tempFile returns the path of a new, empty, temporary file.
+func tempFile() (string, error) {
+	f, err := os.CreateTemp("", "kit-cache-*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cacheKey(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "main.go")
+	assert.NoError(t, os.WriteFile(source, []byte("package main"), 0o644))
+	task := types.Task{WorkingDir: dir, Command: []string{"go", "build"}, Watch: []string{"."}, Targets: []string{"app"}}
+
+	key, err := cacheKey(task)
+	assert.NoError(t, err)
+
+	t.Run("Modification time changed", func(t *testing.T) {
+		assert.NoError(t, os.Chtimes(source, time.Now(), time.Now().Add(time.Hour)))
+		again, err := cacheKey(task)
+		assert.NoError(t, err)
+		assert.Equal(t, key, again)
+	})
+	t.Run("Source changed", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(source, []byte("package main // changed"), 0o644))
+		changed, err := cacheKey(task)
+		assert.NoError(t, err)
+		assert.NotEqual(t, key, changed)
+	})
+}
+
+func Test_remoteCache(t *testing.T) {
+	objects := sync.Map{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := objects.Load(r.URL.Path)
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data.([]byte))
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects.Store(r.URL.Path, data)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("KIT_CACHE_TOKEN", "secret")
+	cache, err := newRemoteCache(&types.Cache{URL: server.URL + "/kit/"})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	build := types.Task{WorkingDir: t.TempDir(), Targets: []string{"app", "dist"}}
+	assert.NoError(t, os.WriteFile(filepath.Join(build.WorkingDir, "app"), []byte("binary"), 0o755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(build.WorkingDir, "dist", "css"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(build.WorkingDir, "dist", "css", "main.css"), []byte("body {}"), 0o644))
+
+	restore := types.Task{WorkingDir: t.TempDir(), Targets: build.Targets}
+	ok, err := restoreTargets(ctx, cache, "abc", restore)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, pushTargets(ctx, cache, "abc", build))
+	_, ok = objects.Load("/kit/abc.tar.gz")
+	assert.True(t, ok)
+
+	// a stale target is replaced
+	assert.NoError(t, os.MkdirAll(filepath.Join(restore.WorkingDir, "dist"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(restore.WorkingDir, "dist", "stale.js"), nil, 0o644))
+	ok, err = restoreTargets(ctx, cache, "abc", restore)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	data, err := os.ReadFile(filepath.Join(restore.WorkingDir, "app"))
+	assert.NoError(t, err)
+	assert.Equal(t, "binary", string(data))
+	info, err := os.Stat(filepath.Join(restore.WorkingDir, "app"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	data, err = os.ReadFile(filepath.Join(restore.WorkingDir, "dist", "css", "main.css"))
+	assert.NoError(t, err)
+	assert.Equal(t, "body {}", string(data))
+	assert.NoFileExists(t, filepath.Join(restore.WorkingDir, "dist", "stale.js"))
+}
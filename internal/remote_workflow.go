@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IsRemoteWorkflow returns true if the config file is fetched, rather than read from disk, e.g.
+// "https://example.com/devenv/tasks.yaml" or "git::github.com/org/devenv//tasks.yaml?ref=v2".
+func IsRemoteWorkflow(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "git::")
+}
+
+// remoteWorkflow is a config file to fetch.
+type remoteWorkflow struct {
+	// the URL of the file, or of the git repository
+	url string
+	// the file in the git repository
+	file string
+	// the git branch or tag
+	ref string
+	// the hex SHA-256 the file must have, if pinned
+	sha256 string
+}
+
+// parseRemoteWorkflow parses the source. A git source is the repository, then "//" and the file in it (default
+// tasks.yaml), then an optional "?ref=" branch or tag. Repositories without a scheme are cloned with https. Either
+// may end with "#sha256=<hex>", to pin the file's content, which is required for http, as anyone on the network could
+// change a file fetched over it.
+func parseRemoteWorkflow(source string) (remoteWorkflow, error) {
+	r := remoteWorkflow{}
+	if i := strings.LastIndex(source, "#"); i >= 0 {
+		pin, ok := strings.CutPrefix(source[i+1:], "sha256=")
+		if !ok || len(pin) != 64 {
+			return r, fmt.Errorf("invalid pin %q, must be sha256=<hex>", source[i+1:])
+		}
+		r.sha256 = strings.ToLower(pin)
+		source = source[:i]
+	}
+	repo, ok := strings.CutPrefix(source, "git::")
+	if !ok {
+		r.url = source
+		return r, r.checkPinned(source)
+	}
+	if i := strings.LastIndex(repo, "?"); i >= 0 {
+		query, err := url.ParseQuery(repo[i+1:])
+		if err != nil {
+			return r, fmt.Errorf("invalid query in %q: %w", source, err)
+		}
+		r.ref = query.Get("ref")
+		repo = repo[:i]
+	}
+	scheme := ""
+	if i := strings.Index(repo, "://"); i >= 0 {
+		scheme, repo = repo[:i+3], repo[i+3:]
+	}
+	repo, r.file, _ = strings.Cut(repo, "//")
+	if r.file == "" {
+		r.file = "tasks.yaml"
+	}
+	if scheme == "" && !strings.HasPrefix(repo, "git@") {
+		scheme = "https://"
+	}
+	r.url = scheme + repo
+	if !filepath.IsLocal(r.file) {
+		return r, fmt.Errorf("invalid file %q in %q", r.file, source)
+	}
+	return r, r.checkPinned(source)
+}
+
+// checkPinned returns an error if the file is fetched over http, but not pinned.
+func (r remoteWorkflow) checkPinned(source string) error {
+	if strings.HasPrefix(r.url, "http://") && r.sha256 == "" {
+		return fmt.Errorf("%q is fetched over http, so must be pinned with #sha256=<hex>, or use https", source)
+	}
+	return nil
+}
+
+// dir returns the name of the directory the file is fetched into, i.e. the repository's name, or the name of the
+// directory the file is in on the server. Workflows are named after their directory when merged with others.
+func (r remoteWorkflow) dir() string {
+	if r.file != "" {
+		return strings.TrimSuffix(path.Base(r.url), ".git")
+	}
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return "remote"
+	}
+	if dir := path.Base(path.Dir(u.Path)); dir != "/" && dir != "." {
+		return dir
+	}
+	return u.Hostname()
+}
+
+// FetchWorkflow fetches the config file into the cache, and returns the path of the cached copy. A pinned file that is
+// already cached is not fetched again. If the file cannot be fetched, e.g. when offline, the cached copy is used.
+func FetchWorkflow(ctx context.Context, logger *log.Logger, source string) (string, error) {
+	r, err := parseRemoteWorkflow(source)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(source))
+	root := filepath.Join(cacheDir, "kit", "workflows", hex.EncodeToString(sum[:8]))
+	file := filepath.Join(r.dir(), "tasks.yaml")
+	if r.file != "" {
+		file = filepath.Join(r.dir(), filepath.FromSlash(r.file))
+	}
+	if r.sha256 != "" && r.verify(filepath.Join(root, file)) == nil {
+		return filepath.Join(root, file), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if err := os.MkdirAll(filepath.Dir(root), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(root), "fetch-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := r.fetch(ctx, filepath.Join(tmp, r.dir()), filepath.Join(tmp, file)); err != nil {
+		cached := filepath.Join(root, file)
+		if _, statErr := os.Stat(cached); statErr == nil && r.verify(cached) == nil {
+			logger.Printf("failed to fetch %s, using the cached copy: %v\n", source, err)
+			return cached, nil
+		}
+		return "", fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+	if err := r.verify(filepath.Join(tmp, file)); err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+	if err := os.RemoveAll(root); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, root); err != nil {
+		return "", fmt.Errorf("failed to cache %s: %w", source, err)
+	}
+	return filepath.Join(root, file), nil
+}
+
+// fetch downloads the file, or clones the repository into the directory.
+func (r remoteWorkflow) fetch(ctx context.Context, dir, file string) error {
+	if r.file != "" {
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if r.ref != "" {
+			args = append(args, "--branch", r.ref)
+		}
+		out, err := exec.CommandContext(ctx, "git", append(args, r.url, dir)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("git clone: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// verify returns an error if the file does not have the pinned SHA-256.
+func (r remoteWorkflow) verify(file string) error {
+	if r.sha256 == "" {
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != r.sha256 {
+		return fmt.Errorf("has sha256 %s, but is pinned to %s", actual, r.sha256)
+	}
+	return nil
+}
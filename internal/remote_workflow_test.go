@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRemoteWorkflow(t *testing.T) {
+	pin := strings.Repeat("a", 64)
+	tests := []struct {
+		source string
+		want   remoteWorkflow
+		err    string
+	}{
+		{source: "https://example.com/devenv/tasks.yaml", want: remoteWorkflow{url: "https://example.com/devenv/tasks.yaml"}},
+		{source: "https://example.com/tasks.yaml#sha256=" + pin, want: remoteWorkflow{url: "https://example.com/tasks.yaml", sha256: pin}},
+		{source: "https://example.com/tasks.yaml#v2", err: `invalid pin "v2", must be sha256=<hex>`},
+		{source: "http://example.com/tasks.yaml", err: `"http://example.com/tasks.yaml" is fetched over http, so must be pinned with #sha256=<hex>, or use https`},
+		{source: "http://example.com/tasks.yaml#sha256=" + pin, want: remoteWorkflow{url: "http://example.com/tasks.yaml", sha256: pin}},
+		{source: "git::http://example.com/devenv", err: `"git::http://example.com/devenv" is fetched over http, so must be pinned with #sha256=<hex>, or use https`},
+		{source: "git::github.com/org/devenv", want: remoteWorkflow{url: "https://github.com/org/devenv", file: "tasks.yaml"}},
+		{source: "git::github.com/org/devenv//dev/tasks.yaml?ref=v2", want: remoteWorkflow{url: "https://github.com/org/devenv", file: "dev/tasks.yaml", ref: "v2"}},
+		{source: "git::ssh://git@github.com/org/devenv.git//tasks.yaml#sha256=" + pin, want: remoteWorkflow{url: "ssh://git@github.com/org/devenv.git", file: "tasks.yaml", sha256: pin}},
+		{source: "git::github.com/org/devenv//../tasks.yaml", err: `invalid file "../tasks.yaml" in "git::github.com/org/devenv//../tasks.yaml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := parseRemoteWorkflow(tt.source)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFetchWorkflow(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	ctx := context.Background()
+	content := "tasks:\n  api:\n    command: [api]\n"
+	sum := sha256.Sum256([]byte(content))
+	pin := hex.EncodeToString(sum[:])
+
+	// files are fetched with the default client, which must trust the test servers
+	defaultClient := http.DefaultClient
+	t.Cleanup(func() { http.DefaultClient = defaultClient })
+
+	t.Run("HTTPS", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(content))
+		}))
+		http.DefaultClient = server.Client()
+		source := server.URL + "/devenv/tasks.yaml"
+
+		file, err := FetchWorkflow(ctx, log.Default(), source)
+		assert.NoError(t, err)
+		assert.Equal(t, "devenv", filepath.Base(filepath.Dir(file)))
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+
+		server.Close()
+		t.Run("Offline", func(t *testing.T) {
+			cached, err := FetchWorkflow(ctx, log.Default(), source)
+			assert.NoError(t, err)
+			assert.Equal(t, file, cached)
+		})
+		t.Run("Offline and not cached", func(t *testing.T) {
+			_, err := FetchWorkflow(ctx, log.Default(), server.URL+"/other/tasks.yaml")
+			assert.ErrorContains(t, err, "failed to fetch")
+		})
+	})
+	t.Run("Pinned", func(t *testing.T) {
+		requests := 0
+		// http, as a pinned file cannot be changed
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			_, _ = w.Write([]byte(content))
+		}))
+		defer server.Close()
+
+		_, err := FetchWorkflow(ctx, log.Default(), server.URL+"/tasks.yaml#sha256="+pin)
+		assert.NoError(t, err)
+		_, err = FetchWorkflow(ctx, log.Default(), server.URL+"/tasks.yaml#sha256="+pin)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, requests, "a pinned file that is cached is not fetched again")
+
+		_, err = FetchWorkflow(ctx, log.Default(), server.URL+"/tasks.yaml#sha256="+strings.Repeat("0", 64))
+		assert.ErrorContains(t, err, "has sha256 "+pin+", but is pinned to 0000")
+	})
+	t.Run("Git", func(t *testing.T) {
+		repo := filepath.Join(t.TempDir(), "devenv")
+		assert.NoError(t, os.MkdirAll(filepath.Join(repo, "dev"), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(repo, "dev", "tasks.yaml"), []byte(content), 0o644))
+		for _, args := range [][]string{
+			{"init", "--quiet"},
+			{"add", "."},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+			{"tag", "v2"},
+		} {
+			out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+			assert.NoError(t, err, string(out))
+		}
+
+		file, err := FetchWorkflow(ctx, log.Default(), "git::file://"+repo+"//dev/tasks.yaml?ref=v2#sha256="+pin)
+		assert.NoError(t, err)
+		assert.Equal(t, "devenv", filepath.Base(filepath.Dir(filepath.Dir(file))), "the repository is cloned into a directory named after it")
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	})
+}
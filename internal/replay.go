@@ -0,0 +1,306 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// the number of sessions whose output is kept to be replayed
+const maxSessions = 10
+
+const sessionFormat = "20060102-150405"
+
+// sessionLine is a line a task wrote, and when, so the session can be replayed.
+type sessionLine struct {
+	Time time.Time `json:"time"`
+	Task string    `json:"task"`
+	// "stdout" or "stderr"
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+// sessionLog records every line the tasks write in a session (as JSON lines), so it can be replayed by "kit replay".
+// A nil session log does nothing.
+type sessionLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openSessionLog creates the log of the session started at the time in the directory, deleting the oldest sessions
+// beyond those kept.
+func openSessionLog(dir string, startedAt time.Time) (*sessionLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	names, err := sessionNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	for len(names) >= maxSessions {
+		if err := os.Remove(filepath.Join(dir, names[0]+".jsonl")); err != nil {
+			return nil, fmt.Errorf("failed to delete old session: %w", err)
+		}
+		names = names[1:]
+	}
+	file, err := os.Create(filepath.Join(dir, startedAt.Format(sessionFormat)+".jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session log: %w", err)
+	}
+	return &sessionLog{file: file}, nil
+}
+
+// writer returns a writer that records each line written to the task's stream, and writes it to w.
+func (l *sessionLog) writer(task, stream string, w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return lineWriter(w, func(line string) {
+		l.record(sessionLine{Task: task, Stream: stream, Line: line})
+	})
+}
+
+// record appends the line, errors are ignored as the log is only for replaying.
+func (l *sessionLog) record(line sessionLine) {
+	line.Time = time.Now()
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.file.Write(append(data, '\n'))
+}
+
+// path returns the path of the log, or empty if there is none.
+func (l *sessionLog) path() string {
+	if l == nil {
+		return ""
+	}
+	return l.file.Name()
+}
+
+func (l *sessionLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// sessionNames returns the names of the recorded sessions, oldest first.
+func sessionNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".jsonl")
+		if _, err := time.ParseInLocation(sessionFormat, name, time.Local); ok && err == nil {
+			names = append(names, name)
+		}
+	}
+	// the names sort by when the session started
+	sort.Strings(names)
+	return names, nil
+}
+
+// replayData is what happened in a session, or a window of it, for the user interface to re-render.
+type replayData struct {
+	Session string    `json:"session"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	// when each task changed phase
+	Transitions []lifecycleEvent `json:"transitions"`
+	Lines       []sessionLine    `json:"lines"`
+	// the tasks that were requested
+	taskNames []string
+}
+
+// loadReplay loads the session, the last one if it is empty, in the state directory, only keeping what happened
+// between from and to, e.g. "15:04:05" or "2006-01-02T15:04:05Z07:00", if they are not empty.
+func loadReplay(stateDir, session, from, to string) (*replayData, error) {
+	dir := filepath.Join(stateDir, "sessions")
+	names, err := sessionNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no sessions have been recorded")
+	}
+	i := len(names) - 1
+	if session != "" {
+		i = sort.SearchStrings(names, session)
+		if i == len(names) || names[i] != session {
+			return nil, fmt.Errorf("session %q not found, must be one of %s", session, strings.Join(names, ", "))
+		}
+	}
+	d := &replayData{Session: names[i]}
+	start, _ := time.ParseInLocation(sessionFormat, names[i], time.Local)
+	// the session ends when the next one starts
+	var end time.Time
+	if i+1 < len(names) {
+		end, _ = time.ParseInLocation(sessionFormat, names[i+1], time.Local)
+	}
+	if d.From, err = parseReplayTime(from, start); err != nil {
+		return nil, err
+	}
+	if d.To, err = parseReplayTime(to, start); err != nil {
+		return nil, err
+	}
+	within := func(t time.Time) bool {
+		return (d.From.IsZero() || !t.Before(d.From)) && (d.To.IsZero() || !t.After(d.To))
+	}
+
+	if err := readJSONLines(filepath.Join(dir, names[i]+".jsonl"), func(line sessionLine) {
+		if within(line.Time) {
+			d.Lines = append(d.Lines, line)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if err := readJSONLines(filepath.Join(stateDir, "events.jsonl"), func(e lifecycleEvent) {
+		if e.Time.Before(start) || !end.IsZero() && !e.Time.Before(end) {
+			return
+		}
+		switch {
+		case e.Event == "start":
+			d.taskNames = strings.Split(e.Message, ",")
+		case e.Event == "transition" && within(e.Time):
+			d.Transitions = append(d.Transitions, e)
+		}
+	}); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// without a window, the session is replayed from its first record to its last
+	if d.From.IsZero() {
+		d.From = start
+	}
+	if d.To.IsZero() {
+		d.To = d.From
+		if n := len(d.Lines); n > 0 {
+			d.To = d.Lines[n-1].Time
+		}
+		if n := len(d.Transitions); n > 0 && d.Transitions[n-1].Time.After(d.To) {
+			d.To = d.Transitions[n-1].Time
+		}
+	}
+	return d, nil
+}
+
+// parseReplayTime parses a time, or a time of day on the day the session started, or returns zero if it is empty.
+func parseReplayTime(s string, start time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	clock, err := time.ParseInLocation(time.TimeOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q must be a time of day, e.g. 15:04:05, or an RFC3339 time", s)
+	}
+	return time.Date(start.Year(), start.Month(), start.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.Local), nil
+}
+
+// readJSONLines decodes each line of the file, skipping any that cannot be, e.g. a line that was not finished when
+// kit was killed.
+func readJSONLines[T any](path string, f func(T)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var v T
+		if err := json.Unmarshal(scanner.Bytes(), &v); err == nil {
+			f(v)
+		}
+	}
+	return scanner.Err()
+}
+
+// replayGraph returns the graph of the requested tasks, and any others in the session, using the workflow as it is now.
+func replayGraph(name string, wf *types.Workflow, d *replayData) DAG[*TaskNode] {
+	dag := NewDAG[*TaskNode](name)
+	add := func(name string) {
+		if _, ok := dag.Nodes[name]; !ok {
+			dag.AddNode(name, &TaskNode{Name: name, Task: wf.Tasks[name], PhaseMachine: types.PhaseMachine{Phase: types.PhasePending}})
+		}
+	}
+	// the tasks may no longer be in the workflow, in which case they are shown without their dependencies
+	if p, err := newPlan(name, wf, d.taskNames, nil); err == nil {
+		for name := range p.visited {
+			add(name)
+			for _, parent := range p.dag.Parents[name] {
+				dag.AddEdge(parent, name)
+			}
+		}
+	}
+	for _, e := range d.Transitions {
+		add(e.Task)
+	}
+	for _, line := range d.Lines {
+		add(line.Task)
+	}
+	return dag
+}
+
+// Replay serves the user interface on the port, re-rendering a session recorded in the namespace, the last one if it
+// is empty, or only what happened between from and to, so you can scrub back to what each task printed when
+// something failed.
+func Replay(ctx context.Context, logger *log.Logger, wf *types.Workflow, port int, namespace, session, from, to string) error {
+	d, err := loadReplay(options{namespace: namespace}.stateDir(), session, from, to)
+	if err != nil {
+		return err
+	}
+	dag := replayGraph(filepath.Base(os.Getenv("PWD")), wf, d)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(indexHTML))
+	})
+	mux.HandleFunc("/dag", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(dag)
+	})
+	mux.HandleFunc("/replay", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(d)
+	})
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+	logger.Printf("replaying session %s, from %s to %s, at http://localhost:%d\n", d.Session, d.From.Format(time.TimeOnly), d.To.Format(time.TimeOnly), port)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
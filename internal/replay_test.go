@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sessionLog(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	startedAt := time.Date(2026, 1, 2, 15, 4, 5, 0, time.Local)
+	for i := 0; i < maxSessions+2; i++ {
+		l, err := openSessionLog(dir, startedAt.Add(time.Duration(i)*time.Minute))
+		assert.NoError(t, err)
+		assert.NoError(t, l.Close())
+	}
+	names, err := sessionNames(dir)
+	assert.NoError(t, err)
+	assert.Len(t, names, maxSessions)
+	assert.Equal(t, "20260102-150605", names[0])
+
+	l, err := openSessionLog(dir, time.Now())
+	assert.NoError(t, err)
+	buf := &bytes.Buffer{}
+	w := l.writer("api", "stderr", buf)
+	_, _ = fmt.Fprint(w, "listening\r\nfail")
+	_, _ = fmt.Fprint(w, "ed\n")
+	assert.NoError(t, l.Close())
+	assert.Equal(t, "listening\r\nfailed\n", buf.String())
+
+	var lines []sessionLine
+	names, _ = sessionNames(dir)
+	assert.NoError(t, readJSONLines(filepath.Join(dir, names[len(names)-1]+".jsonl"), func(line sessionLine) {
+		lines = append(lines, line)
+	}))
+	assert.Len(t, lines, 2)
+	assert.Equal(t, sessionLine{Time: lines[1].Time, Task: "api", Stream: "stderr", Line: "failed"}, lines[1])
+
+	var nilLog *sessionLog
+	assert.Equal(t, buf, nilLog.writer("api", "stdout", buf))
+	assert.NoError(t, nilLog.Close())
+}
+
+func Test_loadReplay(t *testing.T) {
+	stateDir := t.TempDir()
+	at := func(clock string) time.Time {
+		t, _ := time.ParseInLocation(time.DateTime, "2026-01-02 "+clock, time.Local)
+		return t
+	}
+	write := func(path string, records ...any) {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		buf := &bytes.Buffer{}
+		for _, r := range records {
+			assert.NoError(t, json.NewEncoder(buf).Encode(r))
+		}
+		assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	}
+	write(filepath.Join(stateDir, "sessions", "20260102-150000.jsonl"),
+		sessionLine{Time: at("15:00:01"), Task: "db", Stream: "stdout", Line: "ready"},
+		sessionLine{Time: at("15:00:03"), Task: "api", Stream: "stderr", Line: "panic"},
+	)
+	write(filepath.Join(stateDir, "sessions", "20260102-160000.jsonl"))
+	write(filepath.Join(stateDir, "events.jsonl"),
+		lifecycleEvent{Time: at("15:00:00"), Event: "start", Message: "api"},
+		lifecycleEvent{Time: at("15:00:02"), Task: "db", Event: "transition", Phase: "running"},
+		lifecycleEvent{Time: at("15:00:04"), Task: "api", Event: "transition", Phase: "failed"},
+		lifecycleEvent{Time: at("16:00:00"), Event: "start", Message: "db"},
+		lifecycleEvent{Time: at("16:00:01"), Task: "db", Event: "transition", Phase: "running"},
+	)
+
+	t.Run("Last", func(t *testing.T) {
+		d, err := loadReplay(stateDir, "", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "20260102-160000", d.Session)
+		assert.Equal(t, []string{"db"}, d.taskNames)
+		assert.Len(t, d.Transitions, 1)
+		assert.Empty(t, d.Lines)
+	})
+	t.Run("Session", func(t *testing.T) {
+		d, err := loadReplay(stateDir, "20260102-150000", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"api"}, d.taskNames)
+		assert.Len(t, d.Transitions, 2)
+		assert.Len(t, d.Lines, 2)
+		assert.True(t, at("15:00:00").Equal(d.From))
+		assert.True(t, at("15:00:04").Equal(d.To))
+
+		wf := &types.Workflow{Tasks: types.Tasks{
+			"db":  {Ports: []types.Port{{ContainerPort: 5432}}},
+			"api": {Dependencies: &types.Dependencies{AllOf: types.Strings{"db"}}},
+		}}
+		dag := replayGraph("test", wf, d)
+		assert.Len(t, dag.Nodes, 2)
+		assert.Equal(t, []string{"db"}, dag.Parents["api"])
+	})
+	t.Run("Window", func(t *testing.T) {
+		d, err := loadReplay(stateDir, "20260102-150000", "15:00:02", "15:00:03")
+		assert.NoError(t, err)
+		assert.Len(t, d.Transitions, 1)
+		if assert.Len(t, d.Lines, 1) {
+			assert.Equal(t, "panic", d.Lines[0].Line)
+		}
+	})
+	t.Run("Not found", func(t *testing.T) {
+		_, err := loadReplay(stateDir, "20260102-170000", "", "")
+		assert.EqualError(t, err, `session "20260102-170000" not found, must be one of 20260102-150000, 20260102-160000`)
+	})
+	t.Run("Invalid time", func(t *testing.T) {
+		_, err := loadReplay(stateDir, "", "3pm", "")
+		assert.EqualError(t, err, `"3pm" must be a time of day, e.g. 15:04:05, or an RFC3339 time`)
+	})
+}
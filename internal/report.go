@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// duration returns how long the task's last run took, or has taken so far if it is still running.
+func (n *TaskNode) duration() time.Duration {
+	if n.startedAt.IsZero() {
+		return 0
+	}
+	if n.finishedAt.Before(n.startedAt) {
+		return time.Since(n.startedAt)
+	}
+	return n.finishedAt.Sub(n.startedAt)
+}
+
+func sortedNodes(dag DAG[*TaskNode]) []*TaskNode {
+	var nodes []*TaskNode
+	dag.RLock()
+	for _, node := range dag.Nodes {
+		nodes = append(nodes, node)
+	}
+	dag.RUnlock()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name       `xml:"testsuites"`
+	Name     string         `xml:"name,attr"`
+	Tests    int            `xml:"tests,attr"`
+	Failures int            `xml:"failures,attr"`
+	Skipped  int            `xml:"skipped,attr"`
+	Time     string         `xml:"time,attr"`
+	Suites   junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a JUnit XML report with a test case for each task. Failed tasks include the tail of their logs.
+func writeJUnit(w io.Writer, dag DAG[*TaskNode], elapsed time.Duration) error {
+	suite := junitTestSuite{Name: dag.Name, Time: seconds(elapsed)}
+	for _, node := range sortedNodes(dag) {
+		testCase := junitTestCase{Name: node.Name, ClassName: dag.Name, Time: seconds(node.duration())}
+		s := node.Snapshot()
+		switch s.Phase {
+		case "failed":
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: s.Message, Text: strings.Join(node.recent.Tail(50), "\n")}
+		case "skipped":
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{}
+		case "pending", "waiting":
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: "not run"}
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	data, err := xml.MarshalIndent(junitTestSuites{
+		Name:     dag.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   suite,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// writeSummary writes a markdown summary of the tasks, e.g. for GitHub's job summary. Failed tasks include the tail of
+// their logs.
+func writeSummary(w io.Writer, dag DAG[*TaskNode]) error {
+	buf := &strings.Builder{}
+	_, _ = fmt.Fprintf(buf, "## %s\n\n| Task | Phase | Duration | Message |\n| --- | --- | --- | --- |\n", dag.Name)
+	var failures []*TaskNode
+	for _, node := range sortedNodes(dag) {
+		s := node.Snapshot()
+		_, _ = fmt.Fprintf(buf, "| %s | %s | %s | %s |\n", node.Name, s.Phase, node.duration().Round(time.Millisecond), strings.ReplaceAll(s.Message, "|", `\|`))
+		if s.Phase == "failed" {
+			failures = append(failures, node)
+		}
+	}
+	for _, node := range failures {
+		_, _ = fmt.Fprintf(buf, "\n### %s\n\n```\n%s\n```\n", node.Name, strings.Join(node.recent.Tail(50), "\n"))
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// writeReports writes the JUnit and summary reports, if they are configured.
+func writeReports(options *options, dag DAG[*TaskNode], elapsed time.Duration) error {
+	if options.junitReport != "" {
+		file, err := os.Create(options.junitReport)
+		if err != nil {
+			return fmt.Errorf("failed to create JUnit report: %w", err)
+		}
+		defer file.Close()
+		if err := writeJUnit(file, dag, elapsed); err != nil {
+			return fmt.Errorf("failed to write JUnit report: %w", err)
+		}
+	}
+	if options.summaryReport != "" {
+		// the summary is appended to, as GitHub's job summary may be shared by several steps
+		file, err := os.OpenFile(options.summaryReport, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open summary report: %w", err)
+		}
+		defer file.Close()
+		if err := writeSummary(file, dag); err != nil {
+			return fmt.Errorf("failed to write summary report: %w", err)
+		}
+	}
+	return nil
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/ring"
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reports(t *testing.T) {
+	recent := ring.New(10)
+	_, _ = recent.Write([]byte("compiling\nerror: oops\n"))
+	startedAt := time.Now()
+	dag := NewDAG[*TaskNode]("my-app")
+	dag.AddNode("build", &TaskNode{Name: "build", PhaseMachine: types.PhaseMachine{Phase: "failed", Message: "exit status 1"}, recent: recent, startedAt: startedAt, finishedAt: startedAt.Add(1500 * time.Millisecond)})
+	dag.AddNode("lint", &TaskNode{Name: "lint", PhaseMachine: types.PhaseMachine{Phase: "skipped"}})
+	dag.AddNode("test", &TaskNode{Name: "test", PhaseMachine: types.PhaseMachine{Phase: "pending"}})
+
+	t.Run("JUnit", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, writeJUnit(buf, dag, 2*time.Second))
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="my-app" tests="3" failures="1" skipped="2" time="2.000">
+  <testsuite name="my-app" tests="3" failures="1" skipped="2" time="2.000">
+    <testcase name="build" classname="my-app" time="1.500">
+      <failure message="exit status 1">compiling&#xA;error: oops</failure>
+    </testcase>
+    <testcase name="lint" classname="my-app" time="0.000">
+      <skipped></skipped>
+    </testcase>
+    <testcase name="test" classname="my-app" time="0.000">
+      <skipped message="not run"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+	})
+	t.Run("Summary", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, writeSummary(buf, dag))
+		assert.Equal(t, "## my-app\n\n"+
+			"| Task | Phase | Duration | Message |\n"+
+			"| --- | --- | --- | --- |\n"+
+			"| build | failed | 1.5s | exit status 1 |\n"+
+			"| lint | skipped | 0s |  |\n"+
+			"| test | pending | 0s |  |\n"+
+			"\n### build\n\n```\ncompiling\nerror: oops\n```\n", buf.String())
+	})
+}
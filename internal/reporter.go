@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// reporter presents the progress of the workflow, e.g. to a user, or to another program. It is told each time a task
+// changes phase, and each line a task writes.
+type reporter interface {
+	// transition is called after the task changed phase
+	transition(node *TaskNode, t types.Transition)
+	// line is called with each line the task writes to the stream, "stdout" or "stderr"
+	line(task, stream, line string)
+}
+
+// reporterKind is a way of reporting that can be selected with the --reporter flag.
+type reporterKind struct {
+	// new returns the reporter, which writes to the console
+	new func(w io.Writer) reporter
+	// replacesLogs is true if the reporter's output would be corrupted by the tasks' logs in the console, so they are not
+	// written there
+	replacesLogs bool
+}
+
+// the reporters that can be selected, other than the console, which shows the tasks' logs, and is the default
+var reporterKinds = map[string]reporterKind{
+	"json":   {new: func(w io.Writer) reporter { return &jsonReporter{w: w} }, replacesLogs: true},
+	"github": {new: func(w io.Writer) reporter { return &githubReporter{w: w} }},
+}
+
+// Reporters returns the names of the reporters that can be selected, sorted.
+func Reporters() []string {
+	names := []string{"console"}
+	for name := range reporterKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newReporter returns the named reporter, or nil for the console.
+func newReporter(name string, w io.Writer) (reporter, bool, error) {
+	if name == "" || name == "console" {
+		return nil, false, nil
+	}
+	kind, ok := reporterKinds[name]
+	if !ok {
+		return nil, false, fmt.Errorf("invalid reporter %q, must be one of %s", name, strings.Join(Reporters(), ", "))
+	}
+	return kind.new(w), kind.replacesLogs, nil
+}
+
+// reporters reports to each of the reporters.
+type reporters []reporter
+
+func (r reporters) transition(node *TaskNode, t types.Transition) {
+	for _, x := range r {
+		x.transition(node, t)
+	}
+}
+
+func (r reporters) line(task, stream, line string) {
+	for _, x := range r {
+		x.line(task, stream, line)
+	}
+}
+
+// writer returns a writer that reports each line written to the task's stream, and writes it to w.
+func (r reporters) writer(task, stream string, w io.Writer) io.Writer {
+	if len(r) == 0 {
+		return w
+	}
+	return lineWriter(w, func(line string) { r.line(task, stream, line) })
+}
+
+// uiReporter sends each task that changes phase to the user interface.
+type uiReporter struct {
+	events chan<- *TaskNode
+}
+
+func (u uiReporter) transition(node *TaskNode, _ types.Transition) {
+	u.events <- node
+}
+
+func (u uiReporter) line(string, string, string) {}
+
+// jsonRecord is a line of the JSON reporter's stream.
+type jsonRecord struct {
+	Time time.Time `json:"time"`
+	Task string    `json:"task"`
+	// "transition" or "line"
+	Event string `json:"event"`
+	// for transitions
+	From    types.Phase `json:"from,omitempty"`
+	Phase   types.Phase `json:"phase,omitempty"`
+	Message string      `json:"message,omitempty"`
+	// for lines
+	Stream string `json:"stream,omitempty"`
+	Line   string `json:"line,omitempty"`
+}
+
+// jsonReporter writes each transition and line as a line of JSON, e.g. for an editor to show.
+type jsonReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (j *jsonReporter) transition(node *TaskNode, t types.Transition) {
+	j.write(jsonRecord{Time: t.Time, Task: node.Name, Event: "transition", From: t.From, Phase: t.To, Message: t.Message})
+}
+
+func (j *jsonReporter) line(task, stream, line string) {
+	j.write(jsonRecord{Time: time.Now(), Task: task, Event: "line", Stream: stream, Line: line})
+}
+
+func (j *jsonReporter) write(r jsonRecord) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, _ = j.w.Write(append(data, '\n'))
+}
+
+// githubReporter writes GitHub Actions annotations, so a task that fails or stalls is shown on the summary of the run,
+// and the pull request.
+type githubReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (g *githubReporter) transition(node *TaskNode, t types.Transition) {
+	level := ""
+	switch t.To {
+	case types.PhaseFailed:
+		level = "error"
+	case types.PhaseStalled:
+		level = "warning"
+	default:
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, _ = fmt.Fprintf(g.w, "::%s title=%s::%s\n", level, escapeProperty(fmt.Sprintf("%s %s", node.Name, t.To)), escapeData(t.Message))
+}
+
+func (g *githubReporter) line(string, string, string) {}
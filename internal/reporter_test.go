@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporters(t *testing.T) {
+	assert.Equal(t, []string{"console", "github", "json"}, Reporters())
+}
+
+func Test_newReporter(t *testing.T) {
+	t.Run("Console", func(t *testing.T) {
+		r, replacesLogs, err := newReporter("console", &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Nil(t, r)
+		assert.False(t, replacesLogs)
+	})
+	t.Run("JSON", func(t *testing.T) {
+		r, replacesLogs, err := newReporter("json", &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.IsType(t, &jsonReporter{}, r)
+		assert.True(t, replacesLogs)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, _, err := newReporter("tap", &bytes.Buffer{})
+		assert.EqualError(t, err, `invalid reporter "tap", must be one of console, github, json`)
+	})
+}
+
+func Test_jsonReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := reporters{&jsonReporter{w: buf}}
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	r.transition(&TaskNode{Name: "api"}, types.Transition{From: types.PhaseStarting, To: types.PhaseRunning, Message: "readiness probe succeeded", Time: at})
+	_, _ = r.writer("api", "stderr", &bytes.Buffer{}).Write([]byte("oops\r\nagain"))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if assert.Len(t, lines, 2, "the partial line is not reported") {
+		assert.JSONEq(t, `{"time":"2026-01-02T15:04:05Z","task":"api","event":"transition","from":"starting","phase":"running","message":"readiness probe succeeded"}`, string(lines[0]))
+		assert.Contains(t, string(lines[1]), `"task":"api","event":"line","stream":"stderr","line":"oops"}`)
+	}
+}
+
+func Test_githubReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := &githubReporter{w: buf}
+	node := &TaskNode{Name: "build"}
+	r.transition(node, types.Transition{From: types.PhaseWaiting, To: types.PhaseRunning})
+	r.transition(node, types.Transition{From: types.PhaseRunning, To: types.PhaseStalled, Message: "no output for 30s"})
+	r.transition(node, types.Transition{From: types.PhaseStalled, To: types.PhaseFailed, Message: "exit status 1\noops"})
+	assert.Equal(t, "::warning title=build stalled::no output for 30s\n::error title=build failed::exit status 1%0Aoops\n", buf.String())
+}
@@ -0,0 +1,77 @@
+// Package ring keeps the recent lines of each task's output in memory, so they can be shown, e.g. when a task fails,
+// without reading its log file.
+package ring
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Ring is an io.Writer that keeps the last lines written to it. When it is full, the oldest line is dropped for each
+// new one. It is safe to use from several goroutines.
+type Ring struct {
+	mu    sync.Mutex
+	lines []string
+	// the index of the oldest line, once the ring is full
+	start int
+	size  int
+	// the last line written, until it is ended
+	partial []byte
+}
+
+// New returns a ring that keeps the last size lines, or none if size is not positive.
+func New(size int) *Ring {
+	return &Ring{size: max(size, 0)}
+}
+
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partial = append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(r.partial, '\n')
+		if i < 0 {
+			break
+		}
+		r.add(string(bytes.TrimSuffix(r.partial[:i], []byte("\r"))))
+		r.partial = r.partial[i+1:]
+	}
+	// an unended line cannot grow forever, e.g. a progress bar
+	if len(r.partial) > 64*1024 {
+		r.add(string(r.partial))
+		r.partial = nil
+	}
+	return len(p), nil
+}
+
+func (r *Ring) add(line string) {
+	if r.size == 0 {
+		return
+	}
+	if len(r.lines) < r.size {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.start] = line
+	r.start = (r.start + 1) % r.size
+}
+
+// Tail returns the last n lines, oldest first, or all of them if n is not positive. A line that has not been ended yet
+// is included.
+func (r *Ring) Tail(n int) []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := make([]string, 0, len(r.lines)+1)
+	lines = append(lines, r.lines[r.start:]...)
+	lines = append(lines, r.lines[:r.start]...)
+	if len(r.partial) > 0 {
+		lines = append(lines, string(bytes.TrimSuffix(r.partial, []byte("\r"))))
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,54 @@
+package ring
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	t.Run("Lines", func(t *testing.T) {
+		r := New(3)
+		assert.Empty(t, r.Tail(0))
+		_, _ = r.Write([]byte("one\r\ntw"))
+		assert.Equal(t, []string{"one", "tw"}, r.Tail(0), "includes the line being written")
+		_, _ = r.Write([]byte("o\nthree\nfour\n"))
+		assert.Equal(t, []string{"two", "three", "four"}, r.Tail(0), "the oldest line is dropped")
+		assert.Equal(t, []string{"three", "four"}, r.Tail(2))
+		assert.Equal(t, []string{"two", "three", "four"}, r.Tail(10))
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		r := New(0)
+		_, _ = r.Write([]byte("one\n"))
+		assert.Empty(t, r.Tail(0))
+	})
+	t.Run("Nil", func(t *testing.T) {
+		var r *Ring
+		assert.Empty(t, r.Tail(10))
+	})
+	t.Run("Long line", func(t *testing.T) {
+		r := New(3)
+		_, _ = r.Write([]byte(strings.Repeat("=", 100*1024)))
+		_, _ = r.Write([]byte("done\n"))
+		assert.Len(t, r.Tail(0), 2)
+	})
+	t.Run("Concurrent", func(t *testing.T) {
+		r := New(100)
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					_, _ = fmt.Fprintf(r, "%d-%d\n", i, j)
+					_ = r.Tail(10)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Len(t, r.Tail(0), 100)
+	})
+}
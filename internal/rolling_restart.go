@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/proxy"
+	"github.com/kitproj/kit/internal/types"
+)
+
+// allocatePort returns a free port on localhost.
+func allocatePort() (uint16, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate port: %w", err)
+	}
+	defer l.Close()
+	return uint16(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// rollingRestart runs a service behind a router on its port, and, when a watched file changes, starts a replacement
+// on another port, and only routes to it, and stops the old process, once the replacement is ready.
+type rollingRestart struct {
+	router *proxy.Router
+	// the task, on the port the first process is run on
+	task types.Task
+	// newProc returns a process to run the task
+	newProc        func(t types.Task) proc.Interface
+	stdout, stderr io.Writer
+	// receives the file that changed, whenever a watched file does
+	rolls <-chan string
+	// runs the onChange commands before a replacement is started
+	onChange func(ctx context.Context) error
+	// called once a replacement has taken over
+	restarted func(port uint16)
+	logf      func(format string, args ...any)
+}
+
+// instance is a running process of the task.
+type instance struct {
+	port   uint16
+	cancel func()
+	done   chan error
+	// why the process was stopped, e.g. its liveness probe failed
+	cause error
+}
+
+func (r rollingRestart) start(ctx context.Context, p proc.Interface, t types.Task) *instance {
+	ctx, cancel := context.WithCancel(ctx)
+	i := &instance{port: t.Ports[0].GetHostPort(), cancel: cancel, done: make(chan error, 1)}
+	// each process is probed for liveness, so a failing one is not routed to
+	if probe := t.GetLivenessProbe(); probe != nil {
+		go probeLoop(ctx, "liveness probe", *probe, r.logf, func(live bool, err error) {
+			if !live {
+				i.cause = fmt.Errorf("liveness probe failed: %v", err)
+				cancel()
+			}
+		})
+	}
+	go func() {
+		i.done <- p.Run(ctx, r.stdout, r.stderr)
+	}()
+	return i
+}
+
+func (i *instance) stop() {
+	i.cancel()
+	<-i.done
+}
+
+// run runs the process, and a replacement whenever a watched file changes, until the current process exits.
+func (r rollingRestart) run(ctx context.Context, p proc.Interface) error {
+	current := r.start(ctx, p, r.task)
+	r.router.Route(current.port)
+	for {
+		select {
+		case err := <-current.done:
+			if current.cause != nil {
+				return current.cause
+			}
+			return err
+		case file := <-r.rolls:
+			for file != "" {
+				var next *instance
+				var err error
+				next, file, err = r.roll(ctx, current, file)
+				if err != nil {
+					r.logf("not restarted, the old process is still running: %v\n", err)
+					continue
+				}
+				current = next
+			}
+		}
+	}
+}
+
+// roll starts a replacement for the current process, and returns it once it has taken over, and any file that changed
+// while it was starting, so it may need replacing too.
+func (r rollingRestart) roll(ctx context.Context, current *instance, file string) (*instance, string, error) {
+	r.logf("%s changed, starting a replacement\n", file)
+	if err := r.onChange(ctx); err != nil {
+		return nil, "", fmt.Errorf("onChange %w", err)
+	}
+	port, err := allocatePort()
+	if err != nil {
+		return nil, "", err
+	}
+	t := r.task.OnPort(port)
+	next := r.start(ctx, r.newProc(t), t)
+	ready := make(chan error, 1)
+	probeCtx, stopProbe := context.WithCancel(ctx)
+	defer stopProbe()
+	go probeLoop(probeCtx, "readiness probe", *t.GetReadinessProbe(), r.logf, func(ok bool, err error) {
+		select {
+		case ready <- err:
+		default:
+		}
+	})
+	changed := ""
+	for {
+		select {
+		case <-ctx.Done():
+			next.stop()
+			return nil, "", ctx.Err()
+		case changed = <-r.rolls:
+		case err := <-next.done:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return nil, changed, fmt.Errorf("replacement failed: %w", err)
+		case err := <-ready:
+			if err != nil {
+				next.stop()
+				return nil, changed, fmt.Errorf("replacement is not ready: %w", err)
+			}
+			r.router.Route(port)
+			r.logf("replacement ready on port %d, stopping the old process\n", port)
+			current.stop()
+			r.restarted(port)
+			return next, changed, nil
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/proxy"
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// server is a process that serves its generation on its port, or fails to start if broken.
+type server struct {
+	port       uint16
+	generation int
+	broken     bool
+}
+
+func (s server) Run(ctx context.Context, _, _ io.Writer) error {
+	if s.broken {
+		return fmt.Errorf("exit status 1")
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", s.port))
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, s.generation)
+	})}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	_ = srv.Serve(listener)
+	return nil
+}
+
+func Test_rollingRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	port, err := allocatePort()
+	assert.NoError(t, err)
+	router, err := proxy.ListenRouter(port)
+	assert.NoError(t, err)
+	go func() { _ = router.Serve(ctx) }()
+
+	first, err := allocatePort()
+	assert.NoError(t, err)
+	task := types.Task{
+		Command:        types.Strings{"api"},
+		Ports:          []types.Port{{ContainerPort: port}},
+		ReadinessProbe: &types.Probe{TCPSocket: &types.TCPSocketAction{Port: port}, PeriodSeconds: 1},
+		RollingRestart: true,
+	}.OnPort(first)
+	assert.Equal(t, fmt.Sprint(first), task.Env["PORT"].Value)
+	assert.Equal(t, first, task.ReadinessProbe.TCPSocket.Port)
+
+	mu := sync.Mutex{}
+	generation, broken, restarts := 0, false, 0
+	rolls := make(chan string)
+	logs := &strings.Builder{}
+	r := rollingRestart{
+		router: router,
+		task:   task,
+		newProc: func(t types.Task) proc.Interface {
+			mu.Lock()
+			defer mu.Unlock()
+			generation++
+			return server{port: t.Ports[0].ContainerPort, generation: generation, broken: broken}
+		},
+		rolls:    rolls,
+		onChange: func(ctx context.Context) error { return nil },
+		restarted: func(port uint16) {
+			mu.Lock()
+			defer mu.Unlock()
+			restarts++
+		},
+		logf: func(format string, args ...any) {
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = fmt.Fprintf(logs, format, args...)
+		},
+	}
+	done := make(chan error, 1)
+	go func() { done <- r.run(ctx, server{port: first}) }()
+
+	get := func() string {
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%d", port))
+		if err != nil {
+			return err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	assert.Eventually(t, func() bool { return get() == "0" }, 10*time.Second, 10*time.Millisecond)
+
+	// requests are served throughout the restart
+	rolls <- "main.go"
+	assert.Eventually(t, func() bool {
+		body := get()
+		assert.Contains(t, []string{"0", "1"}, body)
+		return body == "1"
+	}, 10*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 1, restarts)
+	broken = true
+	mu.Unlock()
+
+	// a broken replacement leaves the old process serving
+	rolls <- "main.go"
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Contains(logs.String(), "not restarted, the old process is still running: replacement failed: exit status 1")
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "1", get())
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -6,14 +6,23 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/proxy"
+	"github.com/kitproj/kit/internal/ring"
 	"github.com/kitproj/kit/internal/types"
 	"github.com/kitproj/kit/internal/util"
 	"github.com/pkg/browser"
@@ -22,65 +31,132 @@ import (
 
 var poisonPill = struct{}{}
 
-func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openBrowser bool, logger *log.Logger, wf *types.Workflow, taskNames []string, tasksToSkip []string) error {
+// stopTask is the event to stop the named task.
+type stopTask string
 
-	// check that the task names are valid
-	for _, name := range taskNames {
-		if _, ok := wf.Tasks[name]; !ok {
-			return fmt.Errorf("task %q not found in workflow", name)
-		}
+func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openBrowser bool, logger *log.Logger, wf *types.Workflow, taskNames []string, tasksToSkip []string, opts ...Option) error {
+
+	options := &options{}
+	if isCI() {
+		options.summaryReport = os.Getenv("GITHUB_STEP_SUMMARY")
+		options.timings = true
+	}
+	options.timestamps = wf.Timestamps
+	options.concurrency = wf.MaxParallel
+	for _, opt := range opts {
+		opt(options)
 	}
+	startedAt := time.Now()
 
-	// check skipped tasks are valid
-	for _, name := range tasksToSkip {
-		if _, ok := wf.Tasks[name]; !ok {
-			return fmt.Errorf("skipped task %q not found in workflow", name)
-		}
+	// check the output format is valid
+	switch options.output {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid output %q, must be json or yaml", options.output)
+	}
+
+	// in GitHub Actions, failed and stalled tasks are annotated, unless another reporter is chosen
+	if options.reporter == "" && githubActions() {
+		options.reporter = "github"
+	}
+	selected, replacesLogs, err := newReporter(options.reporter, logger.Writer())
+	if err != nil {
+		return err
+	}
+	if replacesLogs && options.output != "" {
+		return fmt.Errorf("cannot use the %s reporter and output %s together", options.reporter, options.output)
+	}
+
+	// check the timestamps mode is valid
+	switch options.timestamps {
+	case "", "rfc3339", "elapsed":
+	default:
+		return fmt.Errorf("invalid timestamps %q, must be rfc3339 or elapsed", options.timestamps)
+	}
+
+	// check the remote cache is valid
+	cache, err := newRemoteCache(wf.Cache)
+	if err != nil {
+		return fmt.Errorf("invalid cache: %w", err)
 	}
+	pushToCache := cache != nil && wf.Cache.GetPush(isCI())
 
 	// name is last part of pwd
 	pwd := os.Getenv("PWD")
 	name := filepath.Base(pwd)
 
-	dag := NewDAG[bool](name)
-	for name, t := range wf.Tasks {
-		dag.AddNode(name, true)
-		for _, dependency := range t.Dependencies {
-			dag.AddEdge(dependency, name)
+	p, err := newPlan(name, wf, taskNames, tasksToSkip)
+	if err != nil {
+		return err
+	}
+	// the tasks as requested are kept, so that the groups can be expanded again when the workflow is reloaded
+	requested, skipped := taskNames, tasksToSkip
+
+	// on Linux, only root can listen on a privileged port, so the task would fail confusingly
+	if runtime.GOOS == "linux" && os.Geteuid() != 0 {
+		for _, u := range p.ports.privileged(wf) {
+			logger.Printf("[%s] port %d is privileged, so listening on it needs root\n", u.task, u.port)
 		}
 	}
-	visited := dag.Subgraph(taskNames)
 
-	taskByName := wf.Tasks
-	subgraph := NewDAG[*TaskNode](name)
-	for name := range visited {
-		task := taskByName[name]
+	exts, err := startExtensions(logger, wf.Extensions)
+	defer exts.stop()
+	if err != nil {
+		return err
+	}
+	taskNames, tasksToSkip = p.taskNames, p.tasksToSkip
 
-		logFile := filepath.Join("logs", fmt.Sprintf("%s.log", name))
+	palette, err := newPalette(wf.Tasks)
+	if err != nil {
+		return err
+	}
+	palette.disabled = palette.disabled || options.plain
+
+	newNode := func(wf *types.Workflow, name string) *TaskNode {
+		task := wf.Tasks[name]
+		if len(task.Shell) == 0 {
+			task.Shell = wf.Shell
+		}
+
+		logFile := filepath.Join(options.logsDir(), fmt.Sprintf("%s.log", name))
 		if task.Log != "" {
 			logFile = task.Log
 		}
 
-		subgraph.AddNode(name, &TaskNode{
-			Name:    name,
-			logFile: logFile,
-			Task:    task,
-			Phase:   "pending",
-			cancel:  func() {},
-			mu:      &sync.Mutex{}})
-		for _, parent := range dag.Parents[name] {
+		return &TaskNode{
+			Name:          name,
+			logFile:       logFile,
+			stderrLogFile: filepath.Join(options.logsDir(), fmt.Sprintf("%s.stderr.log", name)),
+			recent:        ring.New((*types.Spec)(wf).GetLogBufferLines()),
+			Task:          task,
+			PhaseMachine:  types.PhaseMachine{Phase: types.PhasePending, Since: map[types.Phase]time.Time{types.PhasePending: time.Now()}},
+			outputs:       newOutputCapture(task.Outputs),
+			syncs:         make(chan []string),
+			rolls:         make(chan string),
+			cancel:        func() {},
+			mu:            &sync.Mutex{}}
+	}
+
+	subgraph := NewDAG[*TaskNode](name)
+	for name := range p.visited {
+		subgraph.AddNode(name, newNode(wf, name))
+		for _, parent := range p.dag.Parents[name] {
 			subgraph.AddEdge(parent, name)
 		}
 	}
 
-	events := make(chan any, len(subgraph.Nodes)*2)
+	sched := newScheduler()
 
-	// schedule the tasks in the subgraph that are ready to run , this is done by sending the task name to the events channel of any task that does not have any parents
+	// schedule the tasks in the subgraph that are ready to run, i.e. any task that does not have any parents
+	var roots []string
 	for taskName := range subgraph.Nodes {
 		if len(subgraph.Parents[taskName]) == 0 {
-			events <- taskName
+			roots = append(roots, taskName)
 		}
 	}
+	for _, taskName := range byPriority(subgraph.Nodes, roots) {
+		sched.run(taskName)
+	}
 
 	if len(subgraph.Nodes) == 0 {
 		logger.Println("no tasks to run")
@@ -88,74 +164,606 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 	}
 
 	// create logs directory
-	if err := os.MkdirAll("logs", 0755); err != nil && !errors.Is(err, os.ErrExist) {
+	if err := os.MkdirAll(options.logsDir(), 0755); err != nil && !errors.Is(err, os.ErrExist) {
 		return fmt.Errorf("failed to create logs directory: %w", err)
 	}
 
-	// start a file watcher for each task
-	for _, node := range subgraph.Nodes {
+	// only one instance of the workflow can run in a workspace
+	unlock, err := lockInstance(options.stateDir(), subgraph.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// record every lifecycle event, so we can find out why a task restarted
+	eventLog, err := openEventLog(filepath.Join(options.stateDir(), "events.jsonl"))
+	if err != nil {
+		return err
+	}
+	defer eventLog.Close()
+
+	// record every line the tasks write, so the session can be replayed
+	sessionLog, err := openSessionLog(filepath.Join(options.stateDir(), "sessions"), startedAt)
+	if err != nil {
+		return err
+	}
+	defer sessionLog.Close()
+
+	// up-to-date jobs resume from the last run of kit, rather than being run again
+	states := loadTaskStates(filepath.Join(options.stateDir(), "state.json"))
+	eventLog.record(lifecycleEvent{Event: "start", Message: strings.Join(taskNames, ",")})
+
+	// stop anything the last run of kit left running, because it was killed, before it gets in the way, e.g. by using a
+	// task's ports
+	leftovers := loadLeftovers(filepath.Join(options.stateDir(), "leftovers.json"))
+	if err := leftovers.stop(ctx, logger, types.Spec(*wf), func(question string) bool {
+		return confirm(logger, question)
+	}); err != nil {
+		logger.Println(err)
+	}
+
+	// watching can be paused using the keyboard, e.g. while switching branches
+	watchingPaused := &atomic.Bool{}
+
+	// what is being watched, and what is backing off, is dumped with the status of the tasks on SIGUSR1 or "kit debug"
+	diag := newDiagnostics()
+
+	// start a file watcher for each task, these are closed when the task is removed
+	watchers := map[string]*fsnotify.Watcher{}
+	defer func() {
+		for _, watcher := range watchers {
+			_ = watcher.Close()
+		}
+	}()
+	watch := func(node *TaskNode) error {
 
 		// start watching files for changes
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
 			return fmt.Errorf("failed to create watcher: %w", err)
 		}
-		for _, source := range node.Task.Watch {
-			if err := watcher.Add(filepath.Join(node.Task.WorkingDir, source)); err != nil {
+		watchers[node.Name] = watcher
+		sources := append([]string{}, node.Task.Watch...)
+		for _, s := range node.Task.Sync {
+			sources = append(sources, s.Src)
+		}
+		var watched []string
+		for _, source := range sources {
+			path := filepath.Join(node.Task.WorkingDir, source)
+			if err := watcher.Add(path); err != nil {
 				return fmt.Errorf("failed to watch %q: %w", source, err)
 			}
+			watched = append(watched, path)
+		}
+		diag.watching(node.Name, watched)
+
+		// only changes to the files the task's packages are built from restart it
+		var inputs *packageInputs
+		if node.Task.WatchPackages != nil {
+			inputs = newPackageInputs(node.Task.WorkingDir, *node.Task.WatchPackages)
 		}
-		defer watcher.Close()
 
 		go func() {
 			debounceTimer := time.AfterFunc(0, func() {})
 			defer debounceTimer.Stop()
+			// the files to sync are batched, so a burst of changes is copied at once
+			var syncTimer <-chan time.Time
+			changed := map[string]bool{}
 			for {
 				select {
 				case <-ctx.Done():
 					return
-				case event := <-watcher.Events:
+				case <-syncTimer:
+					syncTimer = nil
+					var files []string
+					for file := range changed {
+						files = append(files, file)
+					}
+					clear(changed)
+					sort.Strings(files)
+					select {
+					case node.syncs <- files:
+					default:
+						// nothing is running to copy the files into, so it must be started
+						logger.Printf("[%s] %s changed, re-running\n", node.Name, strings.Join(files, ", "))
+						eventLog.record(lifecycleEvent{Task: node.Name, Event: "watch", Message: fmt.Sprintf("%s changed", strings.Join(files, ", "))})
+						node.changed.Store(true)
+						sched.run(node.Name)
+					}
+				case event, ok := <-watcher.Events:
+					if !ok {
+						return
+					}
+					if watchingPaused.Load() {
+						continue
+					}
+					if _, synced := node.Task.GetSyncTarget(event.Name); synced {
+						if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+							changed[event.Name] = true
+							syncTimer = time.After(node.Task.GetWatchDebounce())
+						}
+						continue
+					}
 					if event.Op&fsnotify.Write == fsnotify.Write {
+						if inputs != nil {
+							ok, err := inputs.contains(ctx, event.Name)
+							if err != nil {
+								// if we cannot tell, it is safer to re-run the task
+								logger.Printf("[%s] %v\n", node.Name, err)
+							} else if !ok {
+								continue
+							}
+						}
 						debounceTimer.Stop()
-						debounceTimer = time.AfterFunc(100*time.Millisecond, func() {
+						debounceTimer = time.AfterFunc(node.Task.GetWatchDebounce(), func() {
+							// a service restarted without downtime keeps running until its replacement is ready
+							if node.Task.RollingRestart {
+								select {
+								case node.rolls <- event.Name:
+									return
+								default:
+								}
+							}
 							logger.Printf("[%s] %s changed, re-running\n", node.Name, event.Name)
-							events <- node.Name
+							eventLog.record(lifecycleEvent{Task: node.Name, Event: "watch", Message: fmt.Sprintf("%s changed", event.Name)})
+							node.changed.Store(true)
+							sched.run(node.Name)
 						})
 					}
 				}
 			}
 		}()
+		return nil
+	}
+	for _, node := range subgraph.Nodes {
+		if err := watch(node); err != nil {
+			return err
+		}
+	}
+
+	// reload the workflow when its config files change
+	if options.load != nil {
+		if err := watchConfig(ctx, logger, options.configFiles, options.load, sched); err != nil {
+			return err
+		}
+	}
+
+	// start a HTTPS proxy in front of each task that needs TLS, these run for the whole session, so that they survive restarts
+	var ca *proxy.CA
+	for _, node := range subgraph.Nodes {
+		t := node.Task
+		if t.TLS == nil {
+			continue
+		}
+		if len(t.Ports) == 0 {
+			return fmt.Errorf("task %q has tls, but no ports", node.Name)
+		}
+		if ca == nil {
+			dir, err := proxy.DefaultCADir()
+			if err != nil {
+				return fmt.Errorf("failed to get CA directory: %w", err)
+			}
+			ca, err = proxy.LoadCA(dir)
+			if err != nil {
+				return err
+			}
+			logger.Printf("certificates are signed by %s, trust it to avoid browser warnings\n", filepath.Join(dir, "rootCA.pem"))
+		}
+		cert, err := ca.Issue(t.TLS.GetHosts())
+		if err != nil {
+			return fmt.Errorf("failed to issue certificate for %q: %w", node.Name, err)
+		}
+		go func(name string, port, target uint16) {
+			logger.Printf("[%s] serving https://localhost:%d\n", name, port)
+			if err := proxy.ServeHTTPS(ctx, port, target, cert); err != nil {
+				logger.Printf("[%s] https proxy failed: %v\n", name, err)
+			}
+		}(node.Name, t.TLS.Port, t.Ports[0].GetHostPort())
+	}
+
+	// services restarted without downtime are behind a router on their port for the whole session, so it can be switched
+	// to their replacement, as are services with faults, so the router can inject them
+	routers := map[string]*proxy.Router{}
+	for _, node := range subgraph.Nodes {
+		if !node.Task.RollingRestart && node.Task.Faults == nil || slices.Contains(tasksToSkip, node.Name) {
+			continue
+		}
+		router, err := proxy.ListenRouter(node.Task.Ports[0].GetHostPort())
+		if err != nil {
+			return fmt.Errorf("failed to listen on the port of %q: %w", node.Name, err)
+		}
+		if f := node.Task.Faults; f != nil && !f.Disabled {
+			router.SetFaults(proxyFaults(*f))
+		}
+		routers[node.Name] = router
+		go func(name string) {
+			if err := router.Serve(ctx); err != nil {
+				logger.Printf("[%s] router failed: %v\n", name, err)
+			}
+		}(node.Name)
+	}
+
+	// the hosts file has an entry for each task, so is updated when tasks are added or removed
+	updateHostsFile := func() error {
+		d := wf.DNS
+		var hostnames []string
+		for name := range subgraph.Nodes {
+			hostnames = append(hostnames, d.Hostname(name))
+		}
+		sort.Strings(hostnames)
+		return updateHosts(d.HostsFile, d.GetDomain(), options.namespace, hostnames)
+	}
+
+	// give each task a name, e.g. "api.kit.local", that resolves to 127.0.0.1
+	if d := wf.DNS; d != nil {
+		if d.Port > 0 {
+			conn, err := net.ListenPacket("udp", fmt.Sprintf("127.0.0.1:%d", d.Port))
+			if err != nil {
+				return fmt.Errorf("failed to listen for DNS queries: %w", err)
+			}
+			resolver := dnsResolver{domain: d.GetDomain(), exists: func(hostname string) bool {
+				subgraph.RLock()
+				defer subgraph.RUnlock()
+				for name := range subgraph.Nodes {
+					if d.Hostname(name) == hostname {
+						return true
+					}
+				}
+				return false
+			}}
+			go func() {
+				if err := resolver.serve(ctx, conn); err != nil {
+					logger.Printf("dns resolver failed: %v\n", err)
+				}
+			}()
+			logger.Printf("resolving the tasks' names in %s on 127.0.0.1:%d\n", d.GetDomain(), d.Port)
+		}
+		if d.HostsFile != "" {
+			if err := updateHostsFile(); err != nil {
+				return err
+			}
+			defer func() {
+				if err := updateHosts(d.HostsFile, d.GetDomain(), options.namespace, nil); err != nil {
+					logger.Println(err)
+				}
+			}()
+		}
+	}
+
+	// write the status of the tasks, rather than the logs
+	if options.output != "" {
+		statusCtx, stopStatus := context.WithCancel(context.Background())
+		statusDone := make(chan struct{})
+		go func(w io.Writer) {
+			defer close(statusDone)
+			emitStatus(statusCtx, w, subgraph, options.output)
+		}(logger.Writer())
+		defer func() {
+			stopStatus()
+			<-statusDone
+		}()
+		logger = log.New(io.Discard, "", 0)
+	}
+
+	// the reporter writes to the console, in place of the logs
+	if replacesLogs {
+		logger = log.New(io.Discard, "", 0)
+	}
+	// the logs are shown in the console, rather than the status of the tasks or the reporter's output
+	console := options.output == "" && !replacesLogs
+
+	// a kit tab in the background shows if a task failed
+	if options.terminalTitle && console && !options.plain {
+		titleCtx, stopTitle := context.WithCancel(context.Background())
+		titleDone := make(chan struct{})
+		go func(w io.Writer) {
+			defer close(titleDone)
+			emitTerminalStatus(titleCtx, w, subgraph)
+		}(logger.Writer())
+		defer func() {
+			stopTitle()
+			<-titleDone
+		}()
+	}
+
+	// route the traffic for intercepted services to their tasks, these run for the whole session too, and restore the
+	// services before we return
+	var intercepts sync.WaitGroup
+	defer intercepts.Wait()
+	interceptCtx, stopIntercepts := context.WithCancel(ctx)
+	defer stopIntercepts()
+	for _, node := range subgraph.Nodes {
+		if node.Task.Intercept == nil || slices.Contains(tasksToSkip, node.Name) {
+			continue
+		}
+		intercepts.Add(1)
+		go func(name string, t types.Task) {
+			defer intercepts.Done()
+			prefix := fmt.Sprintf("[%s] ", name)
+			if err := proc.Intercept(interceptCtx, log.New(logger.Writer(), prefix, logger.Flags()), t); err != nil {
+				logger.Printf("%sintercept failed: %v\n", prefix, err)
+			}
+		}(node.Name, node.Task)
+	}
+
+	// tasks can be controlled using the keyboard, or using "kit stop" and "kit restart", the source is recorded
+	controls := func(source string) taskControls {
+		return taskControls{
+			restart: func(name string) {
+				logger.Printf("[%s] restarting\n", name)
+				eventLog.record(lifecycleEvent{Task: name, Event: source, Message: "restart"})
+				sched.run(name)
+			},
+			stop: func(name string) {
+				logger.Printf("[%s] stopping\n", name)
+				eventLog.record(lifecycleEvent{Task: name, Event: source, Message: "stop"})
+				sched.send(stopTask(name))
+			},
+			pauseWatching: func() bool {
+				paused := !watchingPaused.Load()
+				watchingPaused.Store(paused)
+				return paused
+			},
+			faults: func(name string, on bool) error {
+				subgraph.RLock()
+				f := subgraph.Nodes[name].Task.Faults
+				subgraph.RUnlock()
+				router := routers[name]
+				if f == nil || router == nil {
+					return fmt.Errorf("task %q has no faults", name)
+				}
+				message := "faults off"
+				if on {
+					message = "faults on"
+					router.SetFaults(proxyFaults(*f))
+				} else {
+					router.SetFaults(nil)
+				}
+				logger.Printf("[%s] %s\n", name, message)
+				eventLog.record(lifecycleEvent{Task: name, Event: source, Message: message})
+				return nil
+			},
+			quit: cancel,
+			dump: func() (string, error) {
+				return diag.dump(options.stateDir(), subgraph)
+			},
+		}
+	}
+
+	// dump the diagnostics on a signal, so a hang can be reported without stopping kit
+	if len(debugSignals) > 0 {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, debugSignals...)
+		defer signal.Stop(signals)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-signals:
+					path, err := diag.dump(options.stateDir(), subgraph)
+					if err != nil {
+						logger.Println(err)
+						continue
+					}
+					logger.Printf("wrote debug dump to %s\n", path)
+				}
+			}
+		}()
+	}
+
+	// filter the output in the console using the keyboard
+	filter := &logFilter{}
+	if console {
+		if restore, ok := cbreak(); ok {
+			defer restore()
+			logger.Println("press / to search the logs, t to filter tasks, c to clear, r to restart a task, s to stop one, l to open its log, p to pause watching, q to quit")
+			go readKeys(logger, filter, palette, subgraph, controls("keyboard"))
+		}
+	}
+
+	if err := serveControl(ctx, logger, controlSocket(options.stateDir()), subgraph, controls("control")); err != nil {
+		return err
 	}
 
 	semaphores := util.NewSemaphores(wf.Semaphores)
+	// mutexes and semaphores are shared with the other kit processes on this machine using lock files
+	lockFiles := func(ctx context.Context, key string, n int) (func(), error) {
+		dir, err := util.DefaultLocksDir()
+		if err != nil {
+			return nil, err
+		}
+		return util.LockFiles(ctx, dir, key, n)
+	}
+	// limits the number of jobs running at the same time
+	var parallel *util.PrioritySemaphore
+	if options.concurrency > 0 {
+		parallel = util.NewPrioritySemaphore(options.concurrency)
+	}
+
+	tracer := newTracer(subgraph.Name)
 
 	wg := &sync.WaitGroup{}
 
 	statusEvents := make(chan *TaskNode, 100)
+	// each task's transitions are sent to the UI, and the selected reporter
+	report := reporters{uiReporter{events: statusEvents}}
+	if selected != nil {
+		report = append(report, selected)
+	}
+
+	// trigger re-runs a task, because its webhook was called
+	trigger := func(name string) {
+		logger.Printf("[%s] webhook called, re-running\n", name)
+		eventLog.record(lifecycleEvent{Task: name, Event: "webhook"})
+		sched.run(name)
+	}
 
 	if port > 0 {
-		go StartServer(ctx, port, wg, subgraph, statusEvents)
+		go StartServer(ctx, logger, port, wg, subgraph, statusEvents, trigger)
 		if openBrowser {
 			if err := browser.OpenURL(fmt.Sprintf("http://localhost:%d", port)); err != nil {
 				return fmt.Errorf("failed to open browser: %v", err)
 			}
 		}
+	} else {
+		// there is no UI to send status events to, so we discard them
+		go func() {
+			for range statusEvents {
+			}
+		}()
 	}
 
-	stallTimers := map[string]*time.Timer{}
-	for name, taskNode := range subgraph.Nodes {
-		stalledTime := taskNode.Task.GetStalledTimeout()
-		stallTimers[name] = time.AfterFunc(stalledTime, func() {
-			if taskNode.Phase == "starting" || taskNode.Phase == "running" {
+	// the stall timer is created once for each node, before the node is shared, and is then only reset or stopped
+	startStallTimer := func(node *TaskNode) {
+		stalledTime := node.Task.GetStalledTimeout()
+		node.stallTimer = time.AfterFunc(stalledTime, func() {
+			if phase := node.Snapshot().Phase; phase == "starting" || phase == "running" {
 				// we suffix the message with "starting" so we can differentiate between a task that is starting and one that is running, later on we can change the message to "output received"
 				// and restore the phase to "running" or "starting"
-				taskNode.Message = fmt.Sprintf("no output for %s or more while %s", stalledTime, taskNode.Phase)
-				taskNode.Phase = "stalled"
-				logger.Printf("[%s] %s\n", taskNode.Name, taskNode.Message)
-				statusEvents <- taskNode
+				message := fmt.Sprintf("no output for %s or more while %s", stalledTime, phase)
+				if err := node.Transition(types.PhaseStalled, message); err == nil {
+					logger.Printf("[%s] %s\n", node.Name, message)
+				}
+			}
+		})
+	}
+	// there's no one to look at the browser in CI
+	if options.openURL == nil && !isCI() {
+		options.openURL = browser.OpenURL
+	}
+	open := &opener{logger: logger, wf: wf, dag: subgraph, open: options.openURL}
+	// each change of a task's phase is recorded, traced, and sent to the extensions and the reporters
+	observe := func(node *TaskNode) {
+		node.OnTransition(func(t types.Transition) {
+			eventLog.record(lifecycleEvent{Task: node.Name, Event: "transition", From: t.From, Phase: t.To, Message: t.Message})
+			if !node.blocked() {
+				if node.readyAt.IsZero() {
+					node.readyAt = t.Time
+				}
+				open.ready(node)
 			}
+			subgraph.RLock()
+			parents := subgraph.Parents[node.Name]
+			subgraph.RUnlock()
+			tracer.taskStatus(node, parents)
+			exts.statusChange(node)
+			report.transition(node, t)
 		})
 	}
+	for _, node := range subgraph.Nodes {
+		observe(node)
+		startStallTimer(node)
+	}
+
+	// reload applies the changes to the workflow: the tasks that were added are started, the tasks that were removed are
+	// stopped, and the tasks that changed are restarted, the other tasks keep running
+	reload := func(reloaded *types.Workflow) error {
+		p, err := newPlan(subgraph.Name, reloaded, requested, skipped)
+		if err != nil {
+			return err
+		}
+		// the https proxies, routers, intercepts and DNS resolver are set up when kit starts, so cannot be changed
+		if !reflect.DeepEqual(wf.DNS, reloaded.DNS) {
+			return errors.New("dns changed, restart kit to apply")
+		}
+		subgraph.RLock()
+		var names []string
+		for name := range p.visited {
+			names = append(names, name)
+		}
+		for name := range subgraph.Nodes {
+			if !p.visited[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var changed []string
+		for _, name := range names {
+			before, after := types.Task{}, types.Task{}
+			if _, ok := subgraph.Nodes[name]; ok {
+				before = wf.Tasks[name]
+			}
+			if p.visited[name] {
+				after = reloaded.Tasks[name]
+			}
+			if !reflect.DeepEqual(sessionSettings(before), sessionSettings(after)) {
+				changed = append(changed, name)
+			}
+		}
+		subgraph.RUnlock()
+		if len(changed) > 0 {
+			return fmt.Errorf("the tls, rollingRestart, faults or intercept of %s changed, restart kit to apply", strings.Join(changed, ", "))
+		}
+
+		// a change to the workflow's settings, rather than its tasks, restarts every task
+		before, after := *wf, *reloaded
+		before.Tasks, after.Tasks = nil, nil
+		settingsChanged := !reflect.DeepEqual(before, after)
+
+		stop := func(node *TaskNode) {
+			node.cancel()
+			node.stallTimer.Stop()
+			_ = watchers[node.Name].Close()
+			delete(watchers, node.Name)
+			diag.watching(node.Name, nil)
+		}
+
+		var queue []string
+		subgraph.Lock()
+		for name, node := range subgraph.Nodes {
+			if !p.visited[name] {
+				logger.Printf("[%s] removed, stopping\n", name)
+				eventLog.record(lifecycleEvent{Task: name, Event: "reload", Message: "removed"})
+				stop(node)
+				delete(subgraph.Nodes, name)
+			}
+		}
+		for name := range p.visited {
+			node := newNode(reloaded, name)
+			if old, ok := subgraph.Nodes[name]; ok {
+				if !settingsChanged && reflect.DeepEqual(old.Task, node.Task) {
+					continue
+				}
+				logger.Printf("[%s] changed, restarting\n", name)
+				eventLog.record(lifecycleEvent{Task: name, Event: "reload", Message: "changed"})
+				stop(old)
+				// the new node replaces the old one, but it cannot start until the old one has stopped
+				node.mu = old.mu
+				node.failing.Store(old.failing.Load())
+				node.Restore(old.Snapshot())
+			} else {
+				logger.Printf("[%s] added, starting\n", name)
+				eventLog.record(lifecycleEvent{Task: name, Event: "reload", Message: "added"})
+			}
+			observe(node)
+			startStallTimer(node)
+			subgraph.Nodes[name] = node
+			if err := watch(node); err != nil {
+				logger.Printf("[%s] %v\n", name, err)
+			}
+			queue = append(queue, name)
+		}
+		clear(subgraph.Children)
+		clear(subgraph.Parents)
+		for name := range p.visited {
+			for _, parent := range p.dag.Parents[name] {
+				subgraph.AddEdge(parent, name)
+			}
+		}
+		if d := reloaded.DNS; d != nil && d.HostsFile != "" {
+			if err := updateHostsFile(); err != nil {
+				logger.Println(err)
+			}
+		}
+		subgraph.Unlock()
+
+		wf, taskNames, tasksToSkip = reloaded, p.taskNames, p.tasksToSkip
+		for _, name := range byPriority(subgraph.Nodes, queue) {
+			sched.run(name)
+		}
+		return nil
+	}
 
 	for {
 		select {
@@ -165,266 +773,755 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 
 			wg.Wait()
 
-			// if any task failed, we will return an error
-			var failures []string
+			tracer.shutdown()
+
+			if err := writeReports(options, subgraph, time.Since(startedAt)); err != nil {
+				return err
+			}
+
+			if options.record != "" {
+				r := recording{stateDir: options.stateDir(), sessionFile: sessionLog.path(), startedAt: startedAt}
+				if err := r.write(options.record, wf, subgraph); err != nil {
+					return err
+				}
+				logger.Printf("recorded the session to %s\n", options.record)
+			}
+
+			if options.timings {
+				logger.Println("timings:")
+				if err := writeTimings(logger.Writer(), subgraph, startedAt); err != nil {
+					return err
+				}
+			}
+
+			width := 0
+			if options.width != nil {
+				width = options.width()
+			}
 			for _, node := range subgraph.Nodes {
 
+				s := node.Snapshot()
 				color := 30
 				faint := 0
-				switch node.Phase {
+				switch s.Phase {
 				case "failed":
 					// red
 					color = 31
 					faint = 1
-					failures = append(failures, node.Name)
 				case "pending", "waiting":
 					faint = 2
 				}
 
-				logger.Printf("\033[%d;%dm[%s] (%s) %s\033[0m\n", faint, color, node.Name, node.Phase, node.Message)
+				logger.Println(fit(fmt.Sprintf("%s[%s] (%s) %s%s", palette.sgr(faint, color), node.Name, s.Phase, s.Message, palette.reset()), width))
 			}
 
-			if len(failures) > 0 {
-				return fmt.Errorf("failed tasks: %v", failures)
-			}
-
-			return nil
-		case event := <-events:
-			switch x := event.(type) {
-			// if we get the poison pill, we should see if any job tasks are failed, if so we must exist
-			// if all jobs are either succeeded or skipped, we can exit
-			case struct{}:
-				// if all requests tasks are succeeded, we can exit
-				{
-					pendingTasks := map[string]bool{}
-					for _, x := range taskNames {
-						pendingTasks[x] = true
+			// if any task failed, we will return an error
+			return failedTasks(subgraph)
+		case <-sched.C():
+			for _, event := range sched.take() {
+				switch x := event.(type) {
+				// if we get the poison pill, a task finished, so we check if the requested tasks have completed, or if a task
+				// failed that should not be restarted
+				case struct{}:
+					if reason := options.exitReason(subgraph, taskNames); reason != "" {
+						logger.Println(reason)
+						cancel()
 					}
 
-					for _, node := range subgraph.Nodes {
-						if (node.Phase == "succeeded" || node.Phase == "skipped") && node.Task.GetRestartPolicy() != "Always" {
-							delete(pendingTasks, node.Name)
-						}
-					}
+				// if the event is a string, it is the name of the task to run
+				case string:
+					taskName := x
 
-					if len(pendingTasks) == 0 {
-						logger.Println("exiting because all requested tasks completed and none should be restarted")
-						cancel()
+					// the task may have been removed from the workflow since it was queued
+					if _, ok := subgraph.Nodes[taskName]; !ok {
+						continue
 					}
-				}
 
-				// if a task that should not be restarted failed, we must exit
-				for _, node := range subgraph.Nodes {
-					if node.Phase == "failed" && node.Task.GetRestartPolicy() == "Never" {
-						logger.Printf("exiting because task  %q should not be restarted, and it failed", node.Name)
-						cancel()
+					// we will only execute this task, if its parents are "succeeded" or "skipped" or ("running" and the task is a service),
+					// otherwise it is queued again when they are
+					if reason := blockedBy(subgraph, taskName); reason != "" {
+						logger.Println(reason)
+						continue
 					}
-				}
 
-			// if the event is a string, it is the name of the task to run
-			case string:
-				taskName := x
-
-				// we will only execute this task, if its parents are "succeeded" or "skipped" or ("running" and the task is a service)
-				blocked := false
-				for _, parentName := range subgraph.Parents[taskName] {
-					parent := subgraph.Nodes[parentName]
-					if parent.blocked() {
-						logger.Printf("task %q is blocked by %q (%s): %s\n", taskName, parentName, parent.Phase, parent.Message)
-						blocked = true
-					}
-				}
+					// we might already be pending, waiting, starting or running this task, so we don't want to start it again
+					node := subgraph.Nodes[taskName]
+					skip := slices.Contains(tasksToSkip, taskName)
 
-				if blocked {
-					continue
-				}
+					node.cancel()
 
-				// we might already be pending, waiting, starting or running this task, so we don't want to start it again
-				node := subgraph.Nodes[taskName]
+					if !node.queueRun(wf) {
+						continue
+					}
 
-				node.cancel()
+					// each task is executed in a separate goroutine
+					wg.Add(1)
 
-				// each task is executed in a separate goroutine
-				wg.Add(1)
+					go func(node *TaskNode) {
 
-				go func(node *TaskNode) {
+						// lock the task, so we do not run two instances of it at the same time
+						node.mu.Lock()
+						wf := node.startRun()
 
-					// lock the task, so we do not run two instances of it at the same time
-					node.mu.Lock()
+						ctx, cancel := context.WithCancel(ctx)
+						defer cancel()
 
-					ctx, cancel := context.WithCancel(ctx)
-					defer cancel()
+						node.cancel = cancel
 
-					node.cancel = cancel
+						// send a poison pill to indicate that we've finish and the main loop must check to see if we need to exit
+						defer sched.send(poisonPill)
+						defer wg.Done()
+						defer node.mu.Unlock()
 
-					// send a poison pill to indicate that we've finish and the main loop must check to see if we need to exit
-					defer func() { events <- poisonPill }()
-					defer wg.Done()
-					defer node.mu.Unlock()
+						t := node.Task
+						node.queuedAt, node.startedAt, node.readyAt = time.Now(), time.Time{}, time.Time{}
+
+						// the outputs of the task's dependencies are set as environment variables, but the task's own take precedence
+						inputs := types.EnvVars{}
+						subgraph.RLock()
+						for _, parent := range subgraph.Parents[node.Name] {
+							for k, v := range subgraph.Nodes[parent].outputs.env() {
+								inputs[k] = v
+							}
+						}
+						subgraph.RUnlock()
+						if len(inputs) > 0 {
+							for k, v := range t.Env {
+								inputs[k] = v
+							}
+							t.Env = inputs
+						}
 
-					t := node.Task
+						// in GitHub Actions, a job's output is written as a group when it exits, so it can be collapsed, but a
+						// service's is written as it is printed, as it may not exit until kit does
+						taskLogger := logger
+						if githubActions() && t.GetType() == types.TaskTypeJob {
+							group := &groupBuffer{}
+							taskLogger = log.New(group, "", 0)
+							defer func() { logger.Print(group.flush(node)) }()
+						}
 
-					var out io.Writer = &logWriter{
-						logger: logger,
-						prefixSuffixProvider: func() (string, string) {
-							return fmt.Sprintf("%s[%s] (%s)  ", color(node.Name), node.Name, node.Phase), "\033[0m"
-						},
-					}
+						prefixSuffix := func() (string, string) {
+							status := string(node.Snapshot().Phase)
+							if usage := node.usage.Load(); usage != nil {
+								status += " " + usage.String()
+							}
+							return fmt.Sprintf("%s%s[%s] (%s)  ", palette.color(node.Name), timestamp(options.timestamps, startedAt), node.Name, status), palette.reset()
+						}
+						var out io.Writer = &logWriter{
+							logger:               taskLogger,
+							name:                 node.Name,
+							filter:               filter,
+							plain:                options.plain,
+							prefixSuffixProvider: prefixSuffix,
+						}
+						// stderr is shown in red, as many tools only write real errors to it
+						var errOut io.Writer = &logWriter{
+							logger: taskLogger,
+							name:   node.Name,
+							filter: filter,
+							plain:  options.plain,
+							prefixSuffixProvider: func() (string, string) {
+								prefix, suffix := prefixSuffix()
+								return prefix + palette.sgr(0, 31), suffix
+							},
+						}
 
-					logger := log.New(out, "", 0)
+						logger := log.New(out, "", 0)
 
-					setNodeStatus := func(node *TaskNode, phase string, message string) {
-						node.Phase = phase
-						node.Message = message
-						stallTimers[node.Name].Reset(node.Task.GetStalledTimeout())
-						logger.Println(node.Message)
-						statusEvents <- node
-					}
+						logLevel := t.GetLogLevel(options.quiet)
 
-					setNodeStatus(node, "waiting", "")
+						setNodeStatus := func(node *TaskNode, phase types.Phase, message string) {
+							if err := node.Transition(phase, message); err != nil {
+								logger.Printf("failed to set phase: %v\n", err)
+								return
+							}
+							node.stallTimer.Reset(node.Task.GetStalledTimeout())
+							// in quiet mode, we only want to know about failures
+							if logLevel == "info" || phase == "failed" {
+								logger.Println(message)
+							}
+							for _, event := range taskEvents(node, phase) {
+								if options.desktopNotifications {
+									notifyDesktop(node, event, message)
+								}
+								for _, n := range wf.Notifications {
+									if !n.Wants(event) {
+										continue
+									}
+									data := notificationData{Workflow: subgraph.Name, Task: node.Name, Event: event, Message: message}
+									// notifications are best-effort, and must not block the task
+									go func(n types.Notification) {
+										if err := postNotification(context.Background(), n, data); err != nil {
+											logger.Printf("failed to post notification: %v\n", err)
+										}
+									}(n)
+								}
+							}
+						}
 
-					queueChildren := func() {
-						for _, child := range subgraph.Children[node.Name] {
-							// only queue tasks in the subgraph
-							if _, ok := subgraph.Nodes[child]; ok {
+						setNodeStatus(node, "waiting", "")
+
+						queueChildren := func() {
+							changed := node.artifactsChanged()
+							subgraph.RLock()
+							defer subgraph.RUnlock()
+							for _, child := range byPriority(subgraph.Nodes, subgraph.Children[node.Name]) {
+								// only queue tasks in the subgraph
+								childNode, ok := subgraph.Nodes[child]
+								if !ok {
+									continue
+								}
+								// a task that depends on any of several tasks was already started by the first of them to be ready
+								phase := childNode.Snapshot().Phase
+								if childNode.Task.Dependencies.IsAnyOf(node.Name) && phase != "pending" && anyReady(subgraph.Nodes, childNode.Task.Dependencies.AnyOf, node.Name) {
+									continue
+								}
+								// consumers of our artifacts do not need to re-run if they have not changed
+								if !changed && phase == "succeeded" && childNode.Task.Consumes(node.Name) {
+									logger.Printf("artifacts unchanged, not queuing %q\n", child)
+									continue
+								}
 								logger.Printf("queuing %q\n", child)
-								events <- child
+								sched.run(child)
 							}
 						}
-					}
 
-					// if the task can be skipped, lets exit early
-					if t.Skip() || slices.Contains(tasksToSkip, node.Name) {
-						setNodeStatus(node, "skipped", "")
-						queueChildren()
-						return
-					}
+						// if the task can be skipped, lets exit early
+						if t.Skip() || skip {
+							setNodeStatus(node, "skipped", "")
+							queueChildren()
+							return
+						}
 
-					// if the task needs a mutex, lets wait for it
-					if t.Mutex != "" {
-						mu := util.GetMutex(t.Mutex)
-						setNodeStatus(node, "waiting", "waiting for mutex")
-						mu.Lock()
-						setNodeStatus(node, "waiting", "acquired mutex")
-						defer mu.Unlock()
-					}
+						// if the job succeeded the last time kit ran, and nothing it depends on has changed since, it need not run again
+						if state, ok := states.take(node.Name); ok && state.Phase == "succeeded" && resumable(subgraph, node) {
+							if hash, err := taskHash(t); err == nil && hash == state.Hash {
+								node.restored = true
+								node.restarts.Store(int32(state.Restarts))
+								node.finishedAt = state.FinishedAt
+								setNodeStatus(node, "succeeded", fmt.Sprintf("up to date, succeeded %s", state.FinishedAt.Format(time.Stamp)))
+								queueChildren()
+								return
+							}
+						}
+
+						// if the job has already applied the same files, e.g. loaded the fixtures into the database, it must not apply them again
+						applied := ""
+						if len(t.Once) > 0 {
+							if hash, err := onceHash(t); err != nil {
+								logger.Printf("failed to hash once: %v\n", err)
+							} else if hash == states.applied(node.Name) {
+								node.restored = true
+								setNodeStatus(node, "succeeded", "already applied")
+								queueChildren()
+								return
+							} else {
+								applied = hash
+							}
+						}
+
+						// if the job succeeded with the same inputs on another machine, get its targets from the remote cache, rather than running it
+						pushKey := ""
+						if cache != nil && cacheable(t) {
+							if key, err := cacheKey(t); err != nil {
+								logger.Printf("failed to get cache key: %v\n", err)
+							} else if ok, err := restoreTargets(ctx, cache, key, t); err != nil {
+								logger.Printf("failed to restore targets from cache: %v\n", err)
+							} else if ok {
+								node.restored = true
+								hash, _ := taskHash(t)
+								if err := states.save(node.Name, taskState{Phase: "succeeded", Hash: hash, FinishedAt: time.Now()}); err != nil {
+									logger.Println(err)
+								}
+								setNodeStatus(node, "succeeded", "restored from cache")
+								queueChildren()
+								return
+							} else {
+								pushKey = key
+							}
+						}
+
+						// if the task needs a mutex, lets wait for it
+						if t.Mutex != "" {
+							mu := util.GetMutex(t.Mutex)
+							setNodeStatus(node, "waiting", "waiting for mutex")
+							mu.Lock()
+							defer mu.Unlock()
+							// other kit processes on this machine may share the mutex
+							unlock, err := lockFiles(ctx, "mutex-"+t.Mutex, 1)
+							if err != nil {
+								setNodeStatus(node, "failed", fmt.Sprintf("failed to acquire mutex: %v", err))
+								return
+							}
+							defer unlock()
+							setNodeStatus(node, "waiting", "acquired mutex")
+						}
 
-					// if the task needs a semaphore, lets wait for it
-					if t.Semaphore != "" {
-						sema := semaphores.Get(t.Semaphore)
-						setNodeStatus(node, "waiting", "waiting for semaphore")
-						if err := sema.Acquire(ctx, 1); err != nil {
-							setNodeStatus(node, "failed", fmt.Sprintf("failed to acquire semaphore: %v", err))
+						// if the task needs a semaphore, lets wait for it
+						if t.Semaphore != "" {
+							sema := semaphores.Get(t.Semaphore)
+							setNodeStatus(node, "waiting", "waiting for semaphore")
+							if err := sema.Acquire(ctx, t.Priority); err != nil {
+								setNodeStatus(node, "failed", fmt.Sprintf("failed to acquire semaphore: %v", err))
+								return
+							}
+							defer sema.Release()
+							unlock, err := lockFiles(ctx, "semaphore-"+t.Semaphore, semaphores.Seats(t.Semaphore))
+							if err != nil {
+								setNodeStatus(node, "failed", fmt.Sprintf("failed to acquire semaphore: %v", err))
+								return
+							}
+							defer unlock()
+							setNodeStatus(node, "waiting", "acquired semaphore")
+						}
+
+						// references to other tasks, e.g. "{{ ports.db.host }}", are resolved now, so the task gets what they are using
+						env, err := t.Env.ExpandTaskReferences(func(r types.TaskReference) (string, error) {
+							return resolveTaskReference(wf, subgraph, r)
+						})
+						if err != nil {
+							setNodeStatus(node, "failed", err.Error())
 							return
 						}
-						setNodeStatus(node, "waiting", "acquired semaphore")
-						defer sema.Release(1)
-					}
+						t.Env = env
 
-					p := proc.New(taskName, t, logger, types.Spec(*wf))
+						// the working directory may be made by a dependency, e.g. a clone, so it is checked as late as possible
+						if t.Image == "" {
+							if err := types.CheckWorkingDir(t.WorkingDir); err != nil {
+								setNodeStatus(node, "failed", err.Error())
+								return
+							}
+						}
 
-					if probe := t.GetLivenessProbe(); probe != nil {
-						liveFunc := func(live bool, err error) {
-							if !live {
-								setNodeStatus(node, "failed", fmt.Sprintf("liveness probe failed: %v", err))
-								cancel()
+						// wait for any external dependencies to be available
+						for _, target := range t.WaitFor {
+							setNodeStatus(node, "waiting", fmt.Sprintf("waiting for %s", target))
+							if err := waitFor(ctx, t.WorkingDir, target); err != nil {
+								setNodeStatus(node, "failed", err.Error())
+								return
 							}
 						}
-						go probeLoop(ctx, *probe, liveFunc)
-					}
-					if probe := t.GetReadinessProbe(); probe != nil {
-						readyFunc := func(ready bool, err error) {
-							if ready {
-								setNodeStatus(node, "running", "readiness probe succeeded")
-								queueChildren()
+
+						// a service restarted without downtime is run on a free port, as its port is the router's
+						router := routers[node.Name]
+						if router != nil {
+							port, err := allocatePort()
+							if err != nil {
+								setNodeStatus(node, "failed", err.Error())
+								return
+							}
+							t = t.OnPort(port)
+						}
+
+						// the last run of the task, or a stray process, may still be using its ports
+						if err := waitForPorts(ctx, t.GetHostPorts(), t.GetPortWaitTimeout(), func(message string) {
+							setNodeStatus(node, "waiting", message)
+						}); err != nil {
+							setNodeStatus(node, "failed", err.Error())
+							return
+						}
+
+						// jobs wait for a slot, as late as possible, so they do not hold it while waiting for anything else
+						if parallel != nil && t.GetType() == types.TaskTypeJob {
+							setNodeStatus(node, "waiting", "waiting for a slot")
+							if err := parallel.Acquire(ctx, t.Priority); err != nil {
+								setNodeStatus(node, "failed", fmt.Sprintf("failed to acquire slot: %v", err))
+								return
+							}
+							defer parallel.Release()
+						}
+
+						if err := stageInputs(wf.Tasks, t); err != nil {
+							setNodeStatus(node, "failed", err.Error())
+							return
+						}
+
+						// an extension may stop the task starting, e.g. if it is outside working hours
+						if err := exts.preStart(node.Name); err != nil {
+							setNodeStatus(node, "failed", err.Error())
+							return
+						}
+
+						p := proc.New(taskName, t, logger, types.Spec(*wf))
+
+						if leaver, ok := p.(proc.Leaver); ok {
+							leaver.OnStart(func(leftover proc.Leftover) {
+								if err := leftovers.add(node.Name, leftover); err != nil {
+									logger.Println(err)
+								}
+							})
+							defer func() {
+								if err := leftovers.remove(node.Name); err != nil {
+									logger.Println(err)
+								}
+							}()
+						}
+
+						if sampler, ok := p.(proc.Sampler); ok {
+							go sampleUsage(ctx, sampler, func(usage *proc.Usage) {
+								node.usage.Store(usage)
+								statusEvents <- node
+							})
+						}
+
+						if syncer, ok := p.(proc.Syncer); ok && len(t.Sync) > 0 {
+							go func() {
+								for {
+									select {
+									case <-ctx.Done():
+										return
+									case files := <-node.syncs:
+										logger.Printf("syncing %s\n", strings.Join(files, ", "))
+										if err := syncer.Sync(ctx, files); err != nil {
+											logger.Printf("failed to sync, restarting: %v\n", err)
+											eventLog.record(lifecycleEvent{Task: node.Name, Event: "restart", Message: err.Error()})
+											cancel()
+											sched.run(node.Name)
+										}
+									}
+								}
+							}()
+						}
+
+						// probes are routine, so are not logged in quiet mode
+						probeLogf := func(format string, args ...any) {
+							if logLevel == "info" {
+								logger.Printf(format, args...)
+							}
+						}
+						// a service restarted without downtime probes the liveness of each of its processes
+						if probe := t.GetLivenessProbe(); probe != nil && router == nil {
+							liveFunc := func(live bool, err error) {
+								if !live {
+									setNodeStatus(node, "failed", fmt.Sprintf("liveness probe failed: %v", err))
+									cancel()
+								}
+							}
+							go probeLoop(ctx, "liveness probe", *probe, probeLogf, liveFunc)
+						}
+						var logMatch *logMatcher
+						if probe := t.GetReadinessProbe(); probe != nil {
+							// a service restarted without downtime is only probed until it is first ready, as its replacements are on other ports
+							probeCtx, stopProbe := context.WithCancel(ctx)
+							defer stopProbe()
+							readyFunc := func(ready bool, err error) {
+								if ready {
+									if router != nil {
+										stopProbe()
+									}
+									setNodeStatus(node, "running", "readiness probe succeeded")
+									queueChildren()
+								} else {
+									setNodeStatus(node, "failed", fmt.Sprintf("readiness probe failed: %v", err))
+									cancel()
+								}
+							}
+							// a log match is checked as the task writes its output, rather than polled
+							if probe.LogMatch != "" {
+								logMatch = newLogMatcher(ctx, *probe, readyFunc)
 							} else {
-								setNodeStatus(node, "failed", fmt.Sprintf("readiness probe failed: %v", err))
-								cancel()
+								go probeLoop(probeCtx, "readiness probe", *probe, probeLogf, readyFunc)
 							}
 						}
-						go probeLoop(ctx, *probe, readyFunc)
-					}
 
-					if t.GetType() == types.TaskTypeService {
-						if t.Ports != nil {
-							setNodeStatus(node, "starting", "service starting")
-						} else {
-							setNodeStatus(node, "running", "no ports to expose")
-							queueChildren()
+						// the process says when it is ready, e.g. when its compose services are healthy
+						readier, isReadier := p.(proc.Readier)
+						if isReadier {
+							go func() {
+								for {
+									select {
+									case <-ctx.Done():
+										return
+									case <-time.After(time.Second):
+									}
+									ready, err := readier.Ready(ctx)
+									if err != nil && ctx.Err() == nil {
+										logger.Printf("failed to check readiness: %v\n", err)
+									}
+									if ready {
+										setNodeStatus(node, "running", "ready")
+										queueChildren()
+										return
+									}
+								}
+							}()
 						}
-					} else {
-						// non a service, must be a job
-						setNodeStatus(node, "running", "job running")
-					}
 
-					restart := func() {
-						select {
-						case <-ctx.Done():
-						case <-time.After(3 * time.Second):
-							logger.Println("restarting")
-							cancel()
-							events <- node.Name
+						// the service is ready when its ready condition holds, e.g. a file it writes exists
+						if ready := t.Ready; ready != nil {
+							go func() {
+								for {
+									select {
+									case <-ctx.Done():
+										return
+									case <-time.After(time.Second):
+									}
+									if err := checkReady(ctx, t.WorkingDir, *ready); err == nil {
+										setNodeStatus(node, "running", "ready")
+										queueChildren()
+										return
+									}
+								}
+							}()
 						}
-					}
 
-					file, err := os.Create(node.logFile)
-					if err != nil {
-						setNodeStatus(node, "failed", fmt.Sprintf("failed to create log file: %v", err))
-						return
-					}
-					defer file.Close()
-
-					// if the task has a log file, we will write to that file, we sync after each write
-					// so when we tail the log file, we see the output immediately
-					buf := funcWriter(func(p []byte) (int, error) {
-						stallTimers[node.Name].Reset(node.Task.GetStalledTimeout())
-						if node.Phase == "stalled" {
-							if strings.HasSuffix(node.Message, "starting") {
-								setNodeStatus(node, "starting", "output received")
+						if t.GetType() == types.TaskTypeService {
+							if t.Ports != nil || t.ReadinessProbe != nil || t.Ready != nil || isReadier {
+								setNodeStatus(node, "starting", "service starting")
 							} else {
-								setNodeStatus(node, "running", "output received")
+								setNodeStatus(node, "running", "no ports to expose")
+								queueChildren()
+							}
+						} else {
+							// non a service, must be a job
+							setNodeStatus(node, "running", "job running")
+						}
+
+						restart := func() {
+							diag.backoff(node.Name, time.Now().Add(3*time.Second))
+							defer diag.backoff(node.Name, time.Time{})
+							select {
+							case <-ctx.Done():
+							case <-time.After(3 * time.Second):
+								node.restarts.Add(1)
+								logger.Println("restarting")
+								eventLog.record(lifecycleEvent{Task: node.Name, Event: "restart"})
+								cancel()
+								sched.run(node.Name)
 							}
 						}
-						n, err := file.Write(p)
+
+						// the logs of tasks namespaced by their workflow are in a sub-directory
+						if err := os.MkdirAll(filepath.Dir(node.logFile), 0755); err != nil {
+							setNodeStatus(node, "failed", fmt.Sprintf("failed to create log directory: %v", err))
+							return
+						}
+						file, err := createLog(node.logFile, wf.LogRotation)
 						if err != nil {
-							return n, err
+							setNodeStatus(node, "failed", fmt.Sprintf("failed to create log file: %v", err))
+							return
 						}
-						if err := file.Sync(); err != nil {
-							return n, err
+						defer file.Close()
+						errFile, err := createLog(node.stderrLogFile, wf.LogRotation)
+						if err != nil {
+							setNodeStatus(node, "failed", fmt.Sprintf("failed to create stderr log file: %v", err))
+							return
 						}
-						return n, nil
-					})
+						defer errFile.Close()
+
+						// if the task has a log file, we will write to that file, we sync after each write
+						// so when we tail the log file, we see the output immediately
+						buf := funcWriter(func(p []byte) (int, error) {
+							node.stallTimer.Reset(node.Task.GetStalledTimeout())
+							if s := node.Snapshot(); s.Phase == "stalled" {
+								if strings.HasSuffix(s.Message, "starting") {
+									setNodeStatus(node, "starting", "output received")
+								} else {
+									setNodeStatus(node, "running", "output received")
+								}
+							}
+							_, _ = node.recent.Write(p)
+							n, err := file.Write(p)
+							if err != nil {
+								return n, err
+							}
+							if err := file.Sync(); err != nil {
+								return n, err
+							}
+							return n, nil
+						})
 
-					if t.Log != "" {
-						out = buf
-					} else {
-						out = io.MultiWriter(out, buf)
-					}
+						// stderr is also written to its own log file, so the errors can be read without the rest of the output
+						errBuf := funcWriter(func(p []byte) (int, error) {
+							if _, err := errFile.Write(p); err != nil {
+								return 0, err
+							}
+							if err := errFile.Sync(); err != nil {
+								return 0, err
+							}
+							return buf.Write(p)
+						})
 
-					err = p.Run(ctx, out, out)
-					// if the task was cancelled, we don't want to restart it, this is normal exit
-					if errors.Is(ctx.Err(), context.Canceled) {
-						setNodeStatus(node, "cancelled", "")
-						return
-					}
+						// everything is written to the log file, but we might only show errors (i.e. stderr) in the console
+						var stdout, stderr io.Writer = buf, errBuf
+						if t.Log == "" {
+							switch logLevel {
+							case "info":
+								stdout = io.MultiWriter(out, buf)
+								stderr = io.MultiWriter(errOut, errBuf)
+							case "error":
+								stderr = io.MultiWriter(errOut, errBuf)
+							}
+						}
 
-					if err != nil {
-						setNodeStatus(node, "failed", fmt.Sprint(err))
-						if t.GetRestartPolicy() != "Never" {
+						stdout, stderr = sessionLog.writer(node.Name, "stdout", stdout), sessionLog.writer(node.Name, "stderr", stderr)
+						stdout, stderr = report.writer(node.Name, "stdout", stdout), report.writer(node.Name, "stderr", stderr)
+						// extensions see each line first, e.g. to redact secrets before they're written anywhere
+						stdout, stderr = exts.writer(node.Name, stdout), exts.writer(node.Name, stderr)
+						node.outputs.reset()
+						stdout, stderr = node.outputs.writer(stdout, true), node.outputs.writer(stderr, false)
+						if logMatch != nil {
+							stdout, stderr = logMatch.writer(stdout), logMatch.writer(stderr)
+						}
+
+						node.startedAt = time.Now()
+						// the sources are hashed before the task runs, so a change while it runs means it runs again next time
+						sources, _ := sourcesHash(t)
+						saveState := func(phase types.Phase) {
+							// the targets are hashed after the task runs, as the run changes them
+							hash, _ := targetsHash(t, sources)
+							state := taskState{Phase: phase, Hash: hash, Restarts: int(node.restarts.Load()), FinishedAt: node.finishedAt}
+							// what a failed job applied is unknown, so it is applied again
+							if phase == "succeeded" {
+								state.Applied = applied
+							}
+							if err := states.save(node.Name, state); err != nil {
+								logger.Println(err)
+							}
+						}
+						// a failed onChange command, e.g. code generation, means the task would run stale code, so it is not run
+						if node.changed.Swap(false) {
+							if err := proc.RunHooks(ctx, logger, types.Spec(*wf), t, t.OnChange, nil, stdout, stderr); err != nil {
+								if errors.Is(ctx.Err(), context.Canceled) {
+									setNodeStatus(node, "cancelled", "")
+								} else {
+									setNodeStatus(node, "failed", fmt.Sprintf("onChange %v", err))
+								}
+								return
+							}
+						}
+						// the process's exit code, which may be a success code, even though it's not zero
+						exitCode := 0
+						err = proc.RunHooks(ctx, logger, types.Spec(*wf), t, t.PreRun, nil, stdout, stderr)
+						if err != nil {
+							err = fmt.Errorf("preRun %w", err)
+						} else {
+							if router != nil {
+								err = rollingRestart{
+									router:  router,
+									task:    t,
+									newProc: func(t types.Task) proc.Interface { return proc.New(taskName, t, logger, types.Spec(*wf)) },
+									stdout:  stdout,
+									stderr:  stderr,
+									rolls:   node.rolls,
+									onChange: func(ctx context.Context) error {
+										return proc.RunHooks(ctx, logger, types.Spec(*wf), t, t.OnChange, nil, stdout, stderr)
+									},
+									restarted: func(port uint16) {
+										node.restarts.Add(1)
+										eventLog.record(lifecycleEvent{Task: node.Name, Event: "restart", Message: fmt.Sprintf("rolling, now on port %d", port)})
+									},
+									logf: logger.Printf,
+								}.run(ctx, p)
+							} else {
+								err = p.Run(ctx, stdout, stderr)
+							}
+							exitCode = proc.ExitCode(err)
+							// some tools exit non-zero without failing, e.g. diff exits 1 when the files differ
+							if err != nil && t.IsSuccessCode(exitCode) {
+								err = nil
+							}
+							// post run hooks clean up, so they run even if the task was cancelled
+							env := types.EnvVars{"KIT_EXIT_CODE": {Value: strconv.Itoa(exitCode)}}
+							if postErr := proc.RunHooks(context.WithoutCancel(ctx), logger, types.Spec(*wf), t, t.PostRun, env, stdout, stderr); postErr != nil {
+								if err == nil {
+									err = fmt.Errorf("postRun %w", postErr)
+								} else {
+									logger.Printf("postRun %v", postErr)
+								}
+							}
+						}
+						node.finishedAt = time.Now()
+						// if the task was cancelled, we don't want to restart it, this is normal exit
+						if errors.Is(ctx.Err(), context.Canceled) {
+							// a failed probe cancels the task, which stays failed
+							if node.Snapshot().Phase != "failed" {
+								setNodeStatus(node, "cancelled", "")
+							}
+							return
+						}
+						node.exitCode = proc.ExitCode(err)
+						if err == nil {
+							node.exitCode = exitCode
+						}
+						if options.exited != nil {
+							options.exited(node.Name, node.exitCode)
+						}
+
+						// the onFailure task runs before any restart
+						fail := func(err error) {
+							node.retries = 0
+							message := err.Error()
+							// a task that fails now and then is flaky, which is worth knowing when deciding what to fix
+							if failed, runs := states.failures(node.Name); failed > 1 {
+								message = fmt.Sprintf("%s (failed %d of the last %d runs)", message, failed, runs)
+							}
+							setNodeStatus(node, "failed", message)
+							if t.OnFailure != "" {
+								if err := runOnFailure(ctx, logger, wf, taskName, t, err, stdout, stderr); err != nil {
+									logger.Println(err)
+								}
+							}
+						}
+
+						if err != nil {
+							saveState("failed")
+							if t.GetType() == types.TaskTypeJob && node.retries < t.FlakyRetries && states.flaky(node.Name) {
+								node.retries++
+								failed, runs := states.failures(node.Name)
+								logger.Printf("failed %d of the last %d runs, so is flaky, retrying (%d/%d): %v\n", failed, runs, node.retries, t.FlakyRetries, err)
+								eventLog.record(lifecycleEvent{Task: node.Name, Event: "retry", Message: err.Error()})
+								sched.run(node.Name)
+								return
+							}
+							fail(err)
+							// when failing fast, kit exits rather than restarting a failed job
+							if t.RestartsOnExit(true) && !(options.failFast && t.GetType() == types.TaskTypeJob) {
+								restart()
+							}
+							return
+						}
+
+						if err := verifyArtifacts(t); err != nil {
+							saveState("failed")
+							fail(err)
+							return
+						}
+
+						// pushed before the task succeeds, so kit does not exit while it is being pushed
+						if pushKey != "" && pushToCache {
+							logger.Println("pushing targets to cache")
+							if err := pushTargets(ctx, cache, pushKey, t); err != nil {
+								logger.Printf("failed to push targets to cache: %v\n", err)
+							}
+						}
+
+						node.retries = 0
+						saveState("succeeded")
+						message := ""
+						if node.exitCode != 0 {
+							message = fmt.Sprintf("exit code %d", node.exitCode)
+						}
+						setNodeStatus(node, "succeeded", message)
+						if t.RestartsOnExit(false) {
 							restart()
 						}
-						return
-					}
+						queueChildren()
 
-					setNodeStatus(node, "succeeded", "")
-					if t.GetRestartPolicy() == "Always" {
-						restart()
+					}(node)
+				// a task was stopped using the keyboard
+				case stopTask:
+					if node, ok := subgraph.Nodes[string(x)]; ok {
+						node.cancel()
 					}
-					queueChildren()
-
-				}(node)
-			default:
-				panic(fmt.Sprintf("unexpected event: %v", event))
+				case *types.Workflow:
+					if err := reload(x); err != nil {
+						logger.Printf("not reloading: %v\n", err)
+					}
+				default:
+					panic(fmt.Sprintf("unexpected event: %v", event))
+				}
 			}
 		}
 	}
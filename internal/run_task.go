@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// TaskError is returned by RunTask when the task fails.
+type TaskError struct {
+	Task     string
+	ExitCode int
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %q failed with exit code %d", e.Task, e.ExitCode)
+}
+
+// RunTask runs the task, and the tasks it depends on, to completion. Unlike RunSubgraph, the task is run as a job, even
+// if it's a service, nothing is re-run when files change, and nothing is restarted. If the task fails, a *TaskError
+// with its exit code is returned.
+func RunTask(ctx context.Context, cancel context.CancelFunc, logger *log.Logger, wf *types.Workflow, name string, opts ...Option) error {
+	t, ok := wf.Tasks[name]
+	if !ok {
+		return fmt.Errorf("task %q not found in workflow", name)
+	}
+	w := *wf
+	w.Tasks = types.Tasks{}
+	for n, t := range wf.Tasks {
+		t.Watch = nil
+		t.RestartPolicy = "Never"
+		w.Tasks[n] = t
+	}
+	t = w.Tasks[name]
+	t.Type = types.TaskTypeJob
+	w.Tasks[name] = t
+
+	mu := sync.Mutex{}
+	exitCode := 0
+	opts = append(opts, func(o *options) {
+		o.exited = func(task string, code int) {
+			if task == name {
+				mu.Lock()
+				defer mu.Unlock()
+				exitCode = code
+			}
+		}
+	})
+	err := RunSubgraph(ctx, cancel, 0, false, logger, &w, []string{name}, nil, opts...)
+	mu.Lock()
+	defer mu.Unlock()
+	// a task that exited with one of its success codes succeeded
+	if err != nil && exitCode > 0 {
+		return &TaskError{Task: name, ExitCode: exitCode}
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTask(t *testing.T) {
+	run := func(wf *types.Workflow, name string) (string, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		buffer := &bytes.Buffer{}
+		err := RunTask(ctx, cancel, log.New(buffer, "", 0), wf, name, WithDir(t.TempDir()))
+		return buffer.String(), err
+	}
+	t.Run("Exit code", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: types.Tasks{
+				"build": {Sh: "echo build"},
+				"test":  {Sh: "exit 3", Dependencies: &types.Dependencies{AllOf: types.Strings{"build"}}},
+			},
+		}
+		out, err := run(wf, "test")
+		assert.EqualError(t, err, `task "test" failed with exit code 3`)
+		assert.Equal(t, 3, err.(*TaskError).ExitCode)
+		assert.Contains(t, out, "(running)  build")
+	})
+	t.Run("Service runs to completion", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: types.Tasks{
+				"migrate": {Sh: "echo migrated", Ports: []types.Port{{ContainerPort: 8080}}},
+			},
+		}
+		out, err := run(wf, "migrate")
+		assert.NoError(t, err)
+		assert.Contains(t, out, "[migrate] (succeeded)")
+	})
+	t.Run("Not found", func(t *testing.T) {
+		_, err := run(&types.Workflow{}, "test")
+		assert.EqualError(t, err, `task "test" not found in workflow`)
+	})
+}
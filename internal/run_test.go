@@ -3,8 +3,12 @@ package internal
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -12,12 +16,37 @@ import (
 
 	"github.com/kitproj/kit/internal/types"
 	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
 )
 
+// syncBuffer is a buffer that can be read while the tasks write to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
 func TestRunSubgraph(t *testing.T) {
-	setup := func(t *testing.T) (context.Context, context.CancelFunc, *log.Logger, *bytes.Buffer) {
+	setup := func(t *testing.T) (context.Context, context.CancelFunc, *log.Logger, *syncBuffer) {
 		ctx, cancel := context.WithCancel(context.Background())
-		buffer := &bytes.Buffer{}
+		buffer := &syncBuffer{}
 		out := funcWriter(func(i []byte) (int, error) {
 			t.Log(strings.TrimSuffix(string(i), "\n"))
 			return buffer.Write(i)
@@ -31,24 +60,49 @@ func TestRunSubgraph(t *testing.T) {
 	t.Run("No tasks", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
-		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, nil, nil)
+		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, nil, nil, WithDir(t.TempDir()))
 		assert.NoError(t, err)
 	})
 
 	t.Run("Task not found", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
-		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, []string{"job"}, nil, WithDir(t.TempDir()))
 		assert.EqualError(t, err, "task \"job\" not found in workflow")
 	})
 
 	t.Run("Skipped task not found", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
-		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, nil, []string{"job"})
+		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, nil, []string{"job"}, WithDir(t.TempDir()))
 		assert.EqualError(t, err, "skipped task \"job\" not found in workflow")
 	})
 
+	t.Run("Dependency not found", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {Dependencies: &types.Dependencies{AllOf: []string{"build"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "job" depends on "build", which is not found in workflow`)
+	})
+
+	t.Run("Dependency cycle", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"a": {Dependencies: &types.Dependencies{AllOf: []string{"b"}}},
+				"b": {Dependencies: &types.Dependencies{AllOf: []string{"a"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"a"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "dependency cycle: a → b → a")
+	})
+
 	t.Run("Single successful job", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
@@ -57,7 +111,7 @@ func TestRunSubgraph(t *testing.T) {
 				"job": {Command: []string{"true"}},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
 		assert.NoError(t, err)
 	})
 
@@ -69,8 +123,54 @@ func TestRunSubgraph(t *testing.T) {
 				"job": {Command: []string{"false"}},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
-		assert.EqualError(t, err, "failed tasks: [job]")
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "failed tasks: job (exit code 1)")
+	})
+
+	t.Run("Job exits with a success code", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"diff": {Sh: "exit 2", SuccessCodes: []int{0, 2}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"diff"}, nil, WithDir(t.TempDir()))
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "[diff] (succeeded)  exit code 2")
+	})
+
+	t.Run("Keep going", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"lint":  {Command: []string{"sh", "-c", "exit 3"}},
+				"build": {Command: []string{"sh", "-c", "exit 3"}},
+				"e2e":   {Command: []string{"echo", "e2e"}, Dependencies: &types.Dependencies{AllOf: []string{"build"}}},
+				"test":  {Command: []string{"sh", "-c", "sleep 1; echo tested"}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"lint", "e2e", "test"}, nil, WithKeepGoing(true), WithDir(t.TempDir()))
+		assert.Equal(t, &FailedTasksError{Tasks: []string{"build", "lint"}, ExitCode: 3, ExitCodes: map[string]int{"build": 3, "lint": 3}}, err)
+		assert.Contains(t, buffer.String(), "tested")
+		assert.NotContains(t, buffer.String(), "[e2e] (running)")
+		assert.Contains(t, buffer.String(), "cannot complete because a task failed")
+	})
+
+	t.Run("Fail fast", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job":  {Command: []string{"false"}, RestartPolicy: "OnFailure"},
+				"test": {Command: []string{"sh", "-c", "sleep 10; echo tested"}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "test"}, nil, WithFailFast(true), WithDir(t.TempDir()))
+		assert.Equal(t, &FailedTasksError{Tasks: []string{"job"}, ExitCode: 1, ExitCodes: map[string]int{"job": 1}}, err)
+		assert.NotContains(t, buffer.String(), "tested")
+		assert.Contains(t, buffer.String(), `exiting because job "job" failed, and failing fast`)
 	})
 
 	t.Run("Single running service", func(t *testing.T) {
@@ -87,7 +187,7 @@ func TestRunSubgraph(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil, WithDir(t.TempDir()))
 			assert.NoError(t, err)
 		}()
 
@@ -114,8 +214,8 @@ func TestRunSubgraph(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
-			assert.EqualError(t, err, "failed tasks: [service]")
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil, WithDir(t.TempDir()))
+			assert.EqualError(t, err, "failed tasks: service (exit code 1)")
 		}()
 
 		sleep(t)
@@ -130,22 +230,562 @@ func TestRunSubgraph(t *testing.T) {
 		ctx, cancel, logger, buffer := setup(t)
 		defer cancel()
 
+		logFile := filepath.Join(t.TempDir(), "test.log")
 		wf := &types.Workflow{
 			Tasks: map[string]types.Task{
-				"job": {Command: []string{"echo", "hello"}, Log: "test.log"},
+				"job": {Command: []string{"echo", "hello"}, Log: logFile},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
 		assert.NoError(t, err)
 		assert.NotContains(t, buffer.String(), "hello")
 		assert.Contains(t, buffer.String(), "[job] (succeeded)")
 
 		// check file is written
-		file, err := os.ReadFile("test.log")
+		file, err := os.ReadFile(logFile)
 		assert.NoError(t, err)
 		assert.Equal(t, "hello\n", string(file))
 	})
 
+	t.Run("Quiet", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {Command: []string{"sh", "-c", "echo hello; echo oops >&2"}},
+			},
+		}
+		dir := t.TempDir()
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithQuiet(true), WithDir(dir))
+		assert.NoError(t, err)
+		assert.NotContains(t, buffer.String(), "hello")
+		assert.NotContains(t, buffer.String(), "job running")
+		assert.Contains(t, buffer.String(), "oops")
+
+		// check the log file has all the output
+		file, err := os.ReadFile(filepath.Join(dir, "logs", "job.log"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(file), "hello\n")
+
+		// check the stderr log file only has stderr
+		file, err = os.ReadFile(filepath.Join(dir, "logs", "job.stderr.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, "oops\n", string(file))
+	})
+
+	t.Run("Pre and post run hooks", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {
+					Command: []string{"sh", "-c", "echo main; exit 3"},
+					PreRun:  []types.Strings{{"echo", "pre"}},
+					PostRun: []types.Strings{{"sh", "-c", "echo post $KIT_EXIT_CODE"}},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "failed tasks: job (exit code 3)")
+		assert.Regexp(t, `(?s)pre.*main.*post 3`, buffer.String())
+	})
+
+	t.Run("Failing pre run hook", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {
+					Command: []string{"echo", "main"},
+					PreRun:  []types.Strings{{"false"}},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "failed tasks: job (exit code 1)")
+		assert.Contains(t, buffer.String(), `preRun "false" failed: exit status 1`)
+		assert.NotContains(t, buffer.String(), "main")
+	})
+
+	t.Run("On failure task", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job":  {Sh: "exit 3", OnFailure: "dump"},
+				"dump": {Sh: "echo $KIT_FAILED_TASK exited $KIT_EXIT_CODE"},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "failed tasks: job (exit code 3)")
+		assert.Contains(t, buffer.String(), "job exited 3")
+	})
+
+	t.Run("On failure task not found", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {Sh: "exit 3", OnFailure: "dump"},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "job" has onFailure "dump", which is not another task in workflow`)
+	})
+
+	t.Run("Port used by two tasks", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"api": {Command: []string{"go", "run", "."}, Ports: types.Ports{{ContainerPort: 8080}}},
+				"web": {Image: "nginx", Ports: types.Ports{{ContainerPort: 80, HostPort: 8080}}, Dependencies: &types.Dependencies{AllOf: types.Strings{"api"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"web"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `tasks "api" and "web" both use host port 8080`)
+	})
+
+	t.Run("Outputs are passed to downstream tasks", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"db": {
+					Command: []string{"echo", "port=5432"},
+					Outputs: []types.Output{{Name: "DB_PORT", Regex: `port=(\d+)`}},
+				},
+				"app": {
+					Command:      []string{"sh", "-c", "echo connecting to $DB_PORT"},
+					Dependencies: &types.Dependencies{AllOf: []string{"db"}},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"app"}, nil, WithDir(t.TempDir()))
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "connecting to 5432")
+	})
+
+	t.Run("Env references other tasks", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"db": {Ports: []types.Port{{ContainerPort: 5432, HostPort: 15432}}},
+				"login": {
+					Command: []string{"echo", "token=abc"},
+					Outputs: []types.Output{{Name: "TOKEN", Regex: `token=(\w+)`}},
+				},
+				"app": {
+					Command:      []string{"sh", "-c", "echo connecting to $DATABASE_URL with $AUTH"},
+					Env:          types.EnvVars{"DATABASE_URL": {Value: "postgres://localhost:{{ ports.db.host }}/app"}, "AUTH": {Value: "Bearer {{outputs.login.TOKEN}}"}},
+					Dependencies: &types.Dependencies{AllOf: []string{"login"}},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"app"}, nil, WithDir(t.TempDir()))
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "connecting to postgres://localhost:15432/app with Bearer abc")
+	})
+
+	t.Run("Open when ready", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Open: types.Strings{"http://localhost:{{ ports.api.host }}/docs"},
+			Tasks: map[string]types.Task{
+				"api": {Type: types.TaskTypeJob, Command: []string{"true"}, Ports: []types.Port{{ContainerPort: 8080}}, Open: "http://localhost:8080"},
+				"job": {Command: []string{"true"}},
+			},
+		}
+		mu := sync.Mutex{}
+		var opened []string
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"api", "job"}, nil, func(o *options) {
+			o.openURL = func(url string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				opened = append(opened, url)
+				return nil
+			}
+		}, WithDir(t.TempDir()))
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "[api] opening http://localhost:8080\n")
+		assert.Contains(t, buffer.String(), "opening http://localhost:8080/docs\n", "once every task is ready")
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(opened) == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Invalid open", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+		wf := &types.Workflow{Tasks: map[string]types.Task{"web": {Command: []string{"true"}, Open: "localhost:3000"}}}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, nil, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "web" has invalid open: "localhost:3000" must be an http or https URL`)
+	})
+
+	t.Run("Missing working directory", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{Tasks: map[string]types.Task{"job": {Command: []string{"true"}, WorkingDir: "testdata/missing"}}}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "failed tasks: job")
+		assert.Contains(t, buffer.String(), `working directory "testdata/missing" does not exist`)
+	})
+
+	t.Run("Env references the outputs of a task that is not a dependency", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"login": {Outputs: []types.Output{{Name: "TOKEN"}}},
+				"app":   {Env: types.EnvVars{"AUTH": {Value: "{{ outputs.login.TOKEN }}"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"app"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "app" references {{ outputs.login.TOKEN }}, but "login" is not one of its dependencies`)
+	})
+
+	t.Run("Rolling restart of a container", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"db": {Image: "postgres", Ports: []types.Port{{ContainerPort: 5432}}, RollingRestart: true},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"db"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "db" has invalid rollingRestart: it needs a host task with ports`)
+	})
+
+	t.Run("Image and build", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"api": {Image: "api", Build: &types.Build{}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"api"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "api" has both image and build, but can only run an image or build one`)
+	})
+
+	t.Run("Missing artifact fails the producer", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build": {Command: []string{"true"}, Artifacts: []string{"testdata/missing"}},
+				"app": {
+					Command: []string{"true"},
+					Inputs:  []types.Input{{Task: "build", Artifact: "testdata/missing"}},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"app"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "failed tasks: build")
+		assert.Contains(t, buffer.String(), `artifact "testdata/missing" was not produced`)
+	})
+
+	t.Run("Input that is not an artifact", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build": {Command: []string{"true"}},
+				"app":   {Inputs: []types.Input{{Task: "build", Artifact: "bin/app"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"app"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "app" has input "bin/app", which is not an artifact of "build"`)
+	})
+
+	t.Run("Workflow shell", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Shell: types.Strings{"sh", "-e", "-c"},
+			Tasks: map[string]types.Task{
+				"job": {Sh: "false; echo unreachable"},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, "failed tasks: job (exit code 1)")
+		assert.NotContains(t, buffer.String(), "unreachable")
+	})
+
+	t.Run("Any of dependencies", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"fast": {Sh: "echo fast"},
+				"slow": {Sh: "sleep 1; echo slow"},
+				"app":  {Sh: "echo app", Dependencies: &types.Dependencies{AnyOf: []string{"fast", "slow"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"app", "slow"}, nil, WithDir(t.TempDir()))
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "(running)  slow")
+		// app is started by fast, and not re-started by slow
+		assert.Equal(t, 1, strings.Count(buffer.String(), "(running)  app"))
+	})
+
+	t.Run("Max parallel", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			MaxParallel: 1,
+			Tasks: map[string]types.Task{
+				"a": {Sh: "echo start; sleep 0.2; echo end"},
+				"b": {Sh: "echo start; sleep 0.2; echo end"},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"a", "b"}, nil, WithDir(t.TempDir()))
+		assert.NoError(t, err)
+		var order []string
+		for _, line := range strings.Split(buffer.String(), "\n") {
+			for _, marker := range []string{"start", "end"} {
+				if strings.Contains(line, "(running)  "+marker+"\x1b") {
+					order = append(order, marker)
+				}
+			}
+		}
+		assert.Equal(t, []string{"start", "end", "start", "end"}, order)
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build": {Sh: "echo build"},
+				"api":   {Group: "backend", Sh: "echo api", Dependencies: &types.Dependencies{AllOf: []string{"build"}}},
+				"db":    {Group: "backend", Sh: "echo db"},
+				"web":   {Group: "frontend", Sh: "echo web"},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"backend"}, nil, WithDir(t.TempDir()))
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "(running)  build")
+		assert.Contains(t, buffer.String(), "(running)  api")
+		assert.Contains(t, buffer.String(), "(running)  db")
+		assert.NotContains(t, buffer.String(), "[web]")
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		configFile := filepath.Join(t.TempDir(), "tasks.yaml")
+		write := func(config string) {
+			assert.NoError(t, os.WriteFile(configFile, []byte(config), 0644))
+		}
+		write(`
+tasks:
+  a: {group: all, sh: "echo a-v1; sleep 30"}
+  b: {group: all, sh: "echo b-v1; sleep 30"}
+`)
+		load := func() (*types.Workflow, error) {
+			in, err := os.ReadFile(configFile)
+			if err != nil {
+				return nil, err
+			}
+			wf := &types.Workflow{}
+			return wf, yaml.Unmarshal(in, wf)
+		}
+		wf, err := load()
+		assert.NoError(t, err)
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"all"}, nil, WithReload([]string{configFile}, load), WithDir(t.TempDir()))
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+
+		// a is changed, b is removed, c is added
+		write(`
+tasks:
+  a: {group: all, sh: "echo a-v2; sleep 30"}
+  c: {group: all, sh: "echo c-v1; sleep 30"}
+`)
+
+		sleep(t)
+		sleep(t)
+
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[a] changed, restarting")
+		assert.Contains(t, buffer.String(), "(running)  a-v2")
+		assert.Contains(t, buffer.String(), "[b] removed, stopping")
+		assert.Contains(t, buffer.String(), "[c] added, starting")
+		assert.Contains(t, buffer.String(), "(running)  c-v1")
+	})
+
+	t.Run("Reload of settings applied when kit starts", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		dir := t.TempDir()
+		hostsFile := filepath.Join(dir, "hosts")
+		assert.NoError(t, os.WriteFile(hostsFile, nil, 0644))
+		configFile := filepath.Join(dir, "tasks.yaml")
+		write := func(config string) {
+			assert.NoError(t, os.WriteFile(configFile, []byte(fmt.Sprintf(config, hostsFile)), 0644))
+		}
+		write(`
+dns: {hostsFile: %s}
+tasks:
+  a: {group: all, sh: "echo a-v1; sleep 30"}
+`)
+		load := func() (*types.Workflow, error) {
+			in, err := os.ReadFile(configFile)
+			if err != nil {
+				return nil, err
+			}
+			wf := &types.Workflow{}
+			return wf, yaml.Unmarshal(in, wf)
+		}
+		wf, err := load()
+		assert.NoError(t, err)
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"all"}, nil, WithReload([]string{configFile}, load), WithDir(t.TempDir()))
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+
+		// the https proxy of an added task would never be started
+		write(`
+dns: {hostsFile: %s}
+tasks:
+  a: {group: all, sh: "echo a-v1; sleep 30"}
+  b: {group: all, sh: "echo b-v1; sleep 30", ports: "8080", tls: {port: 8443}}
+`)
+
+		sleep(t)
+		sleep(t)
+
+		// but its name is added to the hosts file
+		write(`
+dns: {hostsFile: %s}
+tasks:
+  a: {group: all, sh: "echo a-v1; sleep 30"}
+  c: {group: all, sh: "echo c-v1; sleep 30"}
+`)
+
+		sleep(t)
+		sleep(t)
+
+		hosts, err := os.ReadFile(hostsFile)
+		assert.NoError(t, err)
+
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "not reloading: the tls, rollingRestart, faults or intercept of b changed, restart kit to apply")
+		assert.NotContains(t, buffer.String(), "(running)  b-v1")
+		assert.Contains(t, buffer.String(), "[c] added, starting")
+		assert.Contains(t, string(hosts), "c.kit.local")
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"seed":    {Sh: "echo seeded", Trigger: &types.Trigger{Webhook: "/hooks/seed"}},
+				"service": {Type: types.TaskTypeService, Command: []string{"sleep", "30"}},
+			},
+		}
+		port := freePort(t)
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, port, false, logger, wf, []string{"seed", "service"}, nil, WithDir(t.TempDir()))
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+
+		post := func(path, origin string, host ...string) int {
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%d%s", port, path), nil)
+			assert.NoError(t, err)
+			if len(host) > 0 {
+				req.Host = host[0]
+			}
+			if origin != "" {
+				req.Header.Set("Origin", origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return 0
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		assert.Equal(t, http.StatusAccepted, post("/hooks/seed", ""))
+		assert.Equal(t, http.StatusNotFound, post("/hooks/other", ""))
+		// another page in the browser cannot re-run the task
+		assert.Equal(t, http.StatusForbidden, post("/hooks/seed", "http://evil.example.com"))
+		// nor can a page that re-bound its own name to us
+		evil := fmt.Sprintf("evil.example.com:%d", port)
+		assert.Equal(t, http.StatusForbidden, post("/hooks/seed", "http://"+evil, evil))
+
+		sleep(t)
+
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[seed] webhook called, re-running")
+		assert.Equal(t, 2, strings.Count(buffer.String(), "(running)  seeded"))
+	})
+
+	t.Run("Invalid webhook", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"seed": {Sh: "echo seeded", Trigger: &types.Trigger{Webhook: "/seed"}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"seed"}, nil, WithDir(t.TempDir()))
+		assert.EqualError(t, err, `task "seed" has invalid trigger: webhook "/seed" must start with /hooks/`)
+	})
+
 	t.Run("Scheduled task twice", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
@@ -160,7 +800,7 @@ func TestRunSubgraph(t *testing.T) {
 		go func() {
 			defer wg.Done()
 
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "job"}, nil)
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "job"}, nil, WithDir(t.TempDir()))
 			assert.NoError(t, err)
 		}()
 
@@ -191,7 +831,7 @@ sleep 30
 		go func() {
 			defer wg.Done()
 
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "service"}, nil)
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "service"}, nil, WithDir(t.TempDir()))
 			assert.NoError(t, err)
 		}()
 
@@ -242,7 +882,7 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil, WithDir(t.TempDir()))
 			assert.NoError(t, err)
 		}()
 
@@ -271,6 +911,89 @@ sleep 30
 		}
 	})
 
+	t.Run("OnWatch job is only re-run when a watched file changes", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {
+					Command:       []string{"sh", "-c", "echo hello; exit 1"},
+					Watch:         []string{"testdata/marker"},
+					RestartPolicy: "OnWatch",
+				},
+			},
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+		}()
+
+		sleep(t)
+
+		// kit keeps running, waiting for a change, even though the job failed
+		select {
+		case err := <-done:
+			t.Fatalf("exited: %v", err)
+		default:
+		}
+		assert.Equal(t, 1, strings.Count(buffer.String(), "hello"))
+
+		err := os.WriteFile("testdata/marker", nil, 0644)
+		assert.NoError(t, err)
+
+		sleep(t)
+
+		cancel()
+		<-done
+
+		assert.Equal(t, 2, strings.Count(buffer.String(), "hello"))
+		assert.NotContains(t, buffer.String(), "restarting")
+	})
+
+	t.Run("Failing onChange command stops the task being re-run", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:  []string{"sh", "-c", "echo hello; sleep 30"},
+					OnChange: []types.Strings{{"sh", "-c", "echo generating; exit 1"}},
+					Watch:    []string{"testdata/marker"},
+					Ports:    []types.Port{{}},
+				},
+			},
+		}
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil, WithDir(t.TempDir()))
+			assert.EqualError(t, err, "failed tasks: service")
+		}()
+
+		sleep(t)
+
+		// onChange is only run when a watched file changes
+		assert.NotContains(t, buffer.String(), "generating")
+
+		err := os.WriteFile("testdata/marker", nil, 0644)
+		assert.NoError(t, err)
+
+		sleep(t)
+
+		cancel()
+
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "generating")
+		assert.Contains(t, buffer.String(), "[service] (failed)  onChange")
+		assert.Equal(t, 1, strings.Count(buffer.String(), "hello"))
+	})
+
 	t.Run("Changing jobs watched file re-runs job and downstream service", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
@@ -285,7 +1008,7 @@ echo "hello"
 				"service": {Command: []string{"sh", "-c", `
 echo "gutten tag"
 sleep 30
-`}, Dependencies: []string{"job"}, Ports: []types.Port{{}},
+`}, Dependencies: &types.Dependencies{AllOf: []string{"job"}}, Ports: []types.Port{{}},
 				},
 			},
 		}
@@ -294,7 +1017,7 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil, WithDir(t.TempDir()))
 			assert.NoError(t, err)
 		}()
 
@@ -324,7 +1047,7 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil, WithDir(t.TempDir()))
 			assert.NoError(t, err)
 		}()
 
@@ -336,6 +1059,210 @@ sleep 30
 		assert.Contains(t, buffer.String(), "[service] (running)")
 	})
 
+	t.Run("Failed liveness probe", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Type:          types.TaskTypeService,
+					Command:       []string{"sleep", "30"},
+					RestartPolicy: "Never",
+					LivenessProbe: &types.Probe{TCPSocket: &types.TCPSocketAction{Port: uint16(freePort(t))}, PeriodSeconds: 1, FailureThreshold: 1},
+				},
+			},
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil, WithDir(t.TempDir()))
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			cancel()
+			<-done
+		}
+
+		assert.Contains(t, buffer.String(), "liveness probe failed")
+		// the probe cancels the task, which stays failed
+		assert.NotContains(t, buffer.String(), "failed to set phase")
+	})
+
+	t.Run("Service ready when its output matches", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {Sh: "echo starting; echo listening on port 8080; sleep 30", ReadinessProbe: &types.Probe{LogMatch: `listening on port \d+`}},
+				"job":     {Command: []string{"true"}, Dependencies: &types.Dependencies{AllOf: []string{"service"}}},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+		cancel()
+
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] (running)  readiness probe succeeded")
+		assert.Less(t, strings.Index(buffer.String(), "listening on port"), strings.Index(buffer.String(), "[job] (running)"))
+	})
+
+	t.Run("Resume from the last run", func(t *testing.T) {
+		const namespace = "resume"
+		dir := t.TempDir()
+		source := filepath.Join(t.TempDir(), "main.go")
+		assert.NoError(t, os.WriteFile(source, nil, 0o644))
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build": {Sh: "echo building", Watch: []string{source}},
+			},
+		}
+		run := func() string {
+			ctx, cancel, logger, buffer := setup(t)
+			defer cancel()
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"build"}, nil, WithNamespace(namespace), WithDir(dir))
+			assert.NoError(t, err)
+			return buffer.String()
+		}
+
+		assert.Contains(t, run(), "building")
+		second := run()
+		assert.NotContains(t, second, "building")
+		assert.Contains(t, second, "[build] (succeeded)  up to date")
+
+		// changing the source means it must run again
+		assert.NoError(t, os.WriteFile(source, []byte("package main"), 0o644))
+		assert.Contains(t, run(), "building")
+	})
+
+	t.Run("Resume a job with targets", func(t *testing.T) {
+		const namespace = "resume-targets"
+		dir := t.TempDir()
+		workingDir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(workingDir, "main.go"), nil, 0o644))
+		// the target is older than the source, as if it was extracted from an archive, so the job is not skipped
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build": {Sh: "echo building; touch -t 200001010000 app", WorkingDir: workingDir, Watch: []string{"main.go"}, Targets: []string{"app"}},
+			},
+		}
+		run := func() string {
+			ctx, cancel, logger, buffer := setup(t)
+			defer cancel()
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"build"}, nil, WithNamespace(namespace), WithDir(dir))
+			assert.NoError(t, err)
+			return buffer.String()
+		}
+
+		assert.Contains(t, run(), "building")
+		assert.Contains(t, run(), "[build] (succeeded)  up to date")
+	})
+
+	t.Run("Record the session", func(t *testing.T) {
+		const namespace = "record"
+		dir := t.TempDir()
+		t.Setenv("KIT_TEST_PASSWORD", "hunter2")
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {Sh: "echo hello; echo oops >&2; echo token $API_TOKEN; echo password $KIT_TEST_PASSWORD", Env: types.EnvVars{"API_TOKEN": {Value: "abc123"}}},
+			},
+		}
+		file := filepath.Join(t.TempDir(), "session.tar.gz")
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithNamespace(namespace), WithDir(dir), WithRecording(file))
+		assert.NoError(t, err)
+
+		files := readTarGz(t, file)
+		assert.ElementsMatch(t, []string{"workflow.yaml", "status.txt", "logs-record/job.log", "logs-record/job.stderr.log", "events.jsonl", "session.jsonl", "versions.txt", "environment.txt"}, sortedKeys(files))
+		assert.Contains(t, files["workflow.yaml"], "API_TOKEN: '[redacted]'")
+		assert.NotContains(t, files["workflow.yaml"], "abc123")
+		assert.Contains(t, files["status.txt"], "job")
+		assert.Contains(t, files["logs-record/job.log"], "hello")
+		// secrets the task printed are redacted too
+		assert.Contains(t, files["logs-record/job.log"], "token [redacted]\npassword [redacted]\n")
+		assert.Contains(t, files["session.jsonl"], `"line":"token [redacted]"`)
+		for name, data := range files {
+			assert.NotContains(t, data, "abc123", name)
+			assert.NotContains(t, data, "hunter2", name)
+		}
+		assert.Contains(t, files["logs-record/job.stderr.log"], "oops")
+		assert.Contains(t, files["events.jsonl"], `"event":"start"`)
+		assert.Contains(t, files["session.jsonl"], `"line":"hello"`)
+		assert.Contains(t, files["versions.txt"], "kit ")
+		assert.Contains(t, files["environment.txt"], "KIT_TEST_PASSWORD=[redacted]\n")
+	})
+
+	t.Run("Seed is only applied once", func(t *testing.T) {
+		const namespace = "once"
+		dir := t.TempDir()
+		fixtures := filepath.Join(t.TempDir(), "fixtures.sql")
+		assert.NoError(t, os.WriteFile(fixtures, []byte("insert into users values (1)"), 0o644))
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"db":   {Sh: "echo database ready"},
+				"seed": {Sh: "echo seeding", Dependencies: &types.Dependencies{AllOf: []string{"db"}}, Once: []string{fixtures}},
+			},
+		}
+		run := func() string {
+			ctx, cancel, logger, buffer := setup(t)
+			defer cancel()
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"seed"}, nil, WithNamespace(namespace), WithDir(dir))
+			assert.NoError(t, err)
+			return buffer.String()
+		}
+
+		assert.Contains(t, run(), "seeding")
+		// unlike resuming, the seed is not applied again just because its dependency ran again
+		second := run()
+		assert.Contains(t, second, "database ready")
+		assert.NotContains(t, second, "seeding")
+		assert.Contains(t, second, "[seed] (succeeded)  already applied")
+
+		// touching the fixtures does not change them
+		assert.NoError(t, os.Chtimes(fixtures, time.Now(), time.Now()))
+		assert.NotContains(t, run(), "seeding")
+
+		assert.NoError(t, os.WriteFile(fixtures, []byte("insert into users values (2)"), 0o644))
+		assert.Contains(t, run(), "seeding")
+	})
+
+	t.Run("Flaky job is retried", func(t *testing.T) {
+		const namespace = "flaky"
+		dir := t.TempDir()
+		marker := filepath.Join(t.TempDir(), "marker")
+		run := func(sh string) (string, error) {
+			ctx, cancel, logger, buffer := setup(t)
+			defer cancel()
+			wf := &types.Workflow{
+				Tasks: map[string]types.Task{
+					"test": {Sh: sh, FlakyRetries: 1},
+				},
+			}
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"test"}, nil, WithNamespace(namespace), WithDir(dir))
+			return buffer.String(), err
+		}
+
+		// the first failure is not retried, as the job is not known to be flaky yet
+		_, err := run("exit 1")
+		assert.EqualError(t, err, "failed tasks: test (exit code 1)")
+		_, err = run("true")
+		assert.NoError(t, err)
+		// it now fails on the first attempt only
+		out, err := run("test -f " + marker + " || { touch " + marker + "; exit 1; }")
+		assert.NoError(t, err)
+		assert.Contains(t, out, "failed 2 of the last 3 runs, so is flaky, retrying (1/1)")
+	})
+
 	t.Run("Job fails while service running", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
@@ -350,8 +1277,8 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "service"}, nil)
-			assert.EqualError(t, err, "failed tasks: [job]")
+			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "service"}, nil, WithDir(t.TempDir()))
+			assert.EqualError(t, err, "failed tasks: job (exit code 1)")
 		}()
 
 		sleep(t)
@@ -369,11 +1296,19 @@ sleep 30
 				"job": {Command: []string{"true"}},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil, WithDir(t.TempDir()))
 		assert.NoError(t, err)
 	})
 }
 
+// freePort returns a port that is free to listen on.
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
 func sleep(t *testing.T) {
 	x := 200 * time.Millisecond
 	t.Logf("sleeping for %s", x)
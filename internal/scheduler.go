@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// scheduler queues the events of the main loop: the names of the tasks to run, the poison pill, the tasks to stop, and
+// reloaded workflows. Queueing never blocks, so a task, a watcher or a timer cannot deadlock with the main loop, e.g.
+// while it waits for the tasks to complete when kit is exiting, however many events there are.
+//
+// A task is queued at most once. If it is queued again, e.g. by a watched file and by its dependency, before the main
+// loop takes it, it is run once, in the place it was first queued, so a task that is re-run over and over cannot starve
+// the others. The scheduler does not limit how many tasks run at once: that is maxParallel, which jobs wait for in order
+// of priority, and then in the order they started waiting.
+type scheduler struct {
+	mu     sync.Mutex
+	events []any
+	// the tasks, and the poison pill, that are queued
+	queued map[any]bool
+	// has a value when there are events to take
+	ready chan struct{}
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{queued: map[any]bool{}, ready: make(chan struct{}, 1)}
+}
+
+// run queues the task to be run.
+func (s *scheduler) run(name string) {
+	s.send(name)
+}
+
+// send queues the event.
+func (s *scheduler) send(event any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch x := event.(type) {
+	case string, struct{}:
+		if s.queued[x] {
+			return
+		}
+		s.queued[x] = true
+	case stopTask:
+		// the task is run again if it is queued after it is stopped
+		delete(s.queued, string(x))
+	}
+	s.events = append(s.events, event)
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// C receives when there are events to take.
+func (s *scheduler) C() <-chan struct{} {
+	return s.ready
+}
+
+// take returns the queued events, oldest first, and empties the queue.
+func (s *scheduler) take() []any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.events
+	s.events = nil
+	clear(s.queued)
+	return events
+}
+
+// blockedBy returns why the task cannot be run yet, as a task it depends on is not ready, or empty if it can be.
+func blockedBy(dag DAG[*TaskNode], name string) string {
+	dependencies := dag.Nodes[name].Task.Dependencies
+	for _, parentName := range dag.Parents[name] {
+		parent := dag.Nodes[parentName]
+		// only one of the any-of dependencies needs to be ready
+		if parent.blocked() && !dependencies.IsAnyOf(parentName) {
+			s := parent.Snapshot()
+			return fmt.Sprintf("task %q is blocked by %q (%s): %s", name, parentName, s.Phase, s.Message)
+		}
+	}
+	if anyOf := dependencies.GetAnyOf(); len(anyOf) > 0 && !anyReady(dag.Nodes, anyOf, "") {
+		return fmt.Sprintf("task %q is blocked by all of %v", name, anyOf)
+	}
+	return ""
+}
+
+// exitReason returns why kit should exit, now that a task has finished, or empty if it should keep running: the
+// requested tasks have completed, or a task failed that should not be restarted.
+func (o options) exitReason(dag DAG[*TaskNode], taskNames []string) string {
+	pendingTasks := map[string]bool{}
+	for _, x := range taskNames {
+		pendingTasks[x] = true
+	}
+	anyDoomed := false
+	for _, node := range dag.Nodes {
+		// a task that is re-run when a watched file changes is never complete
+		if phase := node.Snapshot().Phase; (phase == "succeeded" || phase == "skipped") && !node.Task.RestartsOnExit(false) && node.Task.GetRestartPolicy() != "OnWatch" {
+			delete(pendingTasks, node.Name)
+		}
+		// when keeping going, a task that cannot complete, because it or a task it depends on failed, is done
+		if o.keepGoing && pendingTasks[node.Name] && doomed(dag, node.Name) {
+			delete(pendingTasks, node.Name)
+			anyDoomed = true
+		}
+	}
+	if len(pendingTasks) == 0 {
+		if anyDoomed {
+			return "exiting because all requested tasks completed, or cannot complete because a task failed"
+		}
+		return "exiting because all requested tasks completed and none should be restarted"
+	}
+
+	// if a task that should not be restarted failed, we must exit, unless we keep going with the other tasks
+	for _, name := range sortedKeys(dag.Nodes) {
+		node := dag.Nodes[name]
+		if node.Snapshot().Phase != "failed" {
+			continue
+		}
+		switch {
+		case o.failFast && node.Task.GetType() == types.TaskTypeJob:
+			return fmt.Sprintf("exiting because job %q failed, and failing fast", node.Name)
+		case !o.keepGoing && node.Task.GetRestartPolicy() == "Never":
+			return fmt.Sprintf("exiting because task  %q should not be restarted, and it failed", node.Name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_scheduler(t *testing.T) {
+	t.Run("Never blocks", func(t *testing.T) {
+		s := newScheduler()
+		wg := sync.WaitGroup{}
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					s.send(poisonPill)
+					s.run("api")
+					s.send(stopTask("api"))
+				}
+			}()
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("sending blocked, though nothing is taking the events")
+		}
+		<-s.C()
+		assert.NotEmpty(t, s.take())
+	})
+	t.Run("Queued once, in the order first queued", func(t *testing.T) {
+		s := newScheduler()
+		s.run("build")
+		s.run("api")
+		s.send(poisonPill)
+		// e.g. a watched file changed, and its dependency finished
+		s.run("build")
+		s.send(poisonPill)
+		<-s.C()
+		assert.Equal(t, []any{"build", "api", poisonPill}, s.take())
+
+		s.run("build")
+		<-s.C()
+		assert.Equal(t, []any{"build"}, s.take(), "once taken, it can be queued again")
+		select {
+		case <-s.C():
+			t.Fatal("nothing is queued")
+		default:
+		}
+	})
+	t.Run("Run after stop", func(t *testing.T) {
+		s := newScheduler()
+		s.run("api")
+		s.send(stopTask("api"))
+		s.run("api")
+		assert.Equal(t, []any{"api", stopTask("api"), "api"}, s.take())
+	})
+}
+
+func Test_blockedBy(t *testing.T) {
+	dag := NewDAG[*TaskNode]("")
+	dag.AddNode("build", &TaskNode{Name: "build", PhaseMachine: types.PhaseMachine{Phase: "running"}})
+	dag.AddNode("db", &TaskNode{Name: "db", PhaseMachine: types.PhaseMachine{Phase: "running"}, Task: types.Task{Ports: types.Ports{{ContainerPort: 5432}}}})
+	dag.AddNode("api", &TaskNode{Name: "api", PhaseMachine: types.PhaseMachine{Phase: "pending"}})
+	dag.AddEdge("db", "api")
+	assert.Empty(t, blockedBy(dag, "api"), "a running service is ready")
+
+	dag.AddEdge("build", "api")
+	assert.Equal(t, `task "api" is blocked by "build" (running): `, blockedBy(dag, "api"), "a running job is not")
+
+	dag.AddNode("primary", &TaskNode{Name: "primary", PhaseMachine: types.PhaseMachine{Phase: "failed"}})
+	dag.AddNode("replica", &TaskNode{Name: "replica", PhaseMachine: types.PhaseMachine{Phase: "pending"}})
+	dag.AddNode("web", &TaskNode{Name: "web", Task: types.Task{Dependencies: &types.Dependencies{AnyOf: types.Strings{"primary", "replica"}}}})
+	dag.AddEdge("primary", "web")
+	dag.AddEdge("replica", "web")
+	assert.Equal(t, `task "web" is blocked by all of [primary replica]`, blockedBy(dag, "web"))
+	dag.Nodes["replica"].Phase = "succeeded"
+	assert.Empty(t, blockedBy(dag, "web"))
+}
+
+func Test_options_exitReason(t *testing.T) {
+	newDAG := func() DAG[*TaskNode] {
+		dag := NewDAG[*TaskNode]("")
+		dag.AddNode("build", &TaskNode{Name: "build", PhaseMachine: types.PhaseMachine{Phase: "succeeded"}})
+		dag.AddNode("test", &TaskNode{Name: "test", PhaseMachine: types.PhaseMachine{Phase: "failed"}, Task: types.Task{RestartPolicy: "OnFailure"}})
+		dag.AddNode("e2e", &TaskNode{Name: "e2e", PhaseMachine: types.PhaseMachine{Phase: "pending"}})
+		dag.AddEdge("test", "e2e")
+		return dag
+	}
+	dag := newDAG()
+	assert.Equal(t, "exiting because all requested tasks completed and none should be restarted", options{}.exitReason(dag, []string{"build"}))
+	assert.Empty(t, options{}.exitReason(dag, []string{"build", "e2e"}), "test is restarted")
+	assert.Equal(t, `exiting because job "test" failed, and failing fast`, options{failFast: true}.exitReason(dag, []string{"build", "e2e"}))
+
+	dag.Nodes["test"].Task.RestartPolicy = "Never"
+	assert.Equal(t, `exiting because task  "test" should not be restarted, and it failed`, options{}.exitReason(dag, []string{"build", "e2e"}))
+	assert.Equal(t, "exiting because all requested tasks completed, or cannot complete because a task failed", options{keepGoing: true}.exitReason(dag, []string{"build", "e2e"}))
+}
@@ -0,0 +1,48 @@
+package secrets
+
+import (
+	"strings"
+)
+
+// AWSSecretsManager returns an AWS Secrets Manager secret, by name or ARN, optionally a field of a JSON secret, e.g.
+// "dev/db#password". It uses the aws CLI, so it is configured by AWS_PROFILE, AWS_REGION, etc.
+func AWSSecretsManager(ref string) (string, error) {
+	return cached("aws:"+ref, func() (string, error) {
+		id, field := splitRef(ref)
+		value, err := output("aws", "secretsmanager", "get-secret-value", "--secret-id", id, "--query", "SecretString", "--output", "text")
+		if err != nil {
+			return "", err
+		}
+		return jsonField(value, field)
+	})
+}
+
+// GCPSecretManager returns a Google Cloud Secret Manager secret, either by name in the current project, or by resource
+// name (e.g. "projects/my-project/secrets/db/versions/3"), optionally a field of a JSON secret, e.g. "db#password". It
+// uses the gcloud CLI.
+func GCPSecretManager(ref string) (string, error) {
+	return cached("gcp:"+ref, func() (string, error) {
+		name, field := splitRef(ref)
+		value, err := output("gcloud", gcloudArgs(name)...)
+		if err != nil {
+			return "", err
+		}
+		return jsonField(value, field)
+	})
+}
+
+// gcloudArgs returns the arguments to access the secret, defaulting to the latest version.
+func gcloudArgs(name string) []string {
+	project, version := "", "latest"
+	if parts := strings.Split(name, "/"); len(parts) >= 4 && parts[0] == "projects" && parts[2] == "secrets" {
+		project, name = parts[1], parts[3]
+		if len(parts) == 6 && parts[4] == "versions" {
+			version = parts[5]
+		}
+	}
+	args := []string{"secrets", "versions", "access", version, "--secret", name}
+	if project != "" {
+		args = append(args, "--project", project)
+	}
+	return args
+}
@@ -0,0 +1,7 @@
+package secrets
+
+// keychain returns the password of the generic password item for the service in the macOS Keychain, e.g. one created
+// with `security add-generic-password -s my-api-key -a $USER -w`.
+func keychain(service string) (string, error) {
+	return output("security", "find-generic-password", "-s", service, "-w")
+}
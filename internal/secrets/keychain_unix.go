@@ -0,0 +1,9 @@
+//go:build !darwin && !windows
+
+package secrets
+
+// keychain returns the secret for the service using libsecret (e.g. GNOME Keyring or KWallet), e.g. one created with
+// `secret-tool store --label=my-api-key service my-api-key`.
+func keychain(service string) (string, error) {
+	return output("secret-tool", "lookup", "service", service)
+}
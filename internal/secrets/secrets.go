@@ -0,0 +1,81 @@
+// Package secrets reads secrets from outside the workflow, so they do not need to be committed to tasks.yaml or .env files.
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	mu sync.Mutex
+	// secrets are cached for the life of the process, so we only prompt or call out once
+	cache = map[string]string{}
+)
+
+// cached returns the cached secret, or reads and caches it. The lock is held while reading, so concurrent tasks do not
+// read the same secret twice.
+func cached(key string, read func() (string, error)) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if value, ok := cache[key]; ok {
+		return value, nil
+	}
+	value, err := read()
+	if err != nil {
+		return "", err
+	}
+	cache[key] = value
+	return value, nil
+}
+
+// Keychain returns the secret for the service from the OS keychain.
+func Keychain(service string) (string, error) {
+	return cached("keychain:"+service, func() (string, error) { return keychain(service) })
+}
+
+// splitRef splits a reference such as "secret/data/dev#api_key" into the name and the field.
+func splitRef(ref string) (string, string) {
+	name, field, _ := strings.Cut(ref, "#")
+	return name, field
+}
+
+// jsonField returns the field of a JSON object, or the value if the field is empty.
+func jsonField(value, field string) (string, error) {
+	if field == "" {
+		return value, nil
+	}
+	var x map[string]any
+	if err := json.Unmarshal([]byte(value), &x); err != nil {
+		return "", fmt.Errorf("failed to get field %q, secret is not a JSON object: %w", field, err)
+	}
+	return getField(x, field)
+}
+
+// getField returns the field as a string, fields that are not strings are returned as JSON.
+func getField(x map[string]any, field string) (string, error) {
+	v, ok := x[field]
+	if !ok {
+		return "", fmt.Errorf("secret does not have field %q", field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// output runs the command and returns its output, without the trailing new line.
+func output(name string, args ...string) (string, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
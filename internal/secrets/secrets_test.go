@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cached(t *testing.T) {
+	reads := 0
+	read := func() (string, error) {
+		reads++
+		return "bar", nil
+	}
+	for range 2 {
+		value, err := cached("test:foo", read)
+		assert.NoError(t, err)
+		assert.Equal(t, "bar", value)
+	}
+	assert.Equal(t, 1, reads)
+}
+
+func Test_jsonField(t *testing.T) {
+	t.Run("No field", func(t *testing.T) {
+		value, err := jsonField("secret", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", value)
+	})
+	t.Run("Field", func(t *testing.T) {
+		value, err := jsonField(`{"password": "secret", "port": 5432}`, "password")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", value)
+		value, err = jsonField(`{"password": "secret", "port": 5432}`, "port")
+		assert.NoError(t, err)
+		assert.Equal(t, "5432", value)
+	})
+	t.Run("Missing field", func(t *testing.T) {
+		_, err := jsonField(`{}`, "password")
+		assert.EqualError(t, err, `secret does not have field "password"`)
+	})
+	t.Run("Not JSON", func(t *testing.T) {
+		_, err := jsonField("secret", "password")
+		assert.Error(t, err)
+	})
+}
+
+func Test_vaultField(t *testing.T) {
+	t.Run("KV v1", func(t *testing.T) {
+		value, err := vaultField([]byte(`{"data": {"api_key": "secret"}}`), "api_key")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", value)
+	})
+	t.Run("KV v2", func(t *testing.T) {
+		value, err := vaultField([]byte(`{"data": {"data": {"api_key": "secret"}, "metadata": {}}}`), "api_key")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", value)
+	})
+}
+
+func Test_gcloudArgs(t *testing.T) {
+	assert.Equal(t, []string{"secrets", "versions", "access", "latest", "--secret", "db"}, gcloudArgs("db"))
+	assert.Equal(t, []string{"secrets", "versions", "access", "3", "--secret", "db", "--project", "p"}, gcloudArgs("projects/p/secrets/db/versions/3"))
+	assert.Equal(t, []string{"secrets", "versions", "access", "latest", "--secret", "db", "--project", "p"}, gcloudArgs("projects/p/secrets/db"))
+}
@@ -0,0 +1,38 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Vault returns a field of a HashiCorp Vault secret, e.g. "secret/data/dev#api_key". It uses the vault CLI, so it is
+// configured by VAULT_ADDR, VAULT_TOKEN, etc.
+func Vault(ref string) (string, error) {
+	return cached("vault:"+ref, func() (string, error) {
+		path, field := splitRef(ref)
+		if field == "" {
+			return "", fmt.Errorf("vault secret %q must have a field, e.g. %s#password", ref, ref)
+		}
+		out, err := output("vault", "read", "-format=json", path)
+		if err != nil {
+			return "", err
+		}
+		return vaultField([]byte(out), field)
+	})
+}
+
+// vaultField returns the field from the output of `vault read`. KV version 2 secrets have their fields nested in
+// data.data, while version 1 secrets have them in data.
+func vaultField(out []byte, field string) (string, error) {
+	var x struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(out, &x); err != nil {
+		return "", fmt.Errorf("failed to parse vault output: %w", err)
+	}
+	data := x.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	return getField(data, field)
+}
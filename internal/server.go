@@ -13,14 +13,18 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
+
+	"golang.org/x/net/websocket"
 )
 
 //go:embed index.html
 var indexHTML string
 
-func StartServer(ctx context.Context, port int, wg *sync.WaitGroup, dag DAG[*TaskNode], events chan *TaskNode) {
+func StartServer(ctx context.Context, logger *log.Logger, port int, wg *sync.WaitGroup, dag DAG[*TaskNode], events chan *TaskNode, trigger func(task string)) {
 
 	streams := &sync.Map{}
 
@@ -49,7 +53,9 @@ func StartServer(ctx context.Context, port int, wg *sync.WaitGroup, dag DAG[*Tas
 	})
 	mux.HandleFunc("/dag", func(w http.ResponseWriter, r *http.Request) {
 		// return the dag
+		dag.RLock()
 		marshal, err := json.Marshal(dag)
+		dag.RUnlock()
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -68,9 +74,11 @@ func StartServer(ctx context.Context, port int, wg *sync.WaitGroup, dag DAG[*Tas
 		stream := make(chan *TaskNode, 100)
 
 		// load the stream with the current state
+		dag.RLock()
 		for _, node := range dag.Nodes {
 			stream <- node
 		}
+		dag.RUnlock()
 		streams.Store(id, stream)
 		defer func() {
 			streams.Delete(id)
@@ -95,12 +103,19 @@ func StartServer(ctx context.Context, port int, wg *sync.WaitGroup, dag DAG[*Tas
 	mux.HandleFunc("/logs/{task}", func(w http.ResponseWriter, r *http.Request) {
 		//ctx := r.Context()
 		task := r.PathValue("task")
+		dag.RLock()
 		node, ok := dag.Nodes[task]
+		dag.RUnlock()
 		if !ok {
 			http.Error(w, "task not found", http.StatusNotFound)
 			return
 		}
-		file, err := os.Open(node.logFile)
+		path, err := node.streamLogFile(r.URL.Query().Get("stream"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, err := os.Open(path)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -138,6 +153,121 @@ func StartServer(ctx context.Context, port int, wg *sync.WaitGroup, dag DAG[*Tas
 		}
 	})
 
+	// the last lines of the task's output, from memory, e.g. "/logs/api/tail?lines=200"
+	mux.HandleFunc("/logs/{task}/tail", func(w http.ResponseWriter, r *http.Request) {
+		dag.RLock()
+		node, ok := dag.Nodes[r.PathValue("task")]
+		dag.RUnlock()
+		if !ok {
+			http.Error(w, "task not found", http.StatusNotFound)
+			return
+		}
+		n := 100
+		if lines := r.URL.Query().Get("lines"); lines != "" {
+			var err error
+			if n, err = strconv.Atoi(lines); err != nil || n < 0 {
+				http.Error(w, "lines must be a number", http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		for _, line := range node.recent.Tail(n) {
+			_, _ = fmt.Fprintln(w, line)
+		}
+	})
+
+	// stream the tasks' logs over a WebSocket, as JSON records, starting with the last lines of each
+	mux.HandleFunc("/ws/logs", func(w http.ResponseWriter, r *http.Request) {
+		files := map[string]string{}
+		stream := r.URL.Query().Get("stream")
+		dag.RLock()
+		tasks := r.URL.Query()["task"]
+		if len(tasks) == 0 {
+			for name := range dag.Nodes {
+				tasks = append(tasks, name)
+			}
+		}
+		var err error
+		for _, task := range tasks {
+			if node, ok := dag.Nodes[task]; ok && err == nil {
+				files[task], err = node.streamLogFile(stream)
+			}
+		}
+		dag.RUnlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(files) < len(tasks) {
+			http.Error(w, "task not found", http.StatusNotFound)
+			return
+		}
+		backfill := 100
+		if lines := r.URL.Query().Get("lines"); lines != "" {
+			var err error
+			if backfill, err = strconv.Atoi(lines); err != nil || backfill < 0 {
+				http.Error(w, "lines must be a number", http.StatusBadRequest)
+				return
+			}
+		}
+		websocket.Server{
+			// browsers allow any page to open a WebSocket
+			Handshake: func(config *websocket.Config, r *http.Request) error {
+				return checkOrigin(r)
+			},
+			Handler: func(ws *websocket.Conn) {
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				// we do not expect any messages, but we find out the client has gone when reading fails
+				go func() {
+					defer cancel()
+					_, _ = io.Copy(io.Discard, ws)
+				}()
+				mu := sync.Mutex{}
+				wg := sync.WaitGroup{}
+				for task, file := range files {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer cancel()
+						_ = tailLog(ctx, file, backfill, func(line string) error {
+							mu.Lock()
+							defer mu.Unlock()
+							return websocket.JSON.Send(ws, logRecord{Task: task, Stream: stream, Line: line})
+						})
+					}()
+				}
+				wg.Wait()
+			},
+		}.ServeHTTP(w, r)
+	})
+
+	// a POST to a task's webhook re-runs it
+	mux.HandleFunc("POST /hooks/", func(w http.ResponseWriter, r *http.Request) {
+		// browsers allow any page to POST a form to us
+		if err := checkOrigin(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		var names []string
+		dag.RLock()
+		for name, node := range dag.Nodes {
+			if t := node.Task.Trigger; t != nil && t.Webhook == r.URL.Path {
+				names = append(names, name)
+			}
+		}
+		dag.RUnlock()
+		if len(names) == 0 {
+			http.Error(w, "no task has this webhook", http.StatusNotFound)
+			return
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			trigger(name)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
 	server := &http.Server{
 		// only allow local connections
 		Addr:    fmt.Sprintf("localhost:%d", port),
@@ -151,11 +281,11 @@ func StartServer(ctx context.Context, port int, wg *sync.WaitGroup, dag DAG[*Tas
 		defer wg.Done()
 		<-ctx.Done()
 		if err := server.Shutdown(ctx); err != nil {
-			log.Println(err)
+			logger.Println(err)
 		}
 	}()
 
-	log.Printf("UI available on http://%s", server.Addr)
+	logger.Printf("UI available on http://%s", server.Addr)
 
 	wg.Add(1)
 	err := server.ListenAndServe()
@@ -163,3 +293,22 @@ func StartServer(ctx context.Context, port int, wg *sync.WaitGroup, dag DAG[*Tas
 		panic(err)
 	}
 }
+
+// checkOrigin returns an error if the request was made by a page not served by us. Clients that are not browsers, e.g.
+// curl or a git hook, do not send an origin, so they are allowed. The host must be local, otherwise a page could
+// re-bind its own DNS name to us, and then its origin would match the host.
+func checkOrigin(r *http.Request) error {
+	hostname, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		hostname = r.Host
+	}
+	switch hostname {
+	case "localhost", "127.0.0.1", "::1":
+	default:
+		return fmt.Errorf("host %q not allowed", r.Host)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	return nil
+}
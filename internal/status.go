@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/types"
+	"sigs.k8s.io/yaml"
+)
+
+// TaskStatus is the machine-readable status of a task.
+type TaskStatus struct {
+	Phase types.Phase `json:"phase"`
+	// the code the task exited with, if it has finished, and it was not zero
+	ExitCode int         `json:"exitCode,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Usage    *proc.Usage `json:"usage,omitempty"`
+}
+
+// WriteStatus writes the status of the tasks, as "json" (a single line), "yaml" (a document), or otherwise a table.
+func WriteStatus(w io.Writer, nodes map[string]*TaskNode, output string) error {
+	statuses := map[string]TaskStatus{}
+	for name, node := range nodes {
+		s := node.Snapshot()
+		status := TaskStatus{Phase: s.Phase, Message: s.Message, Usage: node.usage.Load()}
+		// a running task may have exited with a code before it was restarted
+		if s.Phase == "succeeded" || s.Phase == "failed" {
+			status.ExitCode = max(node.exitCode, 0)
+		}
+		statuses[name] = status
+	}
+	switch output {
+	case "json":
+		return json.NewEncoder(w).Encode(statuses)
+	case "yaml":
+		data, err := yaml.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", data)
+		return err
+	default:
+		var names []string
+		for name := range statuses {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "TASK\tPHASE\tEXIT\tUSAGE\tMESSAGE")
+		for _, name := range names {
+			status := statuses[name]
+			exitCode := ""
+			if status.ExitCode != 0 {
+				exitCode = strconv.Itoa(status.ExitCode)
+			}
+			usage := ""
+			if status.Usage != nil {
+				usage = status.Usage.String()
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", name, status.Phase, exitCode, usage, status.Message)
+		}
+		return tw.Flush()
+	}
+}
+
+// emitStatus writes the status of the tasks every second, if it has changed, until the context is cancelled, when it
+// writes the final status.
+func emitStatus(ctx context.Context, w io.Writer, dag DAG[*TaskNode], output string) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var last []byte
+	emit := func() {
+		buf := &bytes.Buffer{}
+		dag.RLock()
+		err := WriteStatus(buf, dag.Nodes, output)
+		dag.RUnlock()
+		if err == nil && !bytes.Equal(buf.Bytes(), last) {
+			_, _ = w.Write(buf.Bytes())
+			last = buf.Bytes()
+		}
+	}
+	for {
+		emit()
+		select {
+		case <-ctx.Done():
+			emit()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStatus(t *testing.T) {
+	nodes := map[string]*TaskNode{
+		"api":   {PhaseMachine: types.PhaseMachine{Phase: "running", Message: "readiness probe succeeded"}},
+		"build": {PhaseMachine: types.PhaseMachine{Phase: "succeeded"}},
+		"lint":  {PhaseMachine: types.PhaseMachine{Phase: "failed", Message: "exit status 2"}, exitCode: 2},
+	}
+	nodes["api"].usage.Store(&proc.Usage{CPU: 12, Memory: 150 << 20})
+	t.Run("JSON", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, WriteStatus(buf, nodes, "json"))
+		assert.Equal(t, `{"api":{"phase":"running","message":"readiness probe succeeded","usage":{"cpu":12,"memory":157286400}},"build":{"phase":"succeeded"},"lint":{"phase":"failed","exitCode":2,"message":"exit status 2"}}`+"\n", buf.String())
+	})
+	t.Run("YAML", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, WriteStatus(buf, nodes, "yaml"))
+		assert.Equal(t, `---
+api:
+  message: readiness probe succeeded
+  phase: running
+  usage:
+    cpu: 12
+    memory: 157286400
+build:
+  phase: succeeded
+lint:
+  exitCode: 2
+  message: exit status 2
+  phase: failed
+`, buf.String())
+	})
+	t.Run("Table", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, WriteStatus(buf, nodes, ""))
+		assert.Equal(t, `TASK   PHASE      EXIT  USAGE       MESSAGE
+api    running          12% 150MiB  readiness probe succeeded
+build  succeeded                    
+lint   failed     2                 exit status 2
+`, buf.String())
+	})
+}
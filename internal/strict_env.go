@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// checkEnv returns an error listing every required environment variable that is unset, and in strict mode, every
+// variable referenced by the tasks that would be unset when they run.
+func checkEnv(wf *types.Workflow, taskNames []string) error {
+	if len(wf.RequiredEnv) == 0 && !wf.StrictEnv {
+		return nil
+	}
+	spec := types.Spec(*wf)
+	environ, err := spec.Environ()
+	if err != nil {
+		return err
+	}
+	defined := envNames(append(environ, os.Environ()...))
+	var missing []string
+	for _, name := range wf.RequiredEnv {
+		if !defined[name] {
+			missing = append(missing, name)
+		}
+	}
+	if wf.StrictEnv {
+		// the tasks that reference each unset variable
+		unset := map[string][]string{}
+		sort.Strings(taskNames)
+		for _, taskName := range taskNames {
+			t := wf.Tasks[taskName]
+			environ, err := types.Environ(spec, t)
+			if err != nil {
+				return fmt.Errorf("task %q: %w", taskName, err)
+			}
+			environ = append(environ, types.HostEnviron(t)...)
+			defined := envNames(environ)
+			for _, dependency := range t.GetDependencies() {
+				for _, o := range wf.Tasks[dependency].Outputs {
+					defined[o.Name] = true
+				}
+			}
+			defined["KIT_EXIT_CODE"] = true
+			for _, name := range t.GetEnvReferences() {
+				if !defined[name] {
+					unset[name] = append(unset[name], taskName)
+				}
+			}
+		}
+		var names []string
+		for name := range unset {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			missing = append(missing, fmt.Sprintf("%s (used by %s)", name, strings.Join(unset[name], ", ")))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func envNames(environ []string) map[string]bool {
+	names := map[string]bool{}
+	for _, e := range environ {
+		name, _, _ := strings.Cut(e, "=")
+		names[name] = true
+	}
+	return names
+}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkEnv(t *testing.T) {
+	t.Setenv("HOST_VAR", "1")
+	t.Run("Not strict", func(t *testing.T) {
+		wf := &types.Workflow{Tasks: types.Tasks{"job": {Sh: "echo ${MISSING}"}}}
+		assert.NoError(t, checkEnv(wf, []string{"job"}))
+	})
+	t.Run("Required", func(t *testing.T) {
+		wf := &types.Workflow{
+			RequiredEnv: types.Strings{"HOST_VAR", "WF_VAR", "MISSING"},
+			Env:         types.EnvVars{"WF_VAR": {Value: "1"}},
+		}
+		assert.EqualError(t, checkEnv(wf, nil), "missing environment variables: MISSING")
+	})
+	t.Run("Strict", func(t *testing.T) {
+		wf := &types.Workflow{
+			StrictEnv: true,
+			Tasks: types.Tasks{
+				"db":  {Outputs: []types.Output{{Name: "DB_PORT"}}},
+				"api": {Sh: "echo ${HOST_VAR} ${DB_PORT} ${TASK_VAR} ${PORT:-8080} ${MISSING}", Env: types.EnvVars{"TASK_VAR": {Value: "1"}}, Dependencies: &types.Dependencies{AllOf: types.Strings{"db"}}},
+				"web": {Image: "nginx", Command: types.Strings{"sh", "-c", "echo ${HOST_VAR} ${MISSING}"}},
+				"job": {Sh: "echo ${HOST_VAR}", CleanEnv: true},
+			},
+		}
+		assert.EqualError(t, checkEnv(wf, []string{"api", "db", "job", "web"}), "missing environment variables: HOST_VAR (used by job, web), MISSING (used by api, web)")
+	})
+}
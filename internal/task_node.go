@@ -1,8 +1,15 @@
 package internal
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/ring"
 	"github.com/kitproj/kit/internal/types"
 )
 
@@ -11,18 +18,48 @@ type TaskNode struct {
 	Task types.Task `json:"task"`
 	// logFile is the log file path
 	logFile string
-	// the phase of the task, e.g. "pending", "waiting", "running", "stalled", "succeeded", "failed", "cancelled", "skipped"
-	Phase string `json:"phase"`
-	// the message for the task phase, e.g. "exit code 1'
-	Message string `json:"message,omitempty"`
+	// stderrLogFile is the path of the log file of only the task's stderr
+	stderrLogFile string
+	// the last lines of the task's output, so they can be shown without reading the log file
+	recent *ring.Ring
+	types.PhaseMachine
+	// the CPU and memory usage of the task's processes, if it is running
+	usage atomic.Pointer[proc.Usage]
+	// the number of times the task has been restarted
+	restarts atomic.Int32
+	// the exit code of the task's last run, or -1 if it failed without exiting, e.g. its probe failed
+	exitCode int
+	// the number of times the task has been retried since it last succeeded, because it is flaky
+	retries int
+	// restored is true if the task's success was restored from the last run of kit, rather than it being run
+	restored bool
+	// when the last run was queued (i.e. its dependencies were ready), started, finished, and was ready for the tasks
+	// that depend on it
+	queuedAt, startedAt, finishedAt, readyAt time.Time
+	// failing is true if the task failed, and has not yet recovered
+	failing atomic.Bool
+	// captures the task's outputs
+	outputs *outputCapture
+	// the digest of the task's artifacts when its children were last queued
+	digest string
+	// reset whenever the task writes output, so we know when it has stalled
+	stallTimer *time.Timer
+	// changed is true if the task is being re-run because a watched file changed, so its onChange commands must run first
+	changed atomic.Bool
+	// next is the workflow the next run of the task uses, set while the run waits for the last one to stop
+	next atomic.Pointer[types.Workflow]
+	// the files to copy into the running task, received while it is running
+	syncs chan []string
+	// the watched files that changed, received while a task that is restarted without downtime is running
+	rolls chan string
 	// cancel function
 	cancel func()
 	// a mutex
 	mu *sync.Mutex
 }
 
-func (n TaskNode) blocked() bool {
-	switch n.Phase {
+func (n *TaskNode) blocked() bool {
+	switch n.Snapshot().Phase {
 	case "running", "stalled":
 		return n.Task.GetType() == types.TaskTypeJob
 	case "succeeded", "skipped":
@@ -31,3 +68,80 @@ func (n TaskNode) blocked() bool {
 		return true
 	}
 }
+
+// streamLogFile returns the log file of the stream, i.e. the whole log if it is empty, or only stderr.
+func (n *TaskNode) streamLogFile(stream string) (string, error) {
+	switch stream {
+	case "":
+		return n.logFile, nil
+	case "stderr":
+		return n.stderrLogFile, nil
+	default:
+		return "", fmt.Errorf("stream %q must be empty or \"stderr\"", stream)
+	}
+}
+
+// byPriority returns the names of the tasks sorted by priority, highest first, and then by name.
+func byPriority(nodes map[string]*TaskNode, names []string) []string {
+	sorted := append([]string{}, names...)
+	priority := func(name string) int {
+		if node, ok := nodes[name]; ok {
+			return node.Task.Priority
+		}
+		return 0
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if pi, pj := priority(sorted[i]), priority(sorted[j]); pi != pj {
+			return pi > pj
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// MarshalJSON encodes the task with its phase and usage as they are now, as they may be being changed.
+func (n *TaskNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name string     `json:"name"`
+		Task types.Task `json:"task"`
+		types.PhaseSnapshot
+		Usage    *proc.Usage `json:"usage,omitempty"`
+		Restarts int         `json:"restarts,omitempty"`
+	}{n.Name, n.Task, n.Snapshot(), n.usage.Load(), int(n.restarts.Load())})
+}
+
+// anyReady returns true if any of the named tasks, other than except, is ready for the tasks that depend on it.
+func anyReady(nodes map[string]*TaskNode, names []string, except string) bool {
+	for _, name := range names {
+		if node, ok := nodes[name]; ok && name != except && !node.blocked() {
+			return true
+		}
+	}
+	return false
+}
+
+// queueRun sets the workflow the task's next run uses. It returns false if a run is already waiting for the last one to
+// stop, as that run uses the workflow, rather than another run being started, so a burst of events, e.g. files changing
+// while the last run stops, starts one more run, with the latest changes.
+func (n *TaskNode) queueRun(wf *types.Workflow) bool {
+	return n.next.Swap(wf) == nil
+}
+
+// startRun returns the workflow the run uses, once it has the task's lock. A run queued after this starts another.
+func (n *TaskNode) startRun() *types.Workflow {
+	return n.next.Swap(nil)
+}
+
+// artifactsChanged returns true if the task's artifacts have changed since it was last called, or if it has no artifacts.
+func (n *TaskNode) artifactsChanged() bool {
+	if len(n.Task.Artifacts) == 0 {
+		return true
+	}
+	digest, err := artifactsDigest(n.Task)
+	if err != nil {
+		return true
+	}
+	changed := digest != n.digest
+	n.digest = digest
+	return changed
+}
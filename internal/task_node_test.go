@@ -10,48 +10,78 @@ import (
 func Test_taskNode_blocked(t *testing.T) {
 	service := types.Task{Ports: []types.Port{{}}}
 	t.Run("service running", func(t *testing.T) {
-		n := TaskNode{Phase: "running", Task: service}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "running"}, Task: service}
 		assert.False(t, n.blocked())
 	})
 	t.Run("service waiting", func(t *testing.T) {
-		n := TaskNode{Phase: "waiting", Task: service}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "waiting"}, Task: service}
 		assert.True(t, n.blocked())
 	})
 	t.Run("service starting", func(t *testing.T) {
-		n := TaskNode{Phase: "starting", Task: service}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "starting"}, Task: service}
 		assert.True(t, n.blocked())
 	})
 	t.Run("service succeeded", func(t *testing.T) {
-		n := TaskNode{Phase: "succeeded", Task: service}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "succeeded"}, Task: service}
 		assert.False(t, n.blocked())
 	})
 	t.Run("service cancelled", func(t *testing.T) {
-		n := TaskNode{Phase: "cancelled", Task: service}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "cancelled"}, Task: service}
 		assert.True(t, n.blocked())
 	})
 	t.Run("service failed", func(t *testing.T) {
-		n := TaskNode{Phase: "failed", Task: service}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "failed"}, Task: service}
 		assert.True(t, n.blocked())
 	})
 	task := types.Task{}
 	t.Run("task running", func(t *testing.T) {
-		n := TaskNode{Phase: "running", Task: task}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "running"}, Task: task}
 		assert.True(t, n.blocked())
 	})
 	t.Run("task waiting", func(t *testing.T) {
-		n := TaskNode{Phase: "waiting", Task: task}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "waiting"}, Task: task}
 		assert.True(t, n.blocked())
 	})
 	t.Run("task starting", func(t *testing.T) {
-		n := TaskNode{Phase: "starting", Task: task}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "starting"}, Task: task}
 		assert.True(t, n.blocked())
 	})
 	t.Run("task succeeded", func(t *testing.T) {
-		n := TaskNode{Phase: "succeeded", Task: task}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "succeeded"}, Task: task}
 		assert.False(t, n.blocked())
 	})
 	t.Run("task failed", func(t *testing.T) {
-		n := TaskNode{Phase: "failed", Task: task}
+		n := TaskNode{PhaseMachine: types.PhaseMachine{Phase: "failed"}, Task: task}
 		assert.True(t, n.blocked())
 	})
 }
+
+func Test_anyReady(t *testing.T) {
+	nodes := map[string]*TaskNode{
+		"succeeded": {PhaseMachine: types.PhaseMachine{Phase: "succeeded"}},
+		"failed":    {PhaseMachine: types.PhaseMachine{Phase: "failed"}},
+	}
+	assert.True(t, anyReady(nodes, []string{"failed", "succeeded"}, ""))
+	assert.False(t, anyReady(nodes, []string{"failed", "succeeded"}, "succeeded"))
+	assert.False(t, anyReady(nodes, []string{"failed", "missing"}, ""))
+}
+
+func Test_byPriority(t *testing.T) {
+	nodes := map[string]*TaskNode{
+		"a": {Task: types.Task{}},
+		"b": {Task: types.Task{Priority: 10}},
+		"c": {Task: types.Task{Priority: -1}},
+		"d": {Task: types.Task{}},
+	}
+	assert.Equal(t, []string{"b", "a", "d", "c"}, byPriority(nodes, []string{"d", "c", "b", "a"}))
+}
+
+func Test_taskNode_queueRun(t *testing.T) {
+	node := &TaskNode{}
+	v1, v2, v3 := &types.Workflow{}, &types.Workflow{}, &types.Workflow{}
+	assert.True(t, node.queueRun(v1))
+	// e.g. a watched file changed, and its dependency finished, while the last run stops
+	assert.False(t, node.queueRun(v2))
+	assert.Same(t, v2, node.startRun(), "the run uses the latest workflow")
+	assert.True(t, node.queueRun(v3), "once started, another run can be queued")
+}
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// taskState is what is remembered about a task's last run, so the next run of kit can resume from it.
+type taskState struct {
+	// the outcome of the last run, "succeeded" or "failed"
+	Phase types.Phase `json:"phase"`
+	// the hash of the task, and its sources and targets, when it was last run
+	Hash string `json:"hash"`
+	// the hash of what a once job applied, when it last succeeded
+	Applied string `json:"applied,omitempty"`
+	// the number of times the task was restarted
+	Restarts   int       `json:"restarts,omitempty"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// the outcomes of the last runs, oldest first, across runs of kit
+	History []types.Phase `json:"history,omitempty"`
+}
+
+// the number of outcomes kept in a task's history
+const historySize = 10
+
+// taskStates are the states of a workflow's tasks, saved in the state directory. A nil task states does nothing.
+type taskStates struct {
+	mu   sync.Mutex
+	file string
+	// the states saved by the last run of kit, each is only used the first time its task is run
+	last map[string]taskState
+	// the states of this run
+	states map[string]taskState
+}
+
+// loadTaskStates loads the states saved by the last run. A missing or corrupt file is ignored, as the tasks will
+// just be run again.
+func loadTaskStates(file string) *taskStates {
+	s := &taskStates{file: file, last: map[string]taskState{}, states: map[string]taskState{}}
+	if data, err := os.ReadFile(file); err == nil {
+		_ = json.Unmarshal(data, &s.last)
+	}
+	for name, state := range s.last {
+		s.states[name] = state
+	}
+	return s
+}
+
+// take returns the state saved by the last run of kit, if the task has not been run yet.
+func (s *taskStates) take(name string) (taskState, bool) {
+	if s == nil {
+		return taskState{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.last[name]
+	delete(s.last, name)
+	return state, ok
+}
+
+// save records the task's state, adding its phase to its history, and writes all the states to the file.
+func (s *taskStates) save(name string, state taskState) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := append(append([]types.Phase{}, s.states[name].History...), state.Phase)
+	state.History = history[max(len(history)-historySize, 0):]
+	s.states[name] = state
+	if err := writeJSON(s.file, s.states); err != nil {
+		return fmt.Errorf("failed to save task states: %w", err)
+	}
+	return nil
+}
+
+// failures returns how many of the task's last runs failed, and how many runs there were.
+func (s *taskStates) failures(name string) (int, int) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.states[name].History
+	failed := 0
+	for _, phase := range history {
+		if phase == "failed" {
+			failed++
+		}
+	}
+	return failed, len(history)
+}
+
+// applied returns the hash of what the once job last applied successfully, in this run of kit or an earlier one.
+func (s *taskStates) applied(name string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[name].Applied
+}
+
+// flaky returns true if the task has both failed and succeeded in its last runs.
+func (s *taskStates) flaky(name string) bool {
+	failed, runs := s.failures(name)
+	return failed > 0 && failed < runs
+}
+
+// writeJSON writes the value to the file as JSON. It writes to a temporary file, and renames it, so the file is never
+// half written.
+func writeJSON(file string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// taskHash returns a hash of the task, and of the names, sizes and modification times of its sources and targets, so
+// we know if anything that affects it has changed since it was last run.
+func taskHash(t types.Task) (string, error) {
+	sources, err := sourcesHash(t)
+	if err != nil {
+		return "", err
+	}
+	return targetsHash(t, sources)
+}
+
+// sourcesHash returns a hash of the task and its sources. It is taken before the task runs, so a change while it runs
+// means it runs again next time.
+func sourcesHash(t types.Task) (string, error) {
+	h := sha256.New()
+	spec, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	h.Write(spec)
+	if err := hashFiles(h, t.WorkingDir, t.Watch); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// targetsHash returns the hash of the sources, and of the task's targets. It is taken after the task runs, as the run
+// changes the targets.
+func targetsHash(t types.Task, sources string) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(sources))
+	if err := hashFiles(h, t.WorkingDir, t.Targets); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFiles writes the names, sizes and modification times of the files in the paths to the hash.
+func hashFiles(h io.Writer, dir string, paths []string) error {
+	for _, path := range paths {
+		root := filepath.Join(dir, path)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				_, _ = fmt.Fprintf(h, "%s\x00missing\x00", path)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00%d\x00%d\x00", path, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// onceHash returns a hash of the task, and of the names and contents of the files it applies once. Unlike taskHash, the
+// contents are hashed, as what was applied, e.g. to a database, has not changed just because a file was checked out
+// again.
+func onceHash(t types.Task) (string, error) {
+	h := sha256.New()
+	spec, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	h.Write(spec)
+	for _, once := range t.Once {
+		root := filepath.Join(t.WorkingDir, once)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00%s\x00%d\x00", once, filepath.ToSlash(rel), len(data))
+			h.Write(data)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// resumable returns true if the task can resume from the last run of kit. Only jobs with sources or targets can, as
+// otherwise we cannot know if anything has changed, and only if their parents were skipped or resumed too, as otherwise
+// their inputs may have changed.
+func resumable(dag DAG[*TaskNode], node *TaskNode) bool {
+	t := node.Task
+	if t.GetType() != types.TaskTypeJob || len(t.Watch) == 0 && len(t.Targets) == 0 {
+		return false
+	}
+	dag.RLock()
+	defer dag.RUnlock()
+	for _, parent := range dag.Parents[node.Name] {
+		if p, ok := dag.Nodes[parent]; !ok || !p.restored && p.Snapshot().Phase != "skipped" {
+			return false
+		}
+	}
+	return true
+}
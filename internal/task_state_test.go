@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_taskStates(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	states := loadTaskStates(file)
+	_, ok := states.take("build")
+	assert.False(t, ok)
+
+	state := taskState{Phase: "succeeded", Hash: "abc", Restarts: 1, FinishedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	assert.NoError(t, states.save("build", state))
+
+	states = loadTaskStates(file)
+	got, ok := states.take("build")
+	assert.True(t, ok)
+	state.History = []types.Phase{"succeeded"}
+	assert.Equal(t, state, got)
+	// the last state is only used the first time the task is run
+	_, ok = states.take("build")
+	assert.False(t, ok)
+
+	t.Run("History", func(t *testing.T) {
+		states := loadTaskStates(filepath.Join(t.TempDir(), "state.json"))
+		assert.False(t, states.flaky("test"))
+		for i := 0; i < 12; i++ {
+			assert.NoError(t, states.save("test", taskState{Phase: "failed"}))
+		}
+		failed, runs := states.failures("test")
+		assert.Equal(t, 10, failed)
+		assert.Equal(t, 10, runs, "only the last runs are kept")
+		assert.False(t, states.flaky("test"), "always fails")
+		assert.NoError(t, states.save("test", taskState{Phase: "succeeded"}))
+		failed, _ = states.failures("test")
+		assert.Equal(t, 9, failed)
+		assert.True(t, states.flaky("test"))
+	})
+	t.Run("Corrupt", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(file, []byte("{"), 0o644))
+		_, ok := loadTaskStates(file).take("build")
+		assert.False(t, ok)
+	})
+}
+
+func Test_taskHash(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "main.go")
+	assert.NoError(t, os.WriteFile(source, []byte("package main"), 0o644))
+	task := types.Task{WorkingDir: dir, Command: []string{"go", "build"}, Watch: []string{"."}, Targets: []string{"app"}}
+
+	hash, err := taskHash(task)
+	assert.NoError(t, err)
+	again, err := taskHash(task)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, again)
+
+	t.Run("Source changed", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(source, []byte("package main // changed"), 0o644))
+		changed, err := taskHash(task)
+		assert.NoError(t, err)
+		assert.NotEqual(t, hash, changed)
+	})
+	t.Run("Task changed", func(t *testing.T) {
+		before, _ := taskHash(task)
+		task.Command = []string{"go", "build", "-race"}
+		changed, err := taskHash(task)
+		assert.NoError(t, err)
+		assert.NotEqual(t, before, changed)
+	})
+}
+
+func Test_onceHash(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "migrations"), 0o755))
+	migration := filepath.Join(dir, "migrations", "001.sql")
+	assert.NoError(t, os.WriteFile(migration, []byte("create table users"), 0o644))
+	task := types.Task{WorkingDir: dir, Sh: "migrate up", Once: []string{"migrations"}}
+
+	hash, err := onceHash(task)
+	assert.NoError(t, err)
+
+	t.Run("Touched", func(t *testing.T) {
+		assert.NoError(t, os.Chtimes(migration, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+		touched, err := onceHash(task)
+		assert.NoError(t, err)
+		assert.Equal(t, hash, touched)
+	})
+	t.Run("New migration", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "migrations", "002.sql"), []byte("create table orders"), 0o644))
+		changed, err := onceHash(task)
+		assert.NoError(t, err)
+		assert.NotEqual(t, hash, changed)
+	})
+	t.Run("Missing", func(t *testing.T) {
+		_, err := onceHash(types.Task{WorkingDir: dir, Once: []string{"fixtures.sql"}})
+		assert.Error(t, err)
+	})
+}
+
+func Test_resumable(t *testing.T) {
+	dag := NewDAG[*TaskNode]("test")
+	dag.AddNode("build", &TaskNode{Name: "build", Task: types.Task{Watch: []string{"src"}}, restored: true})
+	dag.AddNode("test", &TaskNode{Name: "test", Task: types.Task{Watch: []string{"src"}}})
+	dag.AddNode("api", &TaskNode{Name: "api", Task: types.Task{Watch: []string{"src"}, Ports: []types.Port{{}}}, PhaseMachine: types.PhaseMachine{Phase: "running"}})
+	dag.AddNode("migrate", &TaskNode{Name: "migrate", Task: types.Task{Watch: []string{"db"}}})
+	dag.AddNode("lint", &TaskNode{Name: "lint"})
+	dag.AddEdge("build", "test")
+	dag.AddEdge("api", "migrate")
+
+	assert.True(t, resumable(dag, dag.Nodes["build"]), "no parents")
+	assert.True(t, resumable(dag, dag.Nodes["test"]), "parent restored")
+	assert.False(t, resumable(dag, dag.Nodes["api"]), "service")
+	assert.False(t, resumable(dag, dag.Nodes["migrate"]), "parent is running")
+	assert.False(t, resumable(dag, dag.Nodes["lint"]), "no sources or targets")
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// terminalWidth returns the number of columns of the terminal the file is, or zero if it is not a terminal (e.g. the
+// output is piped to a file), or its size is not available.
+func terminalWidth(f *os.File) int {
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return max(width, 0)
+}
+
+// fit shortens the line, ending it with an ellipsis, so it fits on one row of a terminal of the width, rather than
+// wrapping onto the next. Escape sequences, e.g. colors, take no space and are kept. If the width is zero, i.e. not
+// known, the line is returned as it is.
+func fit(line string, width int) string {
+	escapes := escapeSequence.FindAllStringIndex(line, -1)
+	visible := utf8.RuneCountInString(line)
+	for _, e := range escapes {
+		visible -= utf8.RuneCountInString(line[e[0]:e[1]])
+	}
+	if width <= 0 || visible <= width {
+		return line
+	}
+	out := &strings.Builder{}
+	// the columns left, one is kept for the ellipsis
+	left := width - 1
+	for i := 0; i < len(line); {
+		if len(escapes) > 0 && escapes[0][0] == i {
+			out.WriteString(line[i:escapes[0][1]])
+			i = escapes[0][1]
+			escapes = escapes[1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case left > 0:
+			out.WriteRune(r)
+		case left == 0:
+			out.WriteString("…")
+		}
+		left--
+		i += size
+	}
+	return out.String()
+}
+
+// fitWriter fits each line written to the width of the terminal, as it is when the line is written.
+type fitWriter struct {
+	w       io.Writer
+	width   func() int
+	partial []byte
+}
+
+// FitToTerminal returns a writer that shortens each line to fit on one row of the terminal the file is, or the file, if
+// it is not a terminal, so that output to a file or pipe is not changed.
+func FitToTerminal(f *os.File) io.Writer {
+	if terminalWidth(f) == 0 {
+		return f
+	}
+	return &fitWriter{w: f, width: func() int { return terminalWidth(f) }}
+}
+
+func (f *fitWriter) Write(p []byte) (int, error) {
+	f.partial = append(f.partial, p...)
+	for {
+		i := bytes.IndexByte(f.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := io.WriteString(f.w, fit(string(f.partial[:i]), f.width())+"\n"); err != nil {
+			return 0, err
+		}
+		f.partial = f.partial[i+1:]
+	}
+	return len(p), nil
+}
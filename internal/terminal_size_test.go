@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_fit(t *testing.T) {
+	assert.Equal(t, "[api] (running) listening on :8080", fit("[api] (running) listening on :8080", 0), "width not known")
+	assert.Equal(t, "[api] (running)", fit("[api] (running)", 15))
+	assert.Equal(t, "[api] (runnin…", fit("[api] (running) listening on :8080", 14))
+	assert.Equal(t, "\x1b[31m[api] (fa…\x1b[0m", fit("\x1b[31m[api] (failed) exit code 1\x1b[0m", 10), "colors take no space, and are reset")
+	assert.Equal(t, "[db] ✔✔…", fit("[db] ✔✔✔✔", 8))
+}
+
+func Test_fitWriter(t *testing.T) {
+	buf := &strings.Builder{}
+	w := &fitWriter{w: buf, width: func() int { return 6 }}
+	_, _ = w.Write([]byte("TASK  PHASE\napi"))
+	_, _ = w.Write([]byte("  running\n"))
+	assert.Equal(t, "TASK …\napi  …\n", buf.String())
+}
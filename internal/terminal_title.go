@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// the phases shown in the terminal title, most important first
+var titlePhases = []types.Phase{"failed", "stalled", "running", "starting", "waiting", "succeeded"}
+
+// terminalStatus returns the terminal title for the tasks, e.g. "kit: 7 running, 1 failed", the percentage of them that
+// are done (i.e. jobs that have exited, and services that are running), and the tasks that have failed.
+func terminalStatus(nodes map[string]*TaskNode) (string, int, []string) {
+	counts := map[types.Phase]int{}
+	done := 0
+	var failed []string
+	for name, node := range nodes {
+		phase := node.Snapshot().Phase
+		counts[phase]++
+		switch phase {
+		case "succeeded", "skipped", "cancelled":
+			done++
+		case "failed":
+			done++
+			failed = append(failed, name)
+		case "running":
+			if node.Task.GetType() == types.TaskTypeService {
+				done++
+			}
+		}
+	}
+	sort.Strings(failed)
+	var parts []string
+	for _, phase := range titlePhases {
+		if n := counts[phase]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, phase))
+		}
+	}
+	title := "kit"
+	if len(parts) > 0 {
+		title += ": " + strings.Join(parts, ", ")
+	}
+	progress := 100
+	if len(nodes) > 0 {
+		progress = done * 100 / len(nodes)
+	}
+	return title, progress, failed
+}
+
+// oscProgress returns true if the terminal shows OSC 9;4 progress, e.g. in its tab. Other terminals show OSC 9 as a
+// notification, so it is only used when we know it is supported.
+func oscProgress() bool {
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("ConEmuANSI") == "ON" || os.Getenv("TERM_PROGRAM") == "ghostty"
+}
+
+// oscNotify returns true if the terminal shows OSC 777 notifications.
+func oscNotify() bool {
+	program, term := os.Getenv("TERM_PROGRAM"), os.Getenv("TERM")
+	return program == "ghostty" || program == "WezTerm" || strings.HasPrefix(term, "rxvt") || term == "foot"
+}
+
+// emitTerminalStatus sets the terminal's title to the status of the tasks every second, if it has changed, until the
+// context is cancelled, when the title is restored. Where supported, it also shows the progress of the tasks, and a
+// notification when a task fails, so a kit tab in the background signals failures.
+func emitTerminalStatus(ctx context.Context, w io.Writer, dag DAG[*TaskNode]) {
+	progress, notify := oscProgress(), oscNotify()
+	// save the title, so it can be restored
+	_, _ = io.WriteString(w, "\x1b[22;0t")
+	defer func() {
+		out := "\x1b[23;0t"
+		if progress {
+			out += "\x1b]9;4;0;0\x07"
+		}
+		_, _ = io.WriteString(w, out)
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastTitle, lastProgress := "", ""
+	notified := map[string]bool{}
+	for {
+		dag.RLock()
+		title, percent, failed := terminalStatus(dag.Nodes)
+		dag.RUnlock()
+		out := ""
+		if title != lastTitle {
+			out += fmt.Sprintf("\x1b]0;%s\x07", title)
+			lastTitle = title
+		}
+		if progress {
+			// 1 is normal, 2 is error, and 0 removes the progress once everything is up
+			state := 1
+			if len(failed) > 0 {
+				state = 2
+			} else if percent == 100 {
+				state = 0
+			}
+			if seq := fmt.Sprintf("\x1b]9;4;%d;%d\x07", state, percent); seq != lastProgress {
+				out += seq
+				lastProgress = seq
+			}
+		}
+		failing := map[string]bool{}
+		for _, name := range failed {
+			failing[name] = true
+			if notify && !notified[name] {
+				out += fmt.Sprintf("\x1b]777;notify;kit;%s failed\x07", name)
+			}
+		}
+		// a task that recovers notifies again if it fails again
+		notified = failing
+		if out != "" {
+			_, _ = io.WriteString(w, out)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_terminalStatus(t *testing.T) {
+	title, progress, failed := terminalStatus(map[string]*TaskNode{
+		"api":   {PhaseMachine: types.PhaseMachine{Phase: "running"}, Task: types.Task{Ports: []types.Port{{ContainerPort: 8080}}}},
+		"web":   {PhaseMachine: types.PhaseMachine{Phase: "running"}, Task: types.Task{Ports: []types.Port{{ContainerPort: 3000}}}},
+		"test":  {PhaseMachine: types.PhaseMachine{Phase: "running"}},
+		"lint":  {PhaseMachine: types.PhaseMachine{Phase: "failed"}},
+		"build": {PhaseMachine: types.PhaseMachine{Phase: "succeeded"}},
+	})
+	assert.Equal(t, "kit: 1 failed, 3 running, 1 succeeded", title)
+	assert.Equal(t, 80, progress, "the test job is not done")
+	assert.Equal(t, []string{"lint"}, failed)
+
+	title, progress, _ = terminalStatus(nil)
+	assert.Equal(t, "kit", title)
+	assert.Equal(t, 100, progress)
+}
+
+func Test_emitTerminalStatus(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "ghostty")
+	dag := NewDAG[*TaskNode]("test")
+	dag.AddNode("lint", &TaskNode{Name: "lint", PhaseMachine: types.PhaseMachine{Phase: "failed"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	buf := &bytes.Buffer{}
+	emitTerminalStatus(ctx, buf, dag)
+	assert.Equal(t, "\x1b[22;0t"+
+		"\x1b]0;kit: 1 failed\x07"+
+		"\x1b]9;4;2;100\x07"+
+		"\x1b]777;notify;kit;lint failed\x07"+
+		"\x1b[23;0t\x1b]9;4;0;0\x07", buf.String())
+}
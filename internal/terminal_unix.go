@@ -0,0 +1,82 @@
+//go:build !windows
+
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// isForegroundTerminal returns true if the file is a terminal, and we're in its foreground process group. Changing the
+// mode of the terminal from the background (e.g. "kit &") would stop the process.
+func isForegroundTerminal(f *os.File) bool {
+	pgrp, err := unix.IoctlGetInt(int(f.Fd()), unix.TIOCGPGRP)
+	return err == nil && pgrp == unix.Getpgrp()
+}
+
+// interactive returns true if we can ask the user questions on the terminal.
+func interactive() bool {
+	return isForegroundTerminal(os.Stdin)
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// cbreak puts the terminal into cbreak mode, so key presses can be read without waiting for enter. Unlike raw mode,
+// output is not changed. It returns a function to restore the terminal, and false if stdin is not a terminal.
+func cbreak() (func(), bool) {
+	if !isForegroundTerminal(os.Stdin) {
+		return nil, false
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, false
+	}
+	if _, err := stty("-icanon", "-echo"); err != nil {
+		return nil, false
+	}
+	return func() { _, _ = stty(saved) }, true
+}
+
+// lineMode turns line editing and echo on or off, so we can prompt for a line while in cbreak mode.
+func lineMode(on bool) {
+	if on {
+		_, _ = stty("icanon", "echo")
+	} else {
+		_, _ = stty("-icanon", "-echo")
+	}
+}
+
+// resized returns a channel that receives whenever the terminal the file is, is resized, and a function to stop it.
+func resized(_ *os.File) (<-chan struct{}, func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, unix.SIGWINCH)
+	ch := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				// a burst of resizes, e.g. while a pane is dragged, only needs one redraw
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return ch, func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+func init() {
+	// colors and highlighting use ANSI escape sequences, which the console only understands if we ask it to
+	var mode uint32
+	h := windows.Handle(os.Stdout.Fd())
+	if err := windows.GetConsoleMode(h, &mode); err == nil {
+		_ = windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+}
+
+// interactive returns true if we can ask the user questions on the console.
+func interactive() bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(os.Stdin.Fd()), &mode) == nil
+}
+
+const lineInput = windows.ENABLE_LINE_INPUT | windows.ENABLE_ECHO_INPUT
+
+// cbreak turns off line input and echo on the console, so key presses can be read without waiting for enter. It
+// returns a function to restore the console, and false if stdin is not a console.
+func cbreak() (func(), bool) {
+	h := windows.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return nil, false
+	}
+	if err := windows.SetConsoleMode(h, mode&^lineInput); err != nil {
+		return nil, false
+	}
+	return func() { _ = windows.SetConsoleMode(h, mode) }, true
+}
+
+// lineMode turns line input and echo on or off, so we can prompt for a line while in cbreak mode.
+func lineMode(on bool) {
+	h := windows.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return
+	}
+	if on {
+		mode |= lineInput
+	} else {
+		mode &^= lineInput
+	}
+	_ = windows.SetConsoleMode(h, mode)
+}
+
+// resized returns a channel that receives whenever the console the file is, is resized, and a function to stop it. The
+// console has no signal for this, so its size is polled.
+func resized(f *os.File) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		width := terminalWidth(f)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if w := terminalWidth(f); w != width {
+					width = w
+					select {
+					case ch <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return ch, func() { close(done) }
+}
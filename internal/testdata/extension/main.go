@@ -0,0 +1,49 @@
+// An extension that redacts secrets, drops debug lines, stops the "blocked" task starting, writes each status to its
+// dir, and never responds for the "hung" task. It is built for WASI by the tests.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type event struct {
+	Event string `json:"event"`
+	Task  string `json:"task"`
+	Phase string `json:"phase"`
+	Line  string `json:"line"`
+}
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		e := event{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			panic(err)
+		}
+		response := map[string]any{}
+		switch {
+		case e.Task == "hung":
+			for {
+			}
+		case e.Event == "logLine" && strings.Contains(e.Line, "debug"):
+			response["drop"] = true
+		case e.Event == "logLine":
+			response["line"] = strings.ReplaceAll(e.Line, "secret", "******")
+		case e.Event == "preStart" && e.Task == "blocked":
+			response["error"] = "not allowed"
+		case e.Event == "statusChange":
+			// only the extension's dir is mounted, so the module cannot write anywhere else
+			if err := os.WriteFile("/"+e.Task, []byte(e.Phase), 0o644); err != nil {
+				response["log"] = err.Error()
+			} else {
+				response["log"] = "status changed"
+			}
+		}
+		data, _ := json.Marshal(response)
+		fmt.Println(string(data))
+	}
+}
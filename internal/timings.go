@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// waitedOn returns the dependency the task waited for, i.e. the one that was ready last, or nil if it has none.
+func waitedOn(dag DAG[*TaskNode], node *TaskNode) *TaskNode {
+	var last *TaskNode
+	for _, name := range dag.Parents[node.Name] {
+		parent, ok := dag.Nodes[name]
+		if !ok || parent.readyAt.IsZero() {
+			continue
+		}
+		if last == nil || parent.readyAt.After(last.readyAt) {
+			last = parent
+		}
+	}
+	return last
+}
+
+// criticalPath returns the chain of tasks that determined how long it took for every task to be ready, i.e. the task
+// that was ready last, the dependency it waited for, the dependency that waited for, and so on. Speeding up any other
+// task does not make the workflow ready sooner.
+func criticalPath(dag DAG[*TaskNode]) []*TaskNode {
+	var last *TaskNode
+	for _, node := range sortedNodes(dag) {
+		if !node.readyAt.IsZero() && (last == nil || node.readyAt.After(last.readyAt)) {
+			last = node
+		}
+	}
+	var path []*TaskNode
+	dag.RLock()
+	defer dag.RUnlock()
+	for node := last; node != nil; node = waitedOn(dag, node) {
+		path = append([]*TaskNode{node}, path...)
+	}
+	return path
+}
+
+// writeTimings writes how long each task spent waiting for its dependencies, queued (e.g. for a mutex, or its ports to
+// be free), and running until it was ready, followed by the critical path, so you know which tasks to speed up.
+func writeTimings(w io.Writer, dag DAG[*TaskNode], start time.Time) error {
+	since := func(from, to time.Time) string {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return "-"
+		}
+		return to.Sub(from).Round(time.Millisecond).String()
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TASK\tWAITED ON\tDEPENDENCIES\tQUEUED\tRUN\tREADY AFTER")
+	for _, node := range sortedNodes(dag) {
+		dag.RLock()
+		parent := waitedOn(dag, node)
+		dag.RUnlock()
+		waited := "-"
+		if parent != nil {
+			waited = parent.Name
+		}
+		// a task that was skipped, or restored from the last run, did not run
+		startedAt := node.startedAt
+		if startedAt.IsZero() {
+			startedAt = node.readyAt
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", node.Name, waited, since(start, node.queuedAt), since(node.queuedAt, startedAt), since(startedAt, node.readyAt), since(start, node.readyAt))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	path := criticalPath(dag)
+	if len(path) == 0 {
+		return nil
+	}
+	var steps []string
+	from := start
+	for _, node := range path {
+		steps = append(steps, fmt.Sprintf("%s (%s)", node.Name, since(from, node.readyAt)))
+		from = node.readyAt
+	}
+	_, err := fmt.Fprintf(w, "critical path (%s): %s\n", since(start, path[len(path)-1].readyAt), strings.Join(steps, " → "))
+	return err
+}
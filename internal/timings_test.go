@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_writeTimings(t *testing.T) {
+	start := time.Now()
+	at := func(seconds float64) time.Time {
+		return start.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	dag := NewDAG[*TaskNode]("my-app")
+	dag.AddNode("build", &TaskNode{Name: "build", queuedAt: at(0), startedAt: at(0), readyAt: at(2)})
+	dag.AddNode("db", &TaskNode{Name: "db", queuedAt: at(0), startedAt: at(0.5), readyAt: at(1)})
+	dag.AddNode("api", &TaskNode{Name: "api", queuedAt: at(2), startedAt: at(2.5), readyAt: at(5)})
+	dag.AddNode("lint", &TaskNode{Name: "lint", queuedAt: at(0), readyAt: at(0)})
+	dag.AddNode("test", &TaskNode{Name: "test", queuedAt: at(5), startedAt: at(5)})
+	dag.AddEdge("build", "api")
+	dag.AddEdge("db", "api")
+	dag.AddEdge("api", "test")
+
+	assert.Equal(t, []string{"build", "api"}, func() []string {
+		var names []string
+		for _, node := range criticalPath(dag) {
+			names = append(names, node.Name)
+		}
+		return names
+	}())
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, writeTimings(buf, dag, start))
+	assert.Equal(t, `TASK   WAITED ON  DEPENDENCIES  QUEUED  RUN    READY AFTER
+api    build      2s            500ms   2.5s   5s
+build  -          0s            0s      2s     2s
+db     -          0s            500ms   500ms  1s
+lint   -          0s            0s      0s     0s
+test   api        5s            0s      -      -
+critical path (5s): build (2s) → api (3s)
+`, buf.String())
+}
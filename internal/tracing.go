@@ -0,0 +1,274 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracer exports a span for each task run using OTLP/HTTP (JSON), so a slow workflow can be analyzed in Jaeger or
+// similar. The span of a task is the child of the span of the dependency that became ready last (i.e. the critical
+// path), and is linked to the spans of its other dependencies. A nil tracer does nothing.
+type tracer struct {
+	endpoint string
+	headers  map[string]string
+	traceID  string
+	mu       sync.Mutex
+	root     *span
+	// the open span of each task
+	spans map[string]*span
+	// the last span of each task that became ready, i.e. that unblocked its children
+	ready map[string]*span
+	// the number of times each task has been run
+	attempts map[string]int
+	wg       sync.WaitGroup
+}
+
+// newTracer returns a tracer if OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set, otherwise nil.
+func newTracer(name string) *tracer {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return nil
+	}
+	headers := map[string]string{}
+	for _, header := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, ok := strings.Cut(header, "="); ok {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	t := &tracer{
+		endpoint: endpoint,
+		headers:  headers,
+		traceID:  randomID(16),
+		spans:    map[string]*span{},
+		ready:    map[string]*span{},
+		attempts: map[string]int{},
+	}
+	t.root = t.newSpan(name, "")
+	return t
+}
+
+// taskStatus records the new phase of the task.
+func (t *tracer) taskStatus(node *TaskNode, dependencies []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.spans[node.Name]
+	if s == nil {
+		s = t.startTaskSpan(node, dependencies)
+	}
+	snapshot := node.Snapshot()
+	switch snapshot.Phase {
+	case "running":
+		if s.readyAt.IsZero() {
+			s.readyAt = time.Now()
+			s.addEvent("ready", snapshot.Message)
+			t.ready[node.Name] = s
+		}
+	case "stalled":
+		s.addEvent("stalled", snapshot.Message)
+	case "succeeded":
+		s.readyAt = time.Now()
+		t.ready[node.Name] = s
+		s.Status = spanStatus{Code: statusOK}
+		t.endTaskSpan(node)
+	case "failed":
+		s.Status = spanStatus{Code: statusError, Message: snapshot.Message}
+		t.endTaskSpan(node)
+	case "cancelled", "skipped":
+		t.endTaskSpan(node)
+	}
+}
+
+func (t *tracer) startTaskSpan(node *TaskNode, dependencies []string) *span {
+	// the parent is the dependency that unblocked this task, i.e. the one that became ready last
+	var parent *span
+	var links []spanLink
+	for _, dependency := range dependencies {
+		if d, ok := t.ready[dependency]; ok {
+			links = append(links, spanLink{TraceID: t.traceID, SpanID: d.SpanID})
+			if parent == nil || d.readyAt.After(parent.readyAt) {
+				parent = d
+			}
+		}
+	}
+	if parent == nil {
+		parent = t.root
+	}
+	s := t.newSpan(node.Name, parent.SpanID)
+	s.Links = links
+	t.attempts[node.Name]++
+	attempt := t.attempts[node.Name]
+	s.Attributes = []attribute{
+		stringAttribute("kit.task", node.Name),
+		stringAttribute("kit.type", string(node.Task.GetType())),
+		stringAttribute("kit.attempt", strconv.Itoa(attempt)),
+	}
+	if attempt > 1 {
+		s.addEvent("restart", "")
+	}
+	t.spans[node.Name] = s
+	return s
+}
+
+func (t *tracer) endTaskSpan(node *TaskNode) {
+	s := t.spans[node.Name]
+	delete(t.spans, node.Name)
+	s.addEvent("exit", node.Snapshot().Message)
+	s.end()
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.export(s)
+	}()
+}
+
+// shutdown ends any open spans, and waits for all spans to be exported.
+func (t *tracer) shutdown() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	spans := []*span{t.root}
+	for _, s := range t.spans {
+		spans = append(spans, s)
+	}
+	t.spans = map[string]*span{}
+	t.mu.Unlock()
+	for _, s := range spans {
+		s.end()
+	}
+	t.export(spans...)
+	t.wg.Wait()
+}
+
+func (t *tracer) newSpan(name, parentSpanID string) *span {
+	return &span{
+		TraceID:           t.traceID,
+		SpanID:            randomID(8),
+		ParentSpanID:      parentSpanID,
+		Name:              name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(time.Now()),
+	}
+}
+
+// export exports the spans, errors are ignored, as tracing must not affect the workflow
+func (t *tracer) export(spans ...*span) {
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []attribute{stringAttribute("service.name", "kit")},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/kitproj/kit"},
+				"spans": spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	_ = resp.Body.Close()
+}
+
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+// span is an OTLP span, encoded using the OTLP/JSON mapping
+type span struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano,omitempty"`
+	Attributes        []attribute `json:"attributes,omitempty"`
+	Events            []event     `json:"events,omitempty"`
+	Links             []spanLink  `json:"links,omitempty"`
+	Status            spanStatus  `json:"status"`
+	readyAt           time.Time
+}
+
+func (s *span) addEvent(name, message string) {
+	e := event{TimeUnixNano: unixNano(time.Now()), Name: name}
+	if message != "" {
+		e.Attributes = []attribute{stringAttribute("message", message)}
+	}
+	s.Events = append(s.Events, e)
+}
+
+func (s *span) end() {
+	if s.EndTimeUnixNano == "" {
+		s.EndTimeUnixNano = unixNano(time.Now())
+	}
+}
+
+type event struct {
+	TimeUnixNano string      `json:"timeUnixNano"`
+	Name         string      `json:"name"`
+	Attributes   []attribute `json:"attributes,omitempty"`
+}
+
+type spanLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+type spanStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type attribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+func stringAttribute(key, value string) attribute {
+	return attribute{Key: key, Value: map[string]string{"stringValue": value}}
+}
+
+func unixNano(t time.Time) string {
+	return fmt.Sprint(t.UnixNano())
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
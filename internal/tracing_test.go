@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_tracer(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+		tracer := newTracer("test")
+		assert.Nil(t, tracer)
+		tracer.taskStatus(&TaskNode{Name: "foo", PhaseMachine: types.PhaseMachine{Phase: "running"}}, nil)
+		tracer.shutdown()
+	})
+	t.Run("Enabled", func(t *testing.T) {
+		mu := sync.Mutex{}
+		spans := map[string]span{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/traces", r.URL.Path)
+			assert.Equal(t, "bar", r.Header.Get("foo"))
+			var x struct {
+				ResourceSpans []struct {
+					ScopeSpans []struct {
+						Spans []span `json:"spans"`
+					} `json:"scopeSpans"`
+				} `json:"resourceSpans"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&x))
+			mu.Lock()
+			defer mu.Unlock()
+			for _, s := range x.ResourceSpans[0].ScopeSpans[0].Spans {
+				key := s.Name
+				if len(s.Attributes) > 0 {
+					key += "/" + s.Attributes[2].Value["stringValue"]
+				}
+				spans[key] = s
+			}
+		}))
+		defer server.Close()
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL)
+		t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "foo=bar")
+
+		tracer := newTracer("test")
+		build := &TaskNode{Name: "build", PhaseMachine: types.PhaseMachine{Phase: "waiting"}}
+		tracer.taskStatus(build, nil)
+		build.Phase = "running"
+		tracer.taskStatus(build, nil)
+		build.Phase = "succeeded"
+		tracer.taskStatus(build, nil)
+		api := &TaskNode{Name: "api", PhaseMachine: types.PhaseMachine{Phase: "waiting"}}
+		tracer.taskStatus(api, []string{"build"})
+		api.Phase, api.Message = "failed", "exit code 1"
+		tracer.taskStatus(api, []string{"build"})
+		api.Phase = "running"
+		tracer.taskStatus(api, []string{"build"})
+		tracer.shutdown()
+
+		root := spans["test"]
+		assert.NotEmpty(t, root.EndTimeUnixNano)
+		assert.Equal(t, root.SpanID, spans["build/1"].ParentSpanID)
+		assert.Equal(t, statusOK, spans["build/1"].Status.Code)
+		assert.Equal(t, spans["build/1"].SpanID, spans["api/1"].ParentSpanID)
+		assert.Len(t, spans["api/1"].Links, 1)
+		assert.Equal(t, spanStatus{Code: statusError, Message: "exit code 1"}, spans["api/1"].Status)
+		assert.Equal(t, "restart", spans["api/2"].Events[0].Name)
+		assert.Equal(t, "ready", spans["api/2"].Events[1].Name)
+		assert.NotEmpty(t, spans["api/2"].EndTimeUnixNano)
+	})
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Build describes how to build a container image using BuildKit.
+type Build struct {
+	// The build context directory, relative to the working directory. Defaults to the working directory.
+	Context string `json:"context,omitempty"`
+	// The path to the Dockerfile, relative to the context. Defaults to "Dockerfile".
+	Dockerfile string `json:"dockerfile,omitempty"`
+	// The tags to apply to the built image, e.g. "my-app:dev". Defaults to the task's name. Container tasks that use one of these tags will not pull the image.
+	Tags Strings `json:"tags,omitempty"`
+	// Build-time variables.
+	BuildArgs EnvVars `json:"buildArgs,omitempty"`
+	// The target stage to build.
+	Target string `json:"target,omitempty"`
+	// External cache sources, e.g. "type=local,src=.cache/buildkit".
+	CacheFrom Strings `json:"cacheFrom,omitempty"`
+	// Cache export destinations, e.g. "type=local,dest=.cache/buildkit,mode=max".
+	CacheTo Strings `json:"cacheTo,omitempty"`
+}
+
+func (b *Build) GetContext(workingDir string) string {
+	if b.Context == "" {
+		return filepath.Join(workingDir, ".")
+	}
+	return filepath.Join(workingDir, b.Context)
+}
+
+func (b *Build) GetDockerfile(workingDir string) string {
+	dockerfile := b.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	return filepath.Join(b.GetContext(workingDir), dockerfile)
+}
+
+// GetTags returns the tags to apply to the image, which default to the name of the task's resource.
+func (b *Build) GetTags(name string) Strings {
+	if len(b.Tags) == 0 {
+		return Strings{name}
+	}
+	return b.Tags
+}
+
+// HasTag returns true if the build, of the named task's resource, produces the image. An image without a tag matches
+// the "latest" tag.
+func (b *Build) HasTag(name, image string) bool {
+	if b == nil {
+		return false
+	}
+	for _, tag := range b.GetTags(name) {
+		if normalizeTag(tag) == normalizeTag(image) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeTag(image string) string {
+	// a colon after the last slash is a tag, a colon before it is a registry port
+	if strings.LastIndex(image, ":") <= strings.LastIndex(image, "/") {
+		return image + ":latest"
+	}
+	return image
+}
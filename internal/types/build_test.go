@@ -0,0 +1,25 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild_HasTag(t *testing.T) {
+	b := &Build{Tags: Strings{"app", "localhost:5000/api:dev"}}
+	assert.True(t, b.HasTag("api", "app"))
+	assert.True(t, b.HasTag("api", "app:latest"))
+	assert.True(t, b.HasTag("api", "localhost:5000/api:dev"))
+	assert.False(t, b.HasTag("api", "localhost:5000/api"))
+	assert.False(t, b.HasTag("api", "api"))
+	assert.False(t, b.HasTag("api", "other"))
+	assert.False(t, (*Build)(nil).HasTag("api", "app"))
+	t.Run("Default tag", func(t *testing.T) {
+		b := &Build{}
+		assert.Equal(t, Strings{"api"}, b.GetTags("api"))
+		assert.True(t, b.HasTag("api", "api"))
+		assert.True(t, b.HasTag("api", "api:latest"))
+		assert.False(t, b.HasTag("api", "app"))
+	})
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Cache is a remote cache of the targets of jobs, so CI and teammates' machines can skip jobs whose inputs match a job
+// that succeeded elsewhere.
+type Cache struct {
+	// The URL of the cache, e.g. "https://cache.example.com/kit", "s3://my-bucket/kit" (using the aws CLI), or
+	// "gs://my-bucket/kit" (using the gcloud CLI). If the KIT_CACHE_TOKEN environment variable is set, it is sent as a
+	// bearer token to HTTP caches.
+	URL string `json:"url"`
+	// Whether to push the targets of jobs that succeed to the cache. Defaults to true in CI, and false otherwise, so
+	// that only CI's builds are shared.
+	Push *bool `json:"push,omitempty"`
+}
+
+// Validate returns an error if the cache is not valid.
+func (c Cache) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "s3", "gs":
+		return nil
+	default:
+		return fmt.Errorf("invalid url %q, must be http, https, s3 or gs", c.URL)
+	}
+}
+
+// GetPush returns whether to push to the cache, defaulting to ci.
+func (c Cache) GetPush(ci bool) bool {
+	if c.Push != nil {
+		return *c.Push
+	}
+	return ci
+}
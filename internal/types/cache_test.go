@@ -0,0 +1,17 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	assert.NoError(t, Cache{URL: "https://cache.example.com/kit"}.Validate())
+	assert.NoError(t, Cache{URL: "s3://my-bucket/kit"}.Validate())
+	assert.EqualError(t, Cache{URL: "ftp://cache.example.com"}.Validate(), `invalid url "ftp://cache.example.com", must be http, https, s3 or gs`)
+	assert.True(t, Cache{}.GetPush(true))
+	assert.False(t, Cache{}.GetPush(false))
+	push := true
+	assert.True(t, Cache{Push: &push}.GetPush(false))
+}
@@ -0,0 +1,19 @@
+package types
+
+import "path/filepath"
+
+// Compose runs services defined in a Docker Compose file, for infrastructure that is already defined in one.
+type Compose struct {
+	// The path to the Compose file, relative to the working directory. Defaults to "docker-compose.yaml".
+	File string `json:"file,omitempty"`
+	// The services to run. Defaults to all the services in the file.
+	Services Strings `json:"services,omitempty"`
+}
+
+func (c *Compose) GetFile(workingDir string) string {
+	file := c.File
+	if file == "" {
+		file = "docker-compose.yaml"
+	}
+	return filepath.Join(workingDir, file)
+}
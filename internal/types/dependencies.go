@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Dependencies are the tasks a task depends on. Either a list of tasks that must all be ready, or an object with allOf
+// and anyOf lists.
+type Dependencies struct {
+	// Tasks that must all be ready.
+	AllOf Strings `json:"allOf,omitempty"`
+	// Tasks of which any one must be ready, e.g. alternative providers of the same service.
+	AnyOf Strings `json:"anyOf,omitempty"`
+}
+
+func (d *Dependencies) UnmarshalJSON(data []byte) error {
+	if data[0] != '{' {
+		return json.Unmarshal(data, &d.AllOf)
+	}
+	type plain Dependencies
+	return json.Unmarshal(data, (*plain)(d))
+}
+
+// MarshalJSON marshals all-of dependencies as a list, as that is how they're usually written.
+func (d Dependencies) MarshalJSON() ([]byte, error) {
+	if len(d.AnyOf) == 0 {
+		return json.Marshal(d.AllOf)
+	}
+	type plain Dependencies
+	return json.Marshal(plain(d))
+}
+
+// GetAllOf returns the tasks that must all be ready.
+func (d *Dependencies) GetAllOf() []string {
+	if d == nil {
+		return nil
+	}
+	return d.AllOf
+}
+
+// IsAnyOf returns true if the task is only one of several alternatives.
+func (d *Dependencies) IsAnyOf(task string) bool {
+	return d != nil && slices.Contains(d.AnyOf, task) && !slices.Contains(d.AllOf, task)
+}
+
+// GetAnyOf returns the tasks of which any one must be ready.
+func (d *Dependencies) GetAnyOf() []string {
+	if d == nil {
+		return nil
+	}
+	return d.AnyOf
+}
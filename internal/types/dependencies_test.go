@@ -0,0 +1,40 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestDependencies(t *testing.T) {
+	t.Run("List", func(t *testing.T) {
+		var d Dependencies
+		assert.NoError(t, yaml.Unmarshal([]byte("[a, b]"), &d))
+		assert.Equal(t, Dependencies{AllOf: Strings{"a", "b"}}, d)
+		out, err := yaml.Marshal(d)
+		assert.NoError(t, err)
+		assert.Equal(t, "- a\n- b\n", string(out))
+	})
+	t.Run("String", func(t *testing.T) {
+		var d Dependencies
+		assert.NoError(t, yaml.Unmarshal([]byte("a b"), &d))
+		assert.Equal(t, Dependencies{AllOf: Strings{"a", "b"}}, d)
+	})
+	t.Run("Any of", func(t *testing.T) {
+		var d Dependencies
+		assert.NoError(t, yaml.Unmarshal([]byte("{allOf: [a], anyOf: [b, c]}"), &d))
+		assert.Equal(t, Dependencies{AllOf: Strings{"a"}, AnyOf: Strings{"b", "c"}}, d)
+		assert.False(t, d.IsAnyOf("a"))
+		assert.True(t, d.IsAnyOf("b"))
+		out, err := yaml.Marshal(d)
+		assert.NoError(t, err)
+		assert.Equal(t, "allOf:\n- a\nanyOf:\n- b\n- c\n", string(out))
+	})
+	t.Run("Nil", func(t *testing.T) {
+		var d *Dependencies
+		assert.Empty(t, d.GetAllOf())
+		assert.Empty(t, d.GetAnyOf())
+		assert.False(t, d.IsAnyOf("a"))
+	})
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DNS gives each task a stable name, e.g. "api.kit.local", that resolves to 127.0.0.1, so services can be configured
+// with hostnames like those in a cluster, rather than localhost.
+type DNS struct {
+	// The domain of the tasks' names, e.g. "default.svc.cluster.local" to use the same names as in Kubernetes. Defaults
+	// to "kit.local".
+	Domain string `json:"domain,omitempty"`
+	// The port to run a DNS resolver for the domain on, on 127.0.0.1, e.g. 5354. Point your system's resolver for the
+	// domain at it, e.g. /etc/resolver/kit.local on macOS. If omitted, no resolver is run.
+	Port uint16 `json:"port,omitempty"`
+	// A hosts file to add the tasks' names to, e.g. "/etc/hosts", which must be writable. They are removed when kit
+	// exits. If omitted, no hosts file is changed.
+	HostsFile string `json:"hostsFile,omitempty"`
+}
+
+// the labels of a domain, e.g. "kit.local"
+var domainName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// Validate returns an error if the DNS is not valid.
+func (d DNS) Validate() error {
+	if !domainName.MatchString(d.GetDomain()) {
+		return fmt.Errorf("invalid domain %q, must be lowercase letters, digits, hyphens and dots", d.Domain)
+	}
+	if d.Port == 0 && d.HostsFile == "" {
+		return fmt.Errorf("needs a port to run a resolver on, or a hostsFile to add the names to")
+	}
+	return nil
+}
+
+func (d DNS) GetDomain() string {
+	if d.Domain != "" {
+		return d.Domain
+	}
+	return "kit.local"
+}
+
+// Hostname returns the task's name in the domain. A task of another workflow, e.g. "web/api", is named like a
+// Kubernetes service in a namespace, e.g. "api.web.kit.local".
+func (d DNS) Hostname(task string) string {
+	parts := strings.Split(strings.ToLower(task), "/")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(append(parts, d.GetDomain()), ".")
+}
@@ -0,0 +1,20 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDNS(t *testing.T) {
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, DNS{Port: 5354}.Validate())
+		assert.NoError(t, DNS{Domain: "default.svc.cluster.local", HostsFile: "/etc/hosts"}.Validate())
+		assert.EqualError(t, DNS{}.Validate(), "needs a port to run a resolver on, or a hostsFile to add the names to")
+		assert.EqualError(t, DNS{Domain: "Kit_Local", Port: 5354}.Validate(), `invalid domain "Kit_Local", must be lowercase letters, digits, hyphens and dots`)
+	})
+	t.Run("Hostname", func(t *testing.T) {
+		assert.Equal(t, "api.kit.local", DNS{}.Hostname("api"))
+		assert.Equal(t, "api.web.cluster.local", DNS{Domain: "cluster.local"}.Hostname("web/api"))
+	})
+}
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EnvFromSource is a file or directory to read environment variables from.
+type EnvFromSource struct {
+	// A dotenv file, e.g. ".env".
+	Envfile string `json:"envfile,omitempty"`
+	// A JSON file containing an object, e.g. {"PORT": 8080}.
+	JSONFile string `json:"jsonFile,omitempty"`
+	// A directory where each file is an environment variable, named after the file, with the file's contents as the value,
+	// like a Kubernetes secret or config map volume.
+	Dir string `json:"dir,omitempty"`
+	// A prefix to add to the name of each environment variable.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Environ reads the environment variables from the source, relative to the working directory.
+func (s EnvFromSource) Environ(workingDir string) ([]string, error) {
+	var (
+		environ []string
+		err     error
+	)
+	switch {
+	case s.Envfile != "":
+		environ, err = readEnvfile(filepath.Join(workingDir, s.Envfile))
+	case s.JSONFile != "":
+		environ, err = readJSONFile(filepath.Join(workingDir, s.JSONFile))
+	case s.Dir != "":
+		environ, err = readDir(filepath.Join(workingDir, s.Dir))
+	default:
+		return nil, fmt.Errorf("envFrom must have a source")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.Prefix != "" {
+		for i, e := range environ {
+			environ[i] = s.Prefix + e
+		}
+	}
+	return environ, nil
+}
+
+func readJSONFile(name string) ([]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var x map[string]any
+	if err := json.Unmarshal(data, &x); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", name, err)
+	}
+	var environ []string
+	for k, v := range x {
+		value, ok := v.(string)
+		if !ok {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			value = string(data)
+		}
+		environ = append(environ, fmt.Sprintf("%s=%s", k, value))
+	}
+	sort.Strings(environ)
+	return environ, nil
+}
+
+func readDir(name string) ([]string, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	var environ []string
+	for _, entry := range entries {
+		// Kubernetes volumes contain hidden files and directories (e.g. "..data"), these are not variables
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(name, entry.Name())
+		// entries may be symlinks, so we need to stat the target
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		environ = append(environ, fmt.Sprintf("%s=%s", entry.Name(), value))
+	}
+	return environ, nil
+}
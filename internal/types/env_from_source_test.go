@@ -0,0 +1,29 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvFromSource_Environ(t *testing.T) {
+	t.Run("Envfile", func(t *testing.T) {
+		environ, err := EnvFromSource{Envfile: "task.env"}.Environ("testdata")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"BAZ=3"}, environ)
+	})
+	t.Run("JSONFile", func(t *testing.T) {
+		environ, err := EnvFromSource{JSONFile: "env.json", Prefix: "APP_"}.Environ("testdata")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"APP_HOST=localhost", "APP_PORT=8080"}, environ)
+	})
+	t.Run("Dir", func(t *testing.T) {
+		environ, err := EnvFromSource{Dir: "envdir"}.Environ("testdata")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"PASSWORD=secret"}, environ)
+	})
+	t.Run("No source", func(t *testing.T) {
+		_, err := EnvFromSource{}.Environ("testdata")
+		assert.EqualError(t, err, "envFrom must have a source")
+	})
+}
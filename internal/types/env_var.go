@@ -8,8 +8,9 @@ import (
 
 // A environment variable.
 type EnvVar struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	Name      string        `json:"name"`
+	Value     string        `json:"value"`
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
 }
 
 func (v EnvVar) String() (string, error) {
@@ -31,14 +32,16 @@ func (v *EnvVar) Unstring(s string) error {
 func (v *EnvVar) UnmarshalJSON(data []byte) error {
 	if data[0] == '{' {
 		var x struct {
-			Name  string `json:"name"`
-			Value string `json:"value"`
+			Name      string        `json:"name"`
+			Value     string        `json:"value"`
+			ValueFrom *EnvVarSource `json:"valueFrom"`
 		}
 		if err := json.Unmarshal(data, &x); err != nil {
 			return err
 		}
 		v.Name = x.Name
 		v.Value = x.Value
+		v.ValueFrom = x.ValueFrom
 		return nil
 	}
 	var s string
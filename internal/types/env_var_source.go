@@ -0,0 +1,104 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kitproj/kit/internal/secrets"
+)
+
+// EnvVarValue is the value of an environment variable, either a string, or a source to read it from.
+type EnvVarValue struct {
+	// The value.
+	Value string `json:"value,omitempty"`
+	// Where to read the value from, instead of the workflow, e.g. a secret.
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
+}
+
+func (v *EnvVarValue) UnmarshalJSON(data []byte) error {
+	if data[0] == '{' {
+		var x struct {
+			Value     string        `json:"value"`
+			ValueFrom *EnvVarSource `json:"valueFrom"`
+		}
+		if err := json.Unmarshal(data, &x); err != nil {
+			return err
+		}
+		v.Value = x.Value
+		v.ValueFrom = x.ValueFrom
+		return nil
+	}
+	return json.Unmarshal(data, &v.Value)
+}
+
+func (v EnvVarValue) MarshalJSON() ([]byte, error) {
+	if v.ValueFrom == nil {
+		return json.Marshal(v.Value)
+	}
+	return json.Marshal(struct {
+		ValueFrom *EnvVarSource `json:"valueFrom"`
+	}{v.ValueFrom})
+}
+
+// Resolve returns the value, reading it from its source if it has one.
+func (v EnvVarValue) Resolve() (string, error) {
+	if v.ValueFrom == nil {
+		return v.Value, nil
+	}
+	return v.ValueFrom.Resolve()
+}
+
+// EnvVarSource is a source for the value of an environment variable.
+type EnvVarSource struct {
+	// The name of a secret in the OS keychain: the macOS Keychain, libsecret (e.g. GNOME Keyring) on Linux, or the
+	// Windows Credential Manager.
+	Keychain string `json:"keychain,omitempty"`
+	// A field of a HashiCorp Vault secret, e.g. "secret/data/dev#api_key". Read using the vault CLI.
+	Vault string `json:"vault,omitempty"`
+	// An AWS Secrets Manager secret name or ARN, optionally with a field of a JSON secret, e.g. "dev/db#password". Read using the aws CLI.
+	AWSSecretsManager string `json:"awsSecretsManager,omitempty"`
+	// A Google Cloud Secret Manager secret name or resource name, optionally with a field of a JSON secret, e.g. "db#password".
+	// Read using the gcloud CLI.
+	GCPSecretManager string `json:"gcpSecretManager,omitempty"`
+}
+
+// Resolve reads the value from the source. Values are cached, so each secret is only read once.
+func (s EnvVarSource) Resolve() (string, error) {
+	var (
+		name, ref string
+		read      func(string) (string, error)
+	)
+	switch {
+	case s.Keychain != "":
+		name, ref, read = "keychain", s.Keychain, secrets.Keychain
+	case s.Vault != "":
+		name, ref, read = "Vault", s.Vault, secrets.Vault
+	case s.AWSSecretsManager != "":
+		name, ref, read = "AWS Secrets Manager", s.AWSSecretsManager, secrets.AWSSecretsManager
+	case s.GCPSecretManager != "":
+		name, ref, read = "GCP Secret Manager", s.GCPSecretManager, secrets.GCPSecretManager
+	default:
+		return "", fmt.Errorf("valueFrom must have a source")
+	}
+	value, err := read(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %q from %s: %w", ref, name, err)
+	}
+	return value, nil
+}
+
+// String returns where the value is read from, e.g. "vault secret/data/dev#api_key".
+func (s EnvVarSource) String() string {
+	switch {
+	case s.Keychain != "":
+		return "keychain " + s.Keychain
+	case s.Vault != "":
+		return "vault " + s.Vault
+	case s.AWSSecretsManager != "":
+		return "awsSecretsManager " + s.AWSSecretsManager
+	case s.GCPSecretManager != "":
+		return "gcpSecretManager " + s.GCPSecretManager
+	default:
+		return "nowhere"
+	}
+}
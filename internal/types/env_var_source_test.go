@@ -0,0 +1,34 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvVars_UnmarshalJSON(t *testing.T) {
+	t.Run("Map", func(t *testing.T) {
+		var v EnvVars
+		err := json.Unmarshal([]byte(`{"FOO": "1", "BAR": {"valueFrom": {"keychain": "bar"}}}`), &v)
+		assert.NoError(t, err)
+		assert.Equal(t, EnvVars{"FOO": {Value: "1"}, "BAR": {ValueFrom: &EnvVarSource{Keychain: "bar"}}}, v)
+	})
+	t.Run("List", func(t *testing.T) {
+		var v EnvVars
+		err := json.Unmarshal([]byte(`["FOO=1", {"name": "BAR", "valueFrom": {"keychain": "bar"}}]`), &v)
+		assert.NoError(t, err)
+		assert.Equal(t, EnvVars{"FOO": {Value: "1"}, "BAR": {ValueFrom: &EnvVarSource{Keychain: "bar"}}}, v)
+	})
+}
+
+func TestEnvVarValue_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(EnvVars{"FOO": {Value: "1"}, "BAR": {ValueFrom: &EnvVarSource{Keychain: "bar"}}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"FOO": "1", "BAR": {"valueFrom": {"keychain": "bar"}}}`, string(data))
+}
+
+func TestEnvVarSource_Resolve(t *testing.T) {
+	_, err := EnvVarSource{}.Resolve()
+	assert.EqualError(t, err, "valueFrom must have a source")
+}
@@ -6,7 +6,7 @@ import (
 )
 
 // A list of environment variables.
-type EnvVars map[string]string
+type EnvVars map[string]EnvVarValue
 
 // the legacy format for env vars was an array of named env vars
 func (v *EnvVars) UnmarshalJSON(data []byte) error {
@@ -17,11 +17,11 @@ func (v *EnvVars) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		for _, env := range x {
-			(*v)[env.Name] = env.Value
+			(*v)[env.Name] = EnvVarValue{Value: env.Value, ValueFrom: env.ValueFrom}
 		}
 		return nil
 	}
-	var x = map[string]string{}
+	var x = map[string]EnvVarValue{}
 	if err := json.Unmarshal(data, &x); err != nil {
 		return err
 	}
@@ -35,7 +35,11 @@ func (v *EnvVars) UnmarshalJSON(data []byte) error {
 func (v EnvVars) Environ() ([]string, error) {
 	var environ []string
 	for k, v := range v {
-		environ = append(environ, fmt.Sprintf("%s=%s", k, v))
+		value, err := v.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get value of %s: %w", k, err)
+		}
+		environ = append(environ, fmt.Sprintf("%s=%s", k, value))
 	}
 	return environ, nil
 }
@@ -13,21 +13,31 @@ type Envfile Strings
 func (f Envfile) Environ(workingDir string) ([]string, error) {
 	var environ []string
 	for _, e := range f {
-		file, err := os.Open(filepath.Join(workingDir, e))
-		if err != nil {
-			return nil, err
-		}
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if !strings.HasPrefix(line, "#") {
-				environ = append(environ, line)
-			}
+		if !filepath.IsAbs(e) {
+			e = filepath.Join(workingDir, e)
 		}
-		if err := scanner.Err(); err != nil {
+		x, err := readEnvfile(e)
+		if err != nil {
 			return nil, err
 		}
+		environ = append(environ, x...)
 	}
 	return environ, nil
 }
+
+func readEnvfile(name string) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var environ []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			environ = append(environ, line)
+		}
+	}
+	return environ, scanner.Err()
+}
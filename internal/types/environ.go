@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"os"
 )
 
 func Environ(spec Spec, task Task) ([]string, error) {
@@ -14,5 +15,14 @@ func Environ(spec Spec, task Task) ([]string, error) {
 		return nil, fmt.Errorf("error getting spec environ: %w", err)
 	}
 
-	return append(specEnviron, taskEnviron...), nil
+	return append(append(spec.NetworkEnviron(task), specEnviron...), taskEnviron...), nil
+}
+
+// HostEnviron returns the variables of kit's environment the task inherits: none for a container, which has its own
+// environment, or a task with a clean environment.
+func HostEnviron(task Task) []string {
+	if task.Image != "" || task.CleanEnv {
+		return nil
+	}
+	return os.Environ()
 }
@@ -11,18 +11,26 @@ func TestEnviron(t *testing.T) {
 	environ, err := Environ(Spec{
 		Envfile: Envfile{"testdata/spec.env"},
 		Env: EnvVars{
-			"BAR": "2",
+			"BAR": {Value: "2"},
 		},
 	}, Task{
 		Envfile: Envfile{"testdata/task.env"},
+		EnvFrom: []EnvFromSource{{Dir: "testdata/envdir"}},
 		Env: EnvVars{
-			"QUX": "4",
-			"FUZ": "5",
+			"QUX": {Value: "4"},
+			"FUZ": {Value: "5"},
 		},
 	})
 
 	assert.NoError(t, err)
 
-	assert.ElementsMatch(t, []string{"FOO=1", "BAR=2", "BAZ=3", "QUX=4", "FUZ=5"}, environ)
+	assert.ElementsMatch(t, []string{"FOO=1", "BAR=2", "BAZ=3", "PASSWORD=secret", "QUX=4", "FUZ=5"}, environ)
 
 }
+
+func TestHostEnviron(t *testing.T) {
+	t.Setenv("HOST_VAR", "1")
+	assert.Contains(t, HostEnviron(Task{Command: Strings{"go", "run", "."}}), "HOST_VAR=1")
+	assert.Empty(t, HostEnviron(Task{Command: Strings{"go", "run", "."}, CleanEnv: true}))
+	assert.Empty(t, HostEnviron(Task{Image: "postgres"}), "containers have their own environment")
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// inherit returns the task with the fields it does not set itself taken from the parent.
+func (t Task) inherit(parent Task) (Task, error) {
+	// a copy, so the tasks do not share, e.g., a probe
+	data, err := json.Marshal(parent)
+	if err != nil {
+		return t, err
+	}
+	inherited := Task{}
+	if err := json.Unmarshal(data, &inherited); err != nil {
+		return t, err
+	}
+	child := reflect.ValueOf(&t).Elem()
+	out := reflect.ValueOf(&inherited).Elem()
+	for i := 0; i < child.NumField(); i++ {
+		if !child.Field(i).IsZero() {
+			out.Field(i).Set(child.Field(i))
+		}
+	}
+	if len(parent.Env) > 0 && len(t.Env) > 0 {
+		inherited.Env = EnvVars{}
+		for k, v := range parent.Env {
+			inherited.Env[k] = v
+		}
+		for k, v := range t.Env {
+			inherited.Env[k] = v
+		}
+	}
+	if len(parent.Labels) > 0 && len(t.Labels) > 0 {
+		inherited.Labels = map[string]string{}
+		for k, v := range parent.Labels {
+			inherited.Labels[k] = v
+		}
+		for k, v := range t.Labels {
+			inherited.Labels[k] = v
+		}
+	}
+	inherited.Extends = ""
+	return inherited, nil
+}
+
+// ApplyExtends sets the fields each task that extends another, or is of a built-in kind, inherits from it. A task may
+// extend a task that itself extends another.
+func (s *Spec) ApplyExtends() error {
+	resolved := Tasks{}
+	var resolve func(name string, visiting []string) (Task, error)
+	resolve = func(name string, visiting []string) (Task, error) {
+		if t, ok := resolved[name]; ok {
+			return t, nil
+		}
+		t := s.Tasks[name]
+		if t.Kind != "" {
+			var err error
+			if t, err = s.applyKind(name, t); err != nil {
+				return t, err
+			}
+		}
+		if t.Extends == "" {
+			resolved[name] = t
+			return t, nil
+		}
+		for _, v := range visiting {
+			if v == name {
+				return t, fmt.Errorf("task %q extends itself: %s", name, strings.Join(append(visiting, name), " → "))
+			}
+		}
+		if _, ok := s.Tasks[t.Extends]; !ok {
+			return t, fmt.Errorf("task %q extends %q, which is not found", name, t.Extends)
+		}
+		parent, err := resolve(t.Extends, append(visiting, name))
+		if err != nil {
+			return t, err
+		}
+		t, err = t.inherit(parent)
+		if err != nil {
+			return t, fmt.Errorf("task %q failed to extend %q: %w", name, t.Extends, err)
+		}
+		resolved[name] = t
+		return t, nil
+	}
+	var names []string
+	for name := range s.Tasks {
+		names = append(names, name)
+	}
+	// sorted, so the same cycle is always reported
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := resolve(name, nil); err != nil {
+			return err
+		}
+	}
+	s.Tasks = resolved
+	return nil
+}
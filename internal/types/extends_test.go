@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpec_ApplyExtends(t *testing.T) {
+	t.Run("Inherits", func(t *testing.T) {
+		s := &Spec{Tasks: Tasks{
+			"api": {
+				Command:        Strings{"go", "run", "."},
+				Env:            EnvVars{"PORT": {Value: "8080"}, "LOG": {Value: "info"}},
+				Ports:          Ports{{ContainerPort: 8080}},
+				ReadinessProbe: &Probe{TCPSocket: &TCPSocketAction{Port: 8080}},
+				Watch:          Strings{"src"},
+			},
+			"api-debug": {
+				Extends: "api",
+				Command: Strings{"dlv", "debug", "--headless", "--listen=:2345"},
+				Env:     EnvVars{"LOG": {Value: "debug"}},
+				Ports:   Ports{{ContainerPort: 8080}, {ContainerPort: 2345}},
+			},
+			"api-trace": {Extends: "api-debug", Env: EnvVars{"TRACE": {Value: "1"}}},
+		}}
+		assert.NoError(t, s.ApplyExtends())
+
+		debug := s.Tasks["api-debug"]
+		assert.Empty(t, debug.Extends)
+		assert.Equal(t, Strings{"dlv", "debug", "--headless", "--listen=:2345"}, debug.Command)
+		assert.Equal(t, EnvVars{"PORT": {Value: "8080"}, "LOG": {Value: "debug"}}, debug.Env)
+		assert.Len(t, debug.Ports, 2)
+		assert.Equal(t, Strings{"src"}, debug.Watch)
+		assert.Equal(t, s.Tasks["api"].ReadinessProbe, debug.ReadinessProbe)
+		assert.NotSame(t, s.Tasks["api"].ReadinessProbe, debug.ReadinessProbe, "each task has its own probe")
+
+		trace := s.Tasks["api-trace"]
+		assert.Equal(t, debug.Command, trace.Command)
+		assert.Equal(t, EnvVars{"PORT": {Value: "8080"}, "LOG": {Value: "debug"}, "TRACE": {Value: "1"}}, trace.Env)
+	})
+	t.Run("Not found", func(t *testing.T) {
+		s := &Spec{Tasks: Tasks{"debug": {Extends: "api"}}}
+		assert.EqualError(t, s.ApplyExtends(), `task "debug" extends "api", which is not found`)
+	})
+	t.Run("Cycle", func(t *testing.T) {
+		s := &Spec{Tasks: Tasks{"a": {Extends: "b"}, "b": {Extends: "a"}}}
+		assert.EqualError(t, s.ApplyExtends(), `task "a" extends itself: a → b → a`)
+	})
+}
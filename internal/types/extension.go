@@ -0,0 +1,55 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the events that can be sent to an extension
+var extensionEvents = []string{"statusChange", "logLine", "preStart"}
+
+// Extension is a WebAssembly module that is sent the workflow's lifecycle events, e.g. to redact secrets from logs, or
+// to write metrics, in any language that compiles to WASI, without running a shell command on every event.
+type Extension struct {
+	// The path to the WebAssembly module.
+	Module string `json:"module"`
+	// The events to send: "statusChange", "logLine" and "preStart". Defaults to all of them.
+	Events Strings `json:"events,omitempty"`
+	// The tasks to send events for. Defaults to all tasks.
+	Tasks Strings `json:"tasks,omitempty"`
+	// A directory the module can read and write, e.g. to write metrics to, mounted as its root. Otherwise, the module
+	// cannot access any files, nor the network.
+	Dir string `json:"dir,omitempty"`
+	// How long to wait for the module to respond to each event, e.g. "500ms". A module that does not respond in time is
+	// not sent any more events, so it cannot hold up the tasks. Defaults to 1s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// Validate returns an error if the extension is not valid.
+func (e Extension) Validate() error {
+	if e.Module == "" {
+		return fmt.Errorf("module is required")
+	}
+	for _, event := range e.Events {
+		if !slices.Contains(extensionEvents, event) {
+			return fmt.Errorf("invalid event %q, must be statusChange, logLine or preStart", event)
+		}
+	}
+	return nil
+}
+
+// Wants returns true if the extension should be sent the event for the task.
+func (e Extension) Wants(event, task string) bool {
+	return (len(e.Events) == 0 || slices.Contains(e.Events, event)) && (len(e.Tasks) == 0 || slices.Contains(e.Tasks, task))
+}
+
+// GetTimeout returns how long to wait for the module to respond to each event.
+func (e Extension) GetTimeout() time.Duration {
+	if e.Timeout != nil {
+		return e.Timeout.Duration
+	}
+	return time.Second
+}
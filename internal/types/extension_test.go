@@ -0,0 +1,23 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExtension(t *testing.T) {
+	assert.NoError(t, Extension{Module: "redact.wasm", Events: Strings{"logLine"}}.Validate())
+	assert.EqualError(t, Extension{}.Validate(), "module is required")
+	assert.EqualError(t, Extension{Module: "redact.wasm", Events: Strings{"exit"}}.Validate(), `invalid event "exit", must be statusChange, logLine or preStart`)
+
+	assert.True(t, Extension{}.Wants("logLine", "api"))
+	assert.True(t, Extension{Events: Strings{"logLine"}, Tasks: Strings{"api"}}.Wants("logLine", "api"))
+	assert.False(t, Extension{Events: Strings{"logLine"}}.Wants("preStart", "api"))
+	assert.False(t, Extension{Tasks: Strings{"api"}}.Wants("logLine", "web"))
+
+	assert.Equal(t, time.Second, Extension{}.GetTimeout())
+	assert.Equal(t, 500*time.Millisecond, Extension{Timeout: &metav1.Duration{Duration: 500 * time.Millisecond}}.GetTimeout())
+}
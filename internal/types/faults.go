@@ -0,0 +1,44 @@
+package types
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Faults degrade the connections to a task's first port, to test how the tasks that depend on it behave when it is slow
+// or flaky.
+type Faults struct {
+	// Added to each response, i.e. the first data the task sends back after the client sent some, e.g. "200ms".
+	Latency *metav1.Duration `json:"latency,omitempty"`
+	// Up to this is randomly added to the latency, e.g. "50ms".
+	Jitter *metav1.Duration `json:"jitter,omitempty"`
+	// The maximum bytes per second sent in each direction, or zero for no limit.
+	Bandwidth int `json:"bandwidth,omitempty"`
+	// The percentage of new connections that are reset, between 0 and 100.
+	ErrorRate int `json:"errorRate,omitempty"`
+	// Start with the faults turned off, so they're only turned on with "kit faults on".
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+func (f Faults) Validate() error {
+	if f.ErrorRate < 0 || f.ErrorRate > 100 {
+		return fmt.Errorf("errorRate must be a percentage, between 0 and 100")
+	}
+	if f.Bandwidth < 0 {
+		return fmt.Errorf("bandwidth must not be negative")
+	}
+	return nil
+}
+
+// ValidateFaults returns an error if faults cannot be injected into the task. Like a rolling restart, it must be a host
+// process with a port, so it can be run on a free port behind a proxy on its port.
+func (t *Task) ValidateFaults() error {
+	if t.Faults == nil {
+		return nil
+	}
+	if t.Image != "" || len(t.GetCommand()) == 0 || len(t.Ports) == 0 {
+		return fmt.Errorf("it needs a host task with ports")
+	}
+	return t.Faults.Validate()
+}
@@ -0,0 +1,15 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_ValidateFaults(t *testing.T) {
+	assert.NoError(t, (&Task{}).ValidateFaults())
+	assert.NoError(t, (&Task{Command: Strings{"api"}, Ports: Ports{{ContainerPort: 8080}}, Faults: &Faults{ErrorRate: 10}}).ValidateFaults())
+	assert.EqualError(t, (&Task{Image: "nginx", Ports: Ports{{ContainerPort: 80}}, Faults: &Faults{}}).ValidateFaults(), "it needs a host task with ports")
+	assert.EqualError(t, (&Task{Command: Strings{"api"}, Ports: Ports{{ContainerPort: 8080}}, Faults: &Faults{ErrorRate: 110}}).ValidateFaults(), "errorRate must be a percentage, between 0 and 100")
+	assert.EqualError(t, (&Task{Command: Strings{"api"}, Ports: Ports{{ContainerPort: 8080}}, Faults: &Faults{Bandwidth: -1}}).ValidateFaults(), "bandwidth must not be negative")
+}
@@ -15,6 +15,22 @@ type HTTPGetAction struct {
 	Port uint16 `json:"port,omitempty"`
 	// Path to access on the HTTP server.
 	Path string `json:"path,omitempty"`
+	// The Host header to send, e.g. for a server with virtual hosts. Defaults to localhost.
+	Host string `json:"host,omitempty"`
+	// Headers to send, e.g. an auth header.
+	HTTPHeaders []HTTPHeader `json:"httpHeaders,omitempty"`
+	// Do not verify the server's certificate, e.g. a self-signed one, when the scheme is HTTPS.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// The status codes that mean the server is healthy, e.g. "200-299,401". Defaults to "200-299".
+	StatusCodes string `json:"statusCodes,omitempty"`
+}
+
+// HTTPHeader is a header to send in an HTTP request.
+type HTTPHeader struct {
+	// The header's name.
+	Name string `json:"name"`
+	// The header's value.
+	Value string `json:"value"`
 }
 
 func (a HTTPGetAction) URL() *url.URL {
@@ -53,3 +69,39 @@ func (a HTTPGetAction) GetPort() uint16 {
 	}
 	return 80
 }
+
+// IsURL returns true if the action can be written as a URL, i.e. it only has a scheme, port and path.
+func (a HTTPGetAction) IsURL() bool {
+	return a.Host == "" && len(a.HTTPHeaders) == 0 && !a.InsecureSkipVerify && a.StatusCodes == ""
+}
+
+// Validate returns an error if the status codes are not valid.
+func (a HTTPGetAction) Validate() error {
+	_, err := a.AcceptsStatus(200)
+	return err
+}
+
+// AcceptsStatus returns true if the status code means the server is healthy.
+func (a HTTPGetAction) AcceptsStatus(code int) (bool, error) {
+	if a.StatusCodes == "" {
+		return code >= 200 && code < 300, nil
+	}
+	accepted := false
+	for _, r := range strings.Split(a.StatusCodes, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(r), "-")
+		min, err := strconv.Atoi(from)
+		if err != nil {
+			return false, fmt.Errorf("invalid status codes %q", a.StatusCodes)
+		}
+		max := min
+		if isRange {
+			if max, err = strconv.Atoi(to); err != nil || max < min {
+				return false, fmt.Errorf("invalid status codes %q", a.StatusCodes)
+			}
+		}
+		if code >= min && code <= max {
+			accepted = true
+		}
+	}
+	return accepted, nil
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,3 +14,30 @@ func TestHTTPGetAction_URL(t *testing.T) {
 	}
 	assert.Equal(t, "https://localhost:8080", a.URL().String())
 }
+
+func TestHTTPGetAction_AcceptsStatus(t *testing.T) {
+	tests := []struct {
+		statusCodes string
+		code        int
+		want        bool
+	}{
+		{"", 200, true},
+		{"", 299, true},
+		{"", 301, false},
+		{"", 401, false},
+		{"200-299,401", 401, true},
+		{"200-299, 401", 404, false},
+		{"200-499", 404, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.statusCodes+"/"+strconv.Itoa(tt.code), func(t *testing.T) {
+			got, err := HTTPGetAction{StatusCodes: tt.statusCodes}.AcceptsStatus(tt.code)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		assert.EqualError(t, HTTPGetAction{StatusCodes: "2xx"}.Validate(), `invalid status codes "2xx"`)
+		assert.EqualError(t, HTTPGetAction{StatusCodes: "299-200"}.Validate(), `invalid status codes "299-200"`)
+	})
+}
@@ -0,0 +1,18 @@
+package types
+
+// Input is an artifact produced by another task, that is copied into this task's working directory before it runs.
+type Input struct {
+	// The name of the task that produces the artifact. This task implicitly depends on it.
+	Task string `json:"task"`
+	// The path of the artifact, as declared in the producing task's artifacts.
+	Artifact string `json:"artifact"`
+	// Where to copy the artifact to, relative to this task's working directory. Defaults to the artifact's path.
+	Path string `json:"path,omitempty"`
+}
+
+func (i Input) GetPath() string {
+	if i.Path != "" {
+		return i.Path
+	}
+	return i.Artifact
+}
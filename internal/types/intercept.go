@@ -0,0 +1,21 @@
+package types
+
+import "fmt"
+
+// Intercept routes the traffic for a Kubernetes service to the task's first port, so one service can be developed
+// locally against the rest of the cluster.
+type Intercept struct {
+	// The name of the service, in the task's namespace.
+	Service string `json:"service"`
+	// The port of the service to intercept, which must be its only port, as the rest of its ports could not be routed.
+	// Optional.
+	Port uint16 `json:"port,omitempty"`
+}
+
+// Validate returns an error if the intercept is not valid.
+func (i Intercept) Validate() error {
+	if i.Service == "" {
+		return fmt.Errorf("service is required")
+	}
+	return nil
+}
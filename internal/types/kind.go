@@ -0,0 +1,98 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// kind is a built-in kind of task, a container of a common development dependency, e.g. a cloud emulator.
+type kind struct {
+	// the settings a task of the kind inherits, unless it sets its own
+	task Task
+	// where the task's seed data is mounted, or empty if it cannot be seeded
+	seedPath string
+}
+
+var kinds = map[string]kind{
+	// AWS, seeded by scripts run once it is ready, e.g. "awslocal s3 mb s3://my-bucket"
+	"localstack": {
+		task: Task{
+			Image:          "localstack/localstack",
+			Ports:          Ports{{ContainerPort: 4566}},
+			ReadinessProbe: &Probe{HTTPGet: &HTTPGetAction{Port: 4566, Path: "/_localstack/health"}},
+		},
+		seedPath: "/etc/localstack/init/ready.d",
+	},
+	// S3 compatible storage, with its console on port 9001
+	"minio": {
+		task: Task{
+			Image:          "minio/minio",
+			Args:           Strings{"server", "/data", "--console-address", ":9001"},
+			Ports:          Ports{{ContainerPort: 9000}, {ContainerPort: 9001}},
+			ReadinessProbe: &Probe{HTTPGet: &HTTPGetAction{Port: 9000, Path: "/minio/health/ready"}},
+		},
+	},
+	// Google Cloud Storage, seeded with a directory for each bucket, containing its objects
+	"fake-gcs": {
+		task: Task{
+			Image:          "fsouza/fake-gcs-server",
+			Args:           Strings{"-scheme", "http", "-port", "4443", "-external-url", "http://localhost:4443"},
+			Ports:          Ports{{ContainerPort: 4443}},
+			ReadinessProbe: &Probe{HTTPGet: &HTTPGetAction{Port: 4443, Path: "/storage/v1/b"}},
+		},
+		seedPath: "/data",
+	},
+	// DynamoDB, shared by every client, whatever their region or credentials
+	"dynamodb-local": {
+		task: Task{
+			Image:          "amazon/dynamodb-local",
+			Args:           Strings{"-jar", "DynamoDBLocal.jar", "-sharedDb", "-inMemory"},
+			Ports:          Ports{{ContainerPort: 8000}},
+			ReadinessProbe: &Probe{TCPSocket: &TCPSocketAction{Port: 8000}},
+		},
+	},
+}
+
+// Kinds returns the names of the built-in kinds of task.
+func Kinds() []string {
+	var names []string
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateKind returns an error if the task's kind is not a built-in kind, or it has seed data the kind cannot be
+// seeded with.
+func (t Task) ValidateKind() error {
+	if t.Kind == "" {
+		if t.Seed != "" {
+			return fmt.Errorf("seed data needs a kind")
+		}
+		return nil
+	}
+	k, ok := kinds[t.Kind]
+	if !ok {
+		return fmt.Errorf("kind %q must be one of %s", t.Kind, strings.Join(Kinds(), ", "))
+	}
+	if t.Seed != "" && k.seedPath == "" {
+		return fmt.Errorf("kind %q cannot be seeded", t.Kind)
+	}
+	return nil
+}
+
+// applyKind returns the task with the fields it does not set itself taken from its kind, and its seed data mounted.
+func (s *Spec) applyKind(name string, t Task) (Task, error) {
+	if err := t.ValidateKind(); err != nil {
+		return t, fmt.Errorf("task %q: %w", name, err)
+	}
+	k := kinds[t.Kind]
+	if t.Seed != "" {
+		volume := name + "-seed"
+		s.Volumes = append(s.Volumes, Volume{Name: volume, HostPath: &HostPath{Path: t.Seed}})
+		t.VolumeMounts = append(t.VolumeMounts, VolumeMount{Name: volume, MountPath: k.seedPath, ReadOnly: true})
+	}
+	return t.inherit(k.task)
+}
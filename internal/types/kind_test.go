@@ -0,0 +1,43 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_ValidateKind(t *testing.T) {
+	assert.NoError(t, Task{}.ValidateKind())
+	assert.NoError(t, Task{Kind: "localstack", Seed: "seed"}.ValidateKind())
+	assert.NoError(t, Task{Kind: "minio"}.ValidateKind())
+	assert.EqualError(t, Task{Kind: "redis"}.ValidateKind(), `kind "redis" must be one of dynamodb-local, fake-gcs, localstack, minio`)
+	assert.EqualError(t, Task{Kind: "minio", Seed: "seed"}.ValidateKind(), `kind "minio" cannot be seeded`)
+	assert.EqualError(t, Task{Seed: "seed"}.ValidateKind(), "seed data needs a kind")
+}
+
+func TestSpec_applyKind(t *testing.T) {
+	t.Run("Inherits", func(t *testing.T) {
+		s := &Spec{Tasks: Tasks{
+			"aws":     {Kind: "localstack", Image: "localstack/localstack:3.8", Seed: "seed/aws"},
+			"aws-dev": {Extends: "aws", Env: EnvVars{"DEBUG": {Value: "1"}}},
+		}}
+		assert.NoError(t, s.ApplyExtends())
+
+		aws := s.Tasks["aws"]
+		assert.Equal(t, "localstack/localstack:3.8", aws.Image)
+		assert.Equal(t, uint16(4566), aws.Ports[0].GetHostPort())
+		assert.Equal(t, "/_localstack/health", aws.ReadinessProbe.HTTPGet.Path)
+		assert.NotSame(t, kinds["localstack"].task.ReadinessProbe, aws.ReadinessProbe, "each task has its own probe")
+		assert.Equal(t, []Volume{{Name: "aws-seed", HostPath: &HostPath{Path: "seed/aws"}}}, s.Volumes)
+		assert.Equal(t, []VolumeMount{{Name: "aws-seed", MountPath: "/etc/localstack/init/ready.d", ReadOnly: true}}, aws.VolumeMounts)
+
+		dev := s.Tasks["aws-dev"]
+		assert.Equal(t, aws.Image, dev.Image)
+		assert.Equal(t, aws.VolumeMounts, dev.VolumeMounts)
+		assert.Len(t, s.Volumes, 1, "the seed is only mounted once")
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		s := &Spec{Tasks: Tasks{"cache": {Kind: "redis"}}}
+		assert.EqualError(t, s.ApplyExtends(), `task "cache": kind "redis" must be one of dynamodb-local, fake-gcs, localstack, minio`)
+	})
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// CheckKitVersion returns an error if the version of kit is older than the minimum version the workflow needs, e.g.
+// "v0.9". A version of kit that is not a semantic version, e.g. "(devel)", is assumed to be new enough.
+func CheckKitVersion(minimum, version string) error {
+	if minimum == "" {
+		return nil
+	}
+	canonical := minimum
+	if !strings.HasPrefix(canonical, "v") {
+		canonical = "v" + canonical
+	}
+	if !semver.IsValid(canonical) {
+		return fmt.Errorf("invalid minKitVersion %q, must be a semantic version, e.g. v0.9.0", minimum)
+	}
+	if semver.IsValid(version) && semver.Compare(version, canonical) < 0 {
+		return fmt.Errorf("requires kit >= %s, but this is %s, please upgrade kit", canonical, version)
+	}
+	return nil
+}
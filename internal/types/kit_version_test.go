@@ -0,0 +1,16 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckKitVersion(t *testing.T) {
+	assert.NoError(t, CheckKitVersion("", "v0.1.0"))
+	assert.NoError(t, CheckKitVersion("v0.9", "v0.9.0"))
+	assert.NoError(t, CheckKitVersion("0.9.0", "v0.10.2"))
+	assert.NoError(t, CheckKitVersion("v0.9", "(devel)"), "development builds are new enough")
+	assert.EqualError(t, CheckKitVersion("v0.9", "v0.8.3"), "requires kit >= v0.9, but this is v0.8.3, please upgrade kit")
+	assert.EqualError(t, CheckKitVersion("latest", "v0.8.3"), `invalid minKitVersion "latest", must be a semantic version, e.g. v0.9.0`)
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LogRotation is how the tasks' log files are rotated, so long-running services do not fill the disk. When a log file
+// reaches its maximum size, it is renamed, e.g. "api.log" to "api.log.1", and a new one started.
+type LogRotation struct {
+	// The size to rotate a log file at, e.g. "10Mi". Defaults to 10Mi. Zero disables rotation.
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+	// The number of rotated files to keep for each task. Defaults to 3.
+	MaxFiles *int `json:"maxFiles,omitempty"`
+	// Delete rotated files older than this, e.g. "168h". If omitted, they are kept until there are more than maxFiles.
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// GetMaxSize returns the size in bytes to rotate a log file at, or zero if log files are not rotated.
+func (r *LogRotation) GetMaxSize() int64 {
+	if r == nil || r.MaxSize == nil {
+		return 10 * 1024 * 1024
+	}
+	return r.MaxSize.Value()
+}
+
+// GetMaxFiles returns the number of rotated files to keep.
+func (r *LogRotation) GetMaxFiles() int {
+	if r == nil || r.MaxFiles == nil {
+		return 3
+	}
+	return *r.MaxFiles
+}
+
+// GetMaxAge returns the age of rotated files to delete, or zero if they are not deleted by age.
+func (r *LogRotation) GetMaxAge() time.Duration {
+	if r == nil || r.MaxAge == nil {
+		return 0
+	}
+	return r.MaxAge.Duration
+}
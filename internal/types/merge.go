@@ -0,0 +1,188 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NamedWorkflow is a workflow loaded from a directory, e.g. a repository.
+type NamedWorkflow struct {
+	// The name, used to namespace the workflow's tasks, e.g. "api", or "services/api" for a nested workflow. The tasks
+	// of a workflow without a name are not namespaced, e.g. the root workflow of a monorepo.
+	Name string
+	// The directory the workflow was loaded from, its paths are relative to this.
+	Dir string
+	*Workflow
+}
+
+// Merge merges the workflows into one, so they can be run in one session. Tasks are renamed "<workflow>/<task>", and
+// may depend on tasks in other workflows by that name, or relative to their own workflow, e.g. "../shared:build".
+// Paths are made relative to the current directory. Settings of the workflows are applied to each of their tasks,
+// except where the settings must be shared, where the first set is used.
+func Merge(workflows []NamedWorkflow) (*Workflow, error) {
+	merged := &Workflow{Tasks: Tasks{}, Semaphores: map[string]int{}}
+	names := map[string]bool{}
+	for _, wf := range workflows {
+		if names[wf.Name] {
+			return nil, fmt.Errorf("two workflows are named %q", wf.Name)
+		}
+		names[wf.Name] = true
+		// a task's dependencies are in the same workflow, unless they are namespaced, or relative to the workflow
+		qualify := func(task string) string {
+			if dir, name, ok := strings.Cut(task, ":"); ok && (strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "../")) {
+				if dir := path.Join(wf.Name, dir); dir != "." {
+					return dir + "/" + name
+				}
+				return name
+			}
+			if strings.Contains(task, "/") || wf.Name == "" {
+				return task
+			}
+			return wf.Name + "/" + task
+		}
+		volumes := map[string]string{}
+		for _, v := range wf.Volumes {
+			volumes[v.Name] = strings.ReplaceAll(qualify(v.Name), "/", "-")
+			if v.HostPath != nil {
+				v.HostPath = &HostPath{Path: filepath.Join(wf.Dir, v.HostPath.Path)}
+			}
+			v.Name = volumes[v.Name]
+			merged.Volumes = append(merged.Volumes, v)
+		}
+		for name, t := range wf.Tasks {
+			t = wf.TaskDefaults.Apply(t)
+			if t.Dependencies != nil {
+				t.Dependencies = &Dependencies{
+					AllOf: mapStrings(t.Dependencies.AllOf, qualify),
+					AnyOf: mapStrings(t.Dependencies.AnyOf, qualify),
+				}
+			}
+			if t.OnFailure != "" {
+				t.OnFailure = qualify(t.OnFailure)
+			}
+			var inputs []Input
+			for _, input := range t.Inputs {
+				input.Task = qualify(input.Task)
+				inputs = append(inputs, input)
+			}
+			t.Inputs = inputs
+			var mounts []VolumeMount
+			for _, m := range t.VolumeMounts {
+				if v, ok := volumes[m.Name]; ok {
+					m.Name = v
+				}
+				mounts = append(mounts, m)
+			}
+			t.VolumeMounts = mounts
+			workingDir, err := Spec(*wf.Workflow).WorkingDir(wf.Dir, t)
+			if err != nil {
+				return nil, fmt.Errorf("task %q %w", qualify(name), err)
+			}
+			t.WorkingDir = workingDir
+			if strings.HasPrefix(t.Image, ".") {
+				t.Image = filepath.Join(wf.Dir, t.Image)
+			}
+			if t.Log != "" {
+				t.Log = filepath.Join(wf.Dir, t.Log)
+			}
+			if len(t.Shell) == 0 {
+				t.Shell = wf.Shell
+			}
+			// the workflow's environment is applied to its tasks, but the task's own takes precedence
+			env := EnvVars{}
+			for k, v := range wf.Env {
+				env[k] = v
+			}
+			for k, v := range t.Env {
+				env[k] = v
+			}
+			// references to other tasks, e.g. "{{ ports.db.host }}", are qualified like dependencies
+			qualifyReference := func(r TaskReference) (string, error) {
+				r.Task = qualify(r.Task)
+				return r.String(), nil
+			}
+			t.Env, _ = env.ExpandTaskReferences(qualifyReference)
+			t.Open, _ = ExpandTaskReferences(t.Open, qualifyReference)
+			// the task's envfiles are relative to its working directory, but the workflow's are not
+			var envfile Envfile
+			for _, f := range wf.Envfile {
+				abs, err := filepath.Abs(filepath.Join(wf.Dir, f))
+				if err != nil {
+					return nil, err
+				}
+				envfile = append(envfile, abs)
+			}
+			t.Envfile = append(envfile, t.Envfile...)
+			merged.Tasks[qualify(name)] = t
+		}
+		for name, n := range wf.Semaphores {
+			merged.Semaphores[name] = n
+		}
+		merged.Notifications = append(merged.Notifications, wf.Notifications...)
+		for _, e := range wf.Extensions {
+			e.Module = filepath.Join(wf.Dir, e.Module)
+			if e.Dir != "" {
+				e.Dir = filepath.Join(wf.Dir, e.Dir)
+			}
+			var tasks Strings
+			for _, name := range e.Tasks {
+				tasks = append(tasks, qualify(name))
+			}
+			e.Tasks = tasks
+			merged.Extensions = append(merged.Extensions, e)
+		}
+		for _, u := range wf.Open {
+			u, _ = ExpandTaskReferences(u, func(r TaskReference) (string, error) {
+				r.Task = qualify(r.Task)
+				return r.String(), nil
+			})
+			merged.Open = append(merged.Open, u)
+		}
+		merged.RequiredEnv = append(merged.RequiredEnv, wf.RequiredEnv...)
+		merged.StrictEnv = merged.StrictEnv || wf.StrictEnv
+		if merged.TerminationGracePeriodSeconds == nil {
+			merged.TerminationGracePeriodSeconds = wf.TerminationGracePeriodSeconds
+		}
+		if merged.ContainerRuntime == "" {
+			merged.ContainerRuntime = wf.ContainerRuntime
+		}
+		if merged.Network == "" {
+			merged.Network = wf.Network
+		}
+		if merged.MaxParallel == 0 {
+			merged.MaxParallel = wf.MaxParallel
+		}
+		if merged.Timestamps == "" {
+			merged.Timestamps = wf.Timestamps
+		}
+		if merged.Cache == nil {
+			merged.Cache = wf.Cache
+		}
+	}
+	// check the cross-workflow dependencies exist
+	var taskNames []string
+	for name := range merged.Tasks {
+		taskNames = append(taskNames, name)
+	}
+	sort.Strings(taskNames)
+	for _, name := range taskNames {
+		t := merged.Tasks[name]
+		for _, dependency := range t.GetDependencies() {
+			if _, ok := merged.Tasks[dependency]; !ok {
+				return nil, fmt.Errorf("task %q depends on %q, which is not found in any workflow", name, dependency)
+			}
+		}
+	}
+	return merged, nil
+}
+
+func mapStrings(s Strings, f func(string) string) Strings {
+	var out Strings
+	for _, x := range s {
+		out = append(out, f(x))
+	}
+	return out
+}
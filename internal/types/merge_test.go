@@ -0,0 +1,89 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	t.Run("Namespaced tasks", func(t *testing.T) {
+		merged, err := Merge([]NamedWorkflow{
+			{Name: "api", Dir: "../api", Workflow: &Workflow{
+				Env: EnvVars{"FOO": {Value: "1"}, "BAR": {Value: "1"}},
+				Tasks: Tasks{
+					"build": {Command: Strings{"go", "build", "."}},
+					"run":   {Command: Strings{"./api"}, Dependencies: &Dependencies{AllOf: Strings{"build", "web/serve"}}, Env: EnvVars{"BAR": {Value: "2"}, "DB": {Value: "localhost:{{ ports.db.host }}"}, "WEB": {Value: "{{ ports.web/serve.host }}"}}},
+					"db":    {Image: "./images/db", WorkingDir: "/app"},
+				},
+			}},
+			{Name: "web", Dir: "../web", Workflow: &Workflow{
+				Open: Strings{"http://localhost:{{ ports.serve.host }}"},
+				Tasks: Tasks{
+					"serve": {Command: Strings{"npm", "start"}, WorkingDir: "app", Open: "http://localhost:{{ ports.serve.host }}/admin"},
+				},
+			}},
+		})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"api/build", "api/run", "api/db", "web/serve"}, keys(merged.Tasks))
+		run := merged.Tasks["api/run"]
+		assert.Equal(t, []string{"api/build", "web/serve"}, run.GetDependencies())
+		assert.Equal(t, "../api", run.WorkingDir)
+		assert.Equal(t, EnvVars{"FOO": {Value: "1"}, "BAR": {Value: "2"}, "DB": {Value: "localhost:{{ ports.api/db.host }}"}, "WEB": {Value: "{{ ports.web/serve.host }}"}}, run.Env)
+		assert.Equal(t, "../web/app", merged.Tasks["web/serve"].WorkingDir)
+		assert.Equal(t, "http://localhost:{{ ports.web/serve.host }}/admin", merged.Tasks["web/serve"].Open)
+		assert.Equal(t, Strings{"http://localhost:{{ ports.web/serve.host }}"}, merged.Open)
+		db := merged.Tasks["api/db"]
+		assert.Equal(t, "../api/images/db", db.Image)
+		assert.Equal(t, "/app", db.WorkingDir)
+	})
+	t.Run("Monorepo", func(t *testing.T) {
+		merged, err := Merge([]NamedWorkflow{
+			{Name: "", Dir: ".", Workflow: &Workflow{Tasks: Tasks{
+				"up":   {Dependencies: &Dependencies{AllOf: Strings{"services/api/run", "./services/web:serve"}}},
+				"lint": {},
+			}}},
+			{Name: "services/api", Dir: "services/api", Workflow: &Workflow{Tasks: Tasks{
+				"run": {Dependencies: &Dependencies{AllOf: Strings{"../shared:build", "../..:lint"}}},
+			}}},
+			{Name: "services/web", Dir: "services/web", Workflow: &Workflow{Tasks: Tasks{"serve": {}}}},
+			{Name: "services/shared", Dir: "services/shared", Workflow: &Workflow{Tasks: Tasks{"build": {}}}},
+		})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"up", "lint", "services/api/run", "services/web/serve", "services/shared/build"}, keys(merged.Tasks))
+		up := merged.Tasks["up"]
+		assert.Equal(t, []string{"services/api/run", "services/web/serve"}, up.GetDependencies())
+		run := merged.Tasks["services/api/run"]
+		assert.Equal(t, []string{"services/shared/build", "lint"}, run.GetDependencies())
+	})
+	t.Run("Roots", func(t *testing.T) {
+		merged, err := Merge([]NamedWorkflow{
+			{Name: "devenv", Dir: "../devenv", Workflow: &Workflow{
+				Roots: map[string]string{"frontend": "../frontend"},
+				Tasks: Tasks{"serve": {Command: Strings{"npm", "start"}, Root: "frontend", WorkingDir: "app"}},
+			}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "../frontend/app", merged.Tasks["devenv/serve"].WorkingDir)
+		_, err = Merge([]NamedWorkflow{{Name: "devenv", Workflow: &Workflow{Tasks: Tasks{"serve": {Root: "frontend"}}}}})
+		assert.EqualError(t, err, `task "devenv/serve" has root "frontend", which is not one of the workflow's roots`)
+	})
+	t.Run("Missing dependency", func(t *testing.T) {
+		_, err := Merge([]NamedWorkflow{
+			{Name: "api", Workflow: &Workflow{Tasks: Tasks{"run": {Dependencies: &Dependencies{AllOf: Strings{"web/serve"}}}}}},
+		})
+		assert.EqualError(t, err, `task "api/run" depends on "web/serve", which is not found in any workflow`)
+	})
+	t.Run("Duplicate name", func(t *testing.T) {
+		_, err := Merge([]NamedWorkflow{{Name: "api", Workflow: &Workflow{}}, {Name: "api", Workflow: &Workflow{}}})
+		assert.EqualError(t, err, `two workflows are named "api"`)
+	})
+}
+
+func keys(tasks Tasks) []string {
+	var names []string
+	for name := range tasks {
+		names = append(names, name)
+	}
+	return names
+}
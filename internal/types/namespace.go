@@ -0,0 +1,117 @@
+package types
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// PortOffset returns a port offset for the namespace, a multiple of 100 between 100 and 10,000, so that the ports of two
+// namespaces are unlikely to collide.
+func PortOffset(namespace string) uint16 {
+	if namespace == "" {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace))
+	return uint16(100 * (1 + h.Sum32()%100))
+}
+
+// SetNamespace suffixes the names of the workflow's containers, network and named volumes with the namespace, and
+// offsets its host ports, so two copies of it can run side by side. Host tasks choose their own port, so they are given
+// the PORT environment variable, which they must listen on.
+func (s *Spec) SetNamespace(namespace string, portOffset uint16) error {
+	if namespace == "" {
+		return nil
+	}
+	s.namespace = namespace
+	if s.Network != "" {
+		s.Network += "-" + namespace
+	}
+	volumes := map[string]string{}
+	for i, v := range s.Volumes {
+		if v.HostPath == nil {
+			volumes[v.Name] = v.Name + "-" + namespace
+			s.Volumes[i].Name = volumes[v.Name]
+		}
+	}
+	offset := func(port uint16) (uint16, error) {
+		if port == 0 {
+			return 0, nil
+		}
+		if int(port)+int(portOffset) > 65535 {
+			return 0, fmt.Errorf("port %d plus offset %d is out of range", port, portOffset)
+		}
+		return port + portOffset, nil
+	}
+	for name, t := range s.Tasks {
+		original := t
+		var ports Ports
+		for _, p := range t.Ports {
+			hostPort, err := offset(p.GetHostPort())
+			if err != nil {
+				return fmt.Errorf("task %q: %w", name, err)
+			}
+			ports = append(ports, Port{ContainerPort: p.ContainerPort, HostPort: hostPort})
+		}
+		t.Ports = ports
+		for _, probe := range []*Probe{t.ReadinessProbe, t.LivenessProbe} {
+			if probe == nil {
+				continue
+			}
+			// a container's probe may use its container port, which is not offset, so it is probed on its host port
+			var err error
+			if probe.TCPSocket != nil {
+				x := *probe.TCPSocket
+				x.Port, err = offset(original.hostPort(x.Port))
+				probe.TCPSocket = &x
+			}
+			if probe.HTTPGet != nil && err == nil {
+				x := *probe.HTTPGet
+				x.Port, err = offset(original.hostPort(x.Port))
+				probe.HTTPGet = &x
+			}
+			if err != nil {
+				return fmt.Errorf("task %q: %w", name, err)
+			}
+		}
+		if t.TLS != nil {
+			x := *t.TLS
+			port, err := offset(x.Port)
+			if err != nil {
+				return fmt.Errorf("task %q: %w", name, err)
+			}
+			x.Port = port
+			t.TLS = &x
+		}
+		var mounts []VolumeMount
+		for _, m := range t.VolumeMounts {
+			if v, ok := volumes[m.Name]; ok {
+				m.Name = v
+			}
+			mounts = append(mounts, m)
+		}
+		t.VolumeMounts = mounts
+		if t.Image == "" && len(t.Ports) > 0 {
+			if _, ok := t.Env["PORT"]; !ok {
+				env := EnvVars{"PORT": {Value: fmt.Sprint(t.Ports[0].GetHostPort())}}
+				for k, v := range t.Env {
+					env[k] = v
+				}
+				t.Env = env
+			}
+		}
+		s.Tasks[name] = t
+	}
+	return nil
+}
+
+// ResourceName converts a task name into a name that can be used for a container or Kubernetes resource. Tasks
+// namespaced by their workflow (e.g. "other-repo/api") contain a "/", and names are suffixed by the namespace.
+func (s *Spec) ResourceName(task string) string {
+	name := strings.ReplaceAll(task, "/", "-")
+	if s.namespace != "" {
+		name += "-" + s.namespace
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortOffset(t *testing.T) {
+	assert.Zero(t, PortOffset(""))
+	offset := PortOffset("feature")
+	assert.Equal(t, offset, PortOffset("feature"))
+	assert.Zero(t, offset%100)
+	assert.True(t, offset >= 100 && offset <= 10000)
+}
+
+func TestSpec_SetNamespace(t *testing.T) {
+	t.Run("No namespace", func(t *testing.T) {
+		s := &Spec{Network: "net", Tasks: Tasks{"api": {Ports: Ports{{ContainerPort: 8080}}}}}
+		assert.NoError(t, s.SetNamespace("", 100))
+		assert.Equal(t, "net", s.Network)
+		assert.Equal(t, uint16(8080), s.Tasks["api"].Ports[0].GetHostPort())
+		assert.Equal(t, "api", s.ResourceName("api"))
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		s := &Spec{
+			Network: "net",
+			Volumes: []Volume{{Name: "data"}, {Name: "src", HostPath: &HostPath{Path: "."}}},
+			Tasks: Tasks{
+				"api": {
+					Ports:          Ports{{ContainerPort: 8080}},
+					ReadinessProbe: &Probe{HTTPGet: &HTTPGetAction{Port: 8080}},
+				},
+				"db": {
+					Image:          "postgres",
+					Ports:          Ports{{ContainerPort: 5432, HostPort: 15432}},
+					ReadinessProbe: &Probe{TCPSocket: &TCPSocketAction{Port: 5432}},
+					VolumeMounts:   []VolumeMount{{Name: "data"}, {Name: "src"}},
+				},
+			},
+		}
+		assert.NoError(t, s.SetNamespace("feature", 100))
+		assert.Equal(t, "net-feature", s.Network)
+		assert.Equal(t, "data-feature", s.Volumes[0].Name)
+		assert.Equal(t, "src", s.Volumes[1].Name)
+		api := s.Tasks["api"]
+		assert.Equal(t, uint16(8180), api.Ports[0].GetHostPort())
+		assert.Equal(t, uint16(8180), api.ReadinessProbe.HTTPGet.Port)
+		assert.Equal(t, "8180", api.Env["PORT"].Value)
+		db := s.Tasks["db"]
+		assert.Equal(t, uint16(5432), db.Ports[0].ContainerPort)
+		assert.Equal(t, uint16(15532), db.Ports[0].GetHostPort())
+		assert.Equal(t, uint16(15532), db.ReadinessProbe.TCPSocket.Port, "the container port is probed on its host port")
+		assert.Equal(t, "data-feature", db.VolumeMounts[0].Name)
+		assert.Equal(t, "src", db.VolumeMounts[1].Name)
+		assert.NotContains(t, db.Env, "PORT")
+		assert.Equal(t, "other-api-feature", s.ResourceName("other/api"))
+	})
+	t.Run("Port out of range", func(t *testing.T) {
+		s := &Spec{Tasks: Tasks{"api": {Ports: Ports{{ContainerPort: 65500}}}}}
+		assert.Error(t, s.SetNamespace("feature", 100))
+	})
+}
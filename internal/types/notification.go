@@ -0,0 +1,35 @@
+package types
+
+import (
+	"net/url"
+	"slices"
+)
+
+// Notification posts task events to a webhook, e.g. Slack.
+type Notification struct {
+	// The URL to post to.
+	URL string `json:"url"`
+	// The events to post, either a task phase (e.g. "succeeded" or "failed") or "recovered" when a task is running or succeeds after failing. Defaults to "failed" and "recovered".
+	Events Strings `json:"events,omitempty"`
+	// A Go template for the request body, with the fields .Workflow, .Task, .Event and .Message.
+	// Defaults to a Slack message for Slack URLs, and a JSON object with those fields otherwise.
+	Template string `json:"template,omitempty"`
+}
+
+func (n Notification) GetEvents() []string {
+	if len(n.Events) > 0 {
+		return n.Events
+	}
+	return []string{"failed", "recovered"}
+}
+
+// Wants returns true if the event should be posted.
+func (n Notification) Wants(event string) bool {
+	return slices.Contains(n.GetEvents(), event)
+}
+
+// IsSlack returns true if the URL is a Slack incoming webhook.
+func (n Notification) IsSlack() bool {
+	u, err := url.Parse(n.URL)
+	return err == nil && u.Host == "hooks.slack.com"
+}
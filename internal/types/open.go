@@ -0,0 +1,25 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ValidateOpen returns an error if the URL to open in the browser is not an http or https URL, or references anything
+// but the ports of tasks.
+func ValidateOpen(u string) error {
+	for _, r := range FindTaskReferences(u) {
+		if r.Kind != "ports" {
+			return fmt.Errorf("%q references %s, must be ports.<task>.host or ports.<task>.container", u, r)
+		}
+	}
+	// the references are replaced with a port, so the URL can be parsed
+	parsed, err := url.Parse(taskReference.ReplaceAllString(u, "1"))
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%q must be an http or https URL", u)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOpen(t *testing.T) {
+	assert.NoError(t, ValidateOpen("http://localhost:3000"))
+	assert.NoError(t, ValidateOpen("https://localhost:{{ ports.web.host }}/admin"))
+	assert.EqualError(t, ValidateOpen("localhost:3000"), `"localhost:3000" must be an http or https URL`)
+	assert.EqualError(t, ValidateOpen("http://{{ outputs.login.HOST }}"), `"http://{{ outputs.login.HOST }}" references {{ outputs.login.HOST }}, must be ports.<task>.host or ports.<task>.container`)
+	assert.Error(t, ValidateOpen("http://localhost:port"))
+}
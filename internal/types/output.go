@@ -0,0 +1,26 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Output captures a value from a task's output, so that it can be used by the tasks that depend on it as an environment variable.
+type Output struct {
+	// The name of the environment variable.
+	Name string `json:"name"`
+	// A regular expression matched against each line of output (stdout and stderr). The value is the first capture group,
+	// or the whole match if there are no groups. If omitted, the value is all of stdout, with surrounding white space removed.
+	Regex string `json:"regex,omitempty"`
+}
+
+// Validate returns an error if the output is not valid.
+func (o Output) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("output name is required")
+	}
+	if _, err := regexp.Compile(o.Regex); err != nil {
+		return fmt.Errorf("invalid regex for output %q: %w", o.Name, err)
+	}
+	return nil
+}
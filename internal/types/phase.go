@@ -0,0 +1,138 @@
+package types
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// Phase is where a task is in its lifecycle.
+type Phase string
+
+const (
+	// PhasePending is a task that has not been run yet, e.g. because its dependencies are not ready.
+	PhasePending Phase = "pending"
+	// PhaseWaiting is a task that is about to run, e.g. waiting for a mutex, a semaphore or its ports to be free.
+	PhaseWaiting Phase = "waiting"
+	// PhaseStarting is a service that is running, but not yet ready.
+	PhaseStarting Phase = "starting"
+	// PhaseRunning is a job that is running, or a service that is ready.
+	PhaseRunning Phase = "running"
+	// PhaseStalled is a task that has not written any output for a while.
+	PhaseStalled Phase = "stalled"
+	// PhaseSucceeded is a task that exited successfully, or a job whose run was restored, e.g. from the cache.
+	PhaseSucceeded Phase = "succeeded"
+	// PhaseFailed is a task that exited unsuccessfully, or whose probe failed.
+	PhaseFailed Phase = "failed"
+	// PhaseCancelled is a task that was stopped, e.g. when kit exits.
+	PhaseCancelled Phase = "cancelled"
+	// PhaseSkipped is a task that did not need to run, e.g. because its targets are newer than its sources.
+	PhaseSkipped Phase = "skipped"
+)
+
+// the phases a task can transition to from each phase, a task can always transition to its current phase, e.g. to
+// change its message
+var phaseTransitions = map[Phase][]Phase{
+	PhasePending:   {PhaseWaiting},
+	PhaseWaiting:   {PhaseStarting, PhaseRunning, PhaseSucceeded, PhaseFailed, PhaseCancelled, PhaseSkipped},
+	PhaseStarting:  {PhaseWaiting, PhaseRunning, PhaseStalled, PhaseSucceeded, PhaseFailed, PhaseCancelled},
+	PhaseRunning:   {PhaseWaiting, PhaseStalled, PhaseSucceeded, PhaseFailed, PhaseCancelled},
+	PhaseStalled:   {PhaseWaiting, PhaseStarting, PhaseRunning, PhaseSucceeded, PhaseFailed, PhaseCancelled},
+	PhaseSucceeded: {PhaseWaiting},
+	PhaseFailed:    {PhaseWaiting},
+	PhaseCancelled: {PhaseWaiting},
+	PhaseSkipped:   {PhaseWaiting},
+}
+
+// CanTransitionTo returns true if a task in the phase can transition to the other phase.
+func (p Phase) CanTransitionTo(to Phase) bool {
+	if p == to {
+		_, ok := phaseTransitions[p]
+		return ok
+	}
+	for _, next := range phaseTransitions[p] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition is a change of a task's phase.
+type Transition struct {
+	From    Phase
+	To      Phase
+	Message string
+	Time    time.Time
+}
+
+// PhaseMachine is the phase of a task, which only changes by the transitions in its lifecycle, and when it last
+// entered each phase. The zero value is in no phase, and can only transition to itself. Once the task is running, its
+// phase must be read with Snapshot, as it may be being changed.
+type PhaseMachine struct {
+	// guards the phase, its message, and the times
+	mu sync.RWMutex
+	// the phase of the task, e.g. "pending", "waiting", "running", "stalled", "succeeded", "failed", "cancelled", "skipped"
+	Phase Phase `json:"phase"`
+	// the message for the phase, e.g. "exit code 1"
+	Message string `json:"message,omitempty"`
+	// when the task last entered each phase
+	Since map[Phase]time.Time `json:"since,omitempty"`
+	hooks []func(Transition)
+}
+
+// PhaseSnapshot is the phase of a task at a moment.
+type PhaseSnapshot struct {
+	Phase   Phase               `json:"phase"`
+	Message string              `json:"message,omitempty"`
+	Since   map[Phase]time.Time `json:"since,omitempty"`
+}
+
+// Snapshot returns the phase, its message, and when the task last entered each phase, as they were at the same moment.
+func (m *PhaseMachine) Snapshot() PhaseSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	// the times are not copied, as they are replaced, rather than updated
+	return PhaseSnapshot{Phase: m.Phase, Message: m.Message, Since: m.Since}
+}
+
+// Restore sets the phase, its message, and the times to the snapshot, without a transition, e.g. for a task that
+// replaces another.
+func (m *PhaseMachine) Restore(s PhaseSnapshot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Phase, m.Message, m.Since = s.Phase, s.Message, maps.Clone(s.Since)
+}
+
+// OnTransition adds a hook that is called after each transition, in the order they were added.
+func (m *PhaseMachine) OnTransition(hook func(Transition)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Transition changes the phase, and calls the hooks, or returns an error, and leaves the phase unchanged, if the task
+// cannot transition to it.
+func (m *PhaseMachine) Transition(to Phase, message string) error {
+	m.mu.Lock()
+	if !m.Phase.CanTransitionTo(to) {
+		defer m.mu.Unlock()
+		return fmt.Errorf("cannot transition from %q to %q", m.Phase, to)
+	}
+	t := Transition{From: m.Phase, To: to, Message: message, Time: time.Now()}
+	// the times are copied, rather than updated, as they may be being read, e.g. to show them in the UI
+	since := maps.Clone(m.Since)
+	if since == nil {
+		since = map[Phase]time.Time{}
+	}
+	since[to] = t.Time
+	m.Phase, m.Message, m.Since = to, message, since
+	hooks := m.hooks
+	// the hooks are called without the lock, as they read the phase
+	m.mu.Unlock()
+	for _, hook := range hooks {
+		hook(t)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhase_CanTransitionTo(t *testing.T) {
+	assert.True(t, PhasePending.CanTransitionTo(PhaseWaiting))
+	assert.False(t, PhasePending.CanTransitionTo(PhaseRunning), "a task waits before it runs")
+	assert.True(t, PhaseStalled.CanTransitionTo(PhaseStarting), "output was received")
+	assert.True(t, PhaseFailed.CanTransitionTo(PhaseWaiting), "a task can be restarted")
+	assert.False(t, PhaseCancelled.CanTransitionTo(PhaseRunning), "e.g. a probe succeeding after it was stopped")
+	assert.True(t, PhaseRunning.CanTransitionTo(PhaseRunning), "to change the message")
+	assert.False(t, Phase("unknown").CanTransitionTo("unknown"))
+}
+
+func TestPhaseMachine(t *testing.T) {
+	m := &PhaseMachine{Phase: PhasePending}
+	var transitions []Transition
+	m.OnTransition(func(t Transition) { transitions = append(transitions, t) })
+
+	assert.NoError(t, m.Transition(PhaseWaiting, "waiting for mutex"))
+	assert.NoError(t, m.Transition(PhaseRunning, "job running"))
+	assert.NoError(t, m.Transition(PhaseFailed, "exit code 1"))
+	assert.Equal(t, PhaseFailed, m.Phase)
+	assert.Equal(t, "exit code 1", m.Message)
+
+	assert.EqualError(t, m.Transition(PhaseRunning, "readiness probe succeeded"), `cannot transition from "failed" to "running"`)
+	assert.Equal(t, PhaseFailed, m.Phase, "unchanged")
+	assert.Equal(t, "exit code 1", m.Message)
+
+	if assert.Len(t, transitions, 3) {
+		assert.Equal(t, Transition{From: PhaseRunning, To: PhaseFailed, Message: "exit code 1", Time: transitions[2].Time}, transitions[2])
+	}
+	assert.Equal(t, transitions[1].Time, m.Since[PhaseRunning])
+	assert.Equal(t, transitions[2].Time, m.Since[PhaseFailed])
+	assert.True(t, m.Since[PhasePending].IsZero(), "never entered")
+}
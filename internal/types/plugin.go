@@ -0,0 +1,30 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var pluginKind = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Plugin runs the task using an executable named "kit-task-<kind>" on the PATH, so custom kinds of task, e.g. terraform
+// apply, can be added without changing kit.
+type Plugin struct {
+	// The kind of task, e.g. "terraform" runs "kit-task-terraform".
+	Kind string `json:"kind"`
+	// Options for the plugin, e.g. the variables to pass to terraform. Kit does not interpret these.
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// Validate returns an error if the plugin is not valid.
+func (p Plugin) Validate() error {
+	if !pluginKind.MatchString(p.Kind) {
+		return fmt.Errorf("invalid kind %q, must be lowercase letters, digits and dashes", p.Kind)
+	}
+	return nil
+}
+
+// GetExecutable returns the name of the plugin's executable.
+func (p Plugin) GetExecutable() string {
+	return "kit-task-" + p.Kind
+}
@@ -0,0 +1,13 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin(t *testing.T) {
+	assert.NoError(t, Plugin{Kind: "terraform"}.Validate())
+	assert.EqualError(t, Plugin{Kind: "../terraform"}.Validate(), `invalid kind "../terraform", must be lowercase letters, digits and dashes`)
+	assert.Equal(t, "kit-task-terraform", Plugin{Kind: "terraform"}.GetExecutable())
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodLogs streams the logs of pods running in a Kubernetes cluster, e.g. the remote parts of a hybrid local and
+// cluster set-up. The pods are in the task's namespace.
+type PodLogs struct {
+	// The label selector of the pods, e.g. "app=api".
+	Selector string `json:"selector"`
+	// The container to stream the logs of. Defaults to all the pod's containers.
+	Container string `json:"container,omitempty"`
+}
+
+// Validate returns an error if the pod logs are not valid.
+func (p PodLogs) Validate() error {
+	if p.Selector == "" {
+		return fmt.Errorf("selector is required")
+	}
+	if _, err := labels.Parse(p.Selector); err != nil {
+		return fmt.Errorf("invalid selector %q: %w", p.Selector, err)
+	}
+	return nil
+}
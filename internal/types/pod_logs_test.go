@@ -0,0 +1,13 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPodLogs_Validate(t *testing.T) {
+	assert.NoError(t, PodLogs{Selector: "app=api,tier in (web)"}.Validate())
+	assert.EqualError(t, PodLogs{}.Validate(), "selector is required")
+	assert.Error(t, PodLogs{Selector: "app in"}.Validate())
+}
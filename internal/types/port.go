@@ -69,3 +69,8 @@ func (p Port) GetHostPort() uint16 {
 	}
 	return p.HostPort
 }
+
+// PrivilegedPort returns true if the port is privileged, i.e. below 1024, so only root can listen on it on Linux.
+func PrivilegedPort(port uint16) bool {
+	return port > 0 && port < 1024
+}
@@ -14,6 +14,9 @@ type Probe struct {
 	TCPSocket *TCPSocketAction `json:"tcpSocket,omitempty"`
 	// The action to perform.
 	HTTPGet *HTTPGetAction `json:"httpGet,omitempty"`
+	// A regular expression to match each line of the task's output against, e.g. "Listening on port". The task is ready
+	// when a line matches. Only for readiness probes.
+	LogMatch string `json:"logMatch,omitempty"`
 	// Number of seconds after the process has started before the probe is initiated.
 	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
 	// How often (in seconds) to perform the probe.
@@ -22,6 +25,10 @@ type Probe struct {
 	SuccessThreshold int32 `json:"successThreshold,omitempty"`
 	// Minimum consecutive failures for the probe to be considered failed after having succeeded.
 	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+	// Number of seconds after which an attempt times out, and fails. Defaults to 1 second.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// Log the result of every attempt, rather than only when the probe starts passing or failing.
+	LogAttempts bool `json:"logAttempts,omitempty"`
 }
 
 func (p *Probe) UnmarshalJSON(data []byte) error {
@@ -29,20 +36,26 @@ func (p *Probe) UnmarshalJSON(data []byte) error {
 		x := struct {
 			TCPSocket           *TCPSocketAction `json:"tcpSocket,omitempty"`
 			HTTPGet             *HTTPGetAction   `json:"httpGet,omitempty"`
+			LogMatch            string           `json:"logMatch,omitempty"`
 			InitialDelaySeconds int32            `json:"initialDelaySeconds,omitempty"`
 			PeriodSeconds       int32            `json:"periodSeconds,omitempty"`
 			SuccessThreshold    int32            `json:"successThreshold,omitempty"`
 			FailureThreshold    int32            `json:"failureThreshold,omitempty"`
+			TimeoutSeconds      int32            `json:"timeoutSeconds,omitempty"`
+			LogAttempts         bool             `json:"logAttempts,omitempty"`
 		}{}
 		if err := json.Unmarshal(data, &x); err != nil {
 			return err
 		}
 		p.TCPSocket = x.TCPSocket
 		p.HTTPGet = x.HTTPGet
+		p.LogMatch = x.LogMatch
 		p.InitialDelaySeconds = x.InitialDelaySeconds
 		p.PeriodSeconds = x.PeriodSeconds
 		p.SuccessThreshold = x.SuccessThreshold
 		p.FailureThreshold = x.FailureThreshold
+		p.TimeoutSeconds = x.TimeoutSeconds
+		p.LogAttempts = x.LogAttempts
 		return nil
 	}
 	var s string
@@ -53,10 +66,23 @@ func (p *Probe) UnmarshalJSON(data []byte) error {
 }
 
 func (p Probe) MarshalJSON() ([]byte, error) {
+	// some probes cannot be written as a URL
+	if !p.IsURL() {
+		type probe Probe
+		return json.Marshal(probe(p))
+	}
 	return json.Marshal(p.String())
 }
 
+// IsURL returns true if the probe can be written as a URL, e.g. "http://:8080/healthz".
+func (p Probe) IsURL() bool {
+	return p.LogMatch == "" && !p.LogAttempts && (p.HTTPGet == nil || p.HTTPGet.IsURL()) && (p.TCPSocket == nil || p.TCPSocket.IsURL())
+}
+
 func (p Probe) String() string {
+	if p.LogMatch != "" {
+		return fmt.Sprintf("logMatch=%q", p.LogMatch)
+	}
 	return p.URL().String()
 }
 
@@ -85,6 +111,8 @@ func (p *Probe) Unstring(s string) error {
 	p.PeriodSeconds = int32(period.Seconds())
 	initialDelay, _ := time.ParseDuration(q.Get("initialDelay"))
 	p.InitialDelaySeconds = int32(initialDelay.Seconds())
+	timeout, _ := time.ParseDuration(q.Get("timeout"))
+	p.TimeoutSeconds = int32(timeout.Seconds())
 	return err
 }
 
@@ -113,6 +141,9 @@ func (p Probe) URL() *url.URL {
 	if p.FailureThreshold > 0 {
 		x.Add("failureThreshold", fmt.Sprint(p.GetFailureThreshold()))
 	}
+	if p.TimeoutSeconds > 0 {
+		x.Add("timeout", p.GetTimeout().String())
+	}
 	u.RawQuery = x.Encode()
 	return u
 }
@@ -131,6 +162,13 @@ func (p Probe) GetPeriod() time.Duration {
 	return time.Duration(p.PeriodSeconds) * time.Second
 }
 
+func (p Probe) GetTimeout() time.Duration {
+	if p.TimeoutSeconds == 0 {
+		return time.Second
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
 func (p Probe) GetFailureThreshold() int {
 	if p.FailureThreshold == 0 {
 		return 20 // 1m
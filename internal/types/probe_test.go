@@ -2,6 +2,7 @@ package types
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -16,3 +17,44 @@ func TestProbe_String(t *testing.T) {
 
 	assert.Equal(t, "tcp://localhost:8080?initialDelay=1s", p.String())
 }
+
+func TestProbe_LogMatch(t *testing.T) {
+	p := Probe{LogMatch: "Listening on port"}
+	data, err := p.MarshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"logMatch":"Listening on port"}`, string(data))
+	x := Probe{}
+	assert.NoError(t, x.UnmarshalJSON(data))
+	assert.Equal(t, p, x)
+	assert.Equal(t, `logMatch="Listening on port"`, p.String())
+}
+
+func TestProbe_MarshalJSON(t *testing.T) {
+	t.Run("URL", func(t *testing.T) {
+		data, err := Probe{HTTPGet: &HTTPGetAction{Port: 8080, Path: "/healthz"}}.MarshalJSON()
+		assert.NoError(t, err)
+		assert.Equal(t, `"http://localhost:8080/healthz"`, string(data))
+	})
+	t.Run("TLS", func(t *testing.T) {
+		data, err := Probe{TCPSocket: &TCPSocketAction{Port: 8443, TLS: true}}.MarshalJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"tcpSocket":{"port":8443,"tls":true}}`, string(data))
+	})
+	t.Run("Headers", func(t *testing.T) {
+		p := Probe{HTTPGet: &HTTPGetAction{Port: 8080, HTTPHeaders: []HTTPHeader{{Name: "Authorization", Value: "Bearer x"}}, StatusCodes: "200-401"}}
+		data, err := p.MarshalJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"httpGet":{"port":8080,"httpHeaders":[{"name":"Authorization","value":"Bearer x"}],"statusCodes":"200-401"}}`, string(data))
+		x := Probe{}
+		assert.NoError(t, x.UnmarshalJSON(data))
+		assert.Equal(t, p, x)
+	})
+}
+
+func TestProbe_Timeout(t *testing.T) {
+	p := Probe{}
+	assert.NoError(t, p.Unstring("http://localhost:8080/healthz?timeout=3s"))
+	assert.Equal(t, 3*time.Second, p.GetTimeout())
+	assert.Equal(t, "http://localhost:8080/healthz?timeout=3s", p.String())
+	assert.Equal(t, time.Second, Probe{}.GetTimeout())
+}
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Ready is a condition that a service is ready when it holds, rather than a probe of its own ports, e.g. for a service
+// whose readiness is signalled by a file, or by another endpoint. If both are set, both must hold.
+type Ready struct {
+	// A path that exists once the service is ready, e.g. "./tmp/server.sock", relative to the task's working directory.
+	PathExists string `json:"pathExists,omitempty"`
+	// A URL that responds with a 2xx status once the service is ready, e.g. "http://localhost:4566/_health".
+	URLOk string `json:"urlOk,omitempty"`
+}
+
+// Validate returns an error if the condition is not valid.
+func (r Ready) Validate() error {
+	if r.PathExists == "" && r.URLOk == "" {
+		return fmt.Errorf("must have pathExists or urlOk")
+	}
+	if r.URLOk != "" {
+		u, err := url.Parse(r.URLOk)
+		if err != nil {
+			return fmt.Errorf("invalid urlOk: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid urlOk %q, must be http or https", r.URLOk)
+		}
+	}
+	return nil
+}
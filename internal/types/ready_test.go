@@ -0,0 +1,14 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady_Validate(t *testing.T) {
+	assert.NoError(t, Ready{PathExists: "tmp/server.sock"}.Validate())
+	assert.NoError(t, Ready{URLOk: "http://localhost:4566/_health"}.Validate())
+	assert.EqualError(t, Ready{}.Validate(), "must have pathExists or urlOk")
+	assert.EqualError(t, Ready{URLOk: "localhost:4566"}.Validate(), `invalid urlOk "localhost:4566", must be http or https`)
+}
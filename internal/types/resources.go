@@ -0,0 +1,36 @@
+package types
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Resources are the compute resources of a task.
+type Resources struct {
+	// The maximum resources the task may use. A task that exceeds its memory limit is killed (and restarted according
+	// to its restart policy).
+	Limits *ResourceList `json:"limits,omitempty"`
+}
+
+// ResourceList is a set of resource quantities.
+type ResourceList struct {
+	// CPU, in cores, e.g. "500m" or "2".
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+	// Memory, in bytes, e.g. "512Mi" or "2Gi".
+	Memory *resource.Quantity `json:"memory,omitempty"`
+}
+
+// GetCPULimit returns the CPU limit in millicores, or zero if there is no limit.
+func (r *Resources) GetCPULimit() int64 {
+	if r == nil || r.Limits == nil || r.Limits.CPU == nil {
+		return 0
+	}
+	return r.Limits.CPU.MilliValue()
+}
+
+// GetMemoryLimit returns the memory limit in bytes, or zero if there is no limit.
+func (r *Resources) GetMemoryLimit() int64 {
+	if r == nil || r.Limits == nil || r.Limits.Memory == nil {
+		return 0
+	}
+	return r.Limits.Memory.Value()
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestResources(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		var r *Resources
+		assert.Zero(t, r.GetCPULimit())
+		assert.Zero(t, r.GetMemoryLimit())
+	})
+	t.Run("Limits", func(t *testing.T) {
+		r := &Resources{}
+		assert.NoError(t, yaml.Unmarshal([]byte("limits: {cpu: 500m, memory: 512Mi}"), r))
+		assert.Equal(t, int64(500), r.GetCPULimit())
+		assert.Equal(t, int64(512<<20), r.GetMemoryLimit())
+	})
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"fmt"
+)
+
+// ValidateRollingRestart returns an error if the task cannot be restarted without downtime. It must be a host process
+// with a port, and its readiness must be probed on that port, so we know when its replacement can take over.
+func (t *Task) ValidateRollingRestart() error {
+	if !t.RollingRestart {
+		return nil
+	}
+	if t.Image != "" || len(t.GetCommand()) == 0 || len(t.Ports) == 0 {
+		return fmt.Errorf("it needs a host task with ports")
+	}
+	if t.Ready != nil || t.ReadinessProbe != nil && t.ReadinessProbe.LogMatch != "" {
+		return fmt.Errorf("its readiness must be probed on its port, rather than by ready or logMatch")
+	}
+	return nil
+}
+
+// OnPort returns the task, run on the port, rather than on its first port, e.g. so a replacement can be started while
+// it is running. The port is given as $PORT, and probes of its first port probe the port instead.
+func (t Task) OnPort(port uint16) Task {
+	first := t.Ports[0].GetHostPort()
+	t.Ports = append(Ports{{ContainerPort: port}}, t.Ports[1:]...)
+	move := func(p *Probe) *Probe {
+		if p == nil {
+			return nil
+		}
+		x := *p
+		if x.TCPSocket != nil && x.TCPSocket.Port == first {
+			a := *x.TCPSocket
+			a.Port = port
+			x.TCPSocket = &a
+		}
+		if x.HTTPGet != nil && x.HTTPGet.Port == first {
+			a := *x.HTTPGet
+			a.Port = port
+			x.HTTPGet = &a
+		}
+		return &x
+	}
+	t.ReadinessProbe, t.LivenessProbe = move(t.ReadinessProbe), move(t.LivenessProbe)
+	env := EnvVars{}
+	for k, v := range t.Env {
+		env[k] = v
+	}
+	env["PORT"] = EnvVarValue{Value: fmt.Sprint(port)}
+	t.Env = env
+	return t
+}
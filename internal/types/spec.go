@@ -1,13 +1,26 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
 // Task is a unit of work that should be run.
 type Spec struct {
+	// The minimum version of kit the workflow needs, e.g. "v0.9.0", so older versions fail with a clear error, rather
+	// than failing to parse fields they do not know.
+	MinKitVersion string `json:"minKitVersion,omitempty"`
 	// TerminationGracePeriodSeconds is the grace period for terminating the workflow.
 	TerminationGracePeriodSeconds *int32 `json:"terminationGracePeriodSeconds,omitempty"`
 	// Tasks is a list of tasks that should be run.
 	Tasks Tasks `json:"tasks,omitempty"`
+	// Directories, by name, that tasks' working directories can be relative to, rather than the directory of the config
+	// file, e.g. "frontend: ../frontend", for a workflow that runs the checkouts of several repositories.
+	Roots map[string]string `json:"roots,omitempty"`
+	// Settings that all tasks inherit, unless they set their own.
+	TaskDefaults *TaskDefaults `json:"taskDefaults,omitempty"`
 	// Volumes is a list of volumes that can be mounted by containers belonging to the workflow.
 	Volumes []Volume `json:"volumes,omitempty"`
 	// Semaphores is a list of semaphores that can be acquired by tasks.
@@ -16,6 +29,45 @@ type Spec struct {
 	Env EnvVars `json:"env,omitempty"`
 	// Environment file (e.g. .env) to use
 	Envfile Envfile `json:"envfile,omitempty"`
+	// Environment variables that must be set, on the host or in the workflow's env, e.g. API keys. The workflow fails to
+	// start if any are unset.
+	RequiredEnv Strings `json:"requiredEnv,omitempty"`
+	// Fail to start if a task references an environment variable (e.g. "${API_KEY}") in its command, args, script or hooks
+	// that is not set, rather than the task failing confusingly when it runs. References with a default (e.g.
+	// "${PORT:-8080}") are allowed.
+	StrictEnv bool `json:"strictEnv,omitempty"`
+	// The container runtime to use for container tasks: "docker", "podman" or "nerdctl" (containerd). If omitted, the first one found on the PATH is used.
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+	// The name of a network to connect container tasks to, so they can resolve each other by task name. It is created if it does not exist.
+	// Every task is given environment variables with the host and port of each task with ports, e.g. MY_DB_HOST and MY_DB_PORT.
+	Network string `json:"network,omitempty"`
+	// Notifications post task events to webhooks, e.g. Slack.
+	Notifications []Notification `json:"notifications,omitempty"`
+	// URLs to open in the browser once every task is first ready, e.g. "http://localhost:{{ ports.web.host }}". Not in CI.
+	Open Strings `json:"open,omitempty"`
+	// The default shell to run tasks' scripts with, e.g. "bash -euo pipefail -c", so scripts do not depend on whatever
+	// /bin/sh is.
+	Shell Strings `json:"shell,omitempty"`
+	// The maximum number of jobs to run at the same time, e.g. the number of cores, as running more is slower. Services
+	// run for the whole session, so they are not limited. If omitted, there is no limit.
+	MaxParallel int `json:"maxParallel,omitempty"`
+	// Prefix each line of task output with a timestamp: "rfc3339", or "elapsed" for the time since the workflow started.
+	Timestamps string `json:"timestamps,omitempty"`
+	// How the tasks' log files are rotated, so long-running services do not fill the disk. By default, log files are
+	// rotated at 10Mi, keeping 3 rotated files.
+	LogRotation *LogRotation `json:"logRotation,omitempty"`
+	// The number of recent lines of each task's output that are kept in memory, e.g. to show the output of a task that
+	// failed. Defaults to 1000. Zero keeps none.
+	LogBufferLines *int `json:"logBufferLines,omitempty"`
+	// A remote cache of the targets of jobs with watches and targets, so they can be skipped if they succeeded with the
+	// same inputs on another machine, e.g. in CI.
+	Cache *Cache `json:"cache,omitempty"`
+	// Give each task a name, e.g. "api.kit.local", that resolves to 127.0.0.1, using a DNS resolver or the hosts file.
+	DNS *DNS `json:"dns,omitempty"`
+	// WebAssembly modules that are sent lifecycle events, e.g. to redact secrets from logs, or to write metrics.
+	Extensions []Extension `json:"extensions,omitempty"`
+	// the namespace of this instance of the workflow, so two copies can run side by side
+	namespace string
 }
 
 func (s *Spec) GetTerminationGracePeriod() time.Duration {
@@ -25,6 +77,14 @@ func (s *Spec) GetTerminationGracePeriod() time.Duration {
 	return 3 * time.Second
 }
 
+// GetLogBufferLines returns the number of recent lines of each task's output to keep in memory.
+func (s *Spec) GetLogBufferLines() int {
+	if s.LogBufferLines == nil {
+		return 1000
+	}
+	return *s.LogBufferLines
+}
+
 // Retuns the environment variables for the spec.
 func (s *Spec) Environ() ([]string, error) {
 	environ, err := s.Envfile.Environ("")
@@ -34,3 +94,62 @@ func (s *Spec) Environ() ([]string, error) {
 	e, err := s.Env.Environ()
 	return append(environ, e...), err
 }
+
+// BuildsImage returns true if a task in the spec builds the image, so it does not need to be pulled.
+func (s *Spec) BuildsImage(image string) bool {
+	for name, t := range s.Tasks {
+		if t.Build.HasTag(s.ResourceName(name), image) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVolume returns the named volume.
+func (s *Spec) GetVolume(name string) (Volume, bool) {
+	for _, v := range s.Volumes {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Volume{}, false
+}
+
+// NetworkEnviron returns the environment variables with the host and port of each task with ports, as seen by the consumer.
+// Containers can reach other containers by task name, but must reach processes on the host via host.docker.internal.
+func (s *Spec) NetworkEnviron(consumer Task) []string {
+	if s.Network == "" {
+		return nil
+	}
+	var environ []string
+	for name, t := range s.Tasks {
+		if len(t.Ports) == 0 {
+			continue
+		}
+		host, port := "localhost", t.Ports[0].GetHostPort()
+		if consumer.Image != "" {
+			if t.Image != "" {
+				host, port = s.ResourceName(name), t.Ports[0].ContainerPort
+			} else {
+				host = "host.docker.internal"
+			}
+		}
+		prefix := envVarName(name)
+		environ = append(environ, fmt.Sprintf("%s_HOST=%s", prefix, host), fmt.Sprintf("%s_PORT=%d", prefix, port))
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// envVarName converts a task name into a environment variable name, e.g. "my-db" becomes "MY_DB".
+func envVarName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpec_NetworkEnviron(t *testing.T) {
+	spec := Spec{
+		Network: "kit",
+		Tasks: Tasks{
+			"my-db": {Image: "postgres", Ports: Ports{{ContainerPort: 5432, HostPort: 15432}}},
+			"api":   {Command: Strings{"api"}, Ports: Ports{{ContainerPort: 8080}}},
+			"job":   {Command: Strings{"true"}},
+		},
+	}
+	t.Run("NoNetwork", func(t *testing.T) {
+		assert.Empty(t, (&Spec{Tasks: spec.Tasks}).NetworkEnviron(Task{}))
+	})
+	t.Run("Host", func(t *testing.T) {
+		assert.Equal(t, []string{"API_HOST=localhost", "API_PORT=8080", "MY_DB_HOST=localhost", "MY_DB_PORT=15432"}, spec.NetworkEnviron(Task{}))
+	})
+	t.Run("Container", func(t *testing.T) {
+		assert.Equal(t, []string{"API_HOST=host.docker.internal", "API_PORT=8080", "MY_DB_HOST=my-db", "MY_DB_PORT=5432"}, spec.NetworkEnviron(Task{Image: "app"}))
+	})
+}
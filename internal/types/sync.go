@@ -0,0 +1,25 @@
+package types
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Sync copies the files that change into a running container or pod, rather than restarting the task, e.g. for
+// interpreted languages, where a restart is not needed.
+type Sync struct {
+	// The file or directory to copy, relative to the task's working directory. It is watched for changes.
+	Src string `json:"src"`
+	// The path in the container or pod to copy it to.
+	Dest string `json:"dest"`
+}
+
+// Target returns the path in the container or pod the file is copied to, or false if the file is not in the source.
+func (s Sync) Target(workingDir, file string) (string, bool) {
+	rel, err := filepath.Rel(filepath.Join(workingDir, s.Src), file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return path.Join(s.Dest, filepath.ToSlash(rel)), true
+}
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSync_Target(t *testing.T) {
+	s := Sync{Src: "src", Dest: "/app/src"}
+	t.Run("File in source", func(t *testing.T) {
+		target, ok := s.Target("/work", "/work/src/lib/main.py")
+		assert.True(t, ok)
+		assert.Equal(t, "/app/src/lib/main.py", target)
+	})
+	t.Run("Source file", func(t *testing.T) {
+		target, ok := Sync{Src: "main.py", Dest: "/app/main.py"}.Target("/work", "/work/main.py")
+		assert.True(t, ok)
+		assert.Equal(t, "/app/main.py", target)
+	})
+	t.Run("File not in source", func(t *testing.T) {
+		_, ok := s.Target("/work", "/work/srcs/main.py")
+		assert.False(t, ok)
+	})
+}
@@ -1,8 +1,12 @@
 package types
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,60 +18,203 @@ func (t *Task) HasMutex() bool {
 
 // A task is a container or a command to run.
 type Task struct {
+	// A description of the task, shown when picking tasks to run, and by "kit list".
+	Description string `json:"description,omitempty"`
 	// Type is the type of the task: "service" or "job". If omitted, if there are ports, it's a service, otherwise it's a job.
 	// This is only needed when you have service that does not listen on ports.
 	// Services are running in the background.
 	Type TaskType `json:"type,omitempty"`
+	// Labels to select the task by, e.g. "kit -l tier=backend" runs the tasks labelled "tier: backend", and their
+	// dependencies.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Another task in the workflow that this task inherits from, e.g. "api-debug" might extend "api" to run it with a
+	// debugger. The task inherits every field it does not set itself, and its environment variables and labels are
+	// merged with the other task's.
+	Extends string `json:"extends,omitempty"`
+	// A built-in kind of task, that sets the image, ports and readiness probe of a common development dependency:
+	// "localstack", "minio", "fake-gcs" or "dynamodb-local". The task can override any of them.
+	Kind string `json:"kind,omitempty"`
+	// A directory of seed data for the kind: for "localstack", scripts that are run once it is ready, and for "fake-gcs",
+	// a directory for each bucket, containing its objects.
+	Seed string `json:"seed,omitempty"`
+	// The group the task belongs to, e.g. "backend". Running a group runs all the tasks in it, and their dependencies.
+	Group string `json:"group,omitempty"`
 	// Where to log the output of the task. E.g. if the task is verbose. Defaults to /dev/stdout. Maybe a file, or /dev/null.
 	Log string `json:"log,omitempty"`
+	// The color of the task's output in the console, either a name (e.g. "red" or "cyan"), or a 256 color code (e.g. "208").
+	// If omitted, a color is chosen based on the task's name.
+	Color string `json:"color,omitempty"`
+	// The output to show in the console: "info" (all output), "error" (only stderr), or "none". The log file always has all output.
+	// Defaults to "info", or "error" in quiet mode.
+	LogLevel string `json:"logLevel,omitempty"`
 	// Either the container image to run, or a directory containing a Dockerfile. If omitted, the process runs on the host.
 	Image string `json:"image,omitempty"`
-	// Pull policy, e.g. Always, Never, IfNotPresent
+	// Pull policy, e.g. Always, Never, IfNotPresent. Defaults to Always if the image has the latest tag (or no tag), otherwise IfNotPresent.
 	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
+	// Build a container image using BuildKit, rather than running a process. Downstream container tasks can use the built tags as their image.
+	Build *Build `json:"build,omitempty"`
+	// Run services from a Docker Compose file. They are ready when they are running and healthy, and are taken down when
+	// the task stops.
+	Compose *Compose `json:"compose,omitempty"`
+	// Run the task using an exec plugin, an executable named "kit-task-<kind>" on the PATH, for kinds of task kit does
+	// not have, e.g. terraform apply, or a cloud emulator.
+	Plugin *Plugin `json:"plugin,omitempty"`
 	// A probe to check if the task is alive, it will be restarted if not. If omitted, the task is assumed to be alive.
 	LivenessProbe *Probe `json:"livenessProbe,omitempty"`
 	// A probe to check if the task is ready to serve requests. If omitted, the task is assumed to be ready if when the first port is open.
 	ReadinessProbe *Probe `json:"readinessProbe,omitempty"`
+	// A condition that the service is ready when it holds, e.g. a file exists, or a URL responds, rather than a probe of
+	// its own ports. It cannot be used with a readiness probe.
+	Ready *Ready `json:"ready,omitempty"`
 	// The command to run in the container or on the host. If both the image and the command are omitted, this is a noop.
 	Command Strings `json:"command,omitempty"`
 	// The arguments to pass to the command
 	Args Strings `json:"args,omitempty"`
 	// The shell script to run, instead of the command
 	Sh string `json:"sh,omitempty"`
+	// The shell to run the script with, e.g. "bash -euo pipefail -c" or "pwsh -Command". Defaults to the workflow's shell,
+	// or "sh -c".
+	Shell Strings `json:"shell,omitempty"`
+	// Commands to run on the host before the main command, e.g. to run database migrations. If any fails, then the task fails.
+	PreRun []Strings `json:"preRun,omitempty"`
+	// Commands to run on the host after the main command exits, e.g. to clean up temporary directories. The exit code of the
+	// main command is available in the KIT_EXIT_CODE environment variable.
+	PostRun []Strings `json:"postRun,omitempty"`
 	// A directories or files of Kubernetes manifests to apply. Once running the task will wait for the resources to be ready.
 	Manifests Strings `json:"manifests,omitempty"`
 	// The namespace to run the Kubernetes resource in. Defaults to the namespace of the current Kubernetes context.
 	Namespace string `json:"namespace,omitempty"`
-	// The working directory in the container or on the host
+	// Stream the logs of pods already running in the cluster, rather than running anything.
+	PodLogs *PodLogs `json:"podLogs,omitempty"`
+	// Route the traffic for a service in the cluster to the task, while kit is running. The service is restored when kit
+	// exits.
+	Intercept *Intercept `json:"intercept,omitempty"`
+	// The working directory in the container or on the host. On the host, it is relative to the directory of the config
+	// file, or of the root.
 	WorkingDir string `json:"workingDir,omitempty"`
+	// The name of one of the workflow's roots that the working directory is relative to, e.g. "frontend".
+	Root string `json:"root,omitempty"`
 	// The user to run the task as.
 	User string `json:"user,omitempty"`
 	// Environment variables to set in the container or on the host
 	Env EnvVars `json:"env,omitempty"`
 	// Environment file (e.g. .env) to use
 	Envfile Envfile `json:"envfile,omitempty"`
+	// Files and directories to read environment variables from, merged in order, so later sources take precedence.
+	// Variables in env take precedence over these.
+	EnvFrom []EnvFromSource `json:"envFrom,omitempty"`
+	// Start the task with only the environment variables it declares, and those kit sets (e.g. its ports), rather than
+	// inheriting kit's environment, so stray local variables do not leak into it. Containers never inherit it.
+	CleanEnv bool `json:"cleanEnv,omitempty"`
 	// The ports to expose
 	Ports Ports `json:"ports,omitempty"`
+	// How long to wait for the task's host ports to be free before starting it, e.g. while its last run exits. Defaults to 30s.
+	PortWaitTimeout *metav1.Duration `json:"portWaitTimeout,omitempty"`
+	// Serve the task over HTTPS, e.g. to test OAuth callbacks or secure cookies locally.
+	TLS *TLS `json:"tls,omitempty"`
+	// A URL to open in the browser when the task is first ready, e.g. "http://localhost:3000", or
+	// "http://localhost:{{ ports.web.host }}" so it is right when namespaced. Not in CI.
+	Open string `json:"open,omitempty"`
 	// Volumes to mount in the container
 	VolumeMounts []VolumeMount `json:"volumeMounts,omitempty"`
+	// The compute resources of the task, e.g. to stop a leaky dev server taking down the whole machine.
+	Resources *Resources `json:"resources,omitempty"`
 	// Use a pseudo-TTY
 	TTY bool `json:"tty,omitempty"`
 	// A list of files to watch for changes, and restart the task if they change
 	Watch Strings `json:"watch,omitempty"`
+	// How long to wait after a watched file changes before restarting the task, so a burst of changes (e.g. a git checkout)
+	// only restarts it once. Defaults to 100ms.
+	WatchDebounce *metav1.Duration `json:"watchDebounce,omitempty"`
+	// Only restart the task when a changed file is part of the Go or Node packages it builds or runs, rather than when any
+	// watched file changes.
+	WatchPackages *WatchPackages `json:"watchPackages,omitempty"`
+	// Commands to run on the host when a watched file changes, before the task is re-run, e.g. "go generate". If any fails,
+	// then the task fails, and is not re-run until a watched file changes again.
+	OnChange []Strings `json:"onChange,omitempty"`
+	// Copy the files that change into the running container or pod, rather than restarting the task. If the task is
+	// not running, it is restarted.
+	Sync []Sync `json:"sync,omitempty"`
+	// Restart the service without downtime when a watched file changes. It is run on a free port, given as $PORT, behind
+	// a proxy on its first port. A replacement is started on another free port, and the proxy is only switched to it, and
+	// the old process stopped, once it passes its readiness probe.
+	RollingRestart bool `json:"rollingRestart,omitempty"`
+	// Inject latency, bandwidth limits and errors into the connections to the service's first port, e.g. to test how the
+	// services that depend on it behave when it is slow or flaky. Like a rolling restart, it is run on a free port, behind
+	// a proxy on its first port. The faults can be turned on and off while it runs with "kit faults on|off <task>".
+	Faults *Faults `json:"faults,omitempty"`
+	// Re-run the task when something happens outside kit, e.g. a webhook is called.
+	Trigger *Trigger `json:"trigger,omitempty"`
 	// A mutex to prevent multiple tasks with the same mutex from running at the same time
 	Mutex string `json:"mutex,omitempty"`
+	// Tasks with a higher priority are started first when tasks compete, e.g. for a semaphore, or to run when maxParallel
+	// is set. Defaults to zero, and may be negative.
+	Priority int `json:"priority,omitempty"`
 	// A semaphore to limit the number of tasks with the same semaphore that can run at the same time
 	Semaphore string `json:"semaphore,omitempty"`
-	// A list of tasks to run before this task
-	Dependencies Strings `json:"dependencies,omitempty"`
+	// A list of tasks to run before this task, or an object with "allOf" and "anyOf" lists, e.g. to start when any one of
+	// several alternative providers of the same service is ready.
+	Dependencies *Dependencies `json:"dependencies,omitempty"`
+	// A list of external dependencies that must be available before this task starts, e.g. "tcp://db.example.com:5432",
+	// "https://example.com/healthz", a file path, or a resource in the Kubernetes cluster of the current context, e.g.
+	// "k8s:deployment/backend?namespace=dev".
+	WaitFor Strings `json:"waitFor,omitempty"`
+	// Values captured from the task's output, that are set as environment variables in the tasks that depend on it, e.g. the
+	// random port of an ephemeral database.
+	Outputs []Output `json:"outputs,omitempty"`
+	// Files or directories this task produces, relative to its working directory. The task fails if they do not exist when it exits.
+	Artifacts Strings `json:"artifacts,omitempty"`
+	// Artifacts from other tasks that this task consumes. They're copied into this task's working directory before it
+	// runs, and it re-runs when they change.
+	Inputs []Input `json:"inputs,omitempty"`
+	// Files or directories a job applies to something that outlives kit, e.g. the migrations or fixtures it loads into a
+	// database. Once the job succeeds, the hash of the task and their contents is recorded, and the job is skipped by later
+	// runs of kit until either changes.
+	Once Strings `json:"once,omitempty"`
 	// A list of files this task will create. If these exist, and they're newer than the watched files, the task is skipped.
 	Targets Strings `json:"targets,omitempty"`
-	// The restart policy, e.g. Always, Never, OnFailure. Defaults depends on the type of task.
+	// The exit codes that mean the task succeeded, e.g. [0, 1] for diff, which exits 1 when the files differ. Defaults to
+	// [0].
+	SuccessCodes []int `json:"successCodes,omitempty"`
+	// A task to run when this task fails, before it is restarted, e.g. to dump logs or reset state. It's told the failed task
+	// and its exit code in the KIT_FAILED_TASK and KIT_EXIT_CODE environment variables.
+	OnFailure string `json:"onFailure,omitempty"`
+	// How many times to retry a job that fails, if it is flaky, i.e. it failed in some, but not all, of its last 10 runs.
+	// Defaults to zero.
+	FlakyRetries int `json:"flakyRetries,omitempty"`
+	// The restart policy, Always, Never, OnFailure (only restart if it fails), or OnWatch (only re-run when a watched file
+	// changes). Defaults depends on the type of task.
 	RestartPolicy string `json:"restartPolicy,omitempty"`
 	// The timeout for the task to be considered stalled. If omitted, the task will be considered stalled after 30 seconds of no activity.
 	StalledTimeout *metav1.Duration `json:"stalledTimeout,omitempty"`
 }
 
+// GetDependencies returns the tasks this task depends on, including any-of dependencies and the producers of its inputs.
+func (t *Task) GetDependencies() []string {
+	dependencies := append([]string{}, t.Dependencies.GetAllOf()...)
+	for _, task := range t.Dependencies.GetAnyOf() {
+		if !slices.Contains(dependencies, task) {
+			dependencies = append(dependencies, task)
+		}
+	}
+	for _, input := range t.Inputs {
+		if !slices.Contains(dependencies, input.Task) {
+			dependencies = append(dependencies, input.Task)
+		}
+	}
+	return dependencies
+}
+
+// Consumes returns true if the task has any inputs produced by the named task.
+func (t *Task) Consumes(producer string) bool {
+	for _, input := range t.Inputs {
+		if input.Task == producer {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *Task) GetHostPorts() []uint16 {
 	var ports []uint16
 	for _, p := range t.Ports {
@@ -81,9 +228,10 @@ func (t *Task) GetReadinessProbe() *Probe {
 		return nil
 	}
 	if t.ReadinessProbe != nil {
-		return t.ReadinessProbe
+		return t.probeOnHost(t.ReadinessProbe)
 	}
-	if len(t.Ports) > 0 {
+	// the ready condition is used, rather than checking the first port
+	if len(t.Ports) > 0 && t.Ready == nil {
 		return &Probe{TCPSocket: &TCPSocketAction{Port: t.Ports[0].GetHostPort()}}
 	}
 	return nil
@@ -94,12 +242,42 @@ func (t *Task) GetLivenessProbe() *Probe {
 		return nil
 	}
 	if t.LivenessProbe != nil {
-		return t.LivenessProbe
+		return t.probeOnHost(t.LivenessProbe)
 	}
 	return nil
 
 }
 
+// hostPort returns the port on the host for the port. A container's probe may use the container's port, e.g. 5432,
+// which is probed on the host port it is published on, e.g. 15432.
+func (t *Task) hostPort(port uint16) uint16 {
+	if t.Image == "" || slices.Contains(t.GetHostPorts(), port) {
+		return port
+	}
+	for _, p := range t.Ports {
+		if p.ContainerPort == port {
+			return p.GetHostPort()
+		}
+	}
+	return port
+}
+
+// probeOnHost returns the probe, probing the host ports that any container ports it uses are published on.
+func (t *Task) probeOnHost(p *Probe) *Probe {
+	x := *p
+	if x.TCPSocket != nil {
+		a := *x.TCPSocket
+		a.Port = t.hostPort(a.Port)
+		x.TCPSocket = &a
+	}
+	if x.HTTPGet != nil {
+		a := *x.HTTPGet
+		a.Port = t.hostPort(a.Port)
+		x.HTTPGet = &a
+	}
+	return &x
+}
+
 func (t *Task) GetRestartPolicy() string {
 	if t.RestartPolicy != "" {
 		return t.RestartPolicy
@@ -110,10 +288,48 @@ func (t *Task) GetRestartPolicy() string {
 	return "Never"
 }
 
+// IsSuccessCode returns true if the exit code means the task succeeded.
+func (t *Task) IsSuccessCode(code int) bool {
+	if len(t.SuccessCodes) == 0 {
+		return code == 0
+	}
+	return slices.Contains(t.SuccessCodes, code)
+}
+
+// RestartsOnExit returns true if the task is restarted when it exits, whether it failed or not.
+func (t *Task) RestartsOnExit(failed bool) bool {
+	switch t.GetRestartPolicy() {
+	case "Always":
+		return true
+	case "OnFailure":
+		return failed
+	default:
+		// Never and OnWatch
+		return false
+	}
+}
+
+// GetLogLevel returns the log level, defaulting to "error" in quiet mode, otherwise "info".
+func (t *Task) GetLogLevel(quiet bool) string {
+	if t.LogLevel != "" {
+		return t.LogLevel
+	}
+	if quiet {
+		return "error"
+	}
+	return "info"
+}
+
 func (t *Task) String() string {
 	if t.Image != "" {
 		return t.Image
 	}
+	if t.Build != nil {
+		return "build " + t.Build.Tags.String()
+	}
+	if t.Compose != nil {
+		return "compose " + t.Compose.Services.String()
+	}
 	if len(t.GetCommand()) > 0 {
 		return t.GetCommand().String()
 	}
@@ -128,6 +344,13 @@ func (t *Task) Environ() ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, source := range t.EnvFrom {
+		e, err := source.Environ(t.WorkingDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read envFrom: %w", err)
+		}
+		environ = append(environ, e...)
+	}
 	s, err := t.Env.Environ()
 	return append(environ, s...), err
 }
@@ -137,11 +360,18 @@ func (t *Task) GetCommand() Strings {
 		return t.Command
 	}
 	if t.Sh != "" {
-		return []string{"sh", "-c", t.Sh}
+		return append(append([]string{}, t.GetShell()...), t.Sh)
 	}
 	return nil
 }
 
+func (t *Task) GetShell() Strings {
+	if len(t.Shell) > 0 {
+		return t.Shell
+	}
+	return []string{"sh", "-c"}
+}
+
 // Skip Determines if all the targets exist. And if they're all newer that the newest source file.
 func (t *Task) Skip() bool {
 	// if there are no targets, we must run the task
@@ -179,16 +409,71 @@ func (t *Task) GetType() TaskType {
 	if t.Type != "" {
 		return t.Type
 	}
-	if len(t.Ports) > 0 || t.LivenessProbe != nil || t.ReadinessProbe != nil {
+	if len(t.Ports) > 0 || t.LivenessProbe != nil || t.ReadinessProbe != nil || t.Ready != nil || t.PodLogs != nil || t.Compose != nil {
 		return TaskTypeService
 	}
 	return TaskTypeJob
 
 }
 
+func (t *Task) GetImagePullPolicy() string {
+	if t.ImagePullPolicy != "" {
+		return t.ImagePullPolicy
+	}
+	if strings.HasSuffix(normalizeTag(t.Image), ":latest") {
+		return "Always"
+	}
+	return "IfNotPresent"
+}
+
 func (t *Task) GetStalledTimeout() time.Duration {
 	if t.StalledTimeout != nil {
 		return t.StalledTimeout.Duration
 	}
 	return 30 * time.Second
 }
+
+func (t *Task) GetPortWaitTimeout() time.Duration {
+	if t.PortWaitTimeout != nil {
+		return t.PortWaitTimeout.Duration
+	}
+	return 30 * time.Second
+}
+
+func (t *Task) GetWatchDebounce() time.Duration {
+	if t.WatchDebounce != nil {
+		return t.WatchDebounce.Duration
+	}
+	return 100 * time.Millisecond
+}
+
+// matches "${VAR}", and "${VAR?message}", but not "${VAR:-default}"
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:?\?[^}]*)?\}`)
+
+// GetEnvReferences returns the names of the environment variables referenced (e.g. "${API_KEY}") by the task's command,
+// args, script and hooks, that do not have a default.
+func (t *Task) GetEnvReferences() []string {
+	values := append(append([]string{t.Sh}, t.Command...), t.Args...)
+	for _, hook := range append(append(append([]Strings{}, t.PreRun...), t.PostRun...), t.OnChange...) {
+		values = append(values, hook...)
+	}
+	var names []string
+	for _, value := range values {
+		for _, m := range envReference.FindAllStringSubmatch(value, -1) {
+			if !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// GetSyncTarget returns the path in the container or pod that the file is copied to, or false if it is not synced.
+func (t *Task) GetSyncTarget(file string) (string, bool) {
+	for _, s := range t.Sync {
+		if target, ok := s.Target(t.WorkingDir, file); ok {
+			return target, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TaskDefaults are settings that all tasks inherit, unless they set their own, so they do not need to be repeated.
+type TaskDefaults struct {
+	// Environment variables to set, a task's own take precedence.
+	Env EnvVars `json:"env,omitempty"`
+	// The working directory.
+	WorkingDir string `json:"workingDir,omitempty"`
+	// The restart policy, e.g. Always, Never, OnFailure, OnWatch.
+	RestartPolicy string `json:"restartPolicy,omitempty"`
+	// A probe to check if a task is ready.
+	ReadinessProbe *Probe `json:"readinessProbe,omitempty"`
+	// A probe to check if a task is alive.
+	LivenessProbe *Probe `json:"livenessProbe,omitempty"`
+	// How long to wait after a watched file changes before restarting a task.
+	WatchDebounce *metav1.Duration `json:"watchDebounce,omitempty"`
+}
+
+// Apply returns the task with the defaults set, where it does not set its own.
+func (d *TaskDefaults) Apply(t Task) Task {
+	if d == nil {
+		return t
+	}
+	if len(d.Env) > 0 {
+		env := EnvVars{}
+		for k, v := range d.Env {
+			env[k] = v
+		}
+		for k, v := range t.Env {
+			env[k] = v
+		}
+		t.Env = env
+	}
+	if t.WorkingDir == "" {
+		t.WorkingDir = d.WorkingDir
+	}
+	if t.RestartPolicy == "" {
+		t.RestartPolicy = d.RestartPolicy
+	}
+	// each task has its own copy of a probe, as they may be changed, e.g. by namespacing
+	if t.ReadinessProbe == nil && d.ReadinessProbe != nil {
+		p := *d.ReadinessProbe
+		t.ReadinessProbe = &p
+	}
+	if t.LivenessProbe == nil && d.LivenessProbe != nil {
+		p := *d.LivenessProbe
+		t.LivenessProbe = &p
+	}
+	if t.WatchDebounce == nil {
+		t.WatchDebounce = d.WatchDebounce
+	}
+	return t
+}
+
+// ApplyTaskDefaults sets the task defaults on each task, and then clears them, so they're only applied once.
+func (s *Spec) ApplyTaskDefaults() {
+	if s.TaskDefaults == nil {
+		return
+	}
+	tasks := Tasks{}
+	for name, t := range s.Tasks {
+		tasks[name] = s.TaskDefaults.Apply(t)
+	}
+	s.Tasks = tasks
+	s.TaskDefaults = nil
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSpec_ApplyTaskDefaults(t *testing.T) {
+	probe := &Probe{HTTPGet: &HTTPGetAction{Path: "/healthz"}}
+	s := &Spec{
+		TaskDefaults: &TaskDefaults{
+			Env:            EnvVars{"FOO": {Value: "1"}, "BAR": {Value: "1"}},
+			WorkingDir:     "app",
+			RestartPolicy:  "OnFailure",
+			ReadinessProbe: probe,
+			WatchDebounce:  &metav1.Duration{Duration: time.Second},
+		},
+		Tasks: Tasks{
+			"inherits":  {},
+			"overrides": {Env: EnvVars{"BAR": {Value: "2"}}, WorkingDir: "other", RestartPolicy: "Never", ReadinessProbe: &Probe{TCPSocket: &TCPSocketAction{Port: 8080}}},
+		},
+	}
+	s.ApplyTaskDefaults()
+	assert.Nil(t, s.TaskDefaults)
+
+	inherits := s.Tasks["inherits"]
+	assert.Equal(t, EnvVars{"FOO": {Value: "1"}, "BAR": {Value: "1"}}, inherits.Env)
+	assert.Equal(t, "app", inherits.WorkingDir)
+	assert.Equal(t, "OnFailure", inherits.GetRestartPolicy())
+	assert.Equal(t, probe, inherits.GetReadinessProbe())
+	assert.NotSame(t, probe, inherits.GetReadinessProbe())
+	assert.Equal(t, time.Second, inherits.GetWatchDebounce())
+
+	overrides := s.Tasks["overrides"]
+	assert.Equal(t, EnvVars{"FOO": {Value: "1"}, "BAR": {Value: "2"}}, overrides.Env)
+	assert.Equal(t, "other", overrides.WorkingDir)
+	assert.Equal(t, "Never", overrides.GetRestartPolicy())
+	assert.NotNil(t, overrides.GetReadinessProbe().TCPSocket)
+	assert.Equal(t, time.Second, overrides.GetWatchDebounce())
+}
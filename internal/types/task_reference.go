@@ -0,0 +1,123 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// taskReference matches a reference in an env var's value, or a URL to open, the task may be namespaced by its workflow, e.g. "web/api".
+var taskReference = regexp.MustCompile(`\{\{\s*(\w+)\.([^\s{}]+)\.(\w+)\s*}}`)
+
+// TaskReference is a reference in an env var's value to a fact about another task, resolved when the task starts, e.g.
+// "{{ ports.db.host }}" is the host port of the db task, and "{{ outputs.login.TOKEN }}" is the login task's TOKEN
+// output.
+type TaskReference struct {
+	// "ports" or "outputs"
+	Kind string
+	// the task the fact is about
+	Task string
+	// "host" or "container" for ports, or the name of the output
+	Field string
+}
+
+func (r TaskReference) String() string {
+	return fmt.Sprintf("{{ %s.%s.%s }}", r.Kind, r.Task, r.Field)
+}
+
+// TaskReferences returns the references in the values of the env vars, sorted by the name of the env var.
+func (v EnvVars) TaskReferences() []TaskReference {
+	var names []string
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var refs []TaskReference
+	for _, name := range names {
+		refs = append(refs, FindTaskReferences(v[name].Value)...)
+	}
+	return refs
+}
+
+// FindTaskReferences returns the references in the string, e.g. a URL to open.
+func FindTaskReferences(s string) []TaskReference {
+	var refs []TaskReference
+	for _, m := range taskReference.FindAllStringSubmatch(s, -1) {
+		refs = append(refs, TaskReference{Kind: m[1], Task: m[2], Field: m[3]})
+	}
+	return refs
+}
+
+// ExpandTaskReferences returns the string, with each reference replaced with what the function returns for it.
+func ExpandTaskReferences(s string, f func(TaskReference) (string, error)) (string, error) {
+	var err error
+	s = taskReference.ReplaceAllStringFunc(s, func(s string) string {
+		m := taskReference.FindStringSubmatch(s)
+		expanded, e := f(TaskReference{Kind: m[1], Task: m[2], Field: m[3]})
+		if e != nil && err == nil {
+			err = e
+		}
+		return expanded
+	})
+	return s, err
+}
+
+// ExpandTaskReferences returns a copy of the env vars, with each reference in their values replaced with what the
+// function returns for it.
+func (v EnvVars) ExpandTaskReferences(f func(TaskReference) (string, error)) (EnvVars, error) {
+	if v == nil {
+		return nil, nil
+	}
+	out := EnvVars{}
+	for name, value := range v {
+		var err error
+		value.Value, err = ExpandTaskReferences(value.Value, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", name, err)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// CheckTaskReference returns an error if the reference in the named task's env is to a task that is not in the
+// workflow, or to a fact the task does not have, e.g. the ports of a task without ports. Outputs are only set for the
+// task's dependencies.
+func (s Spec) CheckTaskReference(name string, t Task, r TaskReference) error {
+	other, ok := s.Tasks[r.Task]
+	if !ok {
+		return fmt.Errorf("task %q references %s, but %q is not found", name, r, r.Task)
+	}
+	switch r.Kind {
+	case "ports":
+		if len(other.Ports) == 0 {
+			return fmt.Errorf("task %q references %s, but %q has no ports", name, r, r.Task)
+		}
+		if r.Field != "host" && r.Field != "container" {
+			return fmt.Errorf("task %q references %s, must be ports.%s.host or ports.%s.container", name, r, r.Task, r.Task)
+		}
+	case "outputs":
+		if !slices.Contains(t.GetDependencies(), r.Task) {
+			return fmt.Errorf("task %q references %s, but %q is not one of its dependencies", name, r, r.Task)
+		}
+		if !slices.ContainsFunc(other.Outputs, func(o Output) bool { return o.Name == r.Field }) {
+			return fmt.Errorf("task %q references %s, but %q has no output %q", name, r, r.Task, r.Field)
+		}
+	default:
+		return fmt.Errorf("task %q references %s, must be ports.<task>.host, ports.<task>.container or outputs.<task>.<name>", name, r)
+	}
+	return nil
+}
+
+// ResolvePort returns the host or container port of the task's first port, that a reference is to.
+func (s Spec) ResolvePort(r TaskReference) (string, error) {
+	t, ok := s.Tasks[r.Task]
+	if !ok || len(t.Ports) == 0 {
+		return "", fmt.Errorf("task %q has no ports", r.Task)
+	}
+	if r.Field == "container" {
+		return fmt.Sprint(t.Ports[0].ContainerPort), nil
+	}
+	return fmt.Sprint(t.Ports[0].GetHostPort()), nil
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvVars_ExpandTaskReferences(t *testing.T) {
+	env := EnvVars{
+		"DATABASE_URL": {Value: "postgres://localhost:{{ ports.db.host }}/app"},
+		"WEB":          {Value: "{{ports.web/serve.container}}"},
+		"TEMPLATE":     {Value: "{{ .Name }}"},
+	}
+	assert.Equal(t, []TaskReference{{Kind: "ports", Task: "db", Field: "host"}, {Kind: "ports", Task: "web/serve", Field: "container"}}, env.TaskReferences())
+
+	expanded, err := env.ExpandTaskReferences(func(r TaskReference) (string, error) {
+		return r.Task + ":" + r.Field, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, EnvVars{
+		"DATABASE_URL": {Value: "postgres://localhost:db:host/app"},
+		"WEB":          {Value: "web/serve:container"},
+		"TEMPLATE":     {Value: "{{ .Name }}"},
+	}, expanded)
+
+	_, err = env.ExpandTaskReferences(func(r TaskReference) (string, error) {
+		return "", fmt.Errorf("not ready")
+	})
+	assert.ErrorContains(t, err, "not ready")
+}
+
+func TestSpec_CheckTaskReference(t *testing.T) {
+	spec := Spec{Tasks: Tasks{
+		"db":    {Ports: []Port{{ContainerPort: 5432, HostPort: 15432}}},
+		"login": {Outputs: []Output{{Name: "TOKEN"}}},
+		"app":   {Dependencies: &Dependencies{AllOf: Strings{"login"}}},
+		"lint":  {},
+	}}
+	tests := []struct {
+		ref  string
+		task string
+		err  string
+	}{
+		{ref: "{{ ports.db.host }}", task: "app"},
+		{ref: "{{ ports.db.container }}", task: "app"},
+		{ref: "{{ outputs.login.TOKEN }}", task: "app"},
+		{ref: "{{ ports.missing.host }}", task: "app", err: `task "app" references {{ ports.missing.host }}, but "missing" is not found`},
+		{ref: "{{ ports.lint.host }}", task: "app", err: `task "app" references {{ ports.lint.host }}, but "lint" has no ports`},
+		{ref: "{{ ports.db.url }}", task: "app", err: `task "app" references {{ ports.db.url }}, must be ports.db.host or ports.db.container`},
+		{ref: "{{ outputs.login.PASSWORD }}", task: "app", err: `task "app" references {{ outputs.login.PASSWORD }}, but "login" has no output "PASSWORD"`},
+		{ref: "{{ outputs.login.TOKEN }}", task: "lint", err: `task "lint" references {{ outputs.login.TOKEN }}, but "login" is not one of its dependencies`},
+		{ref: "{{ phase.db.name }}", task: "app", err: `task "app" references {{ phase.db.name }}, must be ports.<task>.host, ports.<task>.container or outputs.<task>.<name>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			refs := EnvVars{"X": {Value: tt.ref}}.TaskReferences()
+			assert.Len(t, refs, 1)
+			err := spec.CheckTaskReference(tt.task, spec.Tasks[tt.task], refs[0])
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	port, err := spec.ResolvePort(TaskReference{Kind: "ports", Task: "db", Field: "host"})
+	assert.NoError(t, err)
+	assert.Equal(t, "15432", port)
+}
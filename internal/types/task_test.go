@@ -57,4 +57,99 @@ func TestTask_GetType(t *testing.T) {
 		task := &Task{ReadinessProbe: &Probe{}}
 		assert.Equal(t, TaskTypeService, task.GetType())
 	})
+	t.Run("Compose", func(t *testing.T) {
+		task := &Task{Compose: &Compose{}}
+		assert.Equal(t, TaskTypeService, task.GetType())
+	})
+	t.Run("PodLogs", func(t *testing.T) {
+		task := &Task{PodLogs: &PodLogs{Selector: "app=api"}}
+		assert.Equal(t, TaskTypeService, task.GetType())
+	})
+}
+
+func TestTask_GetReadinessProbe(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		task := &Task{Image: "postgres", Ports: Ports{{ContainerPort: 5432, HostPort: 15432}}}
+		assert.Equal(t, uint16(15432), task.GetReadinessProbe().TCPSocket.Port)
+	})
+	t.Run("Container port", func(t *testing.T) {
+		task := &Task{
+			Image:          "nginx",
+			Ports:          Ports{{ContainerPort: 80, HostPort: 8080}},
+			ReadinessProbe: &Probe{HTTPGet: &HTTPGetAction{Port: 80, Path: "/healthz"}},
+			LivenessProbe:  &Probe{TCPSocket: &TCPSocketAction{Port: 8080}},
+		}
+		assert.Equal(t, uint16(8080), task.GetReadinessProbe().HTTPGet.Port)
+		assert.Equal(t, uint16(80), task.ReadinessProbe.HTTPGet.Port, "the task is not changed")
+		assert.Equal(t, uint16(8080), task.GetLivenessProbe().TCPSocket.Port)
+	})
+	t.Run("Host process", func(t *testing.T) {
+		task := &Task{Ports: Ports{{ContainerPort: 80, HostPort: 8080}}, ReadinessProbe: &Probe{TCPSocket: &TCPSocketAction{Port: 80}}}
+		assert.Equal(t, uint16(80), task.GetReadinessProbe().TCPSocket.Port)
+	})
+}
+
+func TestTask_GetImagePullPolicy(t *testing.T) {
+	t.Run("Defined", func(t *testing.T) {
+		task := &Task{Image: "nginx", ImagePullPolicy: "Never"}
+		assert.Equal(t, "Never", task.GetImagePullPolicy())
+	})
+	t.Run("NoTag", func(t *testing.T) {
+		task := &Task{Image: "nginx"}
+		assert.Equal(t, "Always", task.GetImagePullPolicy())
+	})
+	t.Run("Latest", func(t *testing.T) {
+		task := &Task{Image: "nginx:latest"}
+		assert.Equal(t, "Always", task.GetImagePullPolicy())
+	})
+	t.Run("Tag", func(t *testing.T) {
+		task := &Task{Image: "localhost:5000/nginx:1.25"}
+		assert.Equal(t, "IfNotPresent", task.GetImagePullPolicy())
+	})
+}
+
+func TestTask_RestartsOnExit(t *testing.T) {
+	for policy, want := range map[string][2]bool{
+		"Always":    {true, true},
+		"Never":     {false, false},
+		"OnFailure": {false, true},
+		"OnWatch":   {false, false},
+	} {
+		task := &Task{RestartPolicy: policy}
+		assert.Equal(t, want[0], task.RestartsOnExit(false), policy)
+		assert.Equal(t, want[1], task.RestartsOnExit(true), policy)
+	}
+}
+
+func TestTask_IsSuccessCode(t *testing.T) {
+	assert.True(t, (&Task{}).IsSuccessCode(0))
+	assert.False(t, (&Task{}).IsSuccessCode(1))
+	task := &Task{SuccessCodes: []int{0, 2}}
+	assert.True(t, task.IsSuccessCode(2))
+	assert.False(t, task.IsSuccessCode(1))
+	assert.False(t, (&Task{SuccessCodes: []int{1}}).IsSuccessCode(0), "zero is only a success if it's listed")
+}
+
+func TestTask_GetCommand(t *testing.T) {
+	t.Run("Command", func(t *testing.T) {
+		task := &Task{Command: Strings{"go", "run", "."}, Sh: "echo"}
+		assert.Equal(t, Strings{"go", "run", "."}, task.GetCommand())
+	})
+	t.Run("Default shell", func(t *testing.T) {
+		task := &Task{Sh: "echo"}
+		assert.Equal(t, Strings{"sh", "-c", "echo"}, task.GetCommand())
+	})
+	t.Run("Shell", func(t *testing.T) {
+		task := &Task{Sh: "echo", Shell: Strings{"bash", "-euo", "pipefail", "-c"}}
+		assert.Equal(t, Strings{"bash", "-euo", "pipefail", "-c", "echo"}, task.GetCommand())
+	})
+}
+
+func TestTask_GetEnvReferences(t *testing.T) {
+	task := Task{
+		Sh:      "echo ${FOO} ${FOO} ${BAR?must be set} ${BAZ:-default} $QUX",
+		Args:    Strings{"${ARG}"},
+		PostRun: []Strings{{"echo", "${HOOK}"}},
+	}
+	assert.Equal(t, []string{"FOO", "BAR", "ARG", "HOOK"}, task.GetEnvReferences())
 }
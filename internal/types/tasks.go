@@ -1,6 +1,12 @@
 package types
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
 
 type Tasks map[string]Task
 
@@ -26,3 +32,47 @@ func (t *Tasks) UnmarshalJSON(data []byte) error {
 	}
 	return nil
 }
+
+// Expand expands the names of groups into the names of the tasks in them. A task takes precedence over a group of the
+// same name.
+func (t Tasks) Expand(names []string) ([]string, error) {
+	var expanded []string
+	for _, name := range names {
+		if _, ok := t[name]; ok {
+			expanded = append(expanded, name)
+			continue
+		}
+		var group []string
+		for taskName, task := range t {
+			if task.Group == name {
+				group = append(group, taskName)
+			}
+		}
+		if len(group) == 0 {
+			return nil, fmt.Errorf("task %q not found in workflow", name)
+		}
+		sort.Strings(group)
+		expanded = append(expanded, group...)
+	}
+	return expanded, nil
+}
+
+// Select returns the names of the tasks whose labels match the selector, e.g. "tier=backend,lang=go", using the same
+// syntax as Kubernetes label selectors.
+func (t Tasks) Select(selector string) ([]string, error) {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	var names []string
+	for name, task := range t {
+		if s.Matches(labels.Set(task.Labels)) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no tasks match selector %q", selector)
+	}
+	sort.Strings(names)
+	return names, nil
+}
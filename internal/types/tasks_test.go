@@ -0,0 +1,67 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasks_Expand(t *testing.T) {
+	tasks := Tasks{
+		"api":      {Group: "backend"},
+		"db":       {Group: "backend"},
+		"web":      {Group: "frontend"},
+		"frontend": {},
+	}
+	t.Run("Tasks", func(t *testing.T) {
+		names, err := tasks.Expand([]string{"web", "api"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"web", "api"}, names)
+	})
+	t.Run("Group", func(t *testing.T) {
+		names, err := tasks.Expand([]string{"backend"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"api", "db"}, names)
+	})
+	t.Run("Task takes precedence", func(t *testing.T) {
+		names, err := tasks.Expand([]string{"frontend"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"frontend"}, names)
+	})
+	t.Run("Not found", func(t *testing.T) {
+		_, err := tasks.Expand([]string{"missing"})
+		assert.EqualError(t, err, `task "missing" not found in workflow`)
+	})
+}
+
+func TestTasks_Select(t *testing.T) {
+	tasks := Tasks{
+		"api": {Labels: map[string]string{"tier": "backend", "lang": "go"}},
+		"db":  {Labels: map[string]string{"tier": "backend"}},
+		"web": {Labels: map[string]string{"tier": "frontend", "lang": "ts"}},
+		"up":  {},
+	}
+	t.Run("Equals", func(t *testing.T) {
+		names, err := tasks.Select("tier=backend")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"api", "db"}, names)
+	})
+	t.Run("Several", func(t *testing.T) {
+		names, err := tasks.Select("tier=backend,lang=go")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"api"}, names)
+	})
+	t.Run("Exists", func(t *testing.T) {
+		names, err := tasks.Select("lang,lang!=ts")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"api"}, names)
+	})
+	t.Run("None", func(t *testing.T) {
+		_, err := tasks.Select("tier=database")
+		assert.EqualError(t, err, `no tasks match selector "tier=database"`)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := tasks.Select("tier==")
+		assert.Error(t, err)
+	})
+}
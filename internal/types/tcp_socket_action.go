@@ -9,8 +9,30 @@ import (
 type TCPSocketAction struct {
 	// Port number of the port to probe.
 	Port uint16 `json:"port"`
+	// Perform a TLS handshake after connecting, e.g. to check an HTTPS-only port.
+	TLS bool `json:"tls,omitempty"`
+	// The server name to send in the TLS handshake (SNI), and to verify the certificate for. Defaults to localhost.
+	ServerName string `json:"serverName,omitempty"`
+	// Do not verify the server's certificate, e.g. a self-signed one.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// What to send after connecting, e.g. "PING\r\n".
+	Send string `json:"send,omitempty"`
+	// What the server must reply with first, e.g. "SSH-" or "+PONG", so the right service is known to be up.
+	Expect string `json:"expect,omitempty"`
 }
 
 func (a TCPSocketAction) URL() *url.URL {
 	return &url.URL{Scheme: "tcp", Host: fmt.Sprintf("localhost:%v", a.Port)}
 }
+
+// IsURL returns true if the action can be written as a URL, i.e. it only has a port.
+func (a TCPSocketAction) IsURL() bool {
+	return !a.TLS && a.ServerName == "" && !a.InsecureSkipVerify && a.Send == "" && a.Expect == ""
+}
+
+func (a TCPSocketAction) GetServerName() string {
+	if a.ServerName == "" {
+		return "localhost"
+	}
+	return a.ServerName
+}
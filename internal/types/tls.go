@@ -0,0 +1,26 @@
+package types
+
+import "fmt"
+
+// TLS terminates TLS in front of a task, using a certificate signed by a locally generated certificate authority.
+type TLS struct {
+	// The port to listen on for HTTPS. Requests are forwarded to the task's first port.
+	Port uint16 `json:"port"`
+	// The hostnames and IP addresses the certificate is valid for. Defaults to localhost, 127.0.0.1 and ::1.
+	Hosts Strings `json:"hosts,omitempty"`
+}
+
+// Validate returns an error if the TLS is not valid.
+func (t TLS) Validate() error {
+	if t.Port == 0 {
+		return fmt.Errorf("port is required")
+	}
+	return nil
+}
+
+func (t TLS) GetHosts() []string {
+	if len(t.Hosts) > 0 {
+		return t.Hosts
+	}
+	return []string{"localhost", "127.0.0.1", "::1"}
+}
@@ -0,0 +1,12 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLS_Validate(t *testing.T) {
+	assert.NoError(t, TLS{Port: 8443}.Validate())
+	assert.EqualError(t, TLS{Hosts: Strings{"localhost"}}.Validate(), "port is required")
+}
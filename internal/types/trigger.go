@@ -0,0 +1,20 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trigger re-runs a task when something happens outside kit.
+type Trigger struct {
+	// A path on kit's API, e.g. "/hooks/deploy". A POST to it re-runs the task. It must start with "/hooks/".
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// Validate returns an error if the trigger is not valid.
+func (t Trigger) Validate() error {
+	if t.Webhook != "" && !strings.HasPrefix(t.Webhook, "/hooks/") {
+		return fmt.Errorf("webhook %q must start with /hooks/", t.Webhook)
+	}
+	return nil
+}
@@ -4,5 +4,6 @@ type Volume struct {
 	// Volume's name.
 	Name string `json:"name"`
 	// HostPath represents a pre-existing file or directory on the host machine that is directly exposed to the container.
-	HostPath HostPath `json:"hostPath"`
+	// If omitted, a named volume managed by the container runtime is used, which persists between runs.
+	HostPath *HostPath `json:"hostPath,omitempty"`
 }
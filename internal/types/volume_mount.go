@@ -6,4 +6,6 @@ type VolumeMount struct {
 	Name string `json:"name"`
 	// Path within the container at which the volume should be mounted.
 	MountPath string `json:"mountPath"`
+	// Mounted read-only if true, read-write otherwise.
+	ReadOnly bool `json:"readOnly,omitempty"`
 }
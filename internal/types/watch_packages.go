@@ -0,0 +1,32 @@
+package types
+
+import "fmt"
+
+// WatchPackages only restarts the task when a changed file is part of the packages it builds or runs, or of the
+// packages they depend on, rather than whenever any watched file changes, e.g. for a monorepo where several tasks watch
+// the same directory.
+type WatchPackages struct {
+	// The language of the packages, either "go" (using `go list`), or "node" (using package.json workspaces).
+	Language string `json:"language"`
+	// The packages, relative to the task's working directory, e.g. "./cmd/api" for Go, or "packages/api" for Node.
+	// Defaults to the package in the working directory.
+	Packages Strings `json:"packages,omitempty"`
+}
+
+// Validate returns an error if the watch packages are not valid.
+func (w WatchPackages) Validate() error {
+	switch w.Language {
+	case "go", "node":
+		return nil
+	default:
+		return fmt.Errorf("invalid language %q, must be go or node", w.Language)
+	}
+}
+
+// GetPackages returns the packages, defaulting to the one in the working directory.
+func (w WatchPackages) GetPackages() []string {
+	if len(w.Packages) == 0 {
+		return []string{"."}
+	}
+	return w.Packages
+}
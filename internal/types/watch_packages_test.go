@@ -0,0 +1,14 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchPackages(t *testing.T) {
+	assert.NoError(t, WatchPackages{Language: "go"}.Validate())
+	assert.EqualError(t, WatchPackages{Language: "rust"}.Validate(), `invalid language "rust", must be go or node`)
+	assert.Equal(t, []string{"."}, WatchPackages{}.GetPackages())
+	assert.Equal(t, []string{"./cmd/api"}, WatchPackages{Packages: Strings{"./cmd/api"}}.GetPackages())
+}
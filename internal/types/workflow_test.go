@@ -29,7 +29,7 @@ func TestPod(t *testing.T) {
 	//
 	tasks := wf.Tasks["bar"]
 	assert.Equal(t, Strings{"sh", "-c", "echo bar"}, tasks.GetCommand())
-	assert.Equal(t, Strings{"baz", "qux"}, tasks.Dependencies)
+	assert.Equal(t, Strings{"baz", "qux"}, tasks.Dependencies.AllOf)
 }
 
 func TestPorts_Map(t *testing.T) {
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WorkingDir returns the working directory of the task, with the directory of the config file, dir, or the task's
+// root, prepended, so the workflow runs the same wherever kit is run from. A container's working directory is in the
+// container, so it is returned as it is.
+func (s Spec) WorkingDir(dir string, t Task) (string, error) {
+	if t.Image != "" {
+		if t.Root != "" {
+			return "", fmt.Errorf("has root %q, but runs in a container", t.Root)
+		}
+		return t.WorkingDir, nil
+	}
+	if t.Root != "" {
+		root, ok := s.Roots[t.Root]
+		if !ok {
+			return "", fmt.Errorf("has root %q, which is not one of the workflow's roots", t.Root)
+		}
+		dir = joinDir(dir, root)
+	}
+	return joinDir(dir, t.WorkingDir), nil
+}
+
+// ResolveWorkingDirs prepends the directory of the config file, dir, or the task's root, to the working directory of
+// each task.
+func (s *Spec) ResolveWorkingDirs(dir string) error {
+	for name, t := range s.Tasks {
+		workingDir, err := s.WorkingDir(dir, t)
+		if err != nil {
+			return fmt.Errorf("task %q %w", name, err)
+		}
+		t.WorkingDir = workingDir
+		s.Tasks[name] = t
+	}
+	return nil
+}
+
+// CheckWorkingDir returns an error if the working directory on the host does not exist, e.g. the repository of a root
+// is not checked out, rather than the task failing with a confusing error when it is run.
+func CheckWorkingDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("working directory %q does not exist", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check working directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("working directory %q is not a directory", dir)
+	}
+	return nil
+}
+
+// joinDir returns the path relative to the directory, unless it is absolute. A path in the current directory is left
+// as it is.
+func joinDir(dir, path string) string {
+	if filepath.IsAbs(path) || dir == "" || dir == "." {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
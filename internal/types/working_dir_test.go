@@ -0,0 +1,60 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpec_WorkingDir(t *testing.T) {
+	s := Spec{Roots: map[string]string{"frontend": "../frontend", "shared": "/src/shared"}}
+	for _, tt := range []struct {
+		name string
+		dir  string
+		task Task
+		want string
+	}{
+		{"Current directory", ".", Task{WorkingDir: "app"}, "app"},
+		{"Config file's directory", "../api", Task{}, "../api"},
+		{"Relative to config file", "../api", Task{WorkingDir: "cmd"}, "../api/cmd"},
+		{"Absolute", "../api", Task{WorkingDir: "/app"}, "/app"},
+		{"Root", "../api", Task{Root: "frontend", WorkingDir: "web"}, "../frontend/web"},
+		{"Absolute root", "../api", Task{Root: "shared"}, "/src/shared"},
+		{"Container", "../api", Task{Image: "nginx", WorkingDir: "app"}, "app"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.WorkingDir(tt.dir, tt.task)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	t.Run("Undefined root", func(t *testing.T) {
+		_, err := s.WorkingDir(".", Task{Root: "backend"})
+		assert.EqualError(t, err, `has root "backend", which is not one of the workflow's roots`)
+	})
+	t.Run("Root of a container", func(t *testing.T) {
+		_, err := s.WorkingDir(".", Task{Image: "nginx", Root: "frontend"})
+		assert.EqualError(t, err, `has root "frontend", but runs in a container`)
+	})
+}
+
+func TestSpec_ResolveWorkingDirs(t *testing.T) {
+	s := &Spec{Tasks: Tasks{"web": {WorkingDir: "app"}, "api": {Root: "backend"}}}
+	assert.EqualError(t, s.ResolveWorkingDirs("../web"), `task "api" has root "backend", which is not one of the workflow's roots`)
+	s.Roots = map[string]string{"backend": "../api"}
+	assert.NoError(t, s.ResolveWorkingDirs("../web"))
+	assert.Equal(t, "../web/app", s.Tasks["web"].WorkingDir)
+	assert.Equal(t, "../api", s.Tasks["api"].WorkingDir)
+}
+
+func TestCheckWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tasks.yaml")
+	assert.NoError(t, os.WriteFile(file, nil, 0o644))
+	assert.NoError(t, CheckWorkingDir(""))
+	assert.NoError(t, CheckWorkingDir(dir))
+	assert.EqualError(t, CheckWorkingDir(filepath.Join(dir, "missing")), `working directory "`+filepath.Join(dir, "missing")+`" does not exist`)
+	assert.EqualError(t, CheckWorkingDir(file), `working directory "`+file+`" is not a directory`)
+}
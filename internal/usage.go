@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/kitproj/kit/internal/proc"
+)
+
+// sampleUsage samples the usage every few seconds until the context is cancelled, when it reports nil usage.
+func sampleUsage(ctx context.Context, sampler proc.Sampler, report func(usage *proc.Usage)) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			report(nil)
+			return
+		case <-ticker.C:
+			// errors are transient, e.g. the process exited while we were listing processes
+			if usage, err := sampler.Usage(); err == nil {
+				report(usage)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// DefaultLocksDir returns the directory of the lock files that kit processes on this machine share.
+func DefaultLocksDir() (string, error) {
+	if dir := os.Getenv("KIT_LOCKS_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kit", "locks"), nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// LockFiles locks one of n lock files in the directory named after the key, so that at most n processes hold the lock at
+// the same time. It blocks until a lock is acquired, or the context is done. The lock is released by calling unlock, or
+// when the process exits.
+func LockFiles(ctx context.Context, dir, key string, n int) (unlock func(), err error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create locks directory: %w", err)
+	}
+	name := unsafeChars.ReplaceAllString(key, "_")
+	for {
+		for i := 0; i < n; i++ {
+			unlock, err := TryLockFile(filepath.Join(dir, fmt.Sprintf("%s.%d.lock", name, i)))
+			if err != nil || unlock != nil {
+				return unlock, err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// TryLockFile locks the file, creating it if it does not exist. If another process has locked it, unlock is nil. The
+// lock is released by calling unlock, or when the process exits.
+func TryLockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := tryLock(f)
+	if err != nil || !locked {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() { _ = f.Close() }, nil
+}
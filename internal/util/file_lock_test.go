@@ -0,0 +1,31 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockFiles(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	unlock1, err := LockFiles(ctx, dir, "my/key", 2)
+	assert.NoError(t, err)
+	unlock2, err := LockFiles(ctx, dir, "my/key", 2)
+	assert.NoError(t, err)
+
+	// both seats are taken
+	timeout, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	_, err = LockFiles(timeout, dir, "my/key", 2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlock1()
+	unlock3, err := LockFiles(ctx, dir, "my/key", 2)
+	assert.NoError(t, err)
+	unlock2()
+	unlock3()
+	assert.FileExists(t, dir+"/my_key.0.lock")
+}
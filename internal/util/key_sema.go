@@ -3,8 +3,6 @@ package util
 import (
 	"runtime"
 	"sync"
-
-	"golang.org/x/sync/semaphore"
 )
 
 type Semaphores struct {
@@ -19,12 +17,16 @@ func NewSemaphores(seatPerKey map[string]int) *Semaphores {
 	}
 }
 
-func (s Semaphores) Get(key string) *semaphore.Weighted {
-	seats, ok := s.seats[key]
-	if !ok {
-		seats = runtime.NumCPU()
+// Seats returns the number of seats of the semaphore, defaulting to the number of CPUs.
+func (s Semaphores) Seats(key string) int {
+	if seats, ok := s.seats[key]; ok {
+		return seats
 	}
-	actual, _ := s.values.LoadOrStore(key, semaphore.NewWeighted(int64(seats)))
-	mutex := actual.(*semaphore.Weighted)
+	return runtime.NumCPU()
+}
+
+func (s Semaphores) Get(key string) *PrioritySemaphore {
+	actual, _ := s.values.LoadOrStore(key, NewPrioritySemaphore(s.Seats(key)))
+	mutex := actual.(*PrioritySemaphore)
 	return mutex
 }
@@ -21,6 +21,6 @@ func TestSemas(t *testing.T) {
 		wg.Done()
 	}()
 	assert.False(t, ok)
-	sema.Release(1)
+	sema.Release()
 	wg.Wait()
 }
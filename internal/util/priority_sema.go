@@ -0,0 +1,81 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+// PrioritySemaphore is a semaphore that is acquired by its waiters in order of priority, highest first, and then in the
+// order they started waiting.
+type PrioritySemaphore struct {
+	mu      sync.Mutex
+	size    int
+	current int
+	waiters []*waiter
+}
+
+type waiter struct {
+	priority int
+	ready    chan struct{}
+}
+
+func NewPrioritySemaphore(size int) *PrioritySemaphore {
+	return &PrioritySemaphore{size: size}
+}
+
+// Acquire blocks until the semaphore is acquired, or the context is done.
+func (s *PrioritySemaphore) Acquire(ctx context.Context, priority int) error {
+	s.mu.Lock()
+	if s.current < s.size && len(s.waiters) == 0 {
+		s.current++
+		s.mu.Unlock()
+		return nil
+	}
+	w := &waiter{priority: priority, ready: make(chan struct{})}
+	i := 0
+	for i < len(s.waiters) && s.waiters[i].priority >= priority {
+		i++
+	}
+	s.waiters = append(s.waiters[:i], append([]*waiter{w}, s.waiters[i:]...)...)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// acquired at the same time as the context was done, so give it back
+			s.current--
+			s.notify()
+		default:
+			for i, x := range s.waiters {
+				if x == w {
+					s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+					break
+				}
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// Release releases the semaphore, so the next waiter can acquire it.
+func (s *PrioritySemaphore) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current--
+	s.notify()
+}
+
+// notify wakes waiters while there is room. Must be called holding the lock.
+func (s *PrioritySemaphore) notify() {
+	for len(s.waiters) > 0 && s.current < s.size {
+		w := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		s.current++
+		close(w.ready)
+	}
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrioritySemaphore(t *testing.T) {
+	t.Run("Highest priority first", func(t *testing.T) {
+		s := NewPrioritySemaphore(1)
+		assert.NoError(t, s.Acquire(context.Background(), 0))
+		var mu sync.Mutex
+		var order []int
+		wg := sync.WaitGroup{}
+		for i, priority := range []int{1, 3, 2} {
+			wg.Add(1)
+			go func(priority int) {
+				defer wg.Done()
+				assert.NoError(t, s.Acquire(context.Background(), priority))
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+				s.Release()
+			}(priority)
+			// wait for it to be queued, so the order of arrival is known
+			assert.Eventually(t, func() bool {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				return len(s.waiters) == i+1
+			}, time.Second, time.Millisecond)
+		}
+		s.Release()
+		wg.Wait()
+		assert.Equal(t, []int{3, 2, 1}, order)
+	})
+	t.Run("Same priority in the order they wait", func(t *testing.T) {
+		// e.g. a task that is re-run over and over waits behind the others, rather than starving them
+		s := NewPrioritySemaphore(1)
+		assert.NoError(t, s.Acquire(context.Background(), 0))
+		var mu sync.Mutex
+		var order []string
+		wg := sync.WaitGroup{}
+		acquire := func(name string) {
+			defer wg.Done()
+			assert.NoError(t, s.Acquire(context.Background(), 0))
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			s.Release()
+		}
+		for i, name := range []string{"a", "b"} {
+			wg.Add(1)
+			go acquire(name)
+			assert.Eventually(t, func() bool {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				return len(s.waiters) == i+1
+			}, time.Second, time.Millisecond)
+		}
+		s.Release()
+		wg.Add(1)
+		acquire("again")
+		wg.Wait()
+		assert.Equal(t, []string{"a", "b", "again"}, order)
+	})
+	t.Run("Context done", func(t *testing.T) {
+		s := NewPrioritySemaphore(1)
+		assert.NoError(t, s.Acquire(context.Background(), 0))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, s.Acquire(ctx, 0), context.Canceled)
+		assert.Empty(t, s.waiters)
+		s.Release()
+		assert.NoError(t, s.Acquire(context.Background(), 0))
+	})
+}
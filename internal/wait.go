@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kitproj/kit/internal/proc"
+)
+
+// waitFor blocks until the target is available, or the context is cancelled.
+func waitFor(ctx context.Context, workingDir, target string) error {
+	// a Kubernetes resource is watched, rather than polled
+	if resource, namespace, ok := resourceTarget(target); ok {
+		return proc.WaitForResource(ctx, resource, namespace)
+	}
+	for {
+		err := available(ctx, workingDir, target)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not available: %w", target, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// available checks if the target is available. The target is either a TCP address (e.g. tcp://localhost:5432),
+// a URL (e.g. https://localhost/healthz), or a file path.
+func available(ctx context.Context, workingDir, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "tcp":
+		conn, err := (&net.Dialer{Timeout: time.Second}).DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "http", "https":
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("%s", resp.Status)
+		}
+		return nil
+	default:
+		_, err := os.Stat(filepath.Join(workingDir, target))
+		return err
+	}
+}
+
+// resourceTarget returns the Kubernetes resource, and its namespace, if the target is one, e.g.
+// "k8s:deployment/backend?namespace=dev".
+func resourceTarget(target string) (string, string, bool) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "k8s" {
+		return "", "", false
+	}
+	return u.Opaque, u.Query().Get("namespace"), true
+}
+
+// validateWaitFor returns an error if the target can never be available, e.g. a Kubernetes resource without a name.
+func validateWaitFor(target string) error {
+	if _, err := url.Parse(target); err != nil {
+		return err
+	}
+	if resource, _, ok := resourceTarget(target); ok {
+		if kind, name, _ := strings.Cut(resource, "/"); kind == "" || name == "" {
+			return fmt.Errorf("%q must be k8s:kind/name, e.g. k8s:deployment/backend", target)
+		}
+	}
+	return nil
+}
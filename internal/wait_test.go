@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_available(t *testing.T) {
+	ctx := context.Background()
+	t.Run("File", func(t *testing.T) {
+		assert.NoError(t, available(ctx, "testdata", "marker"))
+		assert.Error(t, available(ctx, "testdata", "missing"))
+	})
+	t.Run("TCP", func(t *testing.T) {
+		l, err := net.Listen("tcp", "localhost:0")
+		assert.NoError(t, err)
+		defer l.Close()
+		assert.NoError(t, available(ctx, "", "tcp://"+l.Addr().String()))
+	})
+	t.Run("Kubernetes resource", func(t *testing.T) {
+		resource, namespace, ok := resourceTarget("k8s:deployment/backend?namespace=dev")
+		assert.True(t, ok)
+		assert.Equal(t, "deployment/backend", resource)
+		assert.Equal(t, "dev", namespace)
+		_, _, ok = resourceTarget("https://example.com")
+		assert.False(t, ok)
+
+		assert.NoError(t, validateWaitFor("k8s:statefulsets.apps/db"))
+		assert.EqualError(t, validateWaitFor("k8s:deployment"), `"k8s:deployment" must be k8s:kind/name, e.g. k8s:deployment/backend`)
+	})
+	t.Run("HTTP", func(t *testing.T) {
+		ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer ok.Close()
+		assert.NoError(t, available(ctx, "", ok.URL))
+		notFound := httptest.NewServer(http.NotFoundHandler())
+		defer notFound.Close()
+		assert.EqualError(t, available(ctx, "", notFound.URL), "404 Not Found")
+	})
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// packageInputs are the files the task's packages are built from. They're worked out when a file first changes, and
+// again after each change to one of them, as that may have changed what the packages depend on.
+type packageInputs struct {
+	mu         sync.Mutex
+	workingDir string
+	spec       types.WatchPackages
+	loaded     bool
+	// directories whose files are inputs, but not the files in their sub-directories (i.e. Go packages)
+	dirs map[string]bool
+	// directories whose files, including those in sub-directories, are inputs (i.e. Node packages)
+	trees []string
+	files map[string]bool
+}
+
+func newPackageInputs(workingDir string, spec types.WatchPackages) *packageInputs {
+	return &packageInputs{workingDir: workingDir, spec: spec}
+}
+
+// contains returns true if the file is one of the inputs.
+func (p *packageInputs) contains(ctx context.Context, file string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.loaded {
+		if err := p.load(ctx); err != nil {
+			return false, err
+		}
+	}
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return false, err
+	}
+	ok := p.files[file] || p.dirs[filepath.Dir(file)]
+	for _, tree := range p.trees {
+		if rel, err := filepath.Rel(tree, file); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			ok = true
+		}
+	}
+	if ok {
+		p.loaded = false
+	}
+	return ok, nil
+}
+
+func (p *packageInputs) load(ctx context.Context) error {
+	p.dirs, p.trees, p.files = map[string]bool{}, nil, map[string]bool{}
+	workingDir, err := filepath.Abs(p.workingDir)
+	if err != nil {
+		return err
+	}
+	switch p.spec.Language {
+	case "go":
+		err = p.loadGo(ctx, workingDir)
+	case "node":
+		err = p.loadNode(workingDir)
+	default:
+		err = fmt.Errorf("unknown language %q", p.spec.Language)
+	}
+	if err != nil {
+		return err
+	}
+	p.loaded = true
+	return nil
+}
+
+// loadGo lists the non-standard packages the packages depend on, their embedded files, and their modules' go.mod files.
+func (p *packageInputs) loadGo(ctx context.Context, workingDir string) error {
+	const format = `{{if not .Standard}}{{.Dir}}
+{{$dir := .Dir}}{{range .EmbedFiles}}{{$dir}}/{{.}}
+{{end}}{{with .Module}}{{.GoMod}}
+{{end}}{{end}}`
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-deps", "-test", "-f", format}, p.spec.GetPackages()...)...)
+	cmd.Dir = workingDir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to list Go packages: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		line = filepath.Clean(line)
+		if info, err := os.Stat(line); err == nil && info.IsDir() {
+			p.dirs[line] = true
+		} else {
+			p.files[line] = true
+		}
+	}
+	for file := range p.files {
+		if filepath.Base(file) == "go.mod" {
+			p.files[filepath.Join(filepath.Dir(file), "go.sum")] = true
+		}
+	}
+	return nil
+}
+
+type packageJSON struct {
+	Name                 string            `json:"name"`
+	Workspaces           []string          `json:"workspaces"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+func readPackageJSON(dir string) (*packageJSON, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &packageJSON{}
+	if err := json.Unmarshal(data, pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, "package.json"), err)
+	}
+	return pkg, nil
+}
+
+// loadNode finds the workspaces the packages depend on, directly or indirectly, using the package.json files.
+func (p *packageInputs) loadNode(workingDir string) error {
+	// the workspace root is the closest directory with workspaces in its package.json
+	root := workingDir
+	for {
+		if pkg, err := readPackageJSON(root); err == nil && len(pkg.Workspaces) > 0 {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			// not a workspace, so the packages only depend on themselves
+			root = ""
+			break
+		}
+		root = parent
+	}
+
+	workspaces := map[string]string{} // name -> dir
+	if root != "" {
+		pkg, err := readPackageJSON(root)
+		if err != nil {
+			return err
+		}
+		for _, pattern := range pkg.Workspaces {
+			dirs, err := filepath.Glob(filepath.Join(root, pattern))
+			if err != nil {
+				return fmt.Errorf("invalid workspace %q: %w", pattern, err)
+			}
+			for _, dir := range dirs {
+				if w, err := readPackageJSON(dir); err == nil && w.Name != "" {
+					workspaces[w.Name] = dir
+				}
+			}
+		}
+		p.files[filepath.Join(root, "package.json")] = true
+		p.files[filepath.Join(root, "package-lock.json")] = true
+	}
+
+	visited := map[string]bool{}
+	var visit func(dir string) error
+	visit = func(dir string) error {
+		if visited[dir] {
+			return nil
+		}
+		visited[dir] = true
+		p.trees = append(p.trees, dir)
+		pkg, err := readPackageJSON(dir)
+		if err != nil {
+			return err
+		}
+		for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.PeerDependencies, pkg.OptionalDependencies} {
+			for name := range deps {
+				if dir, ok := workspaces[name]; ok {
+					if err := visit(dir); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	for _, pkg := range p.spec.GetPackages() {
+		if err := visit(filepath.Join(workingDir, pkg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
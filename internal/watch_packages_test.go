@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+}
+
+func Test_packageInputs(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Go", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"go.mod":             "module example.com/m\n\ngo 1.22\n",
+			"cmd/api/main.go":    "package main\n\nimport _ \"example.com/m/lib\"\n\nfunc main() {}\n",
+			"cmd/worker/main.go": "package main\n\nfunc main() {}\n",
+			"lib/lib.go":         "package lib\n",
+			"lib/sub/sub.go":     "package sub\n",
+		})
+		inputs := newPackageInputs(dir, types.WatchPackages{Language: "go", Packages: types.Strings{"./cmd/api"}})
+		for file, want := range map[string]bool{
+			"cmd/api/main.go":    true,
+			"lib/lib.go":         true,
+			"go.mod":             true,
+			"go.sum":             true,
+			"cmd/worker/main.go": false,
+			"lib/sub/sub.go":     false,
+		} {
+			ok, err := inputs.contains(ctx, filepath.Join(dir, file))
+			assert.NoError(t, err)
+			assert.Equal(t, want, ok, file)
+		}
+	})
+	t.Run("Node", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"package.json":                 `{"workspaces": ["packages/*"]}`,
+			"packages/api/package.json":    `{"name": "api", "dependencies": {"ui-kit": "*", "left-pad": "1.0.0"}}`,
+			"packages/api/index.js":        "",
+			"packages/ui-kit/package.json": `{"name": "ui-kit"}`,
+			"packages/ui-kit/src/index.js": "",
+			"packages/web/package.json":    `{"name": "web"}`,
+			"packages/web/index.js":        "",
+		})
+		inputs := newPackageInputs(filepath.Join(dir, "packages/api"), types.WatchPackages{Language: "node"})
+		for file, want := range map[string]bool{
+			"packages/api/index.js":        true,
+			"packages/ui-kit/src/index.js": true,
+			"package.json":                 true,
+			"packages/web/index.js":        false,
+		} {
+			ok, err := inputs.contains(ctx, filepath.Join(dir, file))
+			assert.NoError(t, err)
+			assert.Equal(t, want, ok, file)
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		inputs := newPackageInputs(t.TempDir(), types.WatchPackages{Language: "node"})
+		_, err := inputs.contains(ctx, "index.js")
+		assert.Error(t, err)
+	})
+}
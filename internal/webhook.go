@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// notificationData is the data available to notification templates.
+type notificationData struct {
+	Workflow string `json:"workflow"`
+	Task     string `json:"task"`
+	Event    string `json:"event"`
+	Message  string `json:"message,omitempty"`
+}
+
+// body returns the request body for the notification.
+func (d notificationData) body(n types.Notification) ([]byte, error) {
+	if n.Template != "" {
+		tmpl, err := template.New("notification").Parse(n.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, d); err != nil {
+			return nil, fmt.Errorf("failed to execute template: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+	if n.IsSlack() {
+		text := fmt.Sprintf("[%s] task %q %s", d.Workflow, d.Task, d.Event)
+		if d.Message != "" {
+			text += ": " + d.Message
+		}
+		return json.Marshal(map[string]string{"text": text})
+	}
+	return json.Marshal(d)
+}
+
+// postNotification posts the notification to the webhook.
+func postNotification(ctx context.Context, n types.Notification, data notificationData) error {
+	body, err := data.body(n)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_postNotification(t *testing.T) {
+	data := notificationData{Workflow: "my-app", Task: "api", Event: "failed", Message: "exit code 1"}
+	t.Run("Default", func(t *testing.T) {
+		body, err := data.body(types.Notification{URL: "http://localhost"})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"workflow":"my-app","task":"api","event":"failed","message":"exit code 1"}`, string(body))
+	})
+	t.Run("Slack", func(t *testing.T) {
+		body, err := data.body(types.Notification{URL: "https://hooks.slack.com/services/T0/B0/X"})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"text":"[my-app] task \"api\" failed: exit code 1"}`, string(body))
+	})
+	t.Run("Template", func(t *testing.T) {
+		body, err := data.body(types.Notification{Template: "{{.Task}} {{.Event}}"})
+		assert.NoError(t, err)
+		assert.Equal(t, "api failed", string(body))
+	})
+	t.Run("Post", func(t *testing.T) {
+		var got string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			got = string(body)
+		}))
+		defer server.Close()
+		err := postNotification(context.Background(), types.Notification{URL: server.URL, Template: "{{.Message}}"}, data)
+		assert.NoError(t, err)
+		assert.Equal(t, "exit code 1", got)
+	})
+	t.Run("Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		err := postNotification(context.Background(), types.Notification{URL: server.URL}, data)
+		assert.EqualError(t, err, "unexpected status 500 Internal Server Error")
+	})
+}
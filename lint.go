@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kitproj/kit/internal/lint"
+)
+
+// lintFiles prints the problems found in the config files, returning an error if there are any, other than warnings.
+func lintFiles(files []string) error {
+	count := 0
+	for _, file := range files {
+		problems, err := lint.Lint(file)
+		if err != nil {
+			return err
+		}
+		for _, p := range problems {
+			if p.Line == 0 {
+				fmt.Printf("%s: %s\n", file, p)
+			} else {
+				fmt.Printf("%s:%s\n", file, p)
+			}
+			if !p.Warning {
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("found %d problem(s)", count)
+	}
+	return nil
+}
@@ -3,17 +3,20 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"runtime/debug"
+	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 
 	"github.com/kitproj/kit/internal"
 	"github.com/kitproj/kit/internal/types"
+	"golang.org/x/term"
 	"sigs.k8s.io/yaml"
 )
 
@@ -22,22 +25,62 @@ func init() {
 	log.SetFlags(0)
 }
 
+//go:embed schema/workflow.schema.json
+var schema []byte
+
+// commands are the words that are kit's commands, rather than the names of tasks, e.g. "kit lint"
+var commands = []string{"status", "stop", "restart", "faults", "debug", "schema", "lint", "logs", "list", "ports", "replay", "env", "run", "install", "uninstall"}
+
 func main() {
 	help := false
 	printVersion := false
-	configFile := ""
+	var files configFiles
 	tasksToSkip := ""
+	selector := ""
 	port := 0
 	openBrowser := false
 	rewrite := false
+	notify := false
+	junitReport := ""
+	summaryReport := ""
+	record := ""
+	timings := false
+	timestamps := ""
+	quiet := false
+	plain := false
+	output := ""
+	reporter := ""
+	namespace := ""
+	portOffset := -1
+	concurrency := 0
+	failFast := false
+	keepGoing := false
+	dryRun := false
 
 	flag.BoolVar(&help, "h", false, "print help and exit")
 	flag.BoolVar(&printVersion, "v", false, "print version and exit")
-	flag.StringVar(&configFile, "f", "tasks.yaml", "config file (default tasks.yaml)")
+	flag.Var(&files, "f", "config file, directory of config files (dir/... to search sub-directories at any depth), or URL (https:// or git::), may be repeated to run several workflows (default tasks.yaml)")
 	flag.StringVar(&tasksToSkip, "s", "", "tasks to skip (comma separated)")
+	flag.StringVar(&selector, "l", "", "run the tasks whose labels match the selector, e.g. tier=backend,lang=go")
 	flag.IntVar(&port, "p", 3000, "port to start UI on (default 3000, zero disables)")
 	flag.BoolVar(&openBrowser, "b", false, "open the UI in the browser (default false)")
 	flag.BoolVar(&rewrite, "w", false, "rewrite the config file")
+	flag.BoolVar(&notify, "n", false, "show a desktop notification when a task fails or recovers (default false)")
+	flag.StringVar(&junitReport, "junit", "", "write a JUnit XML report of the tasks to the file")
+	flag.StringVar(&summaryReport, "summary", "", "append a markdown summary of the tasks to the file (default $GITHUB_STEP_SUMMARY in CI)")
+	flag.StringVar(&timestamps, "t", "", "prefix task output with timestamps: rfc3339 or elapsed (overrides the workflow)")
+	flag.BoolVar(&timings, "timings", false, "print how long each task took, and the critical path, when kit exits (default true in CI)")
+	flag.BoolVar(&plain, "plain", false, "write plain lines, without colors or escape sequences (default true if stdout is not a terminal, except in CI)")
+	flag.BoolVar(&quiet, "q", false, "quiet, only show errors (i.e. stderr) of tasks without a logLevel (default false)")
+	flag.StringVar(&output, "o", "", "write the status of the tasks as json or yaml, rather than their logs")
+	flag.StringVar(&reporter, "reporter", "", "report the tasks' transitions and output with a reporter: "+strings.Join(internal.Reporters(), ", ")+" (default github in GitHub Actions, otherwise console)")
+	flag.StringVar(&namespace, "namespace", "", "namespace this instance, so two copies of the workflow can run side by side")
+	flag.IntVar(&portOffset, "port-offset", -1, "offset the ports of a namespaced instance by this (default derived from the namespace)")
+	flag.IntVar(&concurrency, "concurrency", 0, "the maximum number of jobs to run at the same time (overrides the workflow's maxParallel)")
+	flag.StringVar(&record, "record", "", "record the session (the config, logs, events, versions and environment, redacted) to a .tar.gz file, e.g. for a bug report")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the plan to run the tasks, without running anything")
+	flag.BoolVar(&failFast, "fail-fast", false, "exit as soon as any job fails, even if it would be restarted (default false)")
+	flag.BoolVar(&keepGoing, "keep-going", false, "keep running the tasks that do not depend on a failed task, rather than exiting when one fails (default false)")
 	flag.Parse()
 	taskNames := flag.Args()
 
@@ -47,27 +90,131 @@ func main() {
 	}
 
 	if printVersion {
-		info, _ := debug.ReadBuildInfo()
-		fmt.Printf("%v\n", info.Main.Version)
+		fmt.Printf("%v\n", kitVersion())
 		os.Exit(0)
 	}
 
 	err := func() error {
 
+		if len(files) == 0 {
+			files = configFiles{"tasks.yaml"}
+		}
+
+		// a task (or group) takes precedence over a command of the same name, e.g. a "lint" task, so a workflow's tasks
+		// are not shadowed by kit's commands
+		command := ""
+		if len(taskNames) > 0 && slices.Contains(commands, taskNames[0]) && !isTask(files, taskNames[0]) {
+			command = taskNames[0]
+		}
+
+		// a namespaced instance's ports, including the UI's, are offset, so they do not collide with other instances
+		offset := types.PortOffset(namespace)
+		if portOffset >= 0 {
+			offset = uint16(portOffset)
+		}
+		if namespace != "" && port != 0 {
+			port += int(offset)
+		}
+
+		// "kit status" prints the status of a running workflow
+		if len(taskNames) == 1 && command == "status" {
+			return printStatus(port, output)
+		}
+
+		// "kit stop <task>" and "kit restart <task>" stop or restart a task of a running workflow
+		if len(taskNames) == 2 && (command == "stop" || command == "restart") {
+			return internal.ControlTask(context.Background(), namespace, taskNames[0], taskNames[1])
+		}
+
+		// "kit faults on <task>" and "kit faults off <task>" turn the faults injected into a task's connections on or off
+		if len(taskNames) == 3 && command == "faults" && (taskNames[1] == "on" || taskNames[1] == "off") {
+			return internal.ControlTask(context.Background(), namespace, "faults-"+taskNames[1], taskNames[2])
+		}
+
+		// "kit debug" dumps the status of the tasks, and the stacks of kit's goroutines, of a running workflow to a file,
+		// so a hang can be reported
+		if len(taskNames) == 1 && command == "debug" {
+			path, err := internal.DebugDump(context.Background(), namespace)
+			if err != nil {
+				return err
+			}
+			fmt.Println(path)
+			return nil
+		}
+
+		// "kit schema" prints the JSON schema of the config file, for editors
+		if len(taskNames) == 1 && command == "schema" {
+			_, err := os.Stdout.Write(schema)
+			return err
+		}
+
 		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 		defer cancel()
 
-		wf := &types.Workflow{}
-
-		in, err := os.ReadFile(configFile)
+		expanded, err := files.expand(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", configFile, err)
+			return err
+		}
+
+		// "kit lint" validates the config files without running anything
+		if len(taskNames) == 1 && command == "lint" {
+			return lintFiles(paths(expanded))
+		}
+
+		// "kit install [tasks...]" runs the tasks as a service at login, e.g. for always-on databases
+		if len(taskNames) > 0 && command == "install" {
+			// there's no terminal to pick the tasks in
+			if len(taskNames) == 1 && selector == "" {
+				return fmt.Errorf("kit install needs the tasks to run")
+			}
+			if _, err := loadWorkflow(expanded); err != nil {
+				return err
+			}
+			// the service is run with the same flags, but the config files must be absolute
+			var args []string
+			flag.Visit(func(f *flag.Flag) {
+				if f.Name != "f" {
+					args = append(args, "-"+f.Name+"="+f.Value.String())
+				}
+			})
+			// remote config files are fetched by the service, so it picks up changes to them
+			for _, f := range files {
+				if internal.IsRemoteWorkflow(f) {
+					args = append(args, "-f", f)
+					continue
+				}
+				abs, err := filepath.Abs(f)
+				if err != nil {
+					return err
+				}
+				args = append(args, "-f", abs)
+			}
+			return internal.Install(log.Default(), ".", append(args, taskNames[1:]...))
+		}
+		// "kit logs --prune" deletes old log files, e.g. left behind by a long-running session
+		if len(taskNames) > 0 && command == "logs" {
+			if len(taskNames) != 2 || taskNames[1] != "--prune" {
+				return fmt.Errorf("usage: kit logs --prune")
+			}
+			wf, err := loadWorkflow(expanded)
+			if err != nil {
+				return err
+			}
+			return internal.PruneLogs(log.Default(), wf, namespace)
 		}
-		if err = yaml.UnmarshalStrict(in, wf); err != nil {
-			return fmt.Errorf("failed to parse %s: %w", configFile, err)
+		if len(taskNames) == 1 && command == "uninstall" {
+			return internal.Uninstall(log.Default(), ".")
 		}
 
 		if rewrite {
+			if len(expanded) != 1 {
+				return fmt.Errorf("can only rewrite one config file")
+			}
+			configFile := expanded[0].path
+			wf, err := readWorkflow(configFile)
+			if err != nil {
+				return err
+			}
 			out, err := yaml.Marshal(wf)
 			if err != nil {
 				return fmt.Errorf("failed to marshal %s: %w", configFile, err)
@@ -75,12 +222,119 @@ func main() {
 			return os.WriteFile(configFile, out, 0644)
 		}
 
+		wf, err := loadWorkflow(expanded)
+		if err != nil {
+			return err
+		}
+
+		if err := (*types.Spec)(wf).SetNamespace(namespace, offset); err != nil {
+			return err
+		}
+
+		// "kit list" prints the tasks, with their descriptions, so they can be discovered without reading the config file
+		if len(taskNames) == 1 && command == "list" {
+			return internal.ListTasks(os.Stdout, wf)
+		}
+
+		// "kit ports" prints the host ports the tasks use, and what is listening on each, e.g. to find what is in the way
+		if len(taskNames) == 1 && command == "ports" {
+			return internal.PrintPorts(os.Stdout, wf)
+		}
+
+		// "kit replay [--from 15:04:05] [--to 15:05:00] [session]" re-renders a past session, the last by default, in the UI
+		if len(taskNames) > 0 && command == "replay" {
+			flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+			from := flags.String("from", "", "only replay what happened from this time, e.g. 15:04:05")
+			to := flags.String("to", "", "only replay what happened until this time, e.g. 15:05:00")
+			if err := flags.Parse(taskNames[1:]); err != nil {
+				return err
+			}
+			if flags.NArg() > 1 {
+				return fmt.Errorf("usage: kit replay [--from time] [--to time] [session]")
+			}
+			return internal.Replay(ctx, log.Default(), wf, port, namespace, flags.Arg(0), *from, *to)
+		}
+
+		// "kit env <task>" prints the environment variables the task is run with
+		if len(taskNames) == 2 && command == "env" {
+			return internal.PrintEnv(os.Stdout, wf, taskNames[1])
+		}
+
 		// split the tasks on comma, but don't end up with a single entry of ""
 		split := strings.Split(tasksToSkip, ",")
 		if len(split) == 1 && split[0] == "" {
 			split = []string{}
 		}
 
+		// the tasks selected by their labels are run as if they were named
+		if selector != "" {
+			selected, err := wf.Tasks.Select(selector)
+			if err != nil {
+				return err
+			}
+			taskNames = append(taskNames, selected...)
+		}
+
+		// escape sequences corrupt output written to a file, but CI systems show them as colors
+		plain = plain || !term.IsTerminal(int(os.Stdout.Fd())) && os.Getenv("CI") != "true"
+
+		// with no tasks, pick one in the terminal, rather than running nothing
+		if len(taskNames) == 0 {
+			taskNames, err = internal.PickTasks(ctx, wf)
+			if err != nil {
+				return err
+			}
+		}
+
+		// "kit run <task>" runs the task to completion, and exits with its exit code
+		if len(taskNames) > 0 && command == "run" {
+			if len(taskNames) != 2 {
+				return fmt.Errorf("kit run takes one task, got %d", len(taskNames)-1)
+			}
+			return internal.RunTask(ctx, cancel, log.Default(), wf, taskNames[1], internal.WithQuiet(quiet), internal.WithPlain(plain), internal.WithTimestamps(timestamps), internal.WithNamespace(namespace))
+		}
+
+		if failFast && keepGoing {
+			return fmt.Errorf("--fail-fast and --keep-going cannot be used together")
+		}
+
+		if dryRun {
+			return internal.DryRun(os.Stdout, wf, taskNames, split)
+		}
+
+		opts := []internal.Option{
+			internal.WithDesktopNotifications(notify),
+			internal.WithJUnitReport(junitReport),
+			internal.WithTimestamps(timestamps),
+			internal.WithQuiet(quiet),
+			internal.WithPlain(plain),
+			internal.WithTerminalTitle(term.IsTerminal(int(os.Stdout.Fd()))),
+			internal.WithTerminalWidth(os.Stdout),
+			internal.WithOutput(output),
+			internal.WithReporter(reporter),
+			internal.WithNamespace(namespace),
+			internal.WithConcurrency(concurrency),
+			internal.WithFailFast(failFast),
+			internal.WithKeepGoing(keepGoing),
+			// the workflow is reloaded when the config files change, so editing a task does not require a restart
+			internal.WithReload(paths(expanded), func() (*types.Workflow, error) {
+				wf, err := loadWorkflow(expanded)
+				if err != nil {
+					return nil, err
+				}
+				return wf, (*types.Spec)(wf).SetNamespace(namespace, offset)
+			}),
+		}
+		if summaryReport != "" {
+			opts = append(opts, internal.WithSummaryReport(summaryReport))
+		}
+		if record != "" {
+			opts = append(opts, internal.WithRecording(record))
+		}
+		if timings {
+			opts = append(opts, internal.WithTimings(true))
+		}
+
 		return internal.RunSubgraph(
 			ctx,
 			cancel,
@@ -90,11 +344,20 @@ func main() {
 			wf,
 			taskNames,
 			split,
+			opts...,
 		)
 	}()
 
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		var taskErr *internal.TaskError
+		if errors.As(err, &taskErr) {
+			os.Exit(taskErr.ExitCode)
+		}
+		var failedErr *internal.FailedTasksError
+		if errors.As(err, &failedErr) {
+			os.Exit(failedErr.ExitCode)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/kitproj/kit/internal"
+)
+
+// printStatus prints the status of the tasks of the workflow running on the port, using the API of its UI.
+func printStatus(port int, output string) error {
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/dag", port))
+	if err != nil {
+		return fmt.Errorf("failed to get status, is kit running with the UI on port %d? %w", port, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get status: %s", resp.Status)
+	}
+	dag := internal.DAG[*internal.TaskNode]{}
+	if err := json.NewDecoder(resp.Body).Decode(&dag); err != nil {
+		return fmt.Errorf("failed to decode status: %w", err)
+	}
+	// the table is fitted to the terminal, rather than wrapping, but JSON and YAML are written as they are
+	var w io.Writer = os.Stdout
+	if output != "json" && output != "yaml" {
+		w = internal.FitToTerminal(os.Stdout)
+	}
+	return internal.WriteStatus(w, dag.Nodes, output)
+}
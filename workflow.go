@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/kitproj/kit/internal"
+	"github.com/kitproj/kit/internal/types"
+	"sigs.k8s.io/yaml"
+)
+
+// configFiles is a flag that can be repeated.
+type configFiles []string
+
+func (f *configFiles) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *configFiles) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// workflowFile is a config file, and the name of its workflow, which its tasks are prefixed with when it is merged with
+// others.
+type workflowFile struct {
+	path string
+	name string
+	// remote is true if the file was fetched, so path is its cached copy
+	remote bool
+}
+
+// dir returns the directory the workflow's paths, e.g. working directories, are relative to: the file's own, or the
+// current directory for a remote file, as its tasks are run in the user's repository, not in the cache.
+func (f workflowFile) dir() string {
+	if f.remote {
+		return "."
+	}
+	return filepath.Dir(f.path)
+}
+
+// expand expands any directories into the tasks.yaml file in it, and the tasks.yaml files of its sub-directories, and
+// fetches any remote files, returning their cached copies. A directory ending in "/..." is searched at any depth, and
+// its workflows are named after their path in it, e.g. "services/api", except its own, whose tasks are not prefixed.
+func (f configFiles) expand(ctx context.Context) ([]workflowFile, error) {
+	var files []workflowFile
+	add := func(file string, remote bool) error {
+		dir, err := filepath.Abs(filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		files = append(files, workflowFile{path: file, name: filepath.Base(dir), remote: remote})
+		return nil
+	}
+	for _, file := range f {
+		if internal.IsRemoteWorkflow(file) {
+			fetched, err := internal.FetchWorkflow(ctx, log.Default(), file)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(fetched, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if root, ok := strings.CutSuffix(filepath.ToSlash(file), "..."); ok {
+			discovered, err := discoverWorkflows(filepath.Clean(root))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, discovered...)
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil || !info.IsDir() {
+			if err := add(file, false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(file, "*", "tasks.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(file, "tasks.yaml")); err == nil {
+			matches = append([]string{filepath.Join(file, "tasks.yaml")}, matches...)
+		}
+		for _, match := range matches {
+			if err := add(match, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config files found in %s", f.String())
+	}
+	return files, nil
+}
+
+// discoverWorkflows walks the directory for tasks.yaml files, skipping hidden directories (e.g. .git) and
+// node_modules.
+func discoverWorkflows(root string) ([]workflowFile, error) {
+	var files []workflowFile
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "tasks.yaml" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == "." {
+			name = ""
+		}
+		files = append(files, workflowFile{path: file, name: name})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover config files in %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// paths returns the paths of the config files.
+func paths(files []workflowFile) []string {
+	var out []string
+	for _, f := range files {
+		out = append(out, f.path)
+	}
+	return out
+}
+
+func readWorkflow(configFile string) (*types.Workflow, error) {
+	wf := &types.Workflow{}
+	in, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configFile, err)
+	}
+	// an older kit fails to parse the fields it does not know, so the version is checked first, for a clearer error
+	minimum := struct {
+		MinKitVersion string `json:"minKitVersion"`
+	}{}
+	_ = yaml.Unmarshal(in, &minimum)
+	if err := types.CheckKitVersion(minimum.MinKitVersion, kitVersion()); err != nil {
+		return nil, fmt.Errorf("%s %w", configFile, err)
+	}
+	if err = yaml.UnmarshalStrict(in, wf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configFile, err)
+	}
+	return wf, nil
+}
+
+// loadWorkflow loads the workflow. If there is more than one, they're merged, and each is named after its directory.
+func loadWorkflow(files []workflowFile) (*types.Workflow, error) {
+	if len(files) == 1 {
+		wf, err := readWorkflow(files[0].path)
+		if err != nil {
+			return nil, err
+		}
+		if err := (*types.Spec)(wf).ApplyExtends(); err != nil {
+			return nil, fmt.Errorf("%s: %w", files[0].path, err)
+		}
+		(*types.Spec)(wf).ApplyTaskDefaults()
+		// working directories are relative to the config file, so it can be run from any directory, e.g. "kit -f ../api"
+		if err := (*types.Spec)(wf).ResolveWorkingDirs(files[0].dir()); err != nil {
+			return nil, fmt.Errorf("%s: %w", files[0].path, err)
+		}
+		return wf, nil
+	}
+	var workflows []types.NamedWorkflow
+	for _, file := range files {
+		wf, err := readWorkflow(file.path)
+		if err != nil {
+			return nil, err
+		}
+		// a task extends a task in its own workflow
+		if err := (*types.Spec)(wf).ApplyExtends(); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.path, err)
+		}
+		workflows = append(workflows, types.NamedWorkflow{Name: file.name, Dir: file.dir(), Workflow: wf})
+	}
+	return types.Merge(workflows)
+}
+
+// isTask returns true if the workflow has a task, or group, of the name. A workflow that cannot be loaded has none.
+func isTask(files configFiles, name string) bool {
+	expanded, err := files.expand(context.Background())
+	if err != nil {
+		return false
+	}
+	wf, err := loadWorkflow(expanded)
+	if err != nil {
+		return false
+	}
+	_, err = wf.Tasks.Expand([]string{name})
+	return err == nil
+}
+
+// kitVersion returns the version of kit, e.g. "v0.9.0", or "(devel)" if it was not installed from a tag.
+func kitVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	return info.Main.Version
+}